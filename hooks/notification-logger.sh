@@ -1,5 +1,6 @@
 #!/bin/bash
 # Notification Logger Hook for Claude Code
+# cct-hook-version: 1
 #
 # This hook captures notification events (permission requests and idle alerts)
 # and stores them in the CCT analytics database for engagement tracking.
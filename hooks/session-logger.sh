@@ -0,0 +1,187 @@
+#!/bin/bash
+# Session Logger Hook for Claude Code
+# cct-hook-version: 1
+#
+# This hook captures session lifecycle events (start and end) and stores them
+# in the CCT analytics database so session durations can be derived.
+#
+# Hook Type: SessionStart, SessionEnd
+# Input: JSON on stdin with session_id, hook_event_name, cwd, source (start) or reason (end)
+# Output: Silent (no stdout/stderr unless error)
+
+set -euo pipefail
+
+# Read JSON from stdin
+INPUT=$(cat)
+
+# Parse JSON fields using jq if available, otherwise use grep/sed
+if command -v jq &> /dev/null; then
+    SESSION_ID=$(echo "$INPUT" | jq -r '.session_id // empty')
+    EVENT=$(echo "$INPUT" | jq -r '.hook_event_name // empty')
+    CWD=$(echo "$INPUT" | jq -r '.cwd // empty')
+    SOURCE=$(echo "$INPUT" | jq -r '.source // empty')
+    REASON=$(echo "$INPUT" | jq -r '.reason // empty')
+else
+    # Fallback to basic parsing (less robust)
+    SESSION_ID=$(echo "$INPUT" | grep -o '"session_id":"[^"]*"' | cut -d'"' -f4 || echo "")
+    EVENT=$(echo "$INPUT" | grep -o '"hook_event_name":"[^"]*"' | cut -d'"' -f4 || echo "")
+    SOURCE=$(echo "$INPUT" | grep -o '"source":"[^"]*"' | cut -d'"' -f4 || echo "")
+    REASON=$(echo "$INPUT" | grep -o '"reason":"[^"]*"' | cut -d'"' -f4 || echo "")
+    CWD=""
+fi
+
+# Validate required fields
+if [[ -z "$SESSION_ID" ]] || [[ -z "$EVENT" ]]; then
+    # Silent failure - don't block Claude Code
+    exit 0
+fi
+
+# Map hook event to lifecycle event
+case "$EVENT" in
+    SessionStart) LIFECYCLE_EVENT="start" ;;
+    SessionEnd) LIFECYCLE_EVENT="end" ;;
+    *) exit 0 ;;
+esac
+
+# Get CWD from environment if not in JSON
+if [[ -z "$CWD" ]]; then
+    CWD=$(pwd)
+fi
+
+# Get git branch from working directory
+GIT_BRANCH=""
+if [[ -d "$CWD/.git" ]]; then
+    GIT_BRANCH=$(cd "$CWD" && git branch --show-current 2>/dev/null || echo "")
+fi
+
+# Generate friendly session name from session_id
+# Use a list of 25 South Park character names and hash the session_id to pick one
+SESSION_NAMES=(
+    "Cartman"
+    "Stan"
+    "Kyle"
+    "Kenny"
+    "Butters"
+    "Randy"
+    "Tweek"
+    "Craig"
+    "Token"
+    "Wendy"
+    "Sheila"
+    "Sharon"
+    "Chef"
+    "Mr-Garrison"
+    "Mr-Mackey"
+    "Jimmy"
+    "Timmy"
+    "Bebe"
+    "Clyde"
+    "Ike"
+    "PC-Principal"
+    "Towelie"
+    "Mr-Hankey"
+    "Big-Gay-Al"
+    "Satan"
+)
+
+# Generate a numeric hash from session_id to pick a name (modulo 25)
+if command -v cksum &> /dev/null; then
+    HASH=$(echo -n "$SESSION_ID" | cksum | cut -d' ' -f1)
+    INDEX=$((HASH % 25))
+else
+    # Fallback: use character values
+    HASH=0
+    for ((i=0; i<${#SESSION_ID} && i<8; i++)); do
+        CHAR="${SESSION_ID:$i:1}"
+        ASCII=$(printf '%d' "'$CHAR")
+        HASH=$((HASH + ASCII))
+    done
+    INDEX=$((HASH % 25))
+fi
+
+SESSION_NAME="${SESSION_NAMES[$INDEX]}"
+
+# Analytics server endpoint (HTTPS by default)
+# Use CCT_ANALYTICS_URL if set, otherwise default to https://localhost:3333
+BASE_URL="${CCT_ANALYTICS_URL:-https://localhost:3333}"
+LIFECYCLE_ENDPOINT="${BASE_URL}/api/sessions/lifecycle"
+
+# Read API key from .secret file if it exists
+API_KEY_FILE="${CCT_API_KEY_FILE:-$HOME/.claude/analytics/.secret}"
+API_KEY=""
+if [[ -f "$API_KEY_FILE" ]]; then
+    API_KEY=$(cat "$API_KEY_FILE")
+fi
+
+# Build JSON payload
+if command -v jq &> /dev/null; then
+    PAYLOAD=$(jq -n \
+        --arg session "$SESSION_ID" \
+        --arg sessionName "$SESSION_NAME" \
+        --arg event "$LIFECYCLE_EVENT" \
+        --arg source "$SOURCE" \
+        --arg reason "$REASON" \
+        --arg cwd "$CWD" \
+        --arg branch "$GIT_BRANCH" \
+        '{
+            session_id: $session,
+            session_name: $sessionName,
+            event: $event,
+            source: $source,
+            reason: $reason,
+            cwd: $cwd,
+            branch: $branch
+        }')
+else
+    # Fallback: basic JSON (escape issues possible)
+    PAYLOAD=$(cat <<EOF
+{
+  "session_id": "$SESSION_ID",
+  "session_name": "$SESSION_NAME",
+  "event": "$LIFECYCLE_EVENT",
+  "source": "$SOURCE",
+  "reason": "$REASON",
+  "cwd": "$CWD",
+  "branch": "$GIT_BRANCH"
+}
+EOF
+)
+fi
+
+# POST to lifecycle endpoint
+if command -v curl &> /dev/null; then
+    if [[ -n "$API_KEY" ]]; then
+        curl -X POST "$LIFECYCLE_ENDPOINT" \
+            -H "Content-Type: application/json" \
+            -H "Authorization: Bearer $API_KEY" \
+            -k \
+            -d "$PAYLOAD" \
+            &> /dev/null &
+    else
+        curl -X POST "$LIFECYCLE_ENDPOINT" \
+            -H "Content-Type: application/json" \
+            -k \
+            -d "$PAYLOAD" \
+            &> /dev/null &
+    fi
+elif command -v wget &> /dev/null; then
+    if [[ -n "$API_KEY" ]]; then
+        wget --quiet --post-data="$PAYLOAD" \
+            --header="Content-Type: application/json" \
+            --header="Authorization: Bearer $API_KEY" \
+            --no-check-certificate \
+            -O /dev/null \
+            "$LIFECYCLE_ENDPOINT" \
+            &> /dev/null &
+    else
+        wget --quiet --post-data="$PAYLOAD" \
+            --header="Content-Type: application/json" \
+            --no-check-certificate \
+            -O /dev/null \
+            "$LIFECYCLE_ENDPOINT" \
+            &> /dev/null &
+    fi
+fi
+
+# Exit successfully (don't block Claude Code)
+exit 0
@@ -1,5 +1,6 @@
 #!/bin/bash
 # Tool Usage Logger Hook for Claude Code
+# cct-hook-version: 1
 #
 # This hook captures all tool usage (Bash commands and Claude tool invocations)
 # and stores them in the CCT analytics database for tracking and analysis.
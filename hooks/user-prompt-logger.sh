@@ -1,5 +1,6 @@
 #!/bin/bash
 # User Prompt Logger Hook for Claude Code
+# cct-hook-version: 1
 #
 # This hook captures user prompts submitted to Claude Code and stores them
 # in the CCT analytics database for tracking and analysis.
@@ -1,6 +1,8 @@
 package analytics
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/fs"
@@ -75,6 +77,49 @@ func (ca *ConversationAnalyzer) LoadConversations(stateCalc *StateCalculator) ([
 	return conversations, nil
 }
 
+// conversationStateHorizon is how long after a file changes its computed
+// status and state can still change with the passage of time
+const conversationStateHorizon = 2 * time.Hour
+
+// ConversationsFingerprint returns a hash of the path, size and modification
+// time of every conversation file. It only stats files, so it is much cheaper
+// than LoadConversations. Since status and state depend on how long ago a file
+// changed, the fingerprint also changes each minute while any file was
+// modified within the last two hours.
+func (ca *ConversationAnalyzer) ConversationsFingerprint() (string, error) {
+	hash := sha256.New()
+	var newest time.Time
+
+	err := filepath.WalkDir(ca.claudeDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip errors
+		}
+
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".jsonl") {
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			fmt.Fprintf(hash, "%s\x00%d\x00%d\n", path, info.Size(), info.ModTime().UnixNano())
+			if info.ModTime().After(newest) {
+				newest = info.ModTime()
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	if time.Since(newest) < conversationStateHorizon {
+		fmt.Fprintf(hash, "minute:%d\n", time.Now().Unix()/60)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
 // parseConversationFile parses a single conversation file
 func (ca *ConversationAnalyzer) parseConversationFile(filePath string, stateCalc *StateCalculator) (Conversation, error) {
 	info, err := os.Stat(filePath)
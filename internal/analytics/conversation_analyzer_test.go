@@ -1,8 +1,10 @@
 package analytics
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestFormatBytes(t *testing.T) {
@@ -217,3 +219,52 @@ func TestConversationAnalyzer_parseMessages(t *testing.T) {
 		})
 	}
 }
+
+func TestConversationAnalyzer_ConversationsFingerprint(t *testing.T) {
+	claudeDir := t.TempDir()
+	ca := NewConversationAnalyzer(claudeDir)
+
+	// Old files keep the fingerprint stable over time
+	old := time.Now().Add(-3 * time.Hour)
+	convPath := filepath.Join(claudeDir, "project", "conv.jsonl")
+	if err := os.MkdirAll(filepath.Dir(convPath), 0755); err != nil {
+		t.Fatalf("Failed to create project dir: %v", err)
+	}
+	if err := os.WriteFile(convPath, []byte(`{"type":"user"}`+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write conversation: %v", err)
+	}
+	if err := os.Chtimes(convPath, old, old); err != nil {
+		t.Fatalf("Failed to set mtime: %v", err)
+	}
+
+	first, err := ca.ConversationsFingerprint()
+	if err != nil {
+		t.Fatalf("ConversationsFingerprint() error = %v", err)
+	}
+	second, err := ca.ConversationsFingerprint()
+	if err != nil {
+		t.Fatalf("ConversationsFingerprint() error = %v", err)
+	}
+	if first != second {
+		t.Errorf("fingerprint changed without file changes: %s != %s", first, second)
+	}
+
+	// Non-conversation files are ignored
+	if err := os.WriteFile(filepath.Join(claudeDir, "notes.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if got, _ := ca.ConversationsFingerprint(); got != first {
+		t.Error("fingerprint changed for a non-conversation file")
+	}
+
+	// Appending to a conversation changes the fingerprint
+	if err := os.WriteFile(convPath, []byte(`{"type":"user"}`+"\n"+`{"type":"assistant"}`+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write conversation: %v", err)
+	}
+	if err := os.Chtimes(convPath, old, old); err != nil {
+		t.Fatalf("Failed to set mtime: %v", err)
+	}
+	if got, _ := ca.ConversationsFingerprint(); got == first {
+		t.Error("fingerprint did not change after a conversation grew")
+	}
+}
@@ -3,6 +3,7 @@ package analytics
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
 	"sync"
 	"time"
@@ -10,6 +11,15 @@ import (
 	"github.com/fsnotify/fsnotify"
 )
 
+const (
+	// refreshDebounce is how long the watcher waits for .jsonl writes to settle
+	// before refreshing, so a burst of writes triggers a single refresh
+	refreshDebounce = 100 * time.Millisecond
+
+	// refreshMaxDelay caps how long a steady stream of writes can postpone a refresh
+	refreshMaxDelay = time.Second
+)
+
 // FileWatcher handles file system watching for real-time updates.
 // It monitors .jsonl files in the Claude directory and triggers callbacks on changes.
 // Safe for concurrent use.
@@ -54,7 +64,8 @@ func NewFileWatcherWithOptions(claudeDir string, refreshCallback func() error, q
 }
 
 // Start begins watching for file changes.
-// It spawns a goroutine for event watching on .jsonl files.
+// It spawns a goroutine for event watching on .jsonl files in the directory
+// and its immediate subdirectories (e.g. one per project under ~/.claude/projects).
 func (fw *FileWatcher) Start() error {
 	// Add the Claude directory to watch
 	err := fw.watcher.Add(fw.claudeDir)
@@ -62,6 +73,16 @@ func (fw *FileWatcher) Start() error {
 		return fmt.Errorf("failed to watch directory: %w", err)
 	}
 
+	// fsnotify is not recursive: watch existing subdirectories too, new ones
+	// are added by watchLoop as they appear
+	if entries, err := os.ReadDir(fw.claudeDir); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() {
+				fw.addDir(filepath.Join(fw.claudeDir, entry.Name()))
+			}
+		}
+	}
+
 	// Watch subdirectories for .jsonl files
 	if !fw.quiet {
 		pattern := filepath.Join(fw.claudeDir, "**/*.jsonl")
@@ -80,9 +101,16 @@ func (fw *FileWatcher) Start() error {
 }
 
 // watchLoop handles file system events until context is cancelled.
+// Changes are coalesced: the refresh runs once writes have been quiet for
+// refreshDebounce, or refreshMaxDelay after the first pending change.
 func (fw *FileWatcher) watchLoop() {
 	defer fw.wg.Done()
 
+	debounce := time.NewTimer(refreshDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+	var pendingSince time.Time
+
 	for {
 		select {
 		case <-fw.ctx.Done():
@@ -93,20 +121,33 @@ func (fw *FileWatcher) watchLoop() {
 				return
 			}
 
-			// Only trigger on .jsonl file changes
-			if filepath.Ext(event.Name) == ".jsonl" {
-				if event.Op&fsnotify.Write == fsnotify.Write ||
-					event.Op&fsnotify.Create == fsnotify.Create {
-					// Debounce: wait a bit to avoid multiple rapid refreshes
-					select {
-					case <-time.After(100 * time.Millisecond):
-						fw.triggerRefresh()
-					case <-fw.ctx.Done():
-						return
-					}
+			// Watch subdirectories created after Start
+			if event.Op&fsnotify.Create == fsnotify.Create && filepath.Dir(event.Name) == filepath.Clean(fw.claudeDir) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					fw.addDir(event.Name)
+					continue
 				}
 			}
 
+			// Only trigger on .jsonl file changes
+			if filepath.Ext(event.Name) != ".jsonl" ||
+				(event.Op&fsnotify.Write != fsnotify.Write && event.Op&fsnotify.Create != fsnotify.Create) {
+				continue
+			}
+
+			if pendingSince.IsZero() {
+				pendingSince = time.Now()
+			}
+			wait := refreshDebounce
+			if remaining := refreshMaxDelay - time.Since(pendingSince); remaining < wait {
+				wait = max(remaining, 0)
+			}
+			debounce.Reset(wait)
+
+		case <-debounce.C:
+			pendingSince = time.Time{}
+			fw.triggerRefresh()
+
 		case err, ok := <-fw.watcher.Errors:
 			if !ok {
 				return
@@ -118,6 +159,13 @@ func (fw *FileWatcher) watchLoop() {
 	}
 }
 
+// addDir adds a directory to the watch list, reporting failures unless quiet
+func (fw *FileWatcher) addDir(dir string) {
+	if err := fw.watcher.Add(dir); err != nil && !fw.quiet {
+		fmt.Printf("⚠️  Failed to watch %s: %v\n", dir, err)
+	}
+}
+
 // periodicRefresh triggers periodic data refreshes every 2 minutes.
 func (fw *FileWatcher) periodicRefresh() {
 	defer fw.wg.Done()
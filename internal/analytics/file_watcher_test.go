@@ -2,6 +2,7 @@ package analytics
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
@@ -286,6 +287,150 @@ func TestFileWatcher_FileChange(t *testing.T) {
 	}
 }
 
+func TestFileWatcher_CoalescesBurst(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	callCount := 0
+	var mu sync.Mutex
+
+	callback := func() error {
+		mu.Lock()
+		callCount++
+		mu.Unlock()
+		return nil
+	}
+
+	fw, err := NewFileWatcherWithOptions(tmpDir, callback, true)
+	if err != nil {
+		t.Fatalf("NewFileWatcher failed: %v", err)
+	}
+	defer fw.Stop()
+
+	fw.Start()
+	time.Sleep(100 * time.Millisecond)
+
+	// A burst of writes to several files settles into one refresh
+	for i := 0; i < 20; i++ {
+		name := filepath.Join(tmpDir, fmt.Sprintf("session-%d.jsonl", i%3))
+		if err := os.WriteFile(name, []byte("{}\n"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(refreshDebounce + 200*time.Millisecond)
+
+	mu.Lock()
+	count := callCount
+	mu.Unlock()
+
+	if count != 1 {
+		t.Errorf("Expected burst to trigger 1 refresh, got %d", count)
+	}
+}
+
+func TestFileWatcher_SteadyWritesStillRefresh(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	refreshed := make(chan struct{}, 10)
+	callback := func() error {
+		refreshed <- struct{}{}
+		return nil
+	}
+
+	fw, err := NewFileWatcherWithOptions(tmpDir, callback, true)
+	if err != nil {
+		t.Fatalf("NewFileWatcher failed: %v", err)
+	}
+	defer fw.Stop()
+
+	fw.Start()
+	time.Sleep(100 * time.Millisecond)
+
+	// Writes arriving faster than the debounce must not postpone the refresh forever
+	name := filepath.Join(tmpDir, "session.jsonl")
+	deadline := time.After(refreshMaxDelay + time.Second)
+	ticker := time.NewTicker(refreshDebounce / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-refreshed:
+			return
+		case <-deadline:
+			t.Fatal("Expected a refresh while writes kept arriving")
+		case <-ticker.C:
+			if err := os.WriteFile(name, []byte("{}\n"), 0644); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+		}
+	}
+}
+
+func TestFileWatcher_SubdirectoryChange(t *testing.T) {
+	tmpDir := t.TempDir()
+	existingDir := filepath.Join(tmpDir, "existing-project")
+	if err := os.Mkdir(existingDir, 0755); err != nil {
+		t.Fatalf("Failed to create project dir: %v", err)
+	}
+
+	callCount := 0
+	var mu sync.Mutex
+
+	callback := func() error {
+		mu.Lock()
+		callCount++
+		mu.Unlock()
+		return nil
+	}
+
+	fw, err := NewFileWatcherWithOptions(tmpDir, callback, true)
+	if err != nil {
+		t.Fatalf("NewFileWatcher failed: %v", err)
+	}
+	defer fw.Stop()
+
+	if err := fw.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	waitForCalls := func(want int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			mu.Lock()
+			count := callCount
+			mu.Unlock()
+			if count >= want {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("Expected at least %d callbacks", want)
+	}
+
+	// A project directory that existed before Start
+	if err := os.WriteFile(filepath.Join(existingDir, "a.jsonl"), []byte("{}"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	waitForCalls(1)
+
+	// A project directory created after Start
+	newDir := filepath.Join(tmpDir, "new-project")
+	if err := os.Mkdir(newDir, 0755); err != nil {
+		t.Fatalf("Failed to create project dir: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	before := callCount
+	mu.Unlock()
+
+	if err := os.WriteFile(filepath.Join(newDir, "b.jsonl"), []byte("{}"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	waitForCalls(before + 1)
+}
+
 func TestFileWatcher_NonJsonlFileIgnored(t *testing.T) {
 	tmpDir := t.TempDir()
 
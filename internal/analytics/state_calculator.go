@@ -6,6 +6,7 @@ package analytics
 import (
 	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -51,6 +52,7 @@ type RunningProcess struct {
 // StateCalculator handles conversation state determination logic
 type StateCalculator struct {
 	processCache map[string]interface{}
+	mu           sync.Mutex // Protects processCache
 }
 
 // NewStateCalculator creates a new StateCalculator
@@ -284,6 +286,8 @@ func (sc *StateCalculator) GetStateClass(conversationState string) string {
 
 // ClearCache clears any cached state information
 func (sc *StateCalculator) ClearCache() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
 	sc.processCache = make(map[string]interface{})
 }
 
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/schlunsen/claude-agent-sdk-go/types"
+	"github.com/schlunsen/claude-control-terminal/internal/database"
+	"github.com/schlunsen/claude-control-terminal/internal/server"
+	"github.com/schlunsen/claude-control-terminal/internal/server/agents"
+)
+
+// resumeResponseTimeout bounds how long we wait between streamed messages
+const resumeResponseTimeout = 5 * time.Minute
+
+// handleResumeAgent finds the most recently updated active/idle agent session,
+// prints its ID and last message, and optionally sends a follow-up prompt.
+func handleResumeAgent() {
+	claudeDir := resolveClaudeDir()
+
+	db, err := database.Initialize(filepath.Join(claudeDir, "cct"))
+	if err != nil {
+		ShowError(fmt.Sprintf("Failed to open database: %v", err))
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	config, err := server.NewConfigManager(claudeDir).LoadOrCreateConfig()
+	if err != nil {
+		ShowError(fmt.Sprintf("Failed to load config: %v", err))
+		os.Exit(1)
+	}
+
+	apiKey := os.Getenv("ANTHROPIC_API_KEY")
+	if apiKey == "" {
+		apiKey = os.Getenv("CLAUDE_API_KEY")
+	}
+
+	sm, err := agents.NewSessionManager(&agents.Config{
+		Model:                 config.Agent.Model,
+		APIKey:                apiKey,
+		MaxConcurrentSessions: config.Agent.MaxConcurrentSessions,
+		Verbose:               verbose,
+	}, db.GetDB())
+	if err != nil {
+		ShowError(fmt.Sprintf("Failed to initialize session manager: %v", err))
+		os.Exit(1)
+	}
+
+	session, err := findLatestResumableSession(sm)
+	if err != nil {
+		ShowError(err.Error())
+		os.Exit(1)
+	}
+
+	ShowInfo(fmt.Sprintf("Session: %s (status: %s, updated: %s)",
+		session.ID, session.Status, session.UpdatedAt.Format("2006-01-02 15:04:05")))
+	if session.Options.WorkingDirectory != nil && *session.Options.WorkingDirectory != "" {
+		ShowInfo(fmt.Sprintf("Working directory: %s", *session.Options.WorkingDirectory))
+	}
+
+	lastMsg, err := sm.GetLastMessage(session.ID)
+	if err != nil {
+		ShowWarning(fmt.Sprintf("Failed to load last message: %v", err))
+	} else if lastMsg == nil {
+		ShowInfo("No messages in this session yet")
+	} else {
+		fmt.Printf("\n[%s] %s\n\n", lastMsg.Role, lastMsg.Content)
+	}
+
+	if continuePrompt == "" {
+		return
+	}
+
+	if err := streamPromptToStdout(sm, session.ID, continuePrompt); err != nil {
+		ShowError(err.Error())
+		os.Exit(1)
+	}
+}
+
+// findLatestResumableSession returns the most recently updated session that is active or idle
+func findLatestResumableSession(sm *agents.SessionManager) (*agents.Session, error) {
+	sessions, err := sm.ListAllSessions("active")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	// Sessions are ordered by updated_at DESC
+	for i := range sessions {
+		if sessions[i].Status == agents.SessionStatusActive || sessions[i].Status == agents.SessionStatusIdle {
+			return &sessions[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("no active or idle agent sessions found")
+}
+
+// streamPromptToStdout sends a prompt to the session and prints assistant text
+// as it arrives, returning once the result message is received.
+func streamPromptToStdout(sm *agents.SessionManager, sessionID uuid.UUID, prompt string) error {
+	responses, err := sm.GetResponseChannel(sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get response channel: %w", err)
+	}
+
+	if err := sm.SendPrompt(sessionID, prompt); err != nil {
+		return fmt.Errorf("failed to send prompt: %w", err)
+	}
+
+	for {
+		select {
+		case msg := <-responses:
+			switch m := msg.(type) {
+			case *types.AssistantMessage:
+				for _, block := range m.Content {
+					if textBlock, ok := block.(*types.TextBlock); ok {
+						fmt.Println(textBlock.Text)
+					}
+				}
+			case *types.ResultMessage:
+				if m.IsError {
+					if m.Result != nil {
+						return fmt.Errorf("agent returned an error: %s", strings.TrimSpace(*m.Result))
+					}
+					return fmt.Errorf("agent returned an error")
+				}
+				if m.TotalCostUSD != nil {
+					ShowSuccess(fmt.Sprintf("Done (%d turns, $%.4f)", m.NumTurns, *m.TotalCostUSD))
+				} else {
+					ShowSuccess(fmt.Sprintf("Done (%d turns)", m.NumTurns))
+				}
+				return nil
+			}
+		case <-time.After(resumeResponseTimeout):
+			return fmt.Errorf("timed out waiting for agent response")
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/schlunsen/claude-control-terminal/internal/database"
+)
+
+// handleDBCheck runs an integrity check on the CCT database, reports orphaned
+// agent messages and, with --repair, deletes them and rebuilds indexes.
+func handleDBCheck() {
+	db, err := database.Initialize(filepath.Join(resolveClaudeDir(), "cct"))
+	if err != nil {
+		ShowError(fmt.Sprintf("Failed to open database: %v", err))
+		ShowInfo("The database may be too damaged to open; restore it from a backup or move it aside to start fresh")
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	ShowInfo(fmt.Sprintf("Checking database: %s", db.Path()))
+
+	report, err := db.Check(repairDB)
+	if err != nil {
+		ShowError(fmt.Sprintf("Database check failed: %v", err))
+		os.Exit(1)
+	}
+
+	if report.IntegrityOK {
+		ShowSuccess("Integrity check: ok")
+	} else {
+		ShowError(fmt.Sprintf("Integrity check: %d problem(s) found", len(report.IntegrityErrors)))
+		for _, problem := range report.IntegrityErrors {
+			fmt.Printf("  - %s\n", problem)
+		}
+	}
+
+	if report.OrphanedMessages == 0 {
+		ShowSuccess("Orphaned agent messages: none")
+	} else {
+		ShowWarning(fmt.Sprintf("Orphaned agent messages: %d", report.OrphanedMessages))
+	}
+
+	if report.Repaired {
+		ShowSuccess(fmt.Sprintf("Repair: deleted %d orphaned message(s) and rebuilt indexes", report.DeletedMessages))
+	} else if report.OrphanedMessages > 0 {
+		ShowInfo("Run with --repair to delete orphaned messages and rebuild indexes")
+	}
+
+	if !report.IntegrityOK {
+		os.Exit(1)
+	}
+}
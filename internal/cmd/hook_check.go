@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/schlunsen/claude-control-terminal/internal/components"
+)
+
+// handleCheckHooks reports hook entries in the project and global settings
+// whose scripts are missing or not executable and, with --prune, removes them.
+func handleCheckHooks() {
+	projectDir, err := filepath.Abs(directory)
+	if err != nil {
+		ShowError(fmt.Sprintf("Failed to resolve project directory: %v", err))
+		os.Exit(1)
+	}
+	homeDir, _ := os.UserHomeDir()
+
+	paths := components.HookSettingsPaths(projectDir, filepath.Join(homeDir, ".claude"))
+	issues, err := components.CheckHookScripts(paths, projectDir)
+	if err != nil {
+		ShowError(fmt.Sprintf("Hook check failed: %v", err))
+		os.Exit(1)
+	}
+
+	if len(issues) == 0 {
+		ShowSuccess("All hook scripts exist and are executable")
+		return
+	}
+
+	ShowWarning(fmt.Sprintf("Dangling hook entries: %d", len(issues)))
+	for _, issue := range issues {
+		note := ""
+		if !issue.Certain {
+			note = " (not found on PATH; left for manual review)"
+		}
+		fmt.Printf("  - %s [%s] %s: %s%s\n", issue.SettingsPath, issue.Event, issue.ScriptPath, issue.Problem, note)
+	}
+
+	if !pruneHooks {
+		ShowInfo("Run with --prune to remove these entries from their settings files")
+		os.Exit(1)
+	}
+
+	removed, err := components.PruneHookIssues(issues)
+	if err != nil {
+		ShowError(fmt.Sprintf("Failed to prune hooks: %v", err))
+		os.Exit(1)
+	}
+	ShowSuccess(fmt.Sprintf("Pruned %d hook entries", removed))
+}
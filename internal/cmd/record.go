@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/schlunsen/claude-control-terminal/internal/database"
+	"github.com/spf13/cobra"
+)
+
+// Flags shared by the record-* subcommands
+var (
+	recordSessionID   string
+	recordSessionName string
+	recordCWD         string
+	recordBranch      string
+	recordProvider    string
+	recordModel       string
+	recordDurationMs  int
+
+	// record-prompt
+	recordPromptText string
+
+	// record-shell
+	recordShellCommand     string
+	recordShellDescription string
+	recordShellExitCode    int
+	recordShellStdout      string
+	recordShellStderr      string
+
+	// record-claude
+	recordToolName   string
+	recordParameters string
+	recordResult     string
+	recordSuccess    bool
+	recordErrorMsg   string
+)
+
+var recordPromptCmd = &cobra.Command{
+	Use:   "record-prompt",
+	Short: "Record a user prompt in the history database without the analytics server",
+	Run: func(cmd *cobra.Command, args []string) {
+		if recordPromptText == "" {
+			ShowError("--prompt is required")
+			os.Exit(1)
+		}
+
+		withRecordRepository(func(repo *database.Repository) error {
+			msg := &database.UserMessage{
+				ConversationID:   recordSessionID,
+				SessionName:      recordSessionName,
+				Message:          recordPromptText,
+				WorkingDirectory: recordWorkingDirectory(),
+				GitBranch:        recordBranch,
+				ModelProvider:    orUnknown(recordProvider),
+				ModelName:        orUnknown(recordModel),
+				MessageLength:    len(recordPromptText),
+				SubmittedAt:      time.Now(),
+			}
+			if err := repo.RecordUserMessage(msg); err != nil {
+				return err
+			}
+			ShowSuccess(fmt.Sprintf("Recorded prompt %d (%d bytes)", msg.ID, msg.MessageLength))
+			return nil
+		})
+	},
+}
+
+var recordShellCmd = &cobra.Command{
+	Use:   "record-shell",
+	Short: "Record a shell command in the history database without the analytics server",
+	Run: func(cmd *cobra.Command, args []string) {
+		if recordShellCommand == "" {
+			ShowError("--command is required")
+			os.Exit(1)
+		}
+
+		withRecordRepository(func(repo *database.Repository) error {
+			shellCmd := &database.ShellCommand{
+				ConversationID:   recordSessionID,
+				SessionName:      recordSessionName,
+				Command:          recordShellCommand,
+				Description:      recordShellDescription,
+				WorkingDirectory: recordWorkingDirectory(),
+				GitBranch:        recordBranch,
+				ModelProvider:    orUnknown(recordProvider),
+				ModelName:        orUnknown(recordModel),
+				Stdout:           recordShellStdout,
+				Stderr:           recordShellStderr,
+				DurationMs:       recordDuration(cmd),
+				ExecutedAt:       time.Now(),
+			}
+			if cmd.Flags().Changed("exit-code") {
+				shellCmd.ExitCode = &recordShellExitCode
+			}
+			if err := repo.RecordShellCommand(shellCmd); err != nil {
+				return err
+			}
+			ShowSuccess(fmt.Sprintf("Recorded shell command %d", shellCmd.ID))
+			return nil
+		})
+	},
+}
+
+var recordClaudeCmd = &cobra.Command{
+	Use:   "record-claude",
+	Short: "Record a Claude tool invocation in the history database without the analytics server",
+	Run: func(cmd *cobra.Command, args []string) {
+		if recordToolName == "" {
+			ShowError("--tool is required")
+			os.Exit(1)
+		}
+
+		withRecordRepository(func(repo *database.Repository) error {
+			claudeCmd := &database.ClaudeCommand{
+				ConversationID:   recordSessionID,
+				SessionName:      recordSessionName,
+				ToolName:         recordToolName,
+				Parameters:       recordParameters,
+				Result:           recordResult,
+				WorkingDirectory: recordWorkingDirectory(),
+				GitBranch:        recordBranch,
+				ModelProvider:    orUnknown(recordProvider),
+				ModelName:        orUnknown(recordModel),
+				Success:          recordSuccess,
+				ErrorMessage:     recordErrorMsg,
+				DurationMs:       recordDuration(cmd),
+				ExecutedAt:       time.Now(),
+			}
+			if err := repo.RecordClaudeCommand(claudeCmd); err != nil {
+				return err
+			}
+			ShowSuccess(fmt.Sprintf("Recorded %s command %d", claudeCmd.ToolName, claudeCmd.ID))
+			return nil
+		})
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{recordPromptCmd, recordShellCmd, recordClaudeCmd} {
+		c.Flags().StringVar(&recordSessionID, "session-id", "", "conversation/session ID to record under (required)")
+		c.Flags().StringVar(&recordSessionName, "session-name", "", "optional session name")
+		c.Flags().StringVar(&recordCWD, "cwd", "", "working directory (default: current directory)")
+		c.Flags().StringVar(&recordBranch, "branch", "", "git branch")
+		c.Flags().StringVar(&recordProvider, "model-provider", "", "model provider (default: Unknown)")
+		c.Flags().StringVar(&recordModel, "model-name", "", "model name (default: Unknown)")
+		c.MarkFlagRequired("session-id")
+		rootCmd.AddCommand(c)
+	}
+
+	recordPromptCmd.Flags().StringVar(&recordPromptText, "prompt", "", "prompt text to record (required)")
+
+	recordShellCmd.Flags().StringVar(&recordShellCommand, "command", "", "shell command to record (required)")
+	recordShellCmd.Flags().StringVar(&recordShellDescription, "description", "", "command description")
+	recordShellCmd.Flags().IntVar(&recordShellExitCode, "exit-code", 0, "command exit code")
+	recordShellCmd.Flags().StringVar(&recordShellStdout, "stdout", "", "captured stdout")
+	recordShellCmd.Flags().StringVar(&recordShellStderr, "stderr", "", "captured stderr")
+	recordShellCmd.Flags().IntVar(&recordDurationMs, "duration-ms", 0, "command duration in milliseconds")
+
+	recordClaudeCmd.Flags().StringVar(&recordToolName, "tool", "", "tool name, e.g. Bash or Edit (required)")
+	recordClaudeCmd.Flags().StringVar(&recordParameters, "parameters", "", "tool parameters as a JSON string")
+	recordClaudeCmd.Flags().StringVar(&recordResult, "result", "", "tool result as a JSON string")
+	recordClaudeCmd.Flags().BoolVar(&recordSuccess, "success", true, "whether the tool call succeeded")
+	recordClaudeCmd.Flags().StringVar(&recordErrorMsg, "error", "", "error message for failed tool calls")
+	recordClaudeCmd.Flags().IntVar(&recordDurationMs, "duration-ms", 0, "tool duration in milliseconds")
+}
+
+// withRecordRepository opens the history database directly, runs fn and exits on failure
+func withRecordRepository(fn func(repo *database.Repository) error) {
+	db, err := database.Initialize(filepath.Join(resolveClaudeDir(), "cct"))
+	if err != nil {
+		ShowError(fmt.Sprintf("Failed to open database: %v", err))
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	if err := fn(database.NewRepository(db)); err != nil {
+		ShowError(fmt.Sprintf("Failed to record: %v", err))
+		db.Close()
+		os.Exit(1)
+	}
+}
+
+// resolveClaudeDir returns ~/.claude, or <directory>/.claude when --directory is set
+func resolveClaudeDir() string {
+	if directory != "." && directory != "" {
+		return filepath.Join(directory, ".claude")
+	}
+	return filepath.Join(os.Getenv("HOME"), ".claude")
+}
+
+// recordWorkingDirectory returns --cwd, falling back to the current directory
+func recordWorkingDirectory() string {
+	if recordCWD != "" {
+		return recordCWD
+	}
+	cwd, _ := os.Getwd()
+	return cwd
+}
+
+// recordDuration returns --duration-ms when it was set
+func recordDuration(cmd *cobra.Command) *int {
+	if !cmd.Flags().Changed("duration-ms") {
+		return nil
+	}
+	return &recordDurationMs
+}
+
+// orUnknown matches the server's fallback for missing model info
+func orUnknown(value string) string {
+	if value == "" {
+		return "Unknown"
+	}
+	return value
+}
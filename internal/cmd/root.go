@@ -1,11 +1,14 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 
 	"github.com/schlunsen/claude-control-terminal/internal/components"
 	"github.com/schlunsen/claude-control-terminal/internal/docker"
@@ -37,6 +40,7 @@ var (
 	hook     string
 	workflow string
 	scope    string // MCP installation scope: "project" or "user"
+	dirs     string // Comma-separated install targets, overriding --directory
 
 	// Service flags
 	analytics    bool
@@ -49,6 +53,10 @@ var (
 	hookStats    bool
 	mcpStats     bool
 
+	// Analytics server flags
+	analyticsHost string
+	analyticsPort int
+
 	// Agent management flags
 	createAgent string
 	listAgents  bool
@@ -75,6 +83,8 @@ var (
 	uninstallToolHook         bool
 	installNotificationHook   bool
 	uninstallNotificationHook bool
+	installSessionHook        bool
+	uninstallSessionHook      bool
 	installAllHooks           bool
 	uninstallAllHooks         bool
 
@@ -90,6 +100,21 @@ var (
 
 	// Claude installer flag
 	installClaude bool
+
+	// Agent session flags
+	resumeAgent    bool
+	continuePrompt string
+
+	// Database maintenance flags
+	dbCheck  bool
+	repairDB bool
+
+	// Hook check flags
+	checkHooks bool
+	pruneHooks bool
+
+	// Version output flag
+	versionJSON bool
 )
 
 // rootCmd represents the base command
@@ -126,11 +151,13 @@ var rootCmd = &cobra.Command{
 			!installUserPromptHook && !uninstallUserPromptHook &&
 			!installToolHook && !uninstallToolHook &&
 			!installNotificationHook && !uninstallNotificationHook &&
-			!installAllHooks && !uninstallAllHooks
+			!installSessionHook && !uninstallSessionHook &&
+			!installAllHooks && !uninstallAllHooks &&
+			!resumeAgent && !dbCheck && !checkHooks
 
 		// If no flags provided, launch TUI
 		if isInteractive {
-			if err := tui.Launch(directory); err != nil {
+			if err := tui.LaunchWithTargets(installTargetDirs()); err != nil {
 				ShowError(fmt.Sprintf("Failed to launch TUI: %v", err))
 				os.Exit(1)
 			}
@@ -148,11 +175,16 @@ func Execute() error {
 }
 
 func init() {
+	// --version --json prints machine-readable build info instead of the default string
+	cobra.AddTemplateFunc("versionOutput", versionOutput)
+	rootCmd.SetVersionTemplate(`{{versionOutput}}`)
+	rootCmd.Flags().BoolVar(&versionJSON, "json", false, "with --version, print version info as JSON")
+
 	// Global flags
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enable verbose logging")
 	rootCmd.PersistentFlags().StringVarP(&directory, "directory", "d", ".", "target directory")
 	rootCmd.PersistentFlags().BoolVarP(&yesFlag, "yes", "y", false, "skip prompts and use defaults")
-	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be copied without copying")
+	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be copied or changed without writing anything")
 	rootCmd.Flags().BoolVarP(&preview, "preview", "p", false, "preview component content without installing")
 
 	// Template selection flags
@@ -167,6 +199,7 @@ func init() {
 	rootCmd.Flags().StringVar(&setting, "setting", "", "install specific setting component")
 	rootCmd.Flags().StringVar(&hook, "hook", "", "install specific hook component")
 	rootCmd.Flags().StringVar(&workflow, "workflow", "", "install workflow from hash or YAML")
+	rootCmd.Flags().StringVar(&dirs, "dirs", "", "comma-separated target directories to install components into (overrides --directory)")
 	rootCmd.Flags().StringVar(&scope, "scope", "project", "MCP installation scope: 'project' (default) or 'user' (global)")
 
 	// Service flags
@@ -175,6 +208,8 @@ func init() {
 	rootCmd.Flags().BoolVar(&chatsMobile, "chats-mobile", false, "launch mobile chats interface")
 	rootCmd.Flags().BoolVar(&plugins, "plugins", false, "launch plugin dashboard")
 	rootCmd.Flags().BoolVar(&tunnel, "tunnel", false, "enable Cloudflare Tunnel for remote access")
+	rootCmd.Flags().StringVar(&analyticsHost, "host", "", "analytics server bind host (overrides config, e.g. 0.0.0.0)")
+	rootCmd.Flags().IntVar(&analyticsPort, "port", 0, "analytics server port (overrides config, default 3333)")
 
 	// Analysis flags
 	rootCmd.Flags().BoolVar(&healthCheck, "health-check", false, "run health check")
@@ -213,16 +248,61 @@ func init() {
 	rootCmd.Flags().BoolVar(&uninstallToolHook, "uninstall-tool-hook", false, "uninstall tool logger hook")
 	rootCmd.Flags().BoolVar(&installNotificationHook, "install-notification-hook", false, "install notification logger hook (project-only)")
 	rootCmd.Flags().BoolVar(&uninstallNotificationHook, "uninstall-notification-hook", false, "uninstall notification logger hook")
+	rootCmd.Flags().BoolVar(&installSessionHook, "install-session-hook", false, "install session start/end logger hook (project-only)")
+	rootCmd.Flags().BoolVar(&uninstallSessionHook, "uninstall-session-hook", false, "uninstall session logger hook")
 	rootCmd.Flags().BoolVar(&installAllHooks, "install-all-hooks", false, "install all hooks (user-prompt + tool + notification loggers, project-only)")
 	rootCmd.Flags().BoolVar(&uninstallAllHooks, "uninstall-all-hooks", false, "uninstall all hooks")
 
 	// Claude installer flag
 	rootCmd.Flags().BoolVar(&installClaude, "install-claude", false, "install Claude CLI automatically")
+
+	// Agent session flags
+	rootCmd.Flags().BoolVar(&resumeAgent, "resume-agent", false, "show the most recent active/idle agent session")
+	rootCmd.Flags().StringVar(&continuePrompt, "continue", "", "with --resume-agent, send a prompt and stream the response (tools need allow-all or always-allow rules)")
+
+	// Database maintenance flags
+	rootCmd.Flags().BoolVar(&dbCheck, "db-check", false, "check database integrity and report orphaned agent messages")
+	rootCmd.Flags().BoolVar(&repairDB, "repair", false, "with --db-check, delete orphaned agent messages and rebuild indexes")
+
+	// Hook check flags
+	rootCmd.Flags().BoolVar(&checkHooks, "check-hooks", false, "report hook entries in project and global settings whose scripts are missing or not executable")
+	rootCmd.Flags().BoolVar(&pruneHooks, "prune", false, "with --check-hooks, remove the dangling hook entries")
+}
+
+// versionOutput renders the --version output, as JSON when --json is set
+func versionOutput() string {
+	if versionJSON {
+		data, err := json.Marshal(version.Get())
+		if err == nil {
+			return string(data) + "\n"
+		}
+	}
+	return fmt.Sprintf("cct version %s\n", Version)
 }
 
 func handleCommand(cmd *cobra.Command, args []string) {
+	// Database check and repair
+	if dbCheck {
+		handleDBCheck()
+		return
+	}
+
+	// Hook script check
+	if checkHooks {
+		handleCheckHooks()
+		return
+	}
+
+	// Resume the latest agent session
+	if resumeAgent {
+		handleResumeAgent()
+		return
+	}
+
 	// Hook management commands
-	if installUserPromptHook || uninstallUserPromptHook || installToolHook || uninstallToolHook || installAllHooks || uninstallAllHooks {
+	if installUserPromptHook || uninstallUserPromptHook || installToolHook || uninstallToolHook ||
+		installNotificationHook || uninstallNotificationHook || installSessionHook || uninstallSessionHook ||
+		installAllHooks || uninstallAllHooks {
 		handleHookManagement()
 		return
 	}
@@ -238,7 +318,7 @@ func handleCommand(cmd *cobra.Command, args []string) {
 		spinner := ShowSpinner("Launching Analytics Dashboard...")
 
 		// Import server package
-		server := createAnalyticsServer(directory)
+		server := createAnalyticsServer(directory, analyticsHost, analyticsPort)
 
 		spinner.Success("Analytics Dashboard starting!")
 		ShowInfo("Press Ctrl+C to stop")
@@ -248,6 +328,17 @@ func handleCommand(cmd *cobra.Command, args []string) {
 			return
 		}
 
+		// Drain active agent connections on SIGTERM (Docker/systemd stop)
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, syscall.SIGTERM)
+		go func() {
+			<-sigChan
+			ShowInfo("Received SIGTERM, draining active sessions...")
+			if err := server.Drain(server.DrainTimeout()); err != nil {
+				ShowError(fmt.Sprintf("Failed to shut down server: %v", err))
+			}
+		}()
+
 		// Server prints its own startup messages with correct protocol and ports
 		if err := server.Start(); err != nil {
 			ShowError(fmt.Sprintf("Failed to start server: %v", err))
@@ -315,7 +406,7 @@ func handleCommand(cmd *cobra.Command, args []string) {
 
 	// Component installation
 	if agent != "" || command != "" || mcp != "" || setting != "" || hook != "" {
-		handleComponentInstallation(directory)
+		handleComponentInstallation(installTargetDirs())
 		return
 	}
 
@@ -325,15 +416,47 @@ func handleCommand(cmd *cobra.Command, args []string) {
 }
 
 // handleComponentInstallation handles installation of individual components
-func handleComponentInstallation(targetDir string) {
+func handleComponentInstallation(targetDirs []string) {
 	if preview {
 		fmt.Println("👁️  Previewing Components...")
-		handleComponentPreview(targetDir)
+		handleComponentPreview(targetDirs[0])
 		return
 	}
 
 	fmt.Println("📦 Installing Components...")
 
+	var failedDirs []string
+	for _, targetDir := range targetDirs {
+		if len(targetDirs) > 1 {
+			fmt.Printf("\n📁 %s\n", targetDir)
+		}
+		if !installComponentsInto(targetDir) {
+			failedDirs = append(failedDirs, targetDir)
+		}
+	}
+
+	// Settings and hooks
+	if setting != "" {
+		fmt.Println("\n⚙️  Settings installation coming soon...")
+	}
+
+	if hook != "" {
+		handleHookInstallation(hook)
+	}
+
+	if len(failedDirs) == 0 {
+		fmt.Println("\n✅ All components installed successfully!")
+	} else if len(targetDirs) > 1 {
+		fmt.Printf("\n❌ Installation failed in %d of %d directories:\n", len(failedDirs), len(targetDirs))
+		for _, dir := range failedDirs {
+			fmt.Printf("  - %s\n", dir)
+		}
+	}
+}
+
+// installComponentsInto installs the requested agents, commands and MCPs
+// into one directory, returning false if any of them failed
+func installComponentsInto(targetDir string) bool {
 	hasErrors := false
 
 	// Install agents
@@ -379,18 +502,16 @@ func handleComponentInstallation(targetDir string) {
 		}
 	}
 
-	// Settings and hooks
-	if setting != "" {
-		fmt.Println("\n⚙️  Settings installation coming soon...")
-	}
-
-	if hook != "" {
-		handleHookInstallation(hook)
-	}
+	return !hasErrors
+}
 
-	if !hasErrors {
-		fmt.Println("\n✅ All components installed successfully!")
+// installTargetDirs returns the directories to install components into:
+// --dirs when set, otherwise --directory
+func installTargetDirs() []string {
+	if targets := parseComponentList(dirs); len(targets) > 0 {
+		return targets
 	}
+	return []string{directory}
 }
 
 // handleComponentPreview displays component content without installing
@@ -461,8 +582,9 @@ func parseComponentList(input string) []string {
 	return result
 }
 
-// createAnalyticsServer creates an analytics server instance
-func createAnalyticsServer(targetDir string) *server.Server {
+// createAnalyticsServer creates an analytics server instance.
+// An empty host or zero port falls back to the server config.
+func createAnalyticsServer(targetDir, host string, port int) *server.Server {
 	// Get Claude directory (default to ~/.claude)
 	claudeDir := filepath.Join(os.Getenv("HOME"), ".claude")
 
@@ -471,8 +593,8 @@ func createAnalyticsServer(targetDir string) *server.Server {
 		claudeDir = filepath.Join(targetDir, ".claude")
 	}
 
-	// Create server with verbose flag from CLI
-	return server.NewServerWithOptions(claudeDir, 3333, false, verbose)
+	// Create server with bind address and verbose flag from CLI
+	return server.NewServerWithOptions(claudeDir, host, port, false, verbose)
 }
 
 // handleHookInstallation handles installation of hooks (legacy via --hook flag)
@@ -516,6 +638,10 @@ func handleHookInstallation(hookName string) {
 // handleHookManagement handles hook installation and removal via dedicated flags
 func handleHookManagement() {
 	hookInstaller := components.NewHookInstaller()
+	hookInstaller.SetDryRun(dryRun)
+	if dryRun {
+		ShowInfo("Dry run: showing planned hook changes without writing anything")
+	}
 
 	// Install all hooks
 	if installAllHooks {
@@ -588,6 +714,24 @@ func handleHookManagement() {
 		}
 		return
 	}
+
+	// Install session hook
+	if installSessionHook {
+		if err := hookInstaller.InstallSessionHook(); err != nil {
+			ShowError(fmt.Sprintf("Failed to install session hook: %v", err))
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Uninstall session hook
+	if uninstallSessionHook {
+		if err := hookInstaller.UninstallSessionHook(); err != nil {
+			ShowError(fmt.Sprintf("Failed to uninstall session hook: %v", err))
+			os.Exit(1)
+		}
+		return
+	}
 }
 
 // handleDockerCommands handles all Docker-related operations
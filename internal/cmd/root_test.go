@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"encoding/json"
 	"testing"
+
+	"github.com/schlunsen/claude-control-terminal/internal/version"
 )
 
 func TestParseComponentList(t *testing.T) {
@@ -70,6 +73,23 @@ func TestParseComponentList(t *testing.T) {
 	}
 }
 
+func TestInstallTargetDirs(t *testing.T) {
+	origDirs, origDirectory := dirs, directory
+	defer func() { dirs, directory = origDirs, origDirectory }()
+
+	directory = "/work/app"
+	dirs = ""
+	if got := installTargetDirs(); len(got) != 1 || got[0] != "/work/app" {
+		t.Errorf("expected --directory fallback, got %v", got)
+	}
+
+	dirs = "/work/a, /work/b,,"
+	got := installTargetDirs()
+	if len(got) != 2 || got[0] != "/work/a" || got[1] != "/work/b" {
+		t.Errorf("expected --dirs targets, got %v", got)
+	}
+}
+
 func TestVersionInfo(t *testing.T) {
 	if Version == "" {
 		t.Error("Version should not be empty")
@@ -84,6 +104,27 @@ func TestVersionInfo(t *testing.T) {
 	}
 }
 
+func TestVersionOutput(t *testing.T) {
+	defer func() { versionJSON = false }()
+
+	versionJSON = false
+	if got := versionOutput(); got != "cct version "+Version+"\n" {
+		t.Errorf("Unexpected plain version output: %q", got)
+	}
+
+	versionJSON = true
+	var info version.Info
+	if err := json.Unmarshal([]byte(versionOutput()), &info); err != nil {
+		t.Fatalf("Expected JSON version output: %v", err)
+	}
+	if info.Version != Version || info.Name != Name {
+		t.Errorf("Unexpected version info: %+v", info)
+	}
+	if info.Commit == "" || info.BuildDate == "" {
+		t.Errorf("Expected commit and build date to be set: %+v", info)
+	}
+}
+
 func TestRootCommandInitialization(t *testing.T) {
 	if rootCmd == nil {
 		t.Fatal("rootCmd should not be nil")
@@ -169,3 +210,23 @@ func TestConstants(t *testing.T) {
 		t.Errorf("Expected Name 'claude-control-terminal', got '%s'", Name)
 	}
 }
+
+func TestRecordSubcommands(t *testing.T) {
+	for _, name := range []string{"record-prompt", "record-shell", "record-claude"} {
+		sub, _, err := rootCmd.Find([]string{name})
+		if err != nil || sub.Name() != name {
+			t.Errorf("Expected subcommand '%s' to be registered", name)
+			continue
+		}
+		if sub.Flags().Lookup("session-id") == nil {
+			t.Errorf("Expected '%s' to define --session-id", name)
+		}
+	}
+
+	if got := orUnknown(""); got != "Unknown" {
+		t.Errorf("Expected 'Unknown' for empty value, got '%s'", got)
+	}
+	if got := orUnknown("sonnet"); got != "sonnet" {
+		t.Errorf("Expected value to be kept, got '%s'", got)
+	}
+}
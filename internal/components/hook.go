@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/schlunsen/claude-control-terminal/hooks"
@@ -15,6 +16,7 @@ import (
 // HookInstaller handles installation of Claude Code hooks
 type HookInstaller struct {
 	claudeDir string
+	dryRun    bool // Print planned changes without writing anything
 }
 
 // ClaudeSettings represents the structure of settings.json
@@ -41,6 +43,62 @@ func NewHookInstallerWithDir(claudeDir string) *HookInstaller {
 	}
 }
 
+// SetDryRun enables dry-run mode, in which install and uninstall only print
+// the files and settings entries they would change
+func (hi *HookInstaller) SetDryRun(dryRun bool) {
+	hi.dryRun = dryRun
+}
+
+// mkdirAll creates a directory, or reports that it would be created in dry-run mode
+func (hi *HookInstaller) mkdirAll(dir string) error {
+	if !hi.dryRun {
+		return os.MkdirAll(dir, 0755)
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		fmt.Printf("   • Would create directory: %s\n", dir)
+	}
+	return nil
+}
+
+// removeHookScript deletes an installed hook script, or reports it in dry-run mode
+func (hi *HookInstaller) removeHookScript(hookScriptPath string) {
+	if hi.dryRun {
+		if _, err := os.Stat(hookScriptPath); err == nil {
+			fmt.Printf("   • Would remove hook script: %s\n", hookScriptPath)
+		}
+		return
+	}
+
+	if err := os.Remove(hookScriptPath); err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("   ⚠️  Failed to remove hook script: %v\n", err)
+		}
+	} else {
+		fmt.Printf("   ✓ Removed hook script: %s\n", hookScriptPath)
+	}
+}
+
+// hookInstallMessage builds the success message for a hook install outcome
+func hookInstallMessage(label string, status string) string {
+	switch status {
+	case HookScriptUpgraded:
+		return fmt.Sprintf("✅ %s upgraded successfully!", label)
+	case HookScriptAlreadyCurrent:
+		return fmt.Sprintf("✅ %s is already current", label)
+	default:
+		return fmt.Sprintf("✅ %s installed successfully!", label)
+	}
+}
+
+// reportDone prints the success message, or a dry-run notice when nothing was written
+func (hi *HookInstaller) reportDone(message string) {
+	if hi.dryRun {
+		fmt.Println("🔍 Dry run: no files were changed")
+		return
+	}
+	fmt.Println(message)
+}
+
 // InstallUserPromptLogger installs the user-prompt-logger hook for current project only
 // Hooks are always installed in the project's .claude directory, never globally
 func (hi *HookInstaller) InstallUserPromptLogger() error {
@@ -54,19 +112,20 @@ func (hi *HookInstaller) InstallUserPromptLogger() error {
 
 	// Project .claude directory
 	settingsDir := filepath.Join(cwd, ".claude")
-	if err := os.MkdirAll(settingsDir, 0755); err != nil {
+	if err := hi.mkdirAll(settingsDir); err != nil {
 		return fmt.Errorf("failed to create project .claude directory: %w", err)
 	}
 
 	// Hooks subdirectory in PROJECT .claude dir (not global)
 	hooksDir := filepath.Join(settingsDir, "hooks")
-	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+	if err := hi.mkdirAll(hooksDir); err != nil {
 		return fmt.Errorf("failed to create project hooks directory: %w", err)
 	}
 
 	// Copy hook script to PROJECT hooks directory
 	hookName := "user-prompt-logger.sh"
-	if err := hi.copyHookScript(hookName, hooksDir); err != nil {
+	status, err := hi.copyHookScript(hookName, hooksDir)
+	if err != nil {
 		return fmt.Errorf("failed to copy hook script: %w", err)
 	}
 
@@ -75,7 +134,7 @@ func (hi *HookInstaller) InstallUserPromptLogger() error {
 		return fmt.Errorf("failed to update settings.json: %w", err)
 	}
 
-	fmt.Println("✅ User Prompt Logger Hook installed successfully!")
+	hi.reportDone(hookInstallMessage("User Prompt Logger Hook", status))
 	fmt.Printf("   Project: %s\n", cwd)
 	fmt.Printf("   Hook script: %s\n", filepath.Join(hooksDir, hookName))
 	fmt.Printf("   Settings: %s\n", filepath.Join(settingsDir, "settings.local.json"))
@@ -85,23 +144,78 @@ func (hi *HookInstaller) InstallUserPromptLogger() error {
 	return nil
 }
 
-// copyHookScript copies a hook script from the embedded hooks directory to Claude's hooks directory
-func (hi *HookInstaller) copyHookScript(hookName string, hooksDir string) error {
+// hookVersionPrefix marks the version comment embedded in every hook script
+const hookVersionPrefix = "# cct-hook-version:"
+
+// Hook script install outcomes reported by copyHookScript
+const (
+	HookScriptInstalled      = "installed"
+	HookScriptUpgraded       = "upgraded"
+	HookScriptAlreadyCurrent = "already current"
+)
+
+// hookScriptVersion returns the version from a script's version comment (0 if absent)
+func hookScriptVersion(content []byte) int {
+	for _, line := range strings.Split(string(content), "\n") {
+		if !strings.HasPrefix(line, hookVersionPrefix) {
+			continue
+		}
+		version, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, hookVersionPrefix)))
+		if err != nil {
+			return 0
+		}
+		return version
+	}
+	return 0
+}
+
+// copyHookScript copies a hook script from the embedded hooks directory to Claude's hooks directory.
+// An installed script with the same or newer version is left untouched; older or
+// unversioned scripts are replaced. Returns installed, upgraded or already current.
+func (hi *HookInstaller) copyHookScript(hookName string, hooksDir string) (string, error) {
 	// Read hook script from embedded filesystem
 	// The hooks package embeds all .sh files directly, so we just use the filename
 	sourceContent, err := hooks.Scripts.ReadFile(hookName)
 	if err != nil {
-		return fmt.Errorf("could not find embedded hook script %s: %w", hookName, err)
+		return "", fmt.Errorf("could not find embedded hook script %s: %w", hookName, err)
 	}
 
-	// Write to destination
 	destPath := filepath.Join(hooksDir, hookName)
+	sourceVersion := hookScriptVersion(sourceContent)
+
+	status := HookScriptInstalled
+	installedContent, err := os.ReadFile(destPath)
+	if err == nil {
+		installedVersion := hookScriptVersion(installedContent)
+		if installedVersion >= sourceVersion {
+			fmt.Printf("   ℹ Hook script already current (v%d): %s\n", installedVersion, destPath)
+			return HookScriptAlreadyCurrent, nil
+		}
+		status = HookScriptUpgraded
+		if hi.dryRun {
+			fmt.Printf("   • Would upgrade hook script v%d → v%d: %s\n", installedVersion, sourceVersion, destPath)
+			return status, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read installed hook script: %w", err)
+	}
+
+	if hi.dryRun {
+		fmt.Printf("   • Would copy hook script to: %s (%d bytes)\n", destPath, len(sourceContent))
+		return status, nil
+	}
+
+	// Write to destination
 	if err := os.WriteFile(destPath, sourceContent, 0755); err != nil {
-		return fmt.Errorf("failed to write hook script: %w", err)
+		return "", fmt.Errorf("failed to write hook script: %w", err)
 	}
 
-	fmt.Printf("   ✓ Copied hook script to: %s\n", destPath)
-	return nil
+	if status == HookScriptUpgraded {
+		fmt.Printf("   ✓ Upgraded hook script to v%d: %s\n", sourceVersion, destPath)
+	} else {
+		fmt.Printf("   ✓ Copied hook script to: %s\n", destPath)
+	}
+	return status, nil
 }
 
 // addHookToSettingsAtPath adds a hook to settings.local.json at specified directory
@@ -159,8 +273,9 @@ func (hi *HookInstaller) addHookToSettingsWithMatcher(settingsDir string, hooksD
 		eventHooks = []interface{}{}
 	}
 
-	// Check if hook already exists
+	// Check if hook already exists, pointing stale paths at the current script
 	hookExists := false
+	pathUpdated := false
 	for _, entry := range eventHooks {
 		if entryMap, ok := entry.(map[string]interface{}); ok {
 			// Check if matcher matches (or both empty)
@@ -171,6 +286,15 @@ func (hi *HookInstaller) addHookToSettingsWithMatcher(settingsDir string, hooksD
 						if hMap, ok := h.(map[string]interface{}); ok {
 							if cmd, ok := hMap["command"].(string); ok && strings.Contains(cmd, hookName) {
 								hookExists = true
+								if cmd != hookScriptPath {
+									if hi.dryRun {
+										fmt.Printf("   • Would update hook path in %s event: %s → %s\n", eventName, cmd, hookScriptPath)
+									} else {
+										hMap["command"] = hookScriptPath
+										fmt.Printf("   ✓ Updated hook path in %s event: %s → %s\n", eventName, cmd, hookScriptPath)
+									}
+									pathUpdated = true
+								}
 								break
 							}
 						}
@@ -199,6 +323,15 @@ func (hi *HookInstaller) addHookToSettingsWithMatcher(settingsDir string, hooksD
 			hookEntry["matcher"] = matcher
 		}
 
+		if hi.dryRun {
+			entryJSON, err := json.MarshalIndent(hookEntry, "     ", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal hook entry: %w", err)
+			}
+			fmt.Printf("   • Would add to %s under hooks.%s:\n     %s\n", settingsPath, eventName, entryJSON)
+			return nil
+		}
+
 		eventHooks = append(eventHooks, hookEntry)
 		hooks[eventName] = eventHooks
 
@@ -208,7 +341,12 @@ func (hi *HookInstaller) addHookToSettingsWithMatcher(settingsDir string, hooksD
 			fmt.Printf("   ✓ Added hook to %s event\n", eventName)
 		}
 	} else {
-		fmt.Printf("   ℹ Hook already exists in %s event\n", eventName)
+		if !pathUpdated {
+			fmt.Printf("   ℹ Hook already exists in %s event\n", eventName)
+		}
+		if hi.dryRun {
+			return nil
+		}
 	}
 
 	rawSettings["hooks"] = hooks
@@ -246,15 +384,9 @@ func (hi *HookInstaller) UninstallUserPromptLogger() error {
 
 	// Remove the hook script file from project directory
 	hookScriptPath := filepath.Join(projectSettingsDir, "hooks", hookName)
-	if err := os.Remove(hookScriptPath); err != nil {
-		if !os.IsNotExist(err) {
-			fmt.Printf("   ⚠️  Failed to remove hook script: %v\n", err)
-		}
-	} else {
-		fmt.Printf("   ✓ Removed hook script: %s\n", hookScriptPath)
-	}
+	hi.removeHookScript(hookScriptPath)
 
-	fmt.Println("✅ User Prompt Logger Hook uninstalled successfully!")
+	hi.reportDone("✅ User Prompt Logger Hook uninstalled successfully!")
 	return nil
 }
 
@@ -324,6 +456,11 @@ func (hi *HookInstaller) removeHookFromSettingsAtPath(settingsDir string, hookNa
 		}
 	}
 
+	if removed && hi.dryRun {
+		fmt.Printf("   • Would remove hook from %s event in %s\n", eventName, settingsPath)
+		return nil
+	}
+
 	if removed {
 		if len(newEventHooks) > 0 {
 			hooks[eventName] = newEventHooks
@@ -432,19 +569,20 @@ func (hi *HookInstaller) InstallToolLogger() error {
 
 	// Project .claude directory
 	settingsDir := filepath.Join(cwd, ".claude")
-	if err := os.MkdirAll(settingsDir, 0755); err != nil {
+	if err := hi.mkdirAll(settingsDir); err != nil {
 		return fmt.Errorf("failed to create project .claude directory: %w", err)
 	}
 
 	// Hooks subdirectory in PROJECT .claude dir (not global)
 	hooksDir := filepath.Join(settingsDir, "hooks")
-	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+	if err := hi.mkdirAll(hooksDir); err != nil {
 		return fmt.Errorf("failed to create project hooks directory: %w", err)
 	}
 
 	// Copy hook script to PROJECT hooks directory
 	hookName := "tool-logger.sh"
-	if err := hi.copyHookScript(hookName, hooksDir); err != nil {
+	status, err := hi.copyHookScript(hookName, hooksDir)
+	if err != nil {
 		return fmt.Errorf("failed to copy hook script: %w", err)
 	}
 
@@ -453,7 +591,7 @@ func (hi *HookInstaller) InstallToolLogger() error {
 		return fmt.Errorf("failed to update settings.json: %w", err)
 	}
 
-	fmt.Println("✅ Tool Logger Hook installed successfully!")
+	hi.reportDone(hookInstallMessage("Tool Logger Hook", status))
 	fmt.Printf("   Project: %s\n", cwd)
 	fmt.Printf("   Hook script: %s\n", filepath.Join(hooksDir, hookName))
 	fmt.Printf("   Settings: %s\n", filepath.Join(settingsDir, "settings.local.json"))
@@ -483,15 +621,9 @@ func (hi *HookInstaller) UninstallToolLogger() error {
 
 	// Remove the hook script file from project directory
 	hookScriptPath := filepath.Join(projectSettingsDir, "hooks", hookName)
-	if err := os.Remove(hookScriptPath); err != nil {
-		if !os.IsNotExist(err) {
-			fmt.Printf("   ⚠️  Failed to remove hook script: %v\n", err)
-		}
-	} else {
-		fmt.Printf("   ✓ Removed hook script: %s\n", hookScriptPath)
-	}
+	hi.removeHookScript(hookScriptPath)
 
-	fmt.Println("✅ Tool Logger Hook uninstalled successfully!")
+	hi.reportDone("✅ Tool Logger Hook uninstalled successfully!")
 	return nil
 }
 
@@ -560,7 +692,7 @@ func (hi *HookInstaller) checkToolLoggerInSettingsFile(settingsPath string) (boo
 	return false, nil
 }
 
-// InstallAllHooks installs all hooks (user-prompt-logger, tool-logger, and notification-logger) for current project
+// InstallAllHooks installs all hooks (user-prompt-logger, tool-logger, notification-logger, and session-logger) for current project
 // Always installs to project's .claude directory, never globally
 func (hi *HookInstaller) InstallAllHooks() error {
 	fmt.Println("📦 Installing All Hooks (project-only)...")
@@ -586,13 +718,22 @@ func (hi *HookInstaller) InstallAllHooks() error {
 	}
 
 	fmt.Println()
-	fmt.Println("✅ All hooks installed successfully!")
-	fmt.Println("   All three hooks are project-specific and will only run in this directory")
+
+	// Install session logger
+	if err := hi.InstallSessionHook(); err != nil {
+		return fmt.Errorf("failed to install session logger: %w", err)
+	}
+
+	fmt.Println()
+	hi.reportDone("✅ All hooks installed successfully!")
+	if !hi.dryRun {
+		fmt.Println("   All hooks are project-specific and will only run in this directory")
+	}
 
 	return nil
 }
 
-// UninstallAllHooks removes both hooks from current project
+// UninstallAllHooks removes all hooks from current project
 func (hi *HookInstaller) UninstallAllHooks() error {
 	fmt.Println("🗑️  Uninstalling All Hooks...")
 	fmt.Println()
@@ -617,7 +758,14 @@ func (hi *HookInstaller) UninstallAllHooks() error {
 	}
 
 	fmt.Println()
-	fmt.Println("✅ All hooks uninstalled successfully!")
+
+	// Uninstall session logger
+	if err := hi.UninstallSessionHook(); err != nil {
+		fmt.Printf("   ⚠️  Session logger: %v\n", err)
+	}
+
+	fmt.Println()
+	hi.reportDone("✅ All hooks uninstalled successfully!")
 
 	return nil
 }
@@ -635,19 +783,20 @@ func (hi *HookInstaller) InstallNotificationLogger() error {
 
 	// Project .claude directory
 	settingsDir := filepath.Join(cwd, ".claude")
-	if err := os.MkdirAll(settingsDir, 0755); err != nil {
+	if err := hi.mkdirAll(settingsDir); err != nil {
 		return fmt.Errorf("failed to create project .claude directory: %w", err)
 	}
 
 	// Hooks subdirectory in PROJECT .claude dir (not global)
 	hooksDir := filepath.Join(settingsDir, "hooks")
-	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+	if err := hi.mkdirAll(hooksDir); err != nil {
 		return fmt.Errorf("failed to create project hooks directory: %w", err)
 	}
 
 	// Copy hook script to PROJECT hooks directory
 	hookName := "notification-logger.sh"
-	if err := hi.copyHookScript(hookName, hooksDir); err != nil {
+	status, err := hi.copyHookScript(hookName, hooksDir)
+	if err != nil {
 		return fmt.Errorf("failed to copy hook script: %w", err)
 	}
 
@@ -656,7 +805,7 @@ func (hi *HookInstaller) InstallNotificationLogger() error {
 		return fmt.Errorf("failed to update settings.json: %w", err)
 	}
 
-	fmt.Println("✅ Notification Logger Hook installed successfully!")
+	hi.reportDone(hookInstallMessage("Notification Logger Hook", status))
 	fmt.Printf("   Project: %s\n", cwd)
 	fmt.Printf("   Hook script: %s\n", filepath.Join(hooksDir, hookName))
 	fmt.Printf("   Settings: %s\n", filepath.Join(settingsDir, "settings.local.json"))
@@ -686,15 +835,9 @@ func (hi *HookInstaller) UninstallNotificationLogger() error {
 
 	// Remove the hook script file from project directory
 	hookScriptPath := filepath.Join(projectSettingsDir, "hooks", hookName)
-	if err := os.Remove(hookScriptPath); err != nil {
-		if !os.IsNotExist(err) {
-			fmt.Printf("   ⚠️  Failed to remove hook script: %v\n", err)
-		}
-	} else {
-		fmt.Printf("   ✓ Removed hook script: %s\n", hookScriptPath)
-	}
+	hi.removeHookScript(hookScriptPath)
 
-	fmt.Println("✅ Notification Logger Hook uninstalled successfully!")
+	hi.reportDone("✅ Notification Logger Hook uninstalled successfully!")
 	return nil
 }
 
@@ -762,3 +905,137 @@ func (hi *HookInstaller) checkNotificationLoggerInSettingsFile(settingsPath stri
 
 	return false, nil
 }
+
+// sessionHookEvents are the lifecycle events the session-logger hook is registered for
+var sessionHookEvents = []string{"SessionStart", "SessionEnd"}
+
+// InstallSessionHook installs the session-logger hook for current project only.
+// It records SessionStart and SessionEnd events so analytics can derive session durations.
+func (hi *HookInstaller) InstallSessionHook() error {
+	// Get current working directory for project-based installation
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	fmt.Println("⏱️  Installing Session Logger Hook (project-only)...")
+
+	// Project .claude directory
+	settingsDir := filepath.Join(cwd, ".claude")
+	if err := hi.mkdirAll(settingsDir); err != nil {
+		return fmt.Errorf("failed to create project .claude directory: %w", err)
+	}
+
+	// Hooks subdirectory in PROJECT .claude dir (not global)
+	hooksDir := filepath.Join(settingsDir, "hooks")
+	if err := hi.mkdirAll(hooksDir); err != nil {
+		return fmt.Errorf("failed to create project hooks directory: %w", err)
+	}
+
+	// Copy hook script to PROJECT hooks directory
+	hookName := "session-logger.sh"
+	status, err := hi.copyHookScript(hookName, hooksDir)
+	if err != nil {
+		return fmt.Errorf("failed to copy hook script: %w", err)
+	}
+
+	// Register the same script for both lifecycle events (no matcher needed)
+	for _, eventName := range sessionHookEvents {
+		if err := hi.addHookToSettingsAtPath(settingsDir, hooksDir, hookName, eventName); err != nil {
+			return fmt.Errorf("failed to update settings.json: %w", err)
+		}
+	}
+
+	hi.reportDone(hookInstallMessage("Session Logger Hook", status))
+	fmt.Printf("   Project: %s\n", cwd)
+	fmt.Printf("   Hook script: %s\n", filepath.Join(hooksDir, hookName))
+	fmt.Printf("   Settings: %s\n", filepath.Join(settingsDir, "settings.local.json"))
+	fmt.Println("\n💡 This hook will record when Claude sessions start and end")
+	fmt.Println("   View analytics: cct --analytics")
+
+	return nil
+}
+
+// UninstallSessionHook removes the session-logger hook from current project
+func (hi *HookInstaller) UninstallSessionHook() error {
+	fmt.Println("🗑️  Uninstalling Session Logger Hook...")
+
+	hookName := "session-logger.sh"
+
+	// Get current working directory
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	// Remove from project settings.json
+	projectSettingsDir := filepath.Join(cwd, ".claude")
+	for _, eventName := range sessionHookEvents {
+		if err := hi.removeHookFromSettingsAtPath(projectSettingsDir, hookName, eventName); err != nil {
+			fmt.Printf("   ℹ Project settings: %v\n", err)
+			break
+		}
+	}
+
+	// Remove the hook script file from project directory
+	hookScriptPath := filepath.Join(projectSettingsDir, "hooks", hookName)
+	hi.removeHookScript(hookScriptPath)
+
+	hi.reportDone("✅ Session Logger Hook uninstalled successfully!")
+	return nil
+}
+
+// CheckSessionHookInstalled checks if the session-logger hook is installed in current project
+// Only checks project-based installation, never global
+func (hi *HookInstaller) CheckSessionHookInstalled() (bool, error) {
+	// Check project-based installation only
+	cwd, err := os.Getwd()
+	if err != nil {
+		return false, fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	projectSettingsPath := filepath.Join(cwd, ".claude", "settings.local.json")
+	return hi.checkHookEventInSettingsFile(projectSettingsPath, "SessionStart", "session-logger")
+}
+
+// checkHookEventInSettingsFile checks if a hook command containing hookName is registered for eventName
+func (hi *HookInstaller) checkHookEventInSettingsFile(settingsPath string, eventName string, hookName string) (bool, error) {
+	content, err := os.ReadFile(settingsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	var rawSettings map[string]interface{}
+	if err := json.Unmarshal(content, &rawSettings); err != nil {
+		return false, err
+	}
+
+	hooks, ok := rawSettings["hooks"].(map[string]interface{})
+	if !ok {
+		return false, nil
+	}
+
+	eventHooks, ok := hooks[eventName].([]interface{})
+	if !ok {
+		return false, nil
+	}
+
+	for _, entry := range eventHooks {
+		if entryMap, ok := entry.(map[string]interface{}); ok {
+			if hooksArr, ok := entryMap["hooks"].([]interface{}); ok {
+				for _, h := range hooksArr {
+					if hMap, ok := h.(map[string]interface{}); ok {
+						if cmd, ok := hMap["command"].(string); ok && strings.Contains(cmd, hookName) {
+							return true, nil
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,354 @@
+package components
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Hook problems reported by CheckHookScripts
+const (
+	HookScriptMissing       = "missing"
+	HookScriptNotExecutable = "not executable"
+)
+
+// HookIssue describes a hook command in a settings file whose script cannot be run
+type HookIssue struct {
+	SettingsPath string // Settings file containing the entry
+	Event        string // Hook event, e.g. PreToolUse
+	Command      string // Command as written in the settings file
+	ScriptPath   string // Resolved script path
+	Problem      string // HookScriptMissing or HookScriptNotExecutable
+	Certain      bool   // ScriptPath is an explicit path rather than a name looked up on PATH; only these are pruned
+}
+
+// scriptInterpreters are commands whose first non-flag argument is the hook script
+var scriptInterpreters = map[string]bool{
+	"bash": true, "sh": true, "zsh": true,
+	"python": true, "python3": true,
+	"node": true, "ruby": true, "perl": true,
+}
+
+// shellBuiltins are commands the shell runs itself, with no program on disk to check
+var shellBuiltins = map[string]bool{
+	"cd": true, "echo": true, "exit": true, "export": true, "source": true, ".": true,
+	"true": true, "false": true, "test": true, "[": true, "printf": true, "eval": true,
+	"exec": true, "set": true, "unset": true, ":": true, "read": true, "command": true,
+}
+
+// hookShellOperators mark compound commands whose programs can't be told apart
+// without a shell parser
+var hookShellOperators = []string{"&&", "||", ";", "|", ">", "<", "`", "$(", "\n"}
+
+// HookSettingsPaths returns the project and global settings files that may
+// register hooks, in the order Claude Code reads them
+func HookSettingsPaths(projectDir string, claudeDir string) []string {
+	return []string{
+		filepath.Join(projectDir, ".claude", "settings.json"),
+		filepath.Join(projectDir, ".claude", "settings.local.json"),
+		filepath.Join(claudeDir, "settings.json"),
+		filepath.Join(claudeDir, "settings.local.json"),
+	}
+}
+
+// CheckHookScripts parses each settings file, resolves the script behind every
+// hook command and reports those that are missing or not executable.
+// Settings files that don't exist are skipped.
+func CheckHookScripts(settingsPaths []string, projectDir string) ([]HookIssue, error) {
+	var issues []HookIssue
+
+	for _, settingsPath := range settingsPaths {
+		rawSettings, err := readSettingsFile(settingsPath)
+		if err != nil {
+			return nil, err
+		}
+		if rawSettings == nil {
+			continue
+		}
+
+		hooks, _ := rawSettings["hooks"].(map[string]interface{})
+		for eventName, eventRaw := range hooks {
+			eventHooks, _ := eventRaw.([]interface{})
+			for _, entry := range eventHooks {
+				for _, command := range hookEntryCommands(entry) {
+					scriptPath, direct := resolveHookScript(command, projectDir)
+					if problem := checkHookScript(scriptPath, direct); problem != "" {
+						issues = append(issues, HookIssue{
+							SettingsPath: settingsPath,
+							Event:        eventName,
+							Command:      command,
+							ScriptPath:   scriptPath,
+							Problem:      problem,
+							Certain:      strings.Contains(scriptPath, "/"),
+						})
+					}
+				}
+			}
+		}
+	}
+
+	return issues, nil
+}
+
+// PruneHookIssues removes the reported hook commands from their settings files.
+// Issues that aren't Certain are left alone, since the program may exist on the
+// PATH the hook actually runs with. Entries left without commands and events
+// left without entries are dropped. Returns the number of hook commands removed.
+func PruneHookIssues(issues []HookIssue) (int, error) {
+	// settings path -> event -> commands to remove
+	byFile := make(map[string]map[string]map[string]bool)
+	for _, issue := range issues {
+		if !issue.Certain {
+			continue
+		}
+		if byFile[issue.SettingsPath] == nil {
+			byFile[issue.SettingsPath] = make(map[string]map[string]bool)
+		}
+		if byFile[issue.SettingsPath][issue.Event] == nil {
+			byFile[issue.SettingsPath][issue.Event] = make(map[string]bool)
+		}
+		byFile[issue.SettingsPath][issue.Event][issue.Command] = true
+	}
+
+	removed := 0
+	for settingsPath, events := range byFile {
+		rawSettings, err := readSettingsFile(settingsPath)
+		if err != nil {
+			return removed, err
+		}
+		if rawSettings == nil {
+			continue
+		}
+
+		hooks, ok := rawSettings["hooks"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		fileRemoved := 0
+		for eventName, commands := range events {
+			eventHooks, ok := hooks[eventName].([]interface{})
+			if !ok {
+				continue
+			}
+
+			newEventHooks, n := pruneEventHooks(eventHooks, commands)
+			fileRemoved += n
+			if len(newEventHooks) > 0 {
+				hooks[eventName] = newEventHooks
+			} else {
+				delete(hooks, eventName)
+			}
+		}
+
+		if fileRemoved == 0 {
+			continue
+		}
+
+		output, err := json.MarshalIndent(rawSettings, "", "  ")
+		if err != nil {
+			return removed, fmt.Errorf("failed to marshal settings: %w", err)
+		}
+		if err := os.WriteFile(settingsPath, output, 0644); err != nil {
+			return removed, fmt.Errorf("failed to write %s: %w", settingsPath, err)
+		}
+		removed += fileRemoved
+	}
+
+	return removed, nil
+}
+
+// readSettingsFile parses a settings file, returning nil if it doesn't exist
+func readSettingsFile(settingsPath string) (map[string]interface{}, error) {
+	content, err := os.ReadFile(settingsPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", settingsPath, err)
+	}
+
+	var rawSettings map[string]interface{}
+	if err := json.Unmarshal(content, &rawSettings); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", settingsPath, err)
+	}
+	return rawSettings, nil
+}
+
+// hookEntryCommands returns the commands of an event entry in either the old
+// (plain string) or new (matcher -> hooks -> type/command) format
+func hookEntryCommands(entry interface{}) []string {
+	if command, ok := entry.(string); ok {
+		return []string{command}
+	}
+
+	entryMap, ok := entry.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	hooksArr, _ := entryMap["hooks"].([]interface{})
+
+	var commands []string
+	for _, h := range hooksArr {
+		if hMap, ok := h.(map[string]interface{}); ok {
+			if command, ok := hMap["command"].(string); ok {
+				commands = append(commands, command)
+			}
+		}
+	}
+	return commands
+}
+
+// pruneEventHooks drops the given commands from an event's entries
+func pruneEventHooks(eventHooks []interface{}, commands map[string]bool) ([]interface{}, int) {
+	var kept []interface{}
+	removed := 0
+
+	for _, entry := range eventHooks {
+		if command, ok := entry.(string); ok {
+			if commands[command] {
+				removed++
+				continue
+			}
+			kept = append(kept, entry)
+			continue
+		}
+
+		entryMap, ok := entry.(map[string]interface{})
+		if !ok {
+			kept = append(kept, entry)
+			continue
+		}
+		hooksArr, ok := entryMap["hooks"].([]interface{})
+		if !ok {
+			kept = append(kept, entry)
+			continue
+		}
+
+		var keptHooks []interface{}
+		for _, h := range hooksArr {
+			if hMap, ok := h.(map[string]interface{}); ok {
+				if command, ok := hMap["command"].(string); ok && commands[command] {
+					removed++
+					continue
+				}
+			}
+			keptHooks = append(keptHooks, h)
+		}
+
+		if len(keptHooks) > 0 {
+			entryMap["hooks"] = keptHooks
+			kept = append(kept, entryMap)
+		}
+	}
+
+	return kept, removed
+}
+
+// resolveHookScript finds the script a hook command runs. direct reports
+// whether the script is executed itself (and so needs the executable bit)
+// rather than passed to an interpreter. An empty path means there is nothing
+// on disk to check with confidence: an inline `bash -c` command, a shell
+// builtin, a compound command or one using a variable that isn't set here.
+func resolveHookScript(command string, projectDir string) (path string, direct bool) {
+	for _, op := range hookShellOperators {
+		if strings.Contains(command, op) {
+			return "", false
+		}
+	}
+
+	fields := strings.Fields(command)
+
+	// Skip leading VAR=value assignments
+	for len(fields) > 0 && strings.Contains(fields[0], "=") && !strings.ContainsAny(fields[0], "/\"'") {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return "", false
+	}
+
+	program, ok := expandHookPath(fields[0], projectDir)
+	if !ok || shellBuiltins[program] {
+		return "", false
+	}
+	if !scriptInterpreters[filepath.Base(program)] {
+		if !strings.Contains(program, "/") {
+			if found, err := exec.LookPath(program); err == nil {
+				return found, true
+			}
+			return program, true
+		}
+		return absHookPath(program, projectDir), true
+	}
+
+	for _, arg := range fields[1:] {
+		if arg == "-c" || arg == "-e" {
+			return "", false
+		}
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		script, ok := expandHookPath(arg, projectDir)
+		if !ok {
+			return "", false
+		}
+		return absHookPath(script, projectDir), false
+	}
+	return "", false
+}
+
+// expandHookPath strips shell quotes and expands ~ and environment variables,
+// with $CLAUDE_PROJECT_DIR set to projectDir. ok is false if the token uses a
+// variable that isn't set, since its value when the hook runs is unknown.
+func expandHookPath(token string, projectDir string) (expanded string, ok bool) {
+	token = strings.NewReplacer(`"`, "", "'", "").Replace(token)
+
+	ok = true
+	token = os.Expand(token, func(name string) string {
+		if name == "CLAUDE_PROJECT_DIR" {
+			return projectDir
+		}
+		value, set := os.LookupEnv(name)
+		if !set {
+			ok = false
+		}
+		return value
+	})
+	if !ok {
+		return "", false
+	}
+
+	if token == "~" || strings.HasPrefix(token, "~/") {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			token = filepath.Join(homeDir, strings.TrimPrefix(token, "~"))
+		}
+	}
+	return token, true
+}
+
+// absHookPath resolves a relative script path against the project directory
+func absHookPath(path string, projectDir string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(projectDir, path)
+}
+
+// checkHookScript returns the problem with a resolved script, or "" if it can run
+func checkHookScript(path string, direct bool) string {
+	if path == "" {
+		return ""
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return HookScriptMissing
+	}
+	if direct && info.Mode()&0111 == 0 {
+		return HookScriptNotExecutable
+	}
+	return ""
+}
@@ -0,0 +1,324 @@
+package components
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHookInstallerDryRun(t *testing.T) {
+	settingsDir := filepath.Join(t.TempDir(), ".claude")
+	hooksDir := filepath.Join(settingsDir, "hooks")
+
+	installer := NewHookInstallerWithDir(settingsDir)
+	installer.SetDryRun(true)
+
+	if err := installer.mkdirAll(hooksDir); err != nil {
+		t.Fatalf("mkdirAll failed: %v", err)
+	}
+	if _, err := installer.copyHookScript("tool-logger.sh", hooksDir); err != nil {
+		t.Fatalf("copyHookScript failed: %v", err)
+	}
+	if err := installer.addHookToSettingsWithMatcher(settingsDir, hooksDir, "tool-logger.sh", "PostToolUse", "*"); err != nil {
+		t.Fatalf("addHookToSettingsWithMatcher failed: %v", err)
+	}
+
+	if _, err := os.Stat(settingsDir); !os.IsNotExist(err) {
+		t.Errorf("expected dry run to leave %s untouched, stat err: %v", settingsDir, err)
+	}
+}
+
+func TestHookInstallerAddHookToSettings(t *testing.T) {
+	settingsDir := t.TempDir()
+	hooksDir := filepath.Join(settingsDir, "hooks")
+
+	installer := NewHookInstallerWithDir(settingsDir)
+
+	if err := installer.mkdirAll(hooksDir); err != nil {
+		t.Fatalf("mkdirAll failed: %v", err)
+	}
+	if _, err := installer.copyHookScript("tool-logger.sh", hooksDir); err != nil {
+		t.Fatalf("copyHookScript failed: %v", err)
+	}
+
+	// Adding twice must not duplicate the entry
+	for i := 0; i < 2; i++ {
+		if err := installer.addHookToSettingsWithMatcher(settingsDir, hooksDir, "tool-logger.sh", "PostToolUse", "*"); err != nil {
+			t.Fatalf("addHookToSettingsWithMatcher failed: %v", err)
+		}
+	}
+
+	installed, err := installer.checkToolLoggerInSettingsFile(filepath.Join(settingsDir, "settings.local.json"))
+	if err != nil {
+		t.Fatalf("checkToolLoggerInSettingsFile failed: %v", err)
+	}
+	if !installed {
+		t.Error("expected tool logger to be installed")
+	}
+
+	content, err := os.ReadFile(filepath.Join(settingsDir, "settings.local.json"))
+	if err != nil {
+		t.Fatalf("failed to read settings: %v", err)
+	}
+	if count := strings.Count(string(content), "tool-logger.sh"); count != 1 {
+		t.Errorf("expected hook to be registered once, found %d", count)
+	}
+}
+
+func TestSessionHookRegistersLifecycleEvents(t *testing.T) {
+	settingsDir := t.TempDir()
+	hooksDir := filepath.Join(settingsDir, "hooks")
+
+	installer := NewHookInstallerWithDir(settingsDir)
+
+	if err := installer.mkdirAll(hooksDir); err != nil {
+		t.Fatalf("mkdirAll failed: %v", err)
+	}
+	if _, err := installer.copyHookScript("session-logger.sh", hooksDir); err != nil {
+		t.Fatalf("copyHookScript failed: %v", err)
+	}
+	for _, eventName := range sessionHookEvents {
+		if err := installer.addHookToSettingsAtPath(settingsDir, hooksDir, "session-logger.sh", eventName); err != nil {
+			t.Fatalf("addHookToSettingsAtPath failed: %v", err)
+		}
+	}
+
+	settingsPath := filepath.Join(settingsDir, "settings.local.json")
+	for _, eventName := range sessionHookEvents {
+		installed, err := installer.checkHookEventInSettingsFile(settingsPath, eventName, "session-logger")
+		if err != nil {
+			t.Fatalf("checkHookEventInSettingsFile failed: %v", err)
+		}
+		if !installed {
+			t.Errorf("expected session logger to be registered for %s", eventName)
+		}
+	}
+
+	if err := installer.removeHookFromSettingsAtPath(settingsDir, "session-logger.sh", "SessionEnd"); err != nil {
+		t.Fatalf("removeHookFromSettingsAtPath failed: %v", err)
+	}
+	installed, err := installer.checkHookEventInSettingsFile(settingsPath, "SessionEnd", "session-logger")
+	if err != nil {
+		t.Fatalf("checkHookEventInSettingsFile failed: %v", err)
+	}
+	if installed {
+		t.Error("expected session logger to be removed from SessionEnd")
+	}
+}
+
+func TestCopyHookScriptUpgrade(t *testing.T) {
+	hooksDir := t.TempDir()
+	installer := NewHookInstallerWithDir(hooksDir)
+
+	status, err := installer.copyHookScript("tool-logger.sh", hooksDir)
+	if err != nil {
+		t.Fatalf("copyHookScript failed: %v", err)
+	}
+	if status != HookScriptInstalled {
+		t.Errorf("expected %q, got %q", HookScriptInstalled, status)
+	}
+
+	status, err = installer.copyHookScript("tool-logger.sh", hooksDir)
+	if err != nil {
+		t.Fatalf("copyHookScript failed: %v", err)
+	}
+	if status != HookScriptAlreadyCurrent {
+		t.Errorf("expected %q, got %q", HookScriptAlreadyCurrent, status)
+	}
+
+	// An unversioned script is treated as outdated
+	scriptPath := filepath.Join(hooksDir, "tool-logger.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/bash\necho old\n"), 0755); err != nil {
+		t.Fatalf("failed to write old script: %v", err)
+	}
+
+	status, err = installer.copyHookScript("tool-logger.sh", hooksDir)
+	if err != nil {
+		t.Fatalf("copyHookScript failed: %v", err)
+	}
+	if status != HookScriptUpgraded {
+		t.Errorf("expected %q, got %q", HookScriptUpgraded, status)
+	}
+
+	content, err := os.ReadFile(scriptPath)
+	if err != nil {
+		t.Fatalf("failed to read script: %v", err)
+	}
+	if hookScriptVersion(content) == 0 {
+		t.Error("expected upgraded script to carry a version comment")
+	}
+}
+
+func TestAddHookToSettingsUpdatesStalePath(t *testing.T) {
+	settingsDir := t.TempDir()
+	hooksDir := filepath.Join(settingsDir, "hooks")
+	settingsPath := filepath.Join(settingsDir, "settings.local.json")
+
+	stale := `{"hooks":{"Notification":[{"hooks":[{"type":"command","command":"/old/place/notification-logger.sh"}]}]}}`
+	if err := os.WriteFile(settingsPath, []byte(stale), 0644); err != nil {
+		t.Fatalf("failed to write settings: %v", err)
+	}
+
+	installer := NewHookInstallerWithDir(settingsDir)
+	if err := installer.addHookToSettingsAtPath(settingsDir, hooksDir, "notification-logger.sh", "Notification"); err != nil {
+		t.Fatalf("addHookToSettingsAtPath failed: %v", err)
+	}
+
+	content, err := os.ReadFile(settingsPath)
+	if err != nil {
+		t.Fatalf("failed to read settings: %v", err)
+	}
+	if strings.Contains(string(content), "/old/place/") {
+		t.Error("expected stale hook path to be replaced")
+	}
+	if count := strings.Count(string(content), filepath.Join(hooksDir, "notification-logger.sh")); count != 1 {
+		t.Errorf("expected current hook path once, found %d", count)
+	}
+}
+
+func TestCheckAndPruneHookScripts(t *testing.T) {
+	projectDir := t.TempDir()
+	hooksDir := filepath.Join(projectDir, ".claude", "hooks")
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		t.Fatalf("failed to create hooks dir: %v", err)
+	}
+
+	okScript := filepath.Join(hooksDir, "ok.sh")
+	if err := os.WriteFile(okScript, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	plainScript := filepath.Join(hooksDir, "plain.sh")
+	if err := os.WriteFile(plainScript, []byte("#!/bin/sh\n"), 0644); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+
+	settingsPath := filepath.Join(projectDir, ".claude", "settings.local.json")
+	settings := `{"model":"sonnet","hooks":{
+		"PreToolUse":[{"matcher":"*","hooks":[
+			{"type":"command","command":"` + okScript + `"},
+			{"type":"command","command":"` + filepath.Join(hooksDir, "gone.sh") + `"}]}],
+		"Stop":[{"hooks":[{"type":"command","command":"` + plainScript + `"}]}],
+		"Notification":[{"hooks":[{"type":"command","command":"bash $CLAUDE_PROJECT_DIR/.claude/hooks/plain.sh"}]}]}}`
+	if err := os.WriteFile(settingsPath, []byte(settings), 0644); err != nil {
+		t.Fatalf("failed to write settings: %v", err)
+	}
+
+	paths := HookSettingsPaths(projectDir, filepath.Join(projectDir, "no-global"))
+	issues, err := CheckHookScripts(paths, projectDir)
+	if err != nil {
+		t.Fatalf("CheckHookScripts failed: %v", err)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("expected 2 issues, got %d: %+v", len(issues), issues)
+	}
+
+	problems := map[string]string{}
+	for _, issue := range issues {
+		problems[issue.Event] = issue.Problem
+	}
+	if problems["PreToolUse"] != HookScriptMissing {
+		t.Errorf("expected missing script for PreToolUse, got %q", problems["PreToolUse"])
+	}
+	if problems["Stop"] != HookScriptNotExecutable {
+		t.Errorf("expected non-executable script for Stop, got %q", problems["Stop"])
+	}
+
+	removed, err := PruneHookIssues(issues)
+	if err != nil {
+		t.Fatalf("PruneHookIssues failed: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("expected 2 removed hooks, got %d", removed)
+	}
+
+	content, err := os.ReadFile(settingsPath)
+	if err != nil {
+		t.Fatalf("failed to read settings: %v", err)
+	}
+	if strings.Contains(string(content), "gone.sh") || strings.Contains(string(content), `"Stop"`) {
+		t.Errorf("expected dangling entries to be pruned, got %s", content)
+	}
+	if !strings.Contains(string(content), "ok.sh") || !strings.Contains(string(content), `"model"`) {
+		t.Errorf("expected remaining settings to be preserved, got %s", content)
+	}
+
+	issues, err = CheckHookScripts(paths, projectDir)
+	if err != nil {
+		t.Fatalf("CheckHookScripts failed: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("expected no issues after pruning, got %+v", issues)
+	}
+}
+
+func TestCheckHookScriptsExpansionAndUncertainCommands(t *testing.T) {
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	os.Unsetenv("CCT_UNSET_HOOK_VAR")
+
+	homeHooks := filepath.Join(homeDir, ".claude", "hooks")
+	if err := os.MkdirAll(homeHooks, 0755); err != nil {
+		t.Fatalf("failed to create hooks dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(homeHooks, "x.sh"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+
+	projectDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(projectDir, ".claude"), 0755); err != nil {
+		t.Fatalf("failed to create .claude dir: %v", err)
+	}
+	settingsPath := filepath.Join(projectDir, ".claude", "settings.json")
+	settings := `{"hooks":{"PreToolUse":[{"hooks":[
+		{"type":"command","command":"$HOME/.claude/hooks/x.sh"},
+		{"type":"command","command":"python3 ${HOME}/.claude/hooks/x.sh"},
+		{"type":"command","command":"cd scripts && ./missing.sh"},
+		{"type":"command","command":"$CCT_UNSET_HOOK_VAR/missing.sh"},
+		{"type":"command","command":"echo done"},
+		{"type":"command","command":"cct-no-such-hook-program --flag"},
+		{"type":"command","command":"$HOME/.claude/hooks/gone.sh"}]}]}}`
+	if err := os.WriteFile(settingsPath, []byte(settings), 0644); err != nil {
+		t.Fatalf("failed to write settings: %v", err)
+	}
+
+	issues, err := CheckHookScripts([]string{settingsPath}, projectDir)
+	if err != nil {
+		t.Fatalf("CheckHookScripts failed: %v", err)
+	}
+
+	byCommand := map[string]HookIssue{}
+	for _, issue := range issues {
+		byCommand[issue.Command] = issue
+	}
+	if len(issues) != 2 {
+		t.Fatalf("expected 2 issues, got %d: %+v", len(issues), issues)
+	}
+	if issue, ok := byCommand["$HOME/.claude/hooks/gone.sh"]; !ok || !issue.Certain || issue.ScriptPath != filepath.Join(homeHooks, "gone.sh") {
+		t.Errorf("expected a certain issue for the missing $HOME script, got %+v", issue)
+	}
+	if issue, ok := byCommand["cct-no-such-hook-program --flag"]; !ok || issue.Certain {
+		t.Errorf("expected an uncertain issue for the unknown program, got %+v", issue)
+	}
+
+	// Only the certain issue is pruned
+	removed, err := PruneHookIssues(issues)
+	if err != nil {
+		t.Fatalf("PruneHookIssues failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("expected 1 removed hook, got %d", removed)
+	}
+	content, err := os.ReadFile(settingsPath)
+	if err != nil {
+		t.Fatalf("failed to read settings: %v", err)
+	}
+	for _, kept := range []string{"hooks/x.sh", "cd scripts", "CCT_UNSET_HOOK_VAR", "echo done", "cct-no-such-hook-program"} {
+		if !strings.Contains(string(content), kept) {
+			t.Errorf("expected %q to be kept, got %s", kept, content)
+		}
+	}
+	if strings.Contains(string(content), "gone.sh") {
+		t.Errorf("expected gone.sh to be pruned, got %s", content)
+	}
+}
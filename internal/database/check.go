@@ -0,0 +1,74 @@
+package database
+
+import (
+	"fmt"
+)
+
+// CheckReport summarizes the result of Database.Check
+type CheckReport struct {
+	IntegrityOK      bool     `json:"integrity_ok"`
+	IntegrityErrors  []string `json:"integrity_errors,omitempty"`
+	OrphanedMessages int64    `json:"orphaned_messages"` // agent_messages rows whose session no longer exists
+	Repaired         bool     `json:"repaired"`
+	DeletedMessages  int64    `json:"deleted_messages"`
+}
+
+// orphanedMessagesCondition selects agent_messages rows without a matching agent session.
+// Foreign keys are only enforced on connections where the pragma is set, so these can accumulate.
+const orphanedMessagesCondition = "session_id NOT IN (SELECT id FROM agent_sessions)"
+
+// Check runs PRAGMA integrity_check and counts orphaned agent messages.
+// With repair, orphaned messages are deleted and all indexes are rebuilt.
+func (d *Database) Check(repair bool) (*CheckReport, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	report := &CheckReport{}
+
+	rows, err := d.db.Query("PRAGMA integrity_check")
+	if err != nil {
+		return nil, fmt.Errorf("failed to run integrity check: %w", err)
+	}
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to read integrity check result: %w", err)
+		}
+		if result != "ok" {
+			report.IntegrityErrors = append(report.IntegrityErrors, result)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("failed to read integrity check results: %w", err)
+	}
+	rows.Close()
+	report.IntegrityOK = len(report.IntegrityErrors) == 0
+
+	err = d.db.QueryRow("SELECT COUNT(*) FROM agent_messages WHERE " + orphanedMessagesCondition).Scan(&report.OrphanedMessages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count orphaned messages: %w", err)
+	}
+
+	if !repair {
+		return report, nil
+	}
+
+	result, err := d.db.Exec("DELETE FROM agent_messages WHERE " + orphanedMessagesCondition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete orphaned messages: %w", err)
+	}
+	report.DeletedMessages, _ = result.RowsAffected()
+
+	if _, err := d.db.Exec("REINDEX"); err != nil {
+		return nil, fmt.Errorf("failed to reindex database: %w", err)
+	}
+	report.Repaired = true
+
+	return report, nil
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// MasterKeyEnvVar names the environment variable holding the passphrase used
+// to encrypt provider API keys at rest. When unset, keys are stored as plaintext.
+const MasterKeyEnvVar = "CCT_MASTER_KEY"
+
+// encryptedPrefix marks values encrypted with EncryptSecret so plaintext
+// values written before encryption was enabled can still be read
+const encryptedPrefix = "enc:v1:"
+
+// EncryptSecret encrypts a value with AES-256-GCM using a key derived from
+// CCT_MASTER_KEY. Returns the value unchanged when the master key is unset,
+// the value is empty, or it is already encrypted.
+func EncryptSecret(value string) (string, error) {
+	masterKey := os.Getenv(MasterKeyEnvVar)
+	if masterKey == "" || value == "" || strings.HasPrefix(value, encryptedPrefix) {
+		return value, nil
+	}
+
+	gcm, err := newSecretCipher(masterKey)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret reverses EncryptSecret. Plaintext values are returned as-is;
+// encrypted values require CCT_MASTER_KEY to be set to the same passphrase.
+func DecryptSecret(value string) (string, error) {
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return value, nil
+	}
+
+	masterKey := os.Getenv(MasterKeyEnvVar)
+	if masterKey == "" {
+		return "", fmt.Errorf("value is encrypted but %s is not set", MasterKeyEnvVar)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode encrypted value: %w", err)
+	}
+
+	gcm, err := newSecretCipher(masterKey)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("encrypted value is too short")
+	}
+
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value (wrong %s?): %w", MasterKeyEnvVar, err)
+	}
+
+	return string(plaintext), nil
+}
+
+// newSecretCipher derives a 256-bit AES key from the passphrase and returns a GCM cipher
+func newSecretCipher(masterKey string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(masterKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return gcm, nil
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"sync"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -24,6 +25,14 @@ type Database struct {
 	mu   sync.RWMutex
 }
 
+// BusyTimeoutEnvVar names the environment variable overriding how long a
+// connection waits on a lock held by another process (hooks recording while
+// the dashboard reads) before failing with "database is locked".
+const BusyTimeoutEnvVar = "CCT_DB_BUSY_TIMEOUT_MS"
+
+// DefaultBusyTimeoutMS is the busy timeout used when BusyTimeoutEnvVar is unset
+const DefaultBusyTimeoutMS = 5000
+
 var (
 	instance *Database
 	once     sync.Once
@@ -55,7 +64,7 @@ func Initialize(dataDir string) (*Database, error) {
 		dbExists = true
 	}
 
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sql.Open("sqlite3", dataSourceName(dbPath, busyTimeoutMS()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -75,12 +84,9 @@ func Initialize(dataDir string) (*Database, error) {
 		}
 	}
 
-	// Enable foreign keys and set pragmas for performance
+	// Connection-level pragmas are set through the DSN so every pooled
+	// connection gets them; only the remaining ones are executed here
 	pragmas := []string{
-		"PRAGMA foreign_keys = ON",
-		"PRAGMA journal_mode = WAL",
-		"PRAGMA synchronous = NORMAL",
-		"PRAGMA cache_size = -64000", // 64MB cache
 		"PRAGMA temp_store = MEMORY",
 	}
 
@@ -126,6 +132,31 @@ func Initialize(dataDir string) (*Database, error) {
 	return instance, nil
 }
 
+// dataSourceName builds the SQLite DSN. WAL lets readers run alongside a
+// writer, and the busy timeout makes a connection wait for another process's
+// write lock instead of failing immediately. In-process access is still
+// serialized by Database.mu, so the timeout only covers other processes.
+func dataSourceName(dbPath string, busyTimeoutMS int) string {
+	return fmt.Sprintf(
+		"%s?_busy_timeout=%d&_journal_mode=WAL&_foreign_keys=on&_synchronous=NORMAL&_cache_size=-64000",
+		dbPath, busyTimeoutMS,
+	)
+}
+
+// busyTimeoutMS returns the busy timeout from BusyTimeoutEnvVar, falling back
+// to DefaultBusyTimeoutMS when it is unset or not a non-negative integer
+func busyTimeoutMS() int {
+	value := os.Getenv(BusyTimeoutEnvVar)
+	if value == "" {
+		return DefaultBusyTimeoutMS
+	}
+	ms, err := strconv.Atoi(value)
+	if err != nil || ms < 0 {
+		return DefaultBusyTimeoutMS
+	}
+	return ms
+}
+
 // GetInstance returns the singleton database instance
 func GetInstance() *Database {
 	return instance
@@ -225,189 +256,3 @@ func (d *Database) Vacuum() error {
 
 	return nil
 }
-
-// runMigrations runs database migrations for existing databases
-func runMigrations(db *sql.DB) error {
-	// Migration 1: Add model_name column to providers table if it doesn't exist
-	var columnExists bool
-	query := `
-		SELECT COUNT(*) > 0
-		FROM pragma_table_info('providers')
-		WHERE name='model_name'
-	`
-	if err := db.QueryRow(query).Scan(&columnExists); err != nil {
-		// If the providers table doesn't exist yet, that's fine - it will be created by schema.sql
-		return nil
-	}
-
-	if !columnExists {
-		// Add the model_name column
-		_, err := db.Exec("ALTER TABLE providers ADD COLUMN model_name TEXT")
-		if err != nil {
-			return fmt.Errorf("failed to add model_name column: %w", err)
-		}
-	}
-
-	// Migration 2: Add session_name column to user_messages table if it doesn't exist
-	var sessionNameExists bool
-	sessionQuery := `
-		SELECT COUNT(*) > 0
-		FROM pragma_table_info('user_messages')
-		WHERE name='session_name'
-	`
-	if err := db.QueryRow(sessionQuery).Scan(&sessionNameExists); err != nil {
-		// If the user_messages table doesn't exist yet, that's fine - it will be created by schema.sql
-		return nil
-	}
-
-	if !sessionNameExists {
-		// Add the session_name column
-		_, err := db.Exec("ALTER TABLE user_messages ADD COLUMN session_name TEXT")
-		if err != nil {
-			return fmt.Errorf("failed to add session_name column to user_messages: %w", err)
-		}
-	}
-
-	// Migration 3: Add session_name column to shell_commands table if it doesn't exist
-	var shellSessionNameExists bool
-	shellSessionQuery := `
-		SELECT COUNT(*) > 0
-		FROM pragma_table_info('shell_commands')
-		WHERE name='session_name'
-	`
-	if err := db.QueryRow(shellSessionQuery).Scan(&shellSessionNameExists); err == nil {
-		if !shellSessionNameExists {
-			_, err := db.Exec("ALTER TABLE shell_commands ADD COLUMN session_name TEXT")
-			if err != nil {
-				return fmt.Errorf("failed to add session_name column to shell_commands: %w", err)
-			}
-		}
-	}
-
-	// Migration 4: Add session_name column to claude_commands table if it doesn't exist
-	var claudeSessionNameExists bool
-	claudeSessionQuery := `
-		SELECT COUNT(*) > 0
-		FROM pragma_table_info('claude_commands')
-		WHERE name='session_name'
-	`
-	if err := db.QueryRow(claudeSessionQuery).Scan(&claudeSessionNameExists); err == nil {
-		if !claudeSessionNameExists {
-			_, err := db.Exec("ALTER TABLE claude_commands ADD COLUMN session_name TEXT")
-			if err != nil {
-				return fmt.Errorf("failed to add session_name column to claude_commands: %w", err)
-			}
-		}
-	}
-
-	// Migration 5: Create notifications table if it doesn't exist
-	var notificationsTableExists bool
-	notificationsQuery := `
-		SELECT COUNT(*) > 0
-		FROM sqlite_master
-		WHERE type='table' AND name='notifications'
-	`
-	if err := db.QueryRow(notificationsQuery).Scan(&notificationsTableExists); err == nil {
-		if !notificationsTableExists {
-			createNotificationsTable := `
-				CREATE TABLE IF NOT EXISTS notifications (
-					id INTEGER PRIMARY KEY AUTOINCREMENT,
-					conversation_id TEXT NOT NULL,
-					session_name TEXT,
-					notification_type TEXT NOT NULL,
-					message TEXT NOT NULL,
-					tool_name TEXT,
-					command_details TEXT,
-					working_directory TEXT,
-					git_branch TEXT,
-					notified_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-					created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-				);
-
-				CREATE INDEX IF NOT EXISTS idx_notifications_conversation
-					ON notifications(conversation_id, notified_at DESC);
-
-				CREATE INDEX IF NOT EXISTS idx_notifications_notified_at
-					ON notifications(notified_at DESC);
-
-				CREATE INDEX IF NOT EXISTS idx_notifications_type
-					ON notifications(notification_type, notified_at DESC);
-
-				CREATE INDEX IF NOT EXISTS idx_notifications_tool
-					ON notifications(tool_name, notified_at DESC) WHERE tool_name IS NOT NULL;
-			`
-			_, err := db.Exec(createNotificationsTable)
-			if err != nil {
-				return fmt.Errorf("failed to create notifications table: %w", err)
-			}
-		}
-	}
-
-	// Migration 6: Add command_details column to notifications table if it doesn't exist
-	var commandDetailsExists bool
-	commandDetailsQuery := `
-		SELECT COUNT(*) > 0
-		FROM pragma_table_info('notifications')
-		WHERE name='command_details'
-	`
-	if err := db.QueryRow(commandDetailsQuery).Scan(&commandDetailsExists); err == nil {
-		if !commandDetailsExists {
-			_, err := db.Exec("ALTER TABLE notifications ADD COLUMN command_details TEXT")
-			if err != nil {
-				return fmt.Errorf("failed to add command_details column to notifications: %w", err)
-			}
-		}
-	}
-
-	// Migration 7: Add model_provider and model_name columns to all tables
-	tables := []string{"shell_commands", "claude_commands", "conversations", "user_messages", "notifications"}
-	for _, table := range tables {
-		// Check if model_provider exists
-		var modelProviderExists bool
-		providerQuery := fmt.Sprintf(`
-			SELECT COUNT(*) > 0
-			FROM pragma_table_info('%s')
-			WHERE name='model_provider'
-		`, table)
-		if err := db.QueryRow(providerQuery).Scan(&modelProviderExists); err == nil {
-			if !modelProviderExists {
-				alterQuery := fmt.Sprintf("ALTER TABLE %s ADD COLUMN model_provider TEXT", table)
-				if _, err := db.Exec(alterQuery); err != nil {
-					return fmt.Errorf("failed to add model_provider column to %s: %w", table, err)
-				}
-			}
-		}
-
-		// Check if model_name exists
-		var modelNameExists bool
-		nameQuery := fmt.Sprintf(`
-			SELECT COUNT(*) > 0
-			FROM pragma_table_info('%s')
-			WHERE name='model_name'
-		`, table)
-		if err := db.QueryRow(nameQuery).Scan(&modelNameExists); err == nil {
-			if !modelNameExists {
-				alterQuery := fmt.Sprintf("ALTER TABLE %s ADD COLUMN model_name TEXT", table)
-				if _, err := db.Exec(alterQuery); err != nil {
-					return fmt.Errorf("failed to add model_name column to %s: %w", table, err)
-				}
-			}
-		}
-	}
-
-	// Create indexes for model columns if they don't exist
-	indexes := []string{
-		"CREATE INDEX IF NOT EXISTS idx_shell_commands_model ON shell_commands(model_provider, model_name)",
-		"CREATE INDEX IF NOT EXISTS idx_claude_commands_model ON claude_commands(model_provider, model_name)",
-		"CREATE INDEX IF NOT EXISTS idx_user_messages_model ON user_messages(model_provider, model_name)",
-		"CREATE INDEX IF NOT EXISTS idx_notifications_model ON notifications(model_provider, model_name)",
-		"CREATE INDEX IF NOT EXISTS idx_conversations_model ON conversations(model_provider, model_name)",
-	}
-	for _, indexSQL := range indexes {
-		if _, err := db.Exec(indexSQL); err != nil {
-			return fmt.Errorf("failed to create index: %w", err)
-		}
-	}
-
-	return nil
-}
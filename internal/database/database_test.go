@@ -1,8 +1,13 @@
 package database
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -57,6 +62,79 @@ func TestDatabaseInitialization(t *testing.T) {
 	}
 }
 
+func TestConnectionPragmas(t *testing.T) {
+	ResetInstance()
+	t.Setenv(BusyTimeoutEnvVar, "1234")
+
+	tempDir, err := os.MkdirTemp("", "cct_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	db, err := Initialize(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer ResetInstance()
+
+	// Hold two connections at once so both come from the pool, not a reused one
+	ctx := context.Background()
+	conns := make([]*sql.Conn, 0, 2)
+	for i := 0; i < 2; i++ {
+		conn, err := db.GetDB().Conn(ctx)
+		if err != nil {
+			t.Fatalf("Failed to get connection: %v", err)
+		}
+		conns = append(conns, conn)
+
+		var busyTimeout, foreignKeys int
+		var journalMode string
+		if err := conn.QueryRowContext(ctx, "PRAGMA busy_timeout").Scan(&busyTimeout); err != nil {
+			t.Fatalf("Failed to read busy_timeout: %v", err)
+		}
+		if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+			t.Fatalf("Failed to read foreign_keys: %v", err)
+		}
+		if err := conn.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&journalMode); err != nil {
+			t.Fatalf("Failed to read journal_mode: %v", err)
+		}
+
+		if busyTimeout != 1234 {
+			t.Errorf("Connection %d: expected busy_timeout 1234, got %d", i, busyTimeout)
+		}
+		if foreignKeys != 1 {
+			t.Errorf("Connection %d: expected foreign keys enabled", i)
+		}
+		if journalMode != "wal" {
+			t.Errorf("Connection %d: expected WAL journal mode, got %q", i, journalMode)
+		}
+	}
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+func TestBusyTimeoutFromEnv(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int
+	}{
+		{"", DefaultBusyTimeoutMS},
+		{"250", 250},
+		{"0", 0},
+		{"-1", DefaultBusyTimeoutMS},
+		{"soon", DefaultBusyTimeoutMS},
+	}
+
+	for _, tt := range tests {
+		t.Setenv(BusyTimeoutEnvVar, tt.value)
+		if got := busyTimeoutMS(); got != tt.expected {
+			t.Errorf("%s=%q: expected %d, got %d", BusyTimeoutEnvVar, tt.value, tt.expected, got)
+		}
+	}
+}
+
 func TestShellCommandRecording(t *testing.T) {
 	// Reset singleton for test
 	ResetInstance()
@@ -196,3 +274,1378 @@ func TestClaudeCommandRecording(t *testing.T) {
 		t.Errorf("Expected 'main', got '%s'", retrieved.GitBranch)
 	}
 }
+
+func TestClaudeCommandSuccessFilter(t *testing.T) {
+	// Reset singleton for test
+	ResetInstance()
+
+	// Create temp directory for test
+	tempDir, err := os.MkdirTemp("", "cct_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Initialize database
+	db, err := Initialize(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	// Record one successful and one failed command
+	for _, success := range []bool{true, false} {
+		cmd := &ClaudeCommand{
+			ConversationID: "test-conv-filter",
+			ToolName:       "Bash",
+			Success:        success,
+			ExecutedAt:     time.Now(),
+		}
+		if !success {
+			cmd.ErrorMessage = "command failed"
+		}
+		if err := repo.RecordClaudeCommand(cmd); err != nil {
+			t.Fatalf("Failed to record claude command: %v", err)
+		}
+	}
+
+	failed := false
+	commands, err := repo.GetClaudeCommands(&CommandHistoryQuery{
+		ConversationID: "test-conv-filter",
+		Success:        &failed,
+	})
+	if err != nil {
+		t.Fatalf("Failed to get claude commands: %v", err)
+	}
+
+	if len(commands) != 1 {
+		t.Fatalf("Expected 1 failed command, got %d", len(commands))
+	}
+
+	if commands[0].Success {
+		t.Error("Expected failed command, got successful one")
+	}
+}
+
+func TestConversationTags(t *testing.T) {
+	// Reset singleton for test
+	ResetInstance()
+
+	// Create temp directory for test
+	tempDir, err := os.MkdirTemp("", "cct_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Initialize database
+	db, err := Initialize(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	// Tags are lowercased and de-duplicated
+	for _, tag := range []string{"Refactor", "refactor ", "bugfix"} {
+		if err := repo.AddTag("conv-tags", tag); err != nil {
+			t.Fatalf("Failed to add tag: %v", err)
+		}
+	}
+
+	tags, err := repo.GetTags("conv-tags")
+	if err != nil {
+		t.Fatalf("Failed to get tags: %v", err)
+	}
+
+	if len(tags) != 2 || tags[0] != "bugfix" || tags[1] != "refactor" {
+		t.Errorf("Expected [bugfix refactor], got %v", tags)
+	}
+
+	ids, err := repo.GetConversationIDsByTag("REFACTOR")
+	if err != nil {
+		t.Fatalf("Failed to get conversations by tag: %v", err)
+	}
+
+	if len(ids) != 1 || ids[0] != "conv-tags" {
+		t.Errorf("Expected [conv-tags], got %v", ids)
+	}
+
+	if err := repo.RemoveTag("conv-tags", "Bugfix"); err != nil {
+		t.Fatalf("Failed to remove tag: %v", err)
+	}
+
+	tags, _ = repo.GetTags("conv-tags")
+	if len(tags) != 1 {
+		t.Errorf("Expected 1 tag after removal, got %v", tags)
+	}
+
+	if err := repo.AddTag("conv-tags", "   "); err == nil {
+		t.Error("Expected error for empty tag")
+	}
+}
+
+func TestSchemaMigrations(t *testing.T) {
+	ResetInstance()
+
+	tempDir, err := os.MkdirTemp("", "cct_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	db, err := Initialize(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	version, err := db.SchemaVersion()
+	if err != nil {
+		t.Fatalf("Failed to get schema version: %v", err)
+	}
+	if version != LatestSchemaVersion() {
+		t.Errorf("Expected schema version %d, got %d", LatestSchemaVersion(), version)
+	}
+
+	// Re-opening the same database must not re-apply migrations
+	ResetInstance()
+	db, err = Initialize(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to re-initialize database: %v", err)
+	}
+	defer ResetInstance()
+
+	applied, err := db.AppliedMigrations()
+	if err != nil {
+		t.Fatalf("Failed to list applied migrations: %v", err)
+	}
+	if len(applied) != len(migrations) {
+		t.Errorf("Expected %d applied migrations, got %d", len(migrations), len(applied))
+	}
+	for i, m := range applied {
+		if m.Version != migrations[i].Version {
+			t.Errorf("Expected migration %d at position %d, got %d", migrations[i].Version, i, m.Version)
+		}
+	}
+}
+
+func TestStreamHistory(t *testing.T) {
+	// Reset singleton for test
+	ResetInstance()
+
+	// Create temp directory for test
+	tempDir, err := os.MkdirTemp("", "cct_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Initialize database
+	db, err := Initialize(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+	base := time.Now().Add(-time.Hour)
+
+	if err := repo.RecordClaudeCommand(&ClaudeCommand{
+		ConversationID: "conv-stream",
+		ToolName:       "Read",
+		Success:        true,
+		ExecutedAt:     base.Add(2 * time.Minute),
+	}); err != nil {
+		t.Fatalf("Failed to record claude command: %v", err)
+	}
+	if err := repo.RecordShellCommand(&ShellCommand{
+		ConversationID: "conv-stream",
+		Command:        "ls",
+		ExecutedAt:     base.Add(1 * time.Minute),
+	}); err != nil {
+		t.Fatalf("Failed to record shell command: %v", err)
+	}
+	if err := repo.RecordShellCommand(&ShellCommand{
+		ConversationID: "conv-other",
+		Command:        "pwd",
+		ExecutedAt:     base,
+	}); err != nil {
+		t.Fatalf("Failed to record shell command: %v", err)
+	}
+
+	var rows []*HistoryRow
+	err = repo.StreamHistory("conv-stream", func(row *HistoryRow) error {
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamHistory failed: %v", err)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows for conv-stream, got %d", len(rows))
+	}
+	if rows[0].Type != "shell" || rows[1].Type != "claude" {
+		t.Errorf("Expected shell then claude ordered by timestamp, got %s then %s", rows[0].Type, rows[1].Type)
+	}
+	if rows[0].Timestamp.IsZero() {
+		t.Error("Expected timestamp to be parsed")
+	}
+
+	var content map[string]interface{}
+	if err := json.Unmarshal(rows[1].Content, &content); err != nil {
+		t.Fatalf("Content is not valid JSON: %v", err)
+	}
+	if content["tool_name"] != "Read" || content["success"] != true {
+		t.Errorf("Unexpected claude content: %v", content)
+	}
+
+	// No filter streams everything
+	total := 0
+	if err := repo.StreamHistory("", func(row *HistoryRow) error {
+		total++
+		return nil
+	}); err != nil {
+		t.Fatalf("StreamHistory failed: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("Expected 3 rows without filter, got %d", total)
+	}
+}
+
+func TestProcessHistory(t *testing.T) {
+	// Reset singleton for test
+	ResetInstance()
+
+	// Create temp directory for test
+	tempDir, err := os.MkdirTemp("", "cct_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Initialize database
+	db, err := Initialize(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+	hour := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	samples := []struct {
+		at    time.Time
+		count int
+	}{
+		{hour.Add(5 * time.Minute), 1},
+		{hour.Add(35 * time.Minute), 3},
+		{hour.Add(65 * time.Minute), 2},
+	}
+	for _, s := range samples {
+		if err := repo.RecordProcessSample(&ProcessSample{SampledAt: s.at, ProcessCount: s.count}); err != nil {
+			t.Fatalf("Failed to record process sample: %v", err)
+		}
+	}
+
+	series, err := repo.GetProcessHistory("hour", hour.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to get process history: %v", err)
+	}
+	if len(series) != 2 {
+		t.Fatalf("Expected 2 hourly buckets, got %d", len(series))
+	}
+	if !series[0].Start.Equal(hour) {
+		t.Errorf("Expected first bucket at %v, got %v", hour, series[0].Start)
+	}
+	if series[0].Samples != 2 || series[0].MaxProcesses != 3 || series[0].AvgProcesses != 2 {
+		t.Errorf("Unexpected first bucket: %+v", series[0])
+	}
+
+	if _, err := repo.GetProcessHistory("week", hour); err == nil {
+		t.Error("Expected error for invalid bucket")
+	}
+
+	deleted, err := repo.DeleteProcessSamplesBefore(hour.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to prune samples: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("Expected 2 pruned samples, got %d", deleted)
+	}
+}
+
+func TestConversationStatus(t *testing.T) {
+	// Reset singleton for test
+	ResetInstance()
+
+	// Create temp directory for test
+	tempDir, err := os.MkdirTemp("", "cct_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Initialize database
+	db, err := Initialize(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	conv, err := repo.GetConversation("conv-1")
+	if err != nil {
+		t.Fatalf("GetConversation failed: %v", err)
+	}
+	if conv != nil {
+		t.Fatalf("Expected nil for unknown conversation, got %+v", conv)
+	}
+
+	now := time.Now()
+	if err := repo.UpsertConversation(&Conversation{
+		ID:             "conv-1",
+		ProjectPath:    "/tmp/project",
+		StartedAt:      now,
+		LastActivityAt: now,
+		TotalTokens:    42,
+		Status:         ConversationStatusActive,
+	}); err != nil {
+		t.Fatalf("UpsertConversation failed: %v", err)
+	}
+
+	conv, err = repo.GetConversation("conv-1")
+	if err != nil {
+		t.Fatalf("GetConversation failed: %v", err)
+	}
+	conv.Status = ConversationStatusArchived
+	if err := repo.UpsertConversation(conv); err != nil {
+		t.Fatalf("UpsertConversation failed: %v", err)
+	}
+
+	statuses, err := repo.GetConversationStatuses()
+	if err != nil {
+		t.Fatalf("GetConversationStatuses failed: %v", err)
+	}
+	if statuses["conv-1"] != ConversationStatusArchived {
+		t.Errorf("Expected status %q, got %q", ConversationStatusArchived, statuses["conv-1"])
+	}
+
+	conv, err = repo.GetConversation("conv-1")
+	if err != nil {
+		t.Fatalf("GetConversation failed: %v", err)
+	}
+	if conv.TotalTokens != 42 || conv.ProjectPath != "/tmp/project" {
+		t.Errorf("Expected other fields to be preserved, got %+v", conv)
+	}
+
+	if !IsValidConversationStatus(ConversationStatusCompleted) || IsValidConversationStatus("deleted") {
+		t.Error("IsValidConversationStatus returned unexpected result")
+	}
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	// Reset singleton for test
+	ResetInstance()
+
+	// Create temp directory for test
+	tempDir, err := os.MkdirTemp("", "cct_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Initialize database
+	db, err := Initialize(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	if err := repo.RecordSessionLifecycle(&CLISession{
+		ConversationID:   "sess-1",
+		SessionName:      "Kyle",
+		WorkingDirectory: "/tmp/project",
+		StartSource:      "startup",
+	}, SessionLifecycleStart, start); err != nil {
+		t.Fatalf("RecordSessionLifecycle(start) failed: %v", err)
+	}
+
+	if err := repo.RecordSessionLifecycle(&CLISession{
+		ConversationID: "sess-1",
+		EndReason:      "prompt_input_exit",
+	}, SessionLifecycleEnd, start.Add(90*time.Second)); err != nil {
+		t.Fatalf("RecordSessionLifecycle(end) failed: %v", err)
+	}
+
+	// An end without a start is still recorded
+	if err := repo.RecordSessionLifecycle(&CLISession{ConversationID: "sess-2"}, SessionLifecycleEnd, start); err != nil {
+		t.Fatalf("RecordSessionLifecycle(end) failed: %v", err)
+	}
+
+	// A start delivered after its end keeps the recorded end
+	if err := repo.RecordSessionLifecycle(&CLISession{
+		ConversationID: "sess-4",
+		EndReason:      "logout",
+	}, SessionLifecycleEnd, start.Add(time.Minute)); err != nil {
+		t.Fatalf("RecordSessionLifecycle(end) failed: %v", err)
+	}
+	if err := repo.RecordSessionLifecycle(&CLISession{
+		ConversationID: "sess-4",
+		StartSource:    "resume",
+	}, SessionLifecycleStart, start); err != nil {
+		t.Fatalf("RecordSessionLifecycle(start) failed: %v", err)
+	}
+
+	if err := repo.RecordSessionLifecycle(&CLISession{ConversationID: "sess-3"}, "pause", start); err == nil {
+		t.Error("Expected error for unknown lifecycle event")
+	}
+
+	sessions, err := repo.GetCLISessions(10)
+	if err != nil {
+		t.Fatalf("GetCLISessions failed: %v", err)
+	}
+	if len(sessions) != 3 {
+		t.Fatalf("Expected 3 sessions, got %d", len(sessions))
+	}
+
+	var sess1, sess4 *CLISession
+	for _, s := range sessions {
+		switch s.ConversationID {
+		case "sess-1":
+			sess1 = s
+		case "sess-4":
+			sess4 = s
+		}
+	}
+	if sess1 == nil {
+		t.Fatal("sess-1 not found")
+	}
+	if sess1.SessionName != "Kyle" || sess1.StartSource != "startup" || sess1.EndReason != "prompt_input_exit" {
+		t.Errorf("Unexpected session fields: %+v", sess1)
+	}
+	if sess1.DurationSeconds == nil || *sess1.DurationSeconds != 90 {
+		t.Errorf("Expected 90s duration, got %v", sess1.DurationSeconds)
+	}
+
+	if sess4 == nil {
+		t.Fatal("sess-4 not found")
+	}
+	if sess4.StartSource != "resume" || sess4.EndReason != "logout" || sess4.EndedAt == nil {
+		t.Errorf("Expected end to survive a later start, got %+v", sess4)
+	}
+	if sess4.DurationSeconds == nil || *sess4.DurationSeconds != 60 {
+		t.Errorf("Expected 60s duration, got %v", sess4.DurationSeconds)
+	}
+}
+
+func TestGetWorkingDirectories(t *testing.T) {
+	// Reset singleton for test
+	ResetInstance()
+
+	// Create temp directory for test
+	tempDir, err := os.MkdirTemp("", "cct_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Initialize database
+	db, err := Initialize(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+	base := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	if err := repo.RecordUserMessage(&UserMessage{
+		ConversationID:   "conv-1",
+		Message:          "hello",
+		WorkingDirectory: "/projects/alpha",
+		SubmittedAt:      base,
+	}); err != nil {
+		t.Fatalf("Failed to record user message: %v", err)
+	}
+	if err := repo.RecordShellCommand(&ShellCommand{
+		ConversationID:   "conv-1",
+		Command:          "ls",
+		WorkingDirectory: "/projects/alpha",
+		ExecutedAt:       base.Add(time.Minute),
+	}); err != nil {
+		t.Fatalf("Failed to record shell command: %v", err)
+	}
+	if err := repo.RecordClaudeCommand(&ClaudeCommand{
+		ConversationID:   "conv-2",
+		ToolName:         "Read",
+		WorkingDirectory: "/projects/beta",
+		Success:          true,
+		ExecutedAt:       base.Add(time.Hour),
+	}); err != nil {
+		t.Fatalf("Failed to record claude command: %v", err)
+	}
+	// Empty directories are excluded
+	if err := repo.RecordShellCommand(&ShellCommand{
+		ConversationID: "conv-3",
+		Command:        "pwd",
+		ExecutedAt:     base,
+	}); err != nil {
+		t.Fatalf("Failed to record shell command: %v", err)
+	}
+
+	dirs, err := repo.GetWorkingDirectories()
+	if err != nil {
+		t.Fatalf("GetWorkingDirectories failed: %v", err)
+	}
+	if len(dirs) != 2 {
+		t.Fatalf("Expected 2 directories, got %d", len(dirs))
+	}
+
+	if dirs[0].Path != "/projects/beta" || dirs[0].ActivityCount != 1 {
+		t.Errorf("Unexpected first directory: %+v", dirs[0])
+	}
+	if dirs[1].Path != "/projects/alpha" || dirs[1].ActivityCount != 2 {
+		t.Errorf("Unexpected second directory: %+v", dirs[1])
+	}
+	if !dirs[1].LastActivity.Equal(base.Add(time.Minute)) {
+		t.Errorf("Expected last activity %v, got %v", base.Add(time.Minute), dirs[1].LastActivity)
+	}
+}
+
+func TestShellHistory(t *testing.T) {
+	// Reset singleton for test
+	ResetInstance()
+
+	// Create temp directory for test
+	tempDir, err := os.MkdirTemp("", "cct_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Initialize database
+	db, err := Initialize(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	build := &BackgroundShellRecord{PID: "100", Command: "bash -c npm run build", Status: "running"}
+	watch := &BackgroundShellRecord{ShellID: "2", PID: "200", Command: "bash -c npm run watch", Status: "running"}
+
+	snapshots := []struct {
+		at     time.Time
+		shells []*BackgroundShellRecord
+	}{
+		{start, []*BackgroundShellRecord{build}},
+		{start.Add(time.Minute), []*BackgroundShellRecord{build, watch}},
+		{start.Add(2 * time.Minute), []*BackgroundShellRecord{watch}},
+	}
+	for _, snap := range snapshots {
+		if err := repo.RecordShellSnapshot(snap.shells, snap.at); err != nil {
+			t.Fatalf("Failed to record shell snapshot: %v", err)
+		}
+	}
+
+	shells, err := repo.GetShellHistory(start.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to get shell history: %v", err)
+	}
+	if len(shells) != 2 {
+		t.Fatalf("Expected 2 shells, got %d", len(shells))
+	}
+
+	// Most recently started first
+	if shells[0].PID != "200" || shells[1].PID != "100" {
+		t.Fatalf("Expected shells [200 100], got [%s %s]", shells[0].PID, shells[1].PID)
+	}
+	if shells[0].EndedAt != nil {
+		t.Error("Expected watch shell to still be open")
+	}
+	if shells[0].ShellID != "2" {
+		t.Errorf("Expected shell ID 2, got %q", shells[0].ShellID)
+	}
+
+	ended := shells[1]
+	if ended.EndedAt == nil || !ended.EndedAt.Equal(start.Add(2*time.Minute)) {
+		t.Errorf("Expected build shell to end at %v, got %v", start.Add(2*time.Minute), ended.EndedAt)
+	}
+	if !ended.FirstSeenAt.Equal(start) || !ended.LastSeenAt.Equal(start.Add(time.Minute)) {
+		t.Errorf("Unexpected build shell window: %v - %v", ended.FirstSeenAt, ended.LastSeenAt)
+	}
+
+	// Pruning only removes shells that ended before the cutoff
+	deleted, err := repo.DeleteShellHistoryBefore(start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to delete shell history: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected 1 pruned shell, got %d", deleted)
+	}
+}
+
+func TestRecordUserMessageDedup(t *testing.T) {
+	// Reset singleton for test
+	ResetInstance()
+
+	// Create temp directory for test
+	tempDir, err := os.MkdirTemp("", "cct_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Initialize database
+	db, err := Initialize(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+	repo.SetPromptDedupWindow(2 * time.Second)
+	now := time.Now()
+
+	record := func(conversationID, text string, at time.Time) *UserMessage {
+		msg := &UserMessage{ConversationID: conversationID, Message: text, MessageLength: len(text), SubmittedAt: at}
+		if err := repo.RecordUserMessage(msg); err != nil {
+			t.Fatalf("Failed to record user message: %v", err)
+		}
+		return msg
+	}
+
+	first := record("conv-1", "fix the tests", now)
+	dup := record("conv-1", "fix the tests", now.Add(500*time.Millisecond))
+	if !dup.Deduplicated || dup.ID != first.ID {
+		t.Errorf("Expected duplicate to reuse ID %d, got %d (deduplicated: %v)", first.ID, dup.ID, dup.Deduplicated)
+	}
+
+	// Same text outside the window, different text, or another conversation are recorded
+	if msg := record("conv-1", "fix the tests", now.Add(5*time.Second)); msg.Deduplicated {
+		t.Error("Expected prompt outside the window to be recorded")
+	}
+	if msg := record("conv-1", "now run lint", now.Add(5100*time.Millisecond)); msg.Deduplicated {
+		t.Error("Expected different prompt to be recorded")
+	}
+	if msg := record("conv-2", "now run lint", now.Add(5200*time.Millisecond)); msg.Deduplicated {
+		t.Error("Expected prompt in another conversation to be recorded")
+	}
+
+	messages, err := repo.GetUserMessages(&CommandHistoryQuery{Limit: 100})
+	if err != nil {
+		t.Fatalf("Failed to get user messages: %v", err)
+	}
+	if len(messages) != 4 {
+		t.Errorf("Expected 4 stored messages, got %d", len(messages))
+	}
+
+	// Disabled by default
+	repo.SetPromptDedupWindow(0)
+	if msg := record("conv-2", "now run lint", now.Add(5300*time.Millisecond)); msg.Deduplicated {
+		t.Error("Expected dedup to be disabled with a zero window")
+	}
+}
+
+func TestProviderAPIKeyEncryption(t *testing.T) {
+	// Reset singleton for test
+	ResetInstance()
+
+	// Create temp directory for test
+	tempDir, err := os.MkdirTemp("", "cct_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Initialize database
+	db, err := Initialize(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	storedKey := func(providerID string) string {
+		var key string
+		if err := db.GetDB().QueryRow("SELECT api_key FROM providers WHERE provider_id = ?", providerID).Scan(&key); err != nil {
+			t.Fatalf("Failed to read stored key: %v", err)
+		}
+		return key
+	}
+
+	// Without a master key the API key is stored as plaintext
+	t.Setenv(MasterKeyEnvVar, "")
+	if err := repo.SaveProvider(&ProviderConfig{ProviderID: "plain", APIKey: "sk-plain"}); err != nil {
+		t.Fatalf("SaveProvider failed: %v", err)
+	}
+	if got := storedKey("plain"); got != "sk-plain" {
+		t.Errorf("Expected plaintext key to be stored, got %q", got)
+	}
+
+	// With a master key the API key is encrypted at rest and decrypted on read
+	t.Setenv(MasterKeyEnvVar, "correct horse battery staple")
+	if err := repo.SaveProvider(&ProviderConfig{ProviderID: "secret", APIKey: "sk-secret"}); err != nil {
+		t.Fatalf("SaveProvider failed: %v", err)
+	}
+	if got := storedKey("secret"); got == "sk-secret" || !strings.HasPrefix(got, encryptedPrefix) {
+		t.Errorf("Expected encrypted key to be stored, got %q", got)
+	}
+
+	provider, err := repo.GetProvider("secret")
+	if err != nil {
+		t.Fatalf("GetProvider failed: %v", err)
+	}
+	if provider.APIKey != "sk-secret" {
+		t.Errorf("Expected decrypted key 'sk-secret', got %q", provider.APIKey)
+	}
+
+	current, err := repo.GetCurrentProvider()
+	if err != nil {
+		t.Fatalf("GetCurrentProvider failed: %v", err)
+	}
+	if current.APIKey != "sk-secret" {
+		t.Errorf("Expected decrypted current key 'sk-secret', got %q", current.APIKey)
+	}
+
+	// Plaintext keys saved earlier still read back
+	provider, err = repo.GetProvider("plain")
+	if err != nil {
+		t.Fatalf("GetProvider failed: %v", err)
+	}
+	if provider.APIKey != "sk-plain" {
+		t.Errorf("Expected plaintext key 'sk-plain', got %q", provider.APIKey)
+	}
+
+	// Reading an encrypted key with the wrong master key fails
+	t.Setenv(MasterKeyEnvVar, "wrong")
+	if _, err := repo.GetProvider("secret"); err == nil {
+		t.Error("Expected error decrypting with the wrong master key")
+	}
+}
+
+func TestAcknowledgeNotifications(t *testing.T) {
+	// Reset singleton for test
+	ResetInstance()
+
+	// Create temp directory for test
+	tempDir, err := os.MkdirTemp("", "cct_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Initialize database
+	db, err := Initialize(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	var ids []int64
+	for i := 0; i < 3; i++ {
+		notif := &Notification{
+			ConversationID:   "conv-1",
+			NotificationType: "other",
+			Message:          fmt.Sprintf("notification %d", i),
+			NotifiedAt:       time.Now(),
+		}
+		if err := repo.RecordNotification(notif); err != nil {
+			t.Fatalf("Failed to record notification: %v", err)
+		}
+		ids = append(ids, notif.ID)
+	}
+
+	count, err := repo.AcknowledgeNotifications(ids[:2])
+	if err != nil {
+		t.Fatalf("AcknowledgeNotifications failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 acknowledged, got %d", count)
+	}
+
+	// Acknowledging again changes nothing
+	if count, _ := repo.AcknowledgeNotifications(ids[:1]); count != 0 {
+		t.Errorf("Expected 0 newly acknowledged, got %d", count)
+	}
+
+	unread, err := repo.GetNotifications(&CommandHistoryQuery{UnreadOnly: true})
+	if err != nil {
+		t.Fatalf("GetNotifications failed: %v", err)
+	}
+	if len(unread) != 1 || unread[0].ID != ids[2] || unread[0].Acknowledged {
+		t.Errorf("Expected only notification %d unread, got %+v", ids[2], unread)
+	}
+
+	stats, err := repo.GetNotificationStats()
+	if err != nil {
+		t.Fatalf("GetNotificationStats failed: %v", err)
+	}
+	if stats.UnreadNotifications != 1 {
+		t.Errorf("Expected 1 unread notification, got %d", stats.UnreadNotifications)
+	}
+
+	// An empty list acknowledges everything
+	if count, _ := repo.AcknowledgeNotifications(nil); count != 1 {
+		t.Errorf("Expected 1 acknowledged, got %d", count)
+	}
+	all, err := repo.GetNotifications(&CommandHistoryQuery{})
+	if err != nil {
+		t.Fatalf("GetNotifications failed: %v", err)
+	}
+	for _, notif := range all {
+		if !notif.Acknowledged {
+			t.Errorf("Expected notification %d to be acknowledged", notif.ID)
+		}
+	}
+}
+
+func TestCheckAndRepair(t *testing.T) {
+	ResetInstance()
+
+	tempDir, err := os.MkdirTemp("", "cct_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	db, err := Initialize(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer ResetInstance()
+
+	sqlDB := db.GetDB()
+	if _, err := sqlDB.Exec(`INSERT INTO agent_sessions (id, status) VALUES ('kept', 'idle')`); err != nil {
+		t.Fatalf("Failed to insert session: %v", err)
+	}
+
+	// Insert orphans on a connection without foreign key enforcement
+	conn, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	if _, err := conn.ExecContext(context.Background(), "PRAGMA foreign_keys = OFF"); err != nil {
+		t.Fatalf("Failed to disable foreign keys: %v", err)
+	}
+	for i, sessionID := range []string{"kept", "gone", "gone"} {
+		_, err := conn.ExecContext(context.Background(),
+			`INSERT INTO agent_messages (id, session_id, sequence, role, content) VALUES (?, ?, ?, 'user', 'hi')`,
+			fmt.Sprintf("msg-%d", i), sessionID, i)
+		if err != nil {
+			t.Fatalf("Failed to insert message: %v", err)
+		}
+	}
+	conn.ExecContext(context.Background(), "PRAGMA foreign_keys = ON")
+	conn.Close()
+
+	report, err := db.Check(false)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !report.IntegrityOK {
+		t.Errorf("Expected integrity ok, got %v", report.IntegrityErrors)
+	}
+	if report.OrphanedMessages != 2 {
+		t.Errorf("Expected 2 orphaned messages, got %d", report.OrphanedMessages)
+	}
+	if report.Repaired {
+		t.Error("Check without repair should not repair")
+	}
+
+	report, err = db.Check(true)
+	if err != nil {
+		t.Fatalf("Check with repair failed: %v", err)
+	}
+	if !report.Repaired || report.DeletedMessages != 2 {
+		t.Errorf("Expected 2 deleted messages, got repaired=%v deleted=%d", report.Repaired, report.DeletedMessages)
+	}
+
+	var remaining int
+	sqlDB.QueryRow("SELECT COUNT(*) FROM agent_messages").Scan(&remaining)
+	if remaining != 1 {
+		t.Errorf("Expected 1 remaining message, got %d", remaining)
+	}
+}
+
+func TestGetDistinctToolNames(t *testing.T) {
+	ResetInstance()
+
+	tempDir, err := os.MkdirTemp("", "cct_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	db, err := Initialize(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer ResetInstance()
+
+	repo := NewRepository(db)
+
+	tools, err := repo.GetDistinctToolNames(&ToolNameQuery{})
+	if err != nil {
+		t.Fatalf("GetDistinctToolNames failed: %v", err)
+	}
+	if len(tools) != 0 {
+		t.Errorf("Expected no tools, got %d", len(tools))
+	}
+
+	for _, name := range []string{"Read", "Bash", "Read", "Edit", "Read", "Bash"} {
+		if err := repo.RecordClaudeCommand(&ClaudeCommand{
+			ConversationID: "conv-1",
+			ToolName:       name,
+			ExecutedAt:     time.Now(),
+		}); err != nil {
+			t.Fatalf("Failed to record claude command: %v", err)
+		}
+	}
+
+	tools, err = repo.GetDistinctToolNames(&ToolNameQuery{})
+	if err != nil {
+		t.Fatalf("GetDistinctToolNames failed: %v", err)
+	}
+
+	expected := []ToolNameCount{{"Read", 3}, {"Bash", 2}, {"Edit", 1}}
+	if len(tools) != len(expected) {
+		t.Fatalf("Expected %d tools, got %d", len(expected), len(tools))
+	}
+	for i, want := range expected {
+		if *tools[i] != want {
+			t.Errorf("Tool %d: expected %+v, got %+v", i, want, *tools[i])
+		}
+	}
+
+	// Filtering is a case-insensitive substring match
+	tools, err = repo.GetDistinctToolNames(&ToolNameQuery{Text: "EA"})
+	if err != nil {
+		t.Fatalf("GetDistinctToolNames failed: %v", err)
+	}
+	if len(tools) != 1 || tools[0].ToolName != "Read" {
+		t.Errorf("Expected only Read to match, got %v", tools)
+	}
+
+	// Paging keeps the usage order and the count ignores it
+	query := &ToolNameQuery{Limit: 1, Offset: 1}
+	tools, err = repo.GetDistinctToolNames(query)
+	if err != nil {
+		t.Fatalf("GetDistinctToolNames failed: %v", err)
+	}
+	if len(tools) != 1 || tools[0].ToolName != "Bash" {
+		t.Errorf("Expected second page to hold Bash, got %v", tools)
+	}
+	total, err := repo.CountDistinctToolNames(query)
+	if err != nil {
+		t.Fatalf("CountDistinctToolNames failed: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("Expected 3 distinct tools, got %d", total)
+	}
+	if total, _ := repo.CountDistinctToolNames(&ToolNameQuery{Text: "e"}); total != 2 {
+		t.Errorf("Expected 2 tools matching \"e\", got %d", total)
+	}
+}
+
+func TestGetUserMessage(t *testing.T) {
+	ResetInstance()
+
+	tempDir, err := os.MkdirTemp("", "cct_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	db, err := Initialize(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer ResetInstance()
+
+	repo := NewRepository(db)
+
+	msg := &UserMessage{
+		ConversationID:   "conv-1",
+		Message:          "refactor the parser",
+		WorkingDirectory: "/projects/alpha",
+		SubmittedAt:      time.Now(),
+	}
+	if err := repo.RecordUserMessage(msg); err != nil {
+		t.Fatalf("Failed to record user message: %v", err)
+	}
+
+	got, err := repo.GetUserMessage(msg.ID)
+	if err != nil {
+		t.Fatalf("GetUserMessage failed: %v", err)
+	}
+	if got == nil {
+		t.Fatal("Expected prompt, got nil")
+	}
+	if got.Message != msg.Message || got.WorkingDirectory != msg.WorkingDirectory {
+		t.Errorf("Unexpected prompt: %+v", got)
+	}
+
+	missing, err := repo.GetUserMessage(msg.ID + 100)
+	if err != nil {
+		t.Fatalf("GetUserMessage failed for missing ID: %v", err)
+	}
+	if missing != nil {
+		t.Errorf("Expected nil for missing prompt, got %+v", missing)
+	}
+}
+
+func TestGetNotification(t *testing.T) {
+	ResetInstance()
+
+	tempDir, err := os.MkdirTemp("", "cct_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	db, err := Initialize(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer ResetInstance()
+
+	repo := NewRepository(db)
+
+	notif := &Notification{
+		ConversationID:   "conv-1",
+		NotificationType: "permission_request",
+		Message:          "Claude needs your permission to use Bash",
+		ToolName:         "Bash",
+		CommandDetails:   "rm -rf build/",
+		NotifiedAt:       time.Now(),
+	}
+	if err := repo.RecordNotification(notif); err != nil {
+		t.Fatalf("Failed to record notification: %v", err)
+	}
+
+	got, err := repo.GetNotification(notif.ID)
+	if err != nil {
+		t.Fatalf("GetNotification failed: %v", err)
+	}
+	if got == nil {
+		t.Fatal("Expected notification, got nil")
+	}
+	if got.CommandDetails != notif.CommandDetails || got.ToolName != "Bash" || got.Acknowledged {
+		t.Errorf("Unexpected notification: %+v", got)
+	}
+
+	missing, err := repo.GetNotification(notif.ID + 100)
+	if err != nil {
+		t.Fatalf("GetNotification failed for missing ID: %v", err)
+	}
+	if missing != nil {
+		t.Errorf("Expected nil for missing notification, got %+v", missing)
+	}
+}
+
+func TestSetCurrentProvider(t *testing.T) {
+	ResetInstance()
+
+	tempDir, err := os.MkdirTemp("", "cct_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	db, err := Initialize(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer ResetInstance()
+
+	repo := NewRepository(db)
+
+	for _, id := range []string{"glm", "deepseek"} {
+		if err := repo.SaveProvider(&ProviderConfig{ProviderID: id, APIKey: "key-" + id}); err != nil {
+			t.Fatalf("Failed to save provider %s: %v", id, err)
+		}
+	}
+
+	// The last saved provider is current
+	current, err := repo.GetCurrentProvider()
+	if err != nil || current == nil || current.ProviderID != "deepseek" {
+		t.Fatalf("Expected deepseek to be current, got %+v (err: %v)", current, err)
+	}
+
+	found, err := repo.SetCurrentProvider("glm")
+	if err != nil || !found {
+		t.Fatalf("SetCurrentProvider failed: found=%v err=%v", found, err)
+	}
+
+	all, err := repo.GetAllProviders()
+	if err != nil {
+		t.Fatalf("GetAllProviders failed: %v", err)
+	}
+	for _, p := range all {
+		if p.IsCurrent != (p.ProviderID == "glm") {
+			t.Errorf("Provider %s: unexpected is_current=%v", p.ProviderID, p.IsCurrent)
+		}
+	}
+
+	found, err = repo.SetCurrentProvider("missing")
+	if err != nil {
+		t.Fatalf("SetCurrentProvider failed for missing provider: %v", err)
+	}
+	if found {
+		t.Error("Expected missing provider not to be found")
+	}
+
+	// A missing provider must not clear the current one
+	current, _ = repo.GetCurrentProvider()
+	if current == nil || current.ProviderID != "glm" {
+		t.Errorf("Expected glm to remain current, got %+v", current)
+	}
+}
+
+func TestDeleteConversation(t *testing.T) {
+	ResetInstance()
+
+	tempDir, err := os.MkdirTemp("", "cct_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	db, err := Initialize(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer ResetInstance()
+
+	repo := NewRepository(db)
+	now := time.Now()
+
+	for _, convID := range []string{"conv-noisy", "conv-keep"} {
+		if err := repo.RecordUserMessage(&UserMessage{ConversationID: convID, Message: "hello " + convID, SubmittedAt: now}); err != nil {
+			t.Fatalf("Failed to record user message: %v", err)
+		}
+		if err := repo.RecordShellCommand(&ShellCommand{ConversationID: convID, Command: "ls", ExecutedAt: now}); err != nil {
+			t.Fatalf("Failed to record shell command: %v", err)
+		}
+		if err := repo.RecordClaudeCommand(&ClaudeCommand{ConversationID: convID, ToolName: "Read", Success: true, ExecutedAt: now}); err != nil {
+			t.Fatalf("Failed to record claude command: %v", err)
+		}
+		if err := repo.RecordNotification(&Notification{ConversationID: convID, NotificationType: "other", Message: "hi", NotifiedAt: now}); err != nil {
+			t.Fatalf("Failed to record notification: %v", err)
+		}
+		if err := repo.AddTag(convID, "noise"); err != nil {
+			t.Fatalf("Failed to add tag: %v", err)
+		}
+		if err := repo.UpsertConversation(&Conversation{ID: convID, StartedAt: now, LastActivityAt: now}); err != nil {
+			t.Fatalf("Failed to upsert conversation: %v", err)
+		}
+	}
+
+	result, err := repo.DeleteConversation("conv-noisy")
+	if err != nil {
+		t.Fatalf("DeleteConversation failed: %v", err)
+	}
+
+	for _, table := range []string{"user_messages", "shell_commands", "claude_commands", "notifications", "conversation_tags", "conversations"} {
+		if result.Deleted[table] != 1 {
+			t.Errorf("Expected 1 row deleted from %s, got %d", table, result.Deleted[table])
+		}
+	}
+	if result.Total != 6 {
+		t.Errorf("Expected 6 rows deleted in total, got %d", result.Total)
+	}
+
+	// The other conversation is untouched
+	if conv, err := repo.GetConversation("conv-keep"); err != nil || conv == nil {
+		t.Errorf("Expected conv-keep to remain, got %v (err: %v)", conv, err)
+	}
+	if tags, err := repo.GetTags("conv-keep"); err != nil || len(tags) != 1 {
+		t.Errorf("Expected conv-keep tags to remain, got %v (err: %v)", tags, err)
+	}
+	if conv, err := repo.GetConversation("conv-noisy"); err != nil || conv != nil {
+		t.Errorf("Expected conv-noisy to be gone, got %v (err: %v)", conv, err)
+	}
+
+	// Deleting again finds nothing
+	result, err = repo.DeleteConversation("conv-noisy")
+	if err != nil {
+		t.Fatalf("DeleteConversation failed: %v", err)
+	}
+	if result.Total != 0 {
+		t.Errorf("Expected nothing left to delete, got %d rows", result.Total)
+	}
+}
+
+func TestSearchConversations(t *testing.T) {
+	// Reset singleton for test
+	ResetInstance()
+
+	// Create temp directory for test
+	tempDir, err := os.MkdirTemp("", "cct_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Initialize database
+	db, err := Initialize(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+	defer ResetInstance()
+
+	repo := NewRepository(db)
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	messages := []*UserMessage{
+		{ConversationID: "conv-api", SessionName: "Fix login", Message: "the login form crashes", WorkingDirectory: "/repo/api", SubmittedAt: base},
+		{ConversationID: "conv-api", SessionName: "Fix login", Message: "also check logout", WorkingDirectory: "/repo/api/auth", SubmittedAt: base.Add(time.Hour)},
+		{ConversationID: "conv-web", SessionName: "Styling", Message: "make the header blue", WorkingDirectory: "/repo/web", SubmittedAt: base.AddDate(0, 0, 5)},
+		{ConversationID: "conv-apiary", SessionName: "Bees", Message: "unrelated project", WorkingDirectory: "/repo/apiary", SubmittedAt: base.AddDate(0, 0, 10)},
+	}
+	for _, msg := range messages {
+		if err := repo.RecordUserMessage(msg); err != nil {
+			t.Fatalf("Failed to record user message: %v", err)
+		}
+	}
+	if err := repo.RecordShellCommand(&ShellCommand{
+		ConversationID:   "conv-api",
+		Command:          "go test",
+		WorkingDirectory: "/repo/api",
+		ExecutedAt:       base.Add(2 * time.Hour),
+	}); err != nil {
+		t.Fatalf("Failed to record shell command: %v", err)
+	}
+
+	// Directory matches subdirectories but not sibling prefixes
+	results, err := repo.SearchConversations(&ConversationSearchQuery{WorkingDirectory: "/repo/api"})
+	if err != nil {
+		t.Fatalf("SearchConversations failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ConversationID != "conv-api" {
+		t.Fatalf("Expected only conv-api, got %+v", results)
+	}
+	api := results[0]
+	if api.PromptCount != 2 || api.ShellCommandCount != 1 || api.FirstPrompt != "the login form crashes" {
+		t.Errorf("Unexpected summary: %+v", api)
+	}
+	if !api.FirstActivity.Equal(base) || !api.LastActivity.Equal(base.Add(2*time.Hour)) {
+		t.Errorf("Unexpected activity range: %v - %v", api.FirstActivity, api.LastActivity)
+	}
+
+	// Date range
+	start, end := base.AddDate(0, 0, 4), base.AddDate(0, 0, 6)
+	results, err = repo.SearchConversations(&ConversationSearchQuery{StartDate: &start, EndDate: &end})
+	if err != nil {
+		t.Fatalf("SearchConversations failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ConversationID != "conv-web" {
+		t.Errorf("Expected only conv-web in date range, got %+v", results)
+	}
+
+	// Text matches session name or first prompt, case-insensitively
+	results, err = repo.SearchConversations(&ConversationSearchQuery{Text: "HEADER"})
+	if err != nil {
+		t.Fatalf("SearchConversations failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ConversationID != "conv-web" {
+		t.Errorf("Expected conv-web for first prompt match, got %+v", results)
+	}
+	results, err = repo.SearchConversations(&ConversationSearchQuery{Text: "logout"})
+	if err != nil {
+		t.Fatalf("SearchConversations failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Expected later prompts not to match, got %+v", results)
+	}
+
+	// No filters returns everything, newest first
+	results, err = repo.SearchConversations(&ConversationSearchQuery{})
+	if err != nil {
+		t.Fatalf("SearchConversations failed: %v", err)
+	}
+	if len(results) != 3 || results[0].ConversationID != "conv-apiary" {
+		t.Errorf("Expected 3 conversations newest first, got %+v", results)
+	}
+}
+
+func TestCountUserMessagesIgnoresPaging(t *testing.T) {
+	// Reset singleton for test
+	ResetInstance()
+
+	// Create temp directory for test
+	tempDir, err := os.MkdirTemp("", "cct_test_*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Initialize database
+	db, err := Initialize(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+	defer ResetInstance()
+
+	repo := NewRepository(db)
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		conversationID := "conv-a"
+		if i == 4 {
+			conversationID = "conv-b"
+		}
+		if err := repo.RecordUserMessage(&UserMessage{
+			ConversationID: conversationID,
+			Message:        fmt.Sprintf("prompt %d", i),
+			SubmittedAt:    base.Add(time.Duration(i) * time.Minute),
+		}); err != nil {
+			t.Fatalf("Failed to record user message: %v", err)
+		}
+	}
+
+	query := &CommandHistoryQuery{ConversationID: "conv-a", Limit: 2, Offset: 1}
+
+	page, err := repo.GetUserMessages(query)
+	if err != nil {
+		t.Fatalf("Failed to get user messages: %v", err)
+	}
+	if len(page) != 2 {
+		t.Errorf("Expected 2 messages in page, got %d", len(page))
+	}
+
+	total, err := repo.CountUserMessages(query)
+	if err != nil {
+		t.Fatalf("Failed to count user messages: %v", err)
+	}
+	if total != 4 {
+		t.Errorf("Expected total of 4, got %d", total)
+	}
+	if query.Limit != 2 || query.Offset != 1 {
+		t.Errorf("Count must not modify the query, got limit %d offset %d", query.Limit, query.Offset)
+	}
+
+	all, err := repo.CountUserMessages(&CommandHistoryQuery{})
+	if err != nil {
+		t.Fatalf("Failed to count user messages: %v", err)
+	}
+	if all != 5 {
+		t.Errorf("Expected 5 messages overall, got %d", all)
+	}
+}
@@ -0,0 +1,237 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// migration is a single ordered schema change applied on top of schema.sql.
+// Migrations must be idempotent: databases created before schema_migrations
+// existed replay every migration once to record their version.
+type migration struct {
+	Version     int
+	Description string
+	Up          func(tx *sql.Tx) error
+}
+
+// AppliedMigration records a migration that has been applied to the database
+type AppliedMigration struct {
+	Version     int       `json:"version"`
+	Description string    `json:"description"`
+	AppliedAt   time.Time `json:"applied_at"`
+}
+
+// migrations lists all schema migrations in the order they must be applied.
+// Append new migrations to the end with the next version number; never reorder.
+var migrations = []migration{
+	{1, "add model_name to providers", func(tx *sql.Tx) error {
+		return addColumnIfMissing(tx, "providers", "model_name", "TEXT")
+	}},
+	{2, "add session_name to user_messages", func(tx *sql.Tx) error {
+		return addColumnIfMissing(tx, "user_messages", "session_name", "TEXT")
+	}},
+	{3, "add session_name to shell_commands", func(tx *sql.Tx) error {
+		return addColumnIfMissing(tx, "shell_commands", "session_name", "TEXT")
+	}},
+	{4, "add session_name to claude_commands", func(tx *sql.Tx) error {
+		return addColumnIfMissing(tx, "claude_commands", "session_name", "TEXT")
+	}},
+	{5, "create notifications table", createNotificationsTable},
+	{6, "add command_details to notifications", func(tx *sql.Tx) error {
+		return addColumnIfMissing(tx, "notifications", "command_details", "TEXT")
+	}},
+	{7, "add model_provider and model_name columns", addModelColumns},
+	{8, "add archived to agent_messages", func(tx *sql.Tx) error {
+		return addColumnIfMissing(tx, "agent_messages", "archived", "INTEGER NOT NULL DEFAULT 0")
+	}},
+	{9, "add parent_session_id to agent_sessions", func(tx *sql.Tx) error {
+		return addColumnIfMissing(tx, "agent_sessions", "parent_session_id", "TEXT")
+	}},
+	{10, "add acknowledged to notifications", func(tx *sql.Tx) error {
+		return addColumnIfMissing(tx, "notifications", "acknowledged", "BOOLEAN NOT NULL DEFAULT 0")
+	}},
+	{11, "add flagged to agent_messages", func(tx *sql.Tx) error {
+		return addColumnIfMissing(tx, "agent_messages", "flagged", "INTEGER NOT NULL DEFAULT 0")
+	}},
+}
+
+// LatestSchemaVersion returns the version of the newest known migration
+func LatestSchemaVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// runMigrations applies every migration newer than the recorded schema version.
+// Each migration runs in its own transaction together with its version record.
+func runMigrations(db *sql.DB) error {
+	current, err := schemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %d: %w", m.Version, err)
+		}
+
+		if err := m.Up(tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Description, err)
+		}
+
+		if _, err := tx.Exec(
+			"INSERT INTO schema_migrations (version, description) VALUES (?, ?)",
+			m.Version, m.Description,
+		); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", m.Version, err)
+		}
+	}
+
+	return nil
+}
+
+// schemaVersion returns the highest applied migration version (0 if none)
+func schemaVersion(db *sql.DB) (int, error) {
+	var version int
+	if err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// SchemaVersion returns the current schema version of the database
+func (d *Database) SchemaVersion() (int, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return schemaVersion(d.db)
+}
+
+// AppliedMigrations returns all recorded migrations in version order
+func (d *Database) AppliedMigrations() ([]AppliedMigration, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	rows, err := d.db.Query("SELECT version, description, applied_at FROM schema_migrations ORDER BY version ASC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := []AppliedMigration{}
+	for rows.Next() {
+		var m AppliedMigration
+		if err := rows.Scan(&m.Version, &m.Description, &m.AppliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan migration: %w", err)
+		}
+		applied = append(applied, m)
+	}
+
+	return applied, rows.Err()
+}
+
+// addColumnIfMissing adds a column to a table unless it already exists.
+// Missing tables are skipped since schema.sql creates them with the column.
+func addColumnIfMissing(tx *sql.Tx, table, column, definition string) error {
+	var tableExists bool
+	if err := tx.QueryRow(
+		"SELECT COUNT(*) > 0 FROM sqlite_master WHERE type='table' AND name=?", table,
+	).Scan(&tableExists); err != nil {
+		return fmt.Errorf("failed to check table %s: %w", table, err)
+	}
+	if !tableExists {
+		return nil
+	}
+
+	var columnExists bool
+	if err := tx.QueryRow(
+		fmt.Sprintf("SELECT COUNT(*) > 0 FROM pragma_table_info('%s') WHERE name=?", table), column,
+	).Scan(&columnExists); err != nil {
+		return fmt.Errorf("failed to check column %s.%s: %w", table, column, err)
+	}
+	if columnExists {
+		return nil
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add %s column to %s: %w", column, table, err)
+	}
+
+	return nil
+}
+
+// createNotificationsTable creates the notifications table for databases that predate it
+func createNotificationsTable(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS notifications (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			conversation_id TEXT NOT NULL,
+			session_name TEXT,
+			notification_type TEXT NOT NULL,
+			message TEXT NOT NULL,
+			tool_name TEXT,
+			command_details TEXT,
+			working_directory TEXT,
+			git_branch TEXT,
+			notified_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_notifications_conversation
+			ON notifications(conversation_id, notified_at DESC);
+
+		CREATE INDEX IF NOT EXISTS idx_notifications_notified_at
+			ON notifications(notified_at DESC);
+
+		CREATE INDEX IF NOT EXISTS idx_notifications_type
+			ON notifications(notification_type, notified_at DESC);
+
+		CREATE INDEX IF NOT EXISTS idx_notifications_tool
+			ON notifications(tool_name, notified_at DESC) WHERE tool_name IS NOT NULL;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create notifications table: %w", err)
+	}
+	return nil
+}
+
+// addModelColumns adds model_provider/model_name to the tracking tables and indexes them
+func addModelColumns(tx *sql.Tx) error {
+	tables := []string{"shell_commands", "claude_commands", "conversations", "user_messages", "notifications"}
+	for _, table := range tables {
+		if err := addColumnIfMissing(tx, table, "model_provider", "TEXT"); err != nil {
+			return err
+		}
+		if err := addColumnIfMissing(tx, table, "model_name", "TEXT"); err != nil {
+			return err
+		}
+	}
+
+	indexes := []string{
+		"CREATE INDEX IF NOT EXISTS idx_shell_commands_model ON shell_commands(model_provider, model_name)",
+		"CREATE INDEX IF NOT EXISTS idx_claude_commands_model ON claude_commands(model_provider, model_name)",
+		"CREATE INDEX IF NOT EXISTS idx_user_messages_model ON user_messages(model_provider, model_name)",
+		"CREATE INDEX IF NOT EXISTS idx_notifications_model ON notifications(model_provider, model_name)",
+		"CREATE INDEX IF NOT EXISTS idx_conversations_model ON conversations(model_provider, model_name)",
+	}
+	for _, indexSQL := range indexes {
+		if _, err := tx.Exec(indexSQL); err != nil {
+			return fmt.Errorf("failed to create index: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -3,7 +3,10 @@
 // conversations, command statistics, and user messages.
 package database
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // ShellCommand represents a shell command execution record
 type ShellCommand struct {
@@ -59,6 +62,22 @@ type Conversation struct {
 	UpdatedAt           time.Time `json:"updated_at"`
 }
 
+// Conversation lifecycle statuses that can be set by users
+const (
+	ConversationStatusActive    = "active"
+	ConversationStatusArchived  = "archived"
+	ConversationStatusCompleted = "completed"
+)
+
+// IsValidConversationStatus reports whether status is a known conversation status
+func IsValidConversationStatus(status string) bool {
+	switch status {
+	case ConversationStatusActive, ConversationStatusArchived, ConversationStatusCompleted:
+		return true
+	}
+	return false
+}
+
 // CommandStat represents aggregated command statistics
 type CommandStat struct {
 	ID              int64     `json:"id"`
@@ -82,6 +101,8 @@ type CommandHistoryQuery struct {
 	EndDate        *time.Time
 	ToolName       string
 	CommandType    string // 'shell' or 'claude'
+	Success        *bool  // Filter claude commands by success status (nil = all)
+	UnreadOnly     bool   // Only return notifications that have not been acknowledged
 }
 
 // UserMessage represents a user's input message
@@ -97,6 +118,7 @@ type UserMessage struct {
 	MessageLength    int       `json:"message_length"`
 	SubmittedAt      time.Time `json:"submitted_at"`
 	CreatedAt        time.Time `json:"created_at"`
+	Deduplicated     bool      `json:"-"` // Set by RecordUserMessage when an identical recent prompt was reused
 }
 
 // ProviderConfig represents an AI provider configuration
@@ -123,6 +145,7 @@ type Notification struct {
 	GitBranch        string    `json:"git_branch,omitempty"`
 	ModelProvider    string    `json:"model_provider,omitempty"`
 	ModelName        string    `json:"model_name,omitempty"`
+	Acknowledged     bool      `json:"acknowledged"`
 	NotifiedAt       time.Time `json:"notified_at"`
 	CreatedAt        time.Time `json:"created_at"`
 }
@@ -133,6 +156,7 @@ type NotificationStats struct {
 	PermissionRequests     int64  `json:"permission_requests"`
 	IdleAlerts             int64  `json:"idle_alerts"`
 	OtherNotifications     int64  `json:"other_notifications"`
+	UnreadNotifications    int64  `json:"unread_notifications"`
 	MostRequestedTool      string `json:"most_requested_tool"`
 	MostRequestedToolCount int64  `json:"most_requested_tool_count"`
 }
@@ -146,3 +170,117 @@ type UserSetting struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
+
+// MetricTotals holds aggregate counters exported for monitoring
+type MetricTotals struct {
+	TotalPrompts int64   `json:"total_prompts"`
+	TotalTokens  int64   `json:"total_tokens"`
+	AgentCostUSD float64 `json:"agent_cost_usd"`
+}
+
+// HistoryRow is a single entry of the unified, timestamp-ordered history stream.
+// Content holds the underlying record as JSON, with the same field names as its model.
+type HistoryRow struct {
+	Type             string          `json:"type"` // 'shell', 'claude', 'prompt' or 'notification'
+	ID               int64           `json:"id"`
+	ConversationID   string          `json:"conversation_id"`
+	SessionName      string          `json:"session_name,omitempty"`
+	Timestamp        time.Time       `json:"timestamp"`
+	WorkingDirectory string          `json:"working_directory,omitempty"`
+	GitBranch        string          `json:"git_branch,omitempty"`
+	Content          json.RawMessage `json:"content"`
+}
+
+// ProcessSample is a periodic snapshot of detected Claude processes
+type ProcessSample struct {
+	ID            int64     `json:"id"`
+	SampledAt     time.Time `json:"sampled_at"`
+	ProcessCount  int       `json:"process_count"`
+	KnownDirCount int       `json:"known_dir_count"` // Processes with a resolved working directory
+}
+
+// ProcessHistoryBucket aggregates process samples over one time bucket
+type ProcessHistoryBucket struct {
+	Start        time.Time `json:"start"`
+	Samples      int       `json:"samples"`
+	AvgProcesses float64   `json:"avg_processes"`
+	MaxProcesses int       `json:"max_processes"`
+}
+
+// BackgroundShellRecord is a background shell seen by the shell sampler. A shell
+// is considered ended once a sample no longer detects it.
+type BackgroundShellRecord struct {
+	ID               int64      `json:"id"`
+	ShellID          string     `json:"shell_id,omitempty"`
+	PID              string     `json:"pid"`
+	Command          string     `json:"command"`
+	Status           string     `json:"status"` // Last detected status: running or idle
+	WorkingDirectory string     `json:"working_directory,omitempty"`
+	FirstSeenAt      time.Time  `json:"first_seen_at"`
+	LastSeenAt       time.Time  `json:"last_seen_at"`
+	EndedAt          *time.Time `json:"ended_at,omitempty"`
+}
+
+// WorkingDirectoryActivity summarizes recorded history for one working directory
+type WorkingDirectoryActivity struct {
+	Path          string    `json:"path"`
+	ActivityCount int       `json:"activity_count"` // Prompts plus shell and Claude commands
+	LastActivity  time.Time `json:"last_activity"`
+}
+
+// ConversationSearchQuery filters conversations in SearchConversations.
+// Zero-valued fields don't filter.
+type ConversationSearchQuery struct {
+	WorkingDirectory string     // Matches this directory and its subdirectories
+	StartDate        *time.Time // Keep conversations with activity at or after this time
+	EndDate          *time.Time // Keep conversations with activity at or before this time
+	Text             string     // Case-insensitive match on session name or first prompt
+	Limit            int
+	Offset           int
+}
+
+// ConversationSummary is a conversation matched by SearchConversations with
+// its recorded activity
+type ConversationSummary struct {
+	ConversationID     string    `json:"conversation_id"`
+	SessionName        string    `json:"session_name,omitempty"`
+	WorkingDirectory   string    `json:"working_directory,omitempty"`
+	FirstPrompt        string    `json:"first_prompt,omitempty"`
+	PromptCount        int       `json:"prompt_count"`
+	ShellCommandCount  int       `json:"shell_command_count"`
+	ClaudeCommandCount int       `json:"claude_command_count"`
+	FirstActivity      time.Time `json:"first_activity"`
+	LastActivity       time.Time `json:"last_activity"`
+}
+
+// ToolNameCount is a distinct Claude tool name with the number of recorded uses
+type ToolNameCount struct {
+	ToolName string `json:"tool_name"`
+	Count    int    `json:"count"`
+}
+
+// ToolNameQuery filters and pages the distinct Claude tool names
+type ToolNameQuery struct {
+	Text   string // Case-insensitive match anywhere in the tool name
+	Limit  int    // 0 returns every match
+	Offset int
+}
+
+// Session lifecycle events reported by the session-logger hook
+const (
+	SessionLifecycleStart = "start"
+	SessionLifecycleEnd   = "end"
+)
+
+// CLISession records the lifecycle of a Claude CLI session from SessionStart/SessionEnd hooks
+type CLISession struct {
+	ConversationID   string     `json:"conversation_id"`
+	SessionName      string     `json:"session_name,omitempty"`
+	WorkingDirectory string     `json:"working_directory,omitempty"`
+	GitBranch        string     `json:"git_branch,omitempty"`
+	StartSource      string     `json:"start_source,omitempty"` // startup, resume, clear, compact
+	EndReason        string     `json:"end_reason,omitempty"`   // clear, logout, prompt_input_exit, other
+	StartedAt        *time.Time `json:"started_at,omitempty"`
+	EndedAt          *time.Time `json:"ended_at,omitempty"`
+	DurationSeconds  *float64   `json:"duration_seconds,omitempty"` // Set when both start and end are known
+}
@@ -4,13 +4,22 @@
 package database
 
 import (
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/schlunsen/claude-control-terminal/internal/logging"
 )
 
 // Repository provides data access methods for command history
 type Repository struct {
 	db *Database
+
+	// promptDedupWindow skips identical consecutive prompts recorded within this window (0 disables)
+	promptDedupWindow time.Duration
 }
 
 // NewRepository creates a new repository instance
@@ -18,6 +27,12 @@ func NewRepository(db *Database) *Repository {
 	return &Repository{db: db}
 }
 
+// SetPromptDedupWindow enables deduplication of identical consecutive prompts
+// for the same conversation recorded within window of each other. Zero disables it.
+func (r *Repository) SetPromptDedupWindow(window time.Duration) {
+	r.promptDedupWindow = window
+}
+
 // RecordShellCommand saves a shell command execution
 func (r *Repository) RecordShellCommand(cmd *ShellCommand) error {
 	r.db.mu.Lock()
@@ -285,6 +300,73 @@ func (r *Repository) UpsertConversation(conv *Conversation) error {
 	return nil
 }
 
+// GetConversation retrieves a conversation record by ID (nil if it has not been recorded)
+func (r *Repository) GetConversation(id string) (*Conversation, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	query := `
+		SELECT id, COALESCE(project_path, ''), started_at, last_activity_at,
+		       COALESCE(total_commands, 0), COALESCE(total_shell_commands, 0), COALESCE(total_tokens, 0),
+		       COALESCE(status, 'active'), COALESCE(model_provider, ''), COALESCE(model_name, ''),
+		       created_at, updated_at
+		FROM conversations
+		WHERE id = ?
+	`
+
+	conv := &Conversation{}
+	var startedAt, lastActivityAt sql.NullTime
+	err := r.db.db.QueryRow(query, id).Scan(
+		&conv.ID,
+		&conv.ProjectPath,
+		&startedAt,
+		&lastActivityAt,
+		&conv.TotalCommands,
+		&conv.TotalShellCommands,
+		&conv.TotalTokens,
+		&conv.Status,
+		&conv.ModelProvider,
+		&conv.ModelName,
+		&conv.CreatedAt,
+		&conv.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	conv.StartedAt = startedAt.Time
+	conv.LastActivityAt = lastActivityAt.Time
+
+	return conv, nil
+}
+
+// GetConversationStatuses returns the recorded status of every conversation keyed by ID
+func (r *Repository) GetConversationStatuses() (map[string]string, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	rows, err := r.db.db.Query("SELECT id, COALESCE(status, 'active') FROM conversations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query conversation statuses: %w", err)
+	}
+	defer rows.Close()
+
+	statuses := make(map[string]string)
+	for rows.Next() {
+		var id, status string
+		if err := rows.Scan(&id, &status); err != nil {
+			return nil, fmt.Errorf("failed to scan conversation status: %w", err)
+		}
+		statuses[id] = status
+	}
+
+	return statuses, rows.Err()
+}
+
 // Helper methods
 
 func (r *Repository) buildShellCommandQuery(query *CommandHistoryQuery) (string, []interface{}) {
@@ -349,6 +431,11 @@ func (r *Repository) buildClaudeCommandQuery(query *CommandHistoryQuery) (string
 		args = append(args, query.ToolName)
 	}
 
+	if query.Success != nil {
+		sql += " AND success = ?"
+		args = append(args, *query.Success)
+	}
+
 	if query.StartDate != nil {
 		sql += " AND executed_at >= ?"
 		args = append(args, query.StartDate)
@@ -374,6 +461,159 @@ func (r *Repository) buildClaudeCommandQuery(query *CommandHistoryQuery) (string
 	return sql, args
 }
 
+func (r *Repository) buildUserMessageQuery(query *CommandHistoryQuery) (string, []interface{}) {
+	sql := `
+		SELECT id, conversation_id, COALESCE(session_name, '') as session_name, message, working_directory, git_branch,
+		       COALESCE(model_provider, '') as model_provider, COALESCE(model_name, '') as model_name,
+		       message_length, submitted_at, created_at
+		FROM user_messages
+		WHERE 1=1
+	`
+
+	args := []interface{}{}
+
+	if query.ConversationID != "" {
+		sql += " AND conversation_id = ?"
+		args = append(args, query.ConversationID)
+	}
+
+	if query.StartDate != nil {
+		sql += " AND submitted_at >= ?"
+		args = append(args, query.StartDate)
+	}
+
+	if query.EndDate != nil {
+		sql += " AND submitted_at <= ?"
+		args = append(args, query.EndDate)
+	}
+
+	sql += " ORDER BY submitted_at DESC"
+
+	if query.Limit > 0 {
+		sql += " LIMIT ?"
+		args = append(args, query.Limit)
+	}
+
+	if query.Offset > 0 {
+		sql += " OFFSET ?"
+		args = append(args, query.Offset)
+	}
+
+	return sql, args
+}
+
+func (r *Repository) buildNotificationQuery(query *CommandHistoryQuery) (string, []interface{}) {
+	sql := `
+		SELECT id, conversation_id, COALESCE(session_name, '') as session_name,
+		       notification_type, message, COALESCE(tool_name, '') as tool_name,
+		       COALESCE(command_details, '') as command_details,
+		       working_directory, git_branch,
+		       COALESCE(model_provider, '') as model_provider, COALESCE(model_name, '') as model_name,
+		       acknowledged, notified_at, created_at
+		FROM notifications
+		WHERE 1=1
+	`
+
+	args := []interface{}{}
+
+	if query.ConversationID != "" {
+		sql += " AND conversation_id = ?"
+		args = append(args, query.ConversationID)
+	}
+
+	if query.UnreadOnly {
+		sql += " AND acknowledged = 0"
+	}
+
+	if query.StartDate != nil {
+		sql += " AND notified_at >= ?"
+		args = append(args, query.StartDate)
+	}
+
+	if query.EndDate != nil {
+		sql += " AND notified_at <= ?"
+		args = append(args, query.EndDate)
+	}
+
+	sql += " ORDER BY notified_at DESC"
+
+	if query.Limit > 0 {
+		sql += " LIMIT ?"
+		args = append(args, query.Limit)
+	}
+
+	if query.Offset > 0 {
+		sql += " OFFSET ?"
+		args = append(args, query.Offset)
+	}
+
+	return sql, args
+}
+
+// countRows counts the rows a query builder would match, ignoring its limit and offset
+func (r *Repository) countRows(build func(*CommandHistoryQuery) (string, []interface{}), query *CommandHistoryQuery) (int, error) {
+	unpaged := *query
+	unpaged.Limit = 0
+	unpaged.Offset = 0
+
+	sql, args := build(&unpaged)
+
+	var count int
+	if err := r.db.db.QueryRow("SELECT COUNT(*) FROM ("+sql+")", args...).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// CountShellCommands returns how many shell commands match the query filters
+func (r *Repository) CountShellCommands(query *CommandHistoryQuery) (int, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	count, err := r.countRows(r.buildShellCommandQuery, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count shell commands: %w", err)
+	}
+	return count, nil
+}
+
+// CountClaudeCommands returns how many Claude commands match the query filters
+func (r *Repository) CountClaudeCommands(query *CommandHistoryQuery) (int, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	count, err := r.countRows(r.buildClaudeCommandQuery, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count claude commands: %w", err)
+	}
+	return count, nil
+}
+
+// CountUserMessages returns how many user messages match the query filters
+func (r *Repository) CountUserMessages(query *CommandHistoryQuery) (int, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	count, err := r.countRows(r.buildUserMessageQuery, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count user messages: %w", err)
+	}
+	return count, nil
+}
+
+// CountNotifications returns how many notifications match the query filters
+func (r *Repository) CountNotifications(query *CommandHistoryQuery) (int, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	count, err := r.countRows(r.buildNotificationQuery, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+	return count, nil
+}
+
 func (r *Repository) updateConversationStats(conversationID string) {
 	// Update conversation totals
 	query := `
@@ -442,11 +682,25 @@ func extractCommandName(command string) string {
 	return command
 }
 
-// RecordUserMessage saves a user's input message
+// RecordUserMessage saves a user's input message. When a dedup window is set and
+// the previous message for the same conversation has identical text within the
+// window, nothing is inserted: msg takes the existing ID and Deduplicated is set.
 func (r *Repository) RecordUserMessage(msg *UserMessage) error {
 	r.db.mu.Lock()
 	defer r.db.mu.Unlock()
 
+	if r.promptDedupWindow > 0 && msg.ConversationID != "" {
+		existingID, err := r.findDuplicateUserMessage(msg)
+		if err != nil {
+			return err
+		}
+		if existingID != 0 {
+			msg.ID = existingID
+			msg.Deduplicated = true
+			return nil
+		}
+	}
+
 	query := `
 		INSERT INTO user_messages (
 			conversation_id, session_name, message, working_directory, git_branch,
@@ -477,48 +731,48 @@ func (r *Repository) RecordUserMessage(msg *UserMessage) error {
 	return nil
 }
 
-// GetUserMessages retrieves user messages with optional filters
-func (r *Repository) GetUserMessages(query *CommandHistoryQuery) ([]*UserMessage, error) {
-	r.db.mu.RLock()
-	defer r.db.mu.RUnlock()
-
-	sql := `
-		SELECT id, conversation_id, COALESCE(session_name, '') as session_name, message, working_directory, git_branch,
-		       COALESCE(model_provider, '') as model_provider, COALESCE(model_name, '') as model_name,
-		       message_length, submitted_at, created_at
+// findDuplicateUserMessage returns the ID of the latest message in the same
+// conversation if it has the same text and falls within the dedup window, else 0.
+// Caller must hold the database lock.
+func (r *Repository) findDuplicateUserMessage(msg *UserMessage) (int64, error) {
+	var id int64
+	var message string
+	var submittedAt time.Time
+	err := r.db.db.QueryRow(`
+		SELECT id, message, submitted_at
 		FROM user_messages
-		WHERE 1=1
-	`
-
-	args := []interface{}{}
-
-	if query.ConversationID != "" {
-		sql += " AND conversation_id = ?"
-		args = append(args, query.ConversationID)
+		WHERE conversation_id = ?
+		ORDER BY submitted_at DESC, id DESC
+		LIMIT 1
+	`, msg.ConversationID).Scan(&id, &message, &submittedAt)
+	if err == sql.ErrNoRows {
+		return 0, nil
 	}
-
-	if query.StartDate != nil {
-		sql += " AND submitted_at >= ?"
-		args = append(args, query.StartDate)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check for duplicate user message: %w", err)
 	}
 
-	if query.EndDate != nil {
-		sql += " AND submitted_at <= ?"
-		args = append(args, query.EndDate)
+	if message != msg.Message {
+		return 0, nil
 	}
 
-	sql += " ORDER BY submitted_at DESC"
-
-	if query.Limit > 0 {
-		sql += " LIMIT ?"
-		args = append(args, query.Limit)
+	gap := msg.SubmittedAt.Sub(submittedAt)
+	if gap < 0 {
+		gap = -gap
 	}
-
-	if query.Offset > 0 {
-		sql += " OFFSET ?"
-		args = append(args, query.Offset)
+	if gap > r.promptDedupWindow {
+		return 0, nil
 	}
 
+	return id, nil
+}
+
+// GetUserMessages retrieves user messages with optional filters
+func (r *Repository) GetUserMessages(query *CommandHistoryQuery) ([]*UserMessage, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	sql, args := r.buildUserMessageQuery(query)
 	rows, err := r.db.db.Query(sql, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query user messages: %w", err)
@@ -550,6 +804,46 @@ func (r *Repository) GetUserMessages(query *CommandHistoryQuery) ([]*UserMessage
 	return messages, nil
 }
 
+// GetUserMessage retrieves a single user prompt by ID.
+// Returns nil, nil when no prompt exists with that ID.
+func (r *Repository) GetUserMessage(id int64) (*UserMessage, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	query := `
+		SELECT id, COALESCE(conversation_id, ''), COALESCE(session_name, ''), message,
+		       COALESCE(working_directory, ''), COALESCE(git_branch, ''),
+		       COALESCE(model_provider, ''), COALESCE(model_name, ''),
+		       COALESCE(message_length, 0), submitted_at, created_at
+		FROM user_messages
+		WHERE id = ?
+	`
+
+	msg := &UserMessage{}
+	err := r.db.db.QueryRow(query, id).Scan(
+		&msg.ID,
+		&msg.ConversationID,
+		&msg.SessionName,
+		&msg.Message,
+		&msg.WorkingDirectory,
+		&msg.GitBranch,
+		&msg.ModelProvider,
+		&msg.ModelName,
+		&msg.MessageLength,
+		&msg.SubmittedAt,
+		&msg.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user message: %w", err)
+	}
+
+	return msg, nil
+}
+
 // SaveProvider saves or updates a provider configuration
 // It sets the provider as current and unsets all other providers
 func (r *Repository) SaveProvider(provider *ProviderConfig) error {
@@ -567,6 +861,11 @@ func (r *Repository) SaveProvider(provider *ProviderConfig) error {
 		return fmt.Errorf("failed to update current providers: %w", err)
 	}
 
+	apiKey, err := EncryptSecret(provider.APIKey)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt provider API key: %w", err)
+	}
+
 	// Insert or update the provider
 	query := `
 		INSERT INTO providers (provider_id, api_key, custom_url, model_name, is_current)
@@ -579,7 +878,7 @@ func (r *Repository) SaveProvider(provider *ProviderConfig) error {
 			updated_at = CURRENT_TIMESTAMP
 	`
 
-	if _, err := tx.Exec(query, provider.ProviderID, provider.APIKey, provider.CustomURL, provider.ModelName); err != nil {
+	if _, err := tx.Exec(query, provider.ProviderID, apiKey, provider.CustomURL, provider.ModelName); err != nil {
 		return fmt.Errorf("failed to save provider: %w", err)
 	}
 
@@ -619,6 +918,10 @@ func (r *Repository) GetProvider(providerID string) (*ProviderConfig, error) {
 		return nil, fmt.Errorf("failed to get provider: %w", err)
 	}
 
+	if provider.APIKey, err = DecryptSecret(provider.APIKey); err != nil {
+		return nil, fmt.Errorf("failed to decrypt provider API key: %w", err)
+	}
+
 	return provider, nil
 }
 
@@ -652,6 +955,10 @@ func (r *Repository) GetCurrentProvider() (*ProviderConfig, error) {
 		return nil, fmt.Errorf("failed to get current provider: %w", err)
 	}
 
+	if provider.APIKey, err = DecryptSecret(provider.APIKey); err != nil {
+		return nil, fmt.Errorf("failed to decrypt provider API key: %w", err)
+	}
+
 	return provider, nil
 }
 
@@ -687,12 +994,49 @@ func (r *Repository) GetAllProviders() ([]*ProviderConfig, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan provider: %w", err)
 		}
+		if provider.APIKey, err = DecryptSecret(provider.APIKey); err != nil {
+			return nil, fmt.Errorf("failed to decrypt provider API key: %w", err)
+		}
 		providers = append(providers, provider)
 	}
 
 	return providers, nil
 }
 
+// SetCurrentProvider marks a saved provider as current and unsets all others.
+// Returns false if no provider with that ID is saved.
+func (r *Repository) SetCurrentProvider(providerID string) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	tx, err := r.db.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM providers WHERE provider_id = ?)", providerID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to look up provider: %w", err)
+	}
+	if !exists {
+		return false, nil
+	}
+
+	if _, err := tx.Exec("UPDATE providers SET is_current = 0"); err != nil {
+		return false, fmt.Errorf("failed to update current providers: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE providers SET is_current = 1, updated_at = CURRENT_TIMESTAMP WHERE provider_id = ?", providerID); err != nil {
+		return false, fmt.Errorf("failed to set current provider: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
 // DeleteProvider removes a provider configuration
 func (r *Repository) DeleteProvider(providerID string) error {
 	r.db.mu.Lock()
@@ -798,18 +1142,116 @@ func (r *Repository) DeleteAllHistory() error {
 	return nil
 }
 
-// GetUniqueSessions retrieves all unique session IDs and names from all tables (user_messages, shell_commands, claude_commands, notifications)
-func (r *Repository) GetUniqueSessions() ([]map[string]string, error) {
-	r.db.mu.RLock()
-	defer r.db.mu.RUnlock()
+// conversationTables lists the tables holding per-conversation rows, keyed by conversation_id
+// (conversations itself is keyed by id and deleted separately)
+var conversationTables = []string{
+	"user_messages",
+	"shell_commands",
+	"claude_commands",
+	"notifications",
+	"conversation_tags",
+	"cli_sessions",
+}
 
-	// Union all four tables to get unique sessions
-	query := `
-		SELECT conversation_id, session_name, MAX(last_activity) as last_activity
-		FROM (
-			SELECT conversation_id, COALESCE(session_name, '') as session_name, submitted_at as last_activity
-			FROM user_messages
-			WHERE conversation_id != '' AND conversation_id IS NOT NULL
+// vacuumFreeBytesThreshold is how much free space deleting a conversation
+// must leave in the database file before it is compacted with VACUUM
+const vacuumFreeBytesThreshold = 16 * 1024 * 1024
+
+// ConversationDeleteResult reports what DeleteConversation removed
+type ConversationDeleteResult struct {
+	ConversationID string           `json:"conversation_id"`
+	Deleted        map[string]int64 `json:"deleted"` // Rows deleted per table
+	Total          int64            `json:"total"`
+	Vacuumed       bool             `json:"vacuumed"`
+}
+
+// DeleteConversation removes every row belonging to one conversation in a single
+// transaction, then vacuums when the freed space crosses vacuumFreeBytesThreshold.
+// A failed VACUUM is logged rather than returned, since the delete has committed.
+func (r *Repository) DeleteConversation(conversationID string) (*ConversationDeleteResult, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	tx, err := r.db.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &ConversationDeleteResult{
+		ConversationID: conversationID,
+		Deleted:        make(map[string]int64, len(conversationTables)+1),
+	}
+
+	deleteRows := func(table, query string) error {
+		res, err := tx.Exec(query, conversationID)
+		if err != nil {
+			return fmt.Errorf("failed to delete from %s: %w", table, err)
+		}
+		n, _ := res.RowsAffected()
+		result.Deleted[table] = n
+		result.Total += n
+		return nil
+	}
+
+	for _, table := range conversationTables {
+		if err := deleteRows(table, "DELETE FROM "+table+" WHERE conversation_id = ?"); err != nil {
+			return nil, err
+		}
+	}
+	if err := deleteRows("conversations", "DELETE FROM conversations WHERE id = ?"); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// The rows are already gone, so a failed VACUUM only leaves the file larger
+	if result.Total > 0 {
+		vacuumed, err := r.vacuumIfFragmented()
+		if err != nil {
+			logging.Warning("Deleted conversation %s but could not compact the database: %v", conversationID, err)
+		}
+		result.Vacuumed = vacuumed
+	}
+
+	return result, nil
+}
+
+// vacuumIfFragmented runs VACUUM when the free pages exceed vacuumFreeBytesThreshold.
+// Caller must hold r.db.mu.
+func (r *Repository) vacuumIfFragmented() (bool, error) {
+	var freePages, pageSize int64
+	if err := r.db.db.QueryRow("PRAGMA freelist_count").Scan(&freePages); err != nil {
+		return false, fmt.Errorf("failed to read freelist count: %w", err)
+	}
+	if err := r.db.db.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+		return false, fmt.Errorf("failed to read page size: %w", err)
+	}
+
+	if freePages*pageSize < vacuumFreeBytesThreshold {
+		return false, nil
+	}
+
+	if _, err := r.db.db.Exec("VACUUM"); err != nil {
+		return false, fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	return true, nil
+}
+
+// GetUniqueSessions retrieves all unique session IDs and names from all tables (user_messages, shell_commands, claude_commands, notifications)
+func (r *Repository) GetUniqueSessions() ([]map[string]string, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	// Union all four tables to get unique sessions
+	query := `
+		SELECT conversation_id, session_name, MAX(last_activity) as last_activity
+		FROM (
+			SELECT conversation_id, COALESCE(session_name, '') as session_name, submitted_at as last_activity
+			FROM user_messages
+			WHERE conversation_id != '' AND conversation_id IS NOT NULL
 			UNION ALL
 			SELECT conversation_id, COALESCE(session_name, '') as session_name, executed_at as last_activity
 			FROM shell_commands
@@ -849,6 +1291,203 @@ func (r *Repository) GetUniqueSessions() ([]map[string]string, error) {
 	return sessions, nil
 }
 
+// GetWorkingDirectories returns every working directory with recorded prompts or
+// commands, with its activity count and most recent activity, newest first
+func (r *Repository) GetWorkingDirectories() ([]*WorkingDirectoryActivity, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	query := `
+		SELECT working_directory, COUNT(*) as activity_count, MAX(activity_at) as last_activity
+		FROM (
+			SELECT working_directory, submitted_at as activity_at
+			FROM user_messages
+			WHERE working_directory != '' AND working_directory IS NOT NULL
+			UNION ALL
+			SELECT working_directory, executed_at as activity_at
+			FROM shell_commands
+			WHERE working_directory != '' AND working_directory IS NOT NULL
+			UNION ALL
+			SELECT working_directory, executed_at as activity_at
+			FROM claude_commands
+			WHERE working_directory != '' AND working_directory IS NOT NULL
+		)
+		GROUP BY working_directory
+		ORDER BY last_activity DESC
+	`
+
+	rows, err := r.db.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query working directories: %w", err)
+	}
+	defer rows.Close()
+
+	directories := []*WorkingDirectoryActivity{}
+	for rows.Next() {
+		dir := &WorkingDirectoryActivity{}
+		var lastActivity interface{}
+		if err := rows.Scan(&dir.Path, &dir.ActivityCount, &lastActivity); err != nil {
+			return nil, fmt.Errorf("failed to scan working directory: %w", err)
+		}
+		// MAX() over a UNION loses the column type, so parse the raw value
+		dir.LastActivity = parseTimestamp(lastActivity)
+		directories = append(directories, dir)
+	}
+
+	return directories, rows.Err()
+}
+
+// SearchConversations finds conversations by working directory, activity date
+// range and session name or first prompt, newest activity first. Prompts,
+// shell commands and Claude commands are aggregated per conversation in SQL.
+func (r *Repository) SearchConversations(query *ConversationSearchQuery) ([]*ConversationSummary, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	sqlQuery := `
+		SELECT conversation_id, MAX(session_name), MAX(working_directory),
+		       MIN(activity_at), MAX(activity_at),
+		       SUM(kind = 'prompt'), SUM(kind = 'shell'), SUM(kind = 'claude'),
+		       COALESCE((
+		           SELECT message FROM user_messages um
+		           WHERE um.conversation_id = activity.conversation_id
+		           ORDER BY submitted_at ASC LIMIT 1
+		       ), '') AS first_prompt
+		FROM (
+			SELECT conversation_id, COALESCE(session_name, '') AS session_name,
+			       COALESCE(working_directory, '') AS working_directory, submitted_at AS activity_at, 'prompt' AS kind
+			FROM user_messages
+			WHERE conversation_id != '' AND conversation_id IS NOT NULL
+			UNION ALL
+			SELECT conversation_id, COALESCE(session_name, ''), COALESCE(working_directory, ''), executed_at, 'shell'
+			FROM shell_commands
+			WHERE conversation_id != ''
+			UNION ALL
+			SELECT conversation_id, COALESCE(session_name, ''), COALESCE(working_directory, ''), executed_at, 'claude'
+			FROM claude_commands
+			WHERE conversation_id != ''
+		) AS activity
+		GROUP BY conversation_id
+		HAVING 1 = 1
+	`
+	var args []interface{}
+
+	if query.WorkingDirectory != "" {
+		dir := strings.TrimSuffix(query.WorkingDirectory, "/")
+		sqlQuery += " AND SUM(working_directory = ? OR substr(working_directory, 1, length(?)) = ?) > 0"
+		args = append(args, dir, dir+"/", dir+"/")
+	}
+	if query.StartDate != nil {
+		sqlQuery += " AND MAX(activity_at) >= ?"
+		args = append(args, query.StartDate)
+	}
+	if query.EndDate != nil {
+		sqlQuery += " AND MIN(activity_at) <= ?"
+		args = append(args, query.EndDate)
+	}
+	if query.Text != "" {
+		sqlQuery += " AND (instr(lower(MAX(session_name)), lower(?)) > 0 OR instr(lower(first_prompt), lower(?)) > 0)"
+		args = append(args, query.Text, query.Text)
+	}
+
+	sqlQuery += " ORDER BY MAX(activity_at) DESC"
+	if query.Limit > 0 {
+		sqlQuery += " LIMIT ?"
+		args = append(args, query.Limit)
+		if query.Offset > 0 {
+			sqlQuery += " OFFSET ?"
+			args = append(args, query.Offset)
+		}
+	}
+
+	rows, err := r.db.db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search conversations: %w", err)
+	}
+	defer rows.Close()
+
+	conversations := []*ConversationSummary{}
+	for rows.Next() {
+		conv := &ConversationSummary{}
+		var firstActivity, lastActivity interface{}
+		if err := rows.Scan(&conv.ConversationID, &conv.SessionName, &conv.WorkingDirectory,
+			&firstActivity, &lastActivity,
+			&conv.PromptCount, &conv.ShellCommandCount, &conv.ClaudeCommandCount, &conv.FirstPrompt); err != nil {
+			return nil, fmt.Errorf("failed to scan conversation: %w", err)
+		}
+		// MIN()/MAX() over a UNION lose the column type, so parse the raw values
+		conv.FirstActivity = parseTimestamp(firstActivity)
+		conv.LastActivity = parseTimestamp(lastActivity)
+		conversations = append(conversations, conv)
+	}
+
+	return conversations, rows.Err()
+}
+
+// GetDistinctToolNames returns the tool names recorded in claude_commands that
+// match the query, most used first, with their usage counts
+func (r *Repository) GetDistinctToolNames(query *ToolNameQuery) ([]*ToolNameCount, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	sqlQuery := `
+		SELECT tool_name, COUNT(*) as count
+		FROM claude_commands
+	`
+	where, args := toolNameFilter(query)
+	sqlQuery += where + `
+		GROUP BY tool_name
+		ORDER BY count DESC, tool_name ASC
+	`
+	if query.Limit > 0 {
+		sqlQuery += " LIMIT ?"
+		args = append(args, query.Limit)
+		if query.Offset > 0 {
+			sqlQuery += " OFFSET ?"
+			args = append(args, query.Offset)
+		}
+	}
+
+	rows, err := r.db.db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tool names: %w", err)
+	}
+	defer rows.Close()
+
+	tools := []*ToolNameCount{}
+	for rows.Next() {
+		tool := &ToolNameCount{}
+		if err := rows.Scan(&tool.ToolName, &tool.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan tool name: %w", err)
+		}
+		tools = append(tools, tool)
+	}
+
+	return tools, rows.Err()
+}
+
+// CountDistinctToolNames returns how many distinct tool names match the query,
+// ignoring its limit and offset
+func (r *Repository) CountDistinctToolNames(query *ToolNameQuery) (int, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	where, args := toolNameFilter(query)
+	var count int
+	if err := r.db.db.QueryRow("SELECT COUNT(DISTINCT tool_name) FROM claude_commands"+where, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count tool names: %w", err)
+	}
+	return count, nil
+}
+
+// toolNameFilter builds the WHERE clause shared by the tool name queries
+func toolNameFilter(query *ToolNameQuery) (string, []interface{}) {
+	if query.Text == "" {
+		return "", nil
+	}
+	return " WHERE instr(lower(tool_name), lower(?)) > 0", []interface{}{query.Text}
+}
+
 // RecordNotification saves a notification event
 func (r *Repository) RecordNotification(notif *Notification) error {
 	r.db.mu.Lock()
@@ -891,46 +1530,7 @@ func (r *Repository) GetNotifications(query *CommandHistoryQuery) ([]*Notificati
 	r.db.mu.RLock()
 	defer r.db.mu.RUnlock()
 
-	sql := `
-		SELECT id, conversation_id, COALESCE(session_name, '') as session_name,
-		       notification_type, message, COALESCE(tool_name, '') as tool_name,
-		       COALESCE(command_details, '') as command_details,
-		       working_directory, git_branch,
-		       COALESCE(model_provider, '') as model_provider, COALESCE(model_name, '') as model_name,
-		       notified_at, created_at
-		FROM notifications
-		WHERE 1=1
-	`
-
-	args := []interface{}{}
-
-	if query.ConversationID != "" {
-		sql += " AND conversation_id = ?"
-		args = append(args, query.ConversationID)
-	}
-
-	if query.StartDate != nil {
-		sql += " AND notified_at >= ?"
-		args = append(args, query.StartDate)
-	}
-
-	if query.EndDate != nil {
-		sql += " AND notified_at <= ?"
-		args = append(args, query.EndDate)
-	}
-
-	sql += " ORDER BY notified_at DESC"
-
-	if query.Limit > 0 {
-		sql += " LIMIT ?"
-		args = append(args, query.Limit)
-	}
-
-	if query.Offset > 0 {
-		sql += " OFFSET ?"
-		args = append(args, query.Offset)
-	}
-
+	sql, args := r.buildNotificationQuery(query)
 	rows, err := r.db.db.Query(sql, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query notifications: %w", err)
@@ -952,6 +1552,7 @@ func (r *Repository) GetNotifications(query *CommandHistoryQuery) ([]*Notificati
 			&notif.GitBranch,
 			&notif.ModelProvider,
 			&notif.ModelName,
+			&notif.Acknowledged,
 			&notif.NotifiedAt,
 			&notif.CreatedAt,
 		)
@@ -964,6 +1565,51 @@ func (r *Repository) GetNotifications(query *CommandHistoryQuery) ([]*Notificati
 	return notifications, nil
 }
 
+// GetNotification retrieves a single notification by ID, including its command details.
+// Returns nil, nil when no notification exists with that ID.
+func (r *Repository) GetNotification(id int64) (*Notification, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	query := `
+		SELECT id, COALESCE(conversation_id, ''), COALESCE(session_name, ''),
+		       notification_type, message, COALESCE(tool_name, ''),
+		       COALESCE(command_details, ''),
+		       COALESCE(working_directory, ''), COALESCE(git_branch, ''),
+		       COALESCE(model_provider, ''), COALESCE(model_name, ''),
+		       acknowledged, notified_at, created_at
+		FROM notifications
+		WHERE id = ?
+	`
+
+	notif := &Notification{}
+	err := r.db.db.QueryRow(query, id).Scan(
+		&notif.ID,
+		&notif.ConversationID,
+		&notif.SessionName,
+		&notif.NotificationType,
+		&notif.Message,
+		&notif.ToolName,
+		&notif.CommandDetails,
+		&notif.WorkingDirectory,
+		&notif.GitBranch,
+		&notif.ModelProvider,
+		&notif.ModelName,
+		&notif.Acknowledged,
+		&notif.NotifiedAt,
+		&notif.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification: %w", err)
+	}
+
+	return notif, nil
+}
+
 // GetNotificationStats retrieves aggregated notification statistics
 func (r *Repository) GetNotificationStats() (*NotificationStats, error) {
 	r.db.mu.RLock()
@@ -977,7 +1623,8 @@ func (r *Repository) GetNotificationStats() (*NotificationStats, error) {
 			COUNT(*) as total,
 			SUM(CASE WHEN notification_type = 'permission_request' THEN 1 ELSE 0 END) as permission_requests,
 			SUM(CASE WHEN notification_type = 'idle_alert' THEN 1 ELSE 0 END) as idle_alerts,
-			SUM(CASE WHEN notification_type = 'other' THEN 1 ELSE 0 END) as other_notifications
+			SUM(CASE WHEN notification_type = 'other' THEN 1 ELSE 0 END) as other_notifications,
+			SUM(CASE WHEN acknowledged = 0 THEN 1 ELSE 0 END) as unread_notifications
 		FROM notifications
 	`
 
@@ -986,6 +1633,7 @@ func (r *Repository) GetNotificationStats() (*NotificationStats, error) {
 		&stats.PermissionRequests,
 		&stats.IdleAlerts,
 		&stats.OtherNotifications,
+		&stats.UnreadNotifications,
 	)
 
 	if err != nil {
@@ -1012,6 +1660,35 @@ func (r *Repository) GetNotificationStats() (*NotificationStats, error) {
 	return stats, nil
 }
 
+// AcknowledgeNotifications marks the given notifications as read.
+// An empty ids slice acknowledges every unread notification.
+// Returns the number of notifications that changed from unread to read.
+func (r *Repository) AcknowledgeNotifications(ids []int64) (int64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	query := "UPDATE notifications SET acknowledged = 1 WHERE acknowledged = 0"
+	args := make([]interface{}, 0, len(ids))
+	if len(ids) > 0 {
+		query += " AND id IN (?" + strings.Repeat(", ?", len(ids)-1) + ")"
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+
+	result, err := r.db.db.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acknowledge notifications: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get acknowledged count: %w", err)
+	}
+
+	return count, nil
+}
+
 // DeleteAllNotifications removes all notifications
 func (r *Repository) DeleteAllNotifications() error {
 	r.db.mu.Lock()
@@ -1134,3 +1811,514 @@ func (r *Repository) DeleteUserSetting(key string) error {
 
 	return nil
 }
+
+// normalizeTag lowercases and trims a tag so tags are case-insensitive
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// AddTag attaches a tag to a conversation (no-op if the tag already exists)
+func (r *Repository) AddTag(conversationID, tag string) error {
+	tag = normalizeTag(tag)
+	if tag == "" {
+		return fmt.Errorf("tag cannot be empty")
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	query := "INSERT OR IGNORE INTO conversation_tags (conversation_id, tag) VALUES (?, ?)"
+	if _, err := r.db.db.Exec(query, conversationID, tag); err != nil {
+		return fmt.Errorf("failed to add tag: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveTag removes a tag from a conversation
+func (r *Repository) RemoveTag(conversationID, tag string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	query := "DELETE FROM conversation_tags WHERE conversation_id = ? AND tag = ?"
+	if _, err := r.db.db.Exec(query, conversationID, normalizeTag(tag)); err != nil {
+		return fmt.Errorf("failed to remove tag: %w", err)
+	}
+
+	return nil
+}
+
+// GetTags retrieves all tags for a conversation in alphabetical order
+func (r *Repository) GetTags(conversationID string) ([]string, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	rows, err := r.db.db.Query("SELECT tag FROM conversation_tags WHERE conversation_id = ? ORDER BY tag", conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+// GetConversationIDsByTag retrieves the IDs of all conversations with the given tag
+func (r *Repository) GetConversationIDsByTag(tag string) ([]string, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	rows, err := r.db.db.Query("SELECT conversation_id FROM conversation_tags WHERE tag = ?", normalizeTag(tag))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tagged conversations: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan conversation id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// GetMetricTotals retrieves aggregate counters for the metrics endpoint
+func (r *Repository) GetMetricTotals() (*MetricTotals, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	totals := &MetricTotals{}
+
+	if err := r.db.db.QueryRow("SELECT COUNT(*) FROM user_messages").Scan(&totals.TotalPrompts); err != nil {
+		return nil, fmt.Errorf("failed to count prompts: %w", err)
+	}
+
+	if err := r.db.db.QueryRow("SELECT COALESCE(SUM(total_tokens), 0) FROM conversations").Scan(&totals.TotalTokens); err != nil {
+		return nil, fmt.Errorf("failed to sum tokens: %w", err)
+	}
+
+	if err := r.db.db.QueryRow("SELECT COALESCE(SUM(cost_usd), 0) FROM agent_sessions").Scan(&totals.AgentCostUSD); err != nil {
+		return nil, fmt.Errorf("failed to sum agent cost: %w", err)
+	}
+
+	return totals, nil
+}
+
+// historyStreamQuery unions all history tables into one timestamp-ordered cursor.
+// Each branch binds the conversation filter twice (empty string disables it).
+const historyStreamQuery = `
+	SELECT 'shell', id, conversation_id, COALESCE(session_name, ''), executed_at AS ts,
+	       COALESCE(working_directory, ''), COALESCE(git_branch, ''),
+	       json_object(
+	           'id', id, 'conversation_id', conversation_id, 'session_name', session_name,
+	           'command', command, 'description', description,
+	           'working_directory', working_directory, 'git_branch', git_branch,
+	           'model_provider', model_provider, 'model_name', model_name,
+	           'exit_code', exit_code, 'stdout', stdout, 'stderr', stderr,
+	           'duration_ms', duration_ms, 'executed_at', executed_at, 'created_at', created_at)
+	FROM shell_commands WHERE (? = '' OR conversation_id = ?)
+	UNION ALL
+	SELECT 'claude', id, conversation_id, COALESCE(session_name, ''), executed_at AS ts,
+	       COALESCE(working_directory, ''), COALESCE(git_branch, ''),
+	       json_object(
+	           'id', id, 'conversation_id', conversation_id, 'session_name', session_name,
+	           'tool_name', tool_name, 'parameters', parameters, 'result', result,
+	           'working_directory', working_directory, 'git_branch', git_branch,
+	           'model_provider', model_provider, 'model_name', model_name,
+	           'success', json(CASE WHEN success THEN 'true' ELSE 'false' END),
+	           'error_message', error_message, 'duration_ms', duration_ms,
+	           'executed_at', executed_at, 'created_at', created_at)
+	FROM claude_commands WHERE (? = '' OR conversation_id = ?)
+	UNION ALL
+	SELECT 'prompt', id, COALESCE(conversation_id, ''), COALESCE(session_name, ''), submitted_at AS ts,
+	       COALESCE(working_directory, ''), COALESCE(git_branch, ''),
+	       json_object(
+	           'id', id, 'conversation_id', conversation_id, 'session_name', session_name,
+	           'message', message, 'working_directory', working_directory, 'git_branch', git_branch,
+	           'model_provider', model_provider, 'model_name', model_name,
+	           'message_length', message_length, 'submitted_at', submitted_at, 'created_at', created_at)
+	FROM user_messages WHERE (? = '' OR conversation_id = ?)
+	UNION ALL
+	SELECT 'notification', id, conversation_id, COALESCE(session_name, ''), notified_at AS ts,
+	       COALESCE(working_directory, ''), COALESCE(git_branch, ''),
+	       json_object(
+	           'id', id, 'conversation_id', conversation_id, 'session_name', session_name,
+	           'notification_type', notification_type, 'message', message,
+	           'tool_name', tool_name, 'command_details', command_details,
+	           'working_directory', working_directory, 'git_branch', git_branch,
+	           'model_provider', model_provider, 'model_name', model_name,
+	           'notified_at', notified_at, 'created_at', created_at)
+	FROM notifications WHERE (? = '' OR conversation_id = ?)
+	ORDER BY ts ASC
+`
+
+// StreamHistory walks every history entry ordered by timestamp (oldest first),
+// calling fn for each row without loading the full result into memory.
+// Iteration stops at the first error returned by fn.
+func (r *Repository) StreamHistory(conversationID string, fn func(*HistoryRow) error) error {
+	args := make([]interface{}, 0, 8)
+	for i := 0; i < 8; i++ {
+		args = append(args, conversationID)
+	}
+
+	// Only hold the lock while starting the query; WAL mode lets the cursor
+	// keep reading while writers record new history during long exports.
+	r.db.mu.RLock()
+	rows, err := r.db.db.Query(historyStreamQuery, args...)
+	r.db.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to query history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := &HistoryRow{}
+		var ts interface{}
+		var content string
+		if err := rows.Scan(&row.Type, &row.ID, &row.ConversationID, &row.SessionName, &ts,
+			&row.WorkingDirectory, &row.GitBranch, &content); err != nil {
+			return fmt.Errorf("failed to scan history row: %w", err)
+		}
+		row.Timestamp = parseTimestamp(ts)
+		row.Content = json.RawMessage(content)
+
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating history: %w", err)
+	}
+
+	return nil
+}
+
+// parseTimestamp converts a raw SQLite timestamp value into a time.Time.
+// Union queries lose the declared column type, so values may arrive as text.
+func parseTimestamp(value interface{}) time.Time {
+	switch v := value.(type) {
+	case time.Time:
+		return v
+	case string:
+		for _, layout := range sqlite3.SQLiteTimestampFormats {
+			if t, err := time.ParseInLocation(layout, v, time.UTC); err == nil {
+				return t
+			}
+		}
+	case []byte:
+		return parseTimestamp(string(v))
+	}
+	return time.Time{}
+}
+
+// processBucketFormats maps supported bucket sizes to SQLite strftime formats
+var processBucketFormats = map[string]string{
+	"minute": "%Y-%m-%d %H:%M:00",
+	"hour":   "%Y-%m-%d %H:00:00",
+	"day":    "%Y-%m-%d 00:00:00",
+}
+
+// IsValidProcessBucket reports whether bucket is a supported process history bucket size
+func IsValidProcessBucket(bucket string) bool {
+	_, ok := processBucketFormats[bucket]
+	return ok
+}
+
+// RecordProcessSample saves a snapshot of detected Claude processes
+func (r *Repository) RecordProcessSample(sample *ProcessSample) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	result, err := r.db.db.Exec(
+		"INSERT INTO process_samples (sampled_at, process_count, known_dir_count) VALUES (?, ?, ?)",
+		sample.SampledAt.UTC(), sample.ProcessCount, sample.KnownDirCount,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record process sample: %w", err)
+	}
+
+	id, _ := result.LastInsertId()
+	sample.ID = id
+	return nil
+}
+
+// GetProcessHistory aggregates process samples since the given time into buckets
+// ("minute", "hour" or "day"), ordered oldest first. Buckets are in UTC.
+func (r *Repository) GetProcessHistory(bucket string, since time.Time) ([]*ProcessHistoryBucket, error) {
+	format, ok := processBucketFormats[bucket]
+	if !ok {
+		return nil, fmt.Errorf("invalid bucket: %s", bucket)
+	}
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	rows, err := r.db.db.Query(`
+		SELECT strftime(?, sampled_at) AS bucket,
+		       COUNT(*), AVG(process_count), MAX(process_count)
+		FROM process_samples
+		WHERE sampled_at >= ?
+		GROUP BY bucket
+		ORDER BY bucket ASC
+	`, format, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query process history: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := []*ProcessHistoryBucket{}
+	for rows.Next() {
+		var start string
+		b := &ProcessHistoryBucket{}
+		if err := rows.Scan(&start, &b.Samples, &b.AvgProcesses, &b.MaxProcesses); err != nil {
+			return nil, fmt.Errorf("failed to scan process history: %w", err)
+		}
+		b.Start, _ = time.Parse("2006-01-02 15:04:05", start)
+		buckets = append(buckets, b)
+	}
+
+	return buckets, rows.Err()
+}
+
+// DeleteProcessSamplesBefore removes process samples older than the cutoff
+func (r *Repository) DeleteProcessSamplesBefore(cutoff time.Time) (int64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	result, err := r.db.db.Exec("DELETE FROM process_samples WHERE sampled_at < ?", cutoff.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete process samples: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// RecordShellSnapshot records the background shells detected at the given time.
+// Shells still open from an earlier snapshot (same PID and command) are updated;
+// open shells missing from this snapshot are marked as ended.
+func (r *Repository) RecordShellSnapshot(shells []*BackgroundShellRecord, at time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	at = at.UTC()
+
+	tx, err := r.db.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin shell snapshot: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, shell := range shells {
+		result, err := tx.Exec(`
+			UPDATE background_shells
+			SET status = ?, last_seen_at = ?
+			WHERE pid = ? AND command = ? AND ended_at IS NULL
+		`, shell.Status, at, shell.PID, shell.Command)
+		if err != nil {
+			return fmt.Errorf("failed to update background shell: %w", err)
+		}
+
+		if updated, _ := result.RowsAffected(); updated > 0 {
+			continue
+		}
+
+		if _, err := tx.Exec(`
+			INSERT INTO background_shells (shell_id, pid, command, status, working_directory, first_seen_at, last_seen_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, shell.ShellID, shell.PID, shell.Command, shell.Status, shell.WorkingDirectory, at, at); err != nil {
+			return fmt.Errorf("failed to record background shell: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(
+		"UPDATE background_shells SET ended_at = ? WHERE ended_at IS NULL AND last_seen_at < ?",
+		at, at,
+	); err != nil {
+		return fmt.Errorf("failed to mark ended background shells: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit shell snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// GetShellHistory returns background shells seen since the given time, most recently started first
+func (r *Repository) GetShellHistory(since time.Time) ([]*BackgroundShellRecord, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	rows, err := r.db.db.Query(`
+		SELECT id, COALESCE(shell_id, ''), pid, command, COALESCE(status, ''),
+		       COALESCE(working_directory, ''), first_seen_at, last_seen_at, ended_at
+		FROM background_shells
+		WHERE last_seen_at >= ?
+		ORDER BY first_seen_at DESC, id DESC
+	`, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shell history: %w", err)
+	}
+	defer rows.Close()
+
+	shells := []*BackgroundShellRecord{}
+	for rows.Next() {
+		shell := &BackgroundShellRecord{}
+		var endedAt sql.NullTime
+		if err := rows.Scan(
+			&shell.ID,
+			&shell.ShellID,
+			&shell.PID,
+			&shell.Command,
+			&shell.Status,
+			&shell.WorkingDirectory,
+			&shell.FirstSeenAt,
+			&shell.LastSeenAt,
+			&endedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan background shell: %w", err)
+		}
+
+		if endedAt.Valid {
+			shell.EndedAt = &endedAt.Time
+		}
+		shells = append(shells, shell)
+	}
+
+	return shells, rows.Err()
+}
+
+// DeleteShellHistoryBefore removes background shells that ended before the cutoff
+func (r *Repository) DeleteShellHistoryBefore(cutoff time.Time) (int64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	result, err := r.db.db.Exec("DELETE FROM background_shells WHERE ended_at IS NOT NULL AND ended_at < ?", cutoff.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete shell history: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// RecordSessionLifecycle stores a session start or end event. Events for the same
+// conversation are merged, so an end recorded before its start is kept.
+func (r *Repository) RecordSessionLifecycle(session *CLISession, event string, at time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var query string
+	var detail string
+	switch event {
+	case SessionLifecycleStart:
+		// Leave any recorded end alone: hooks may deliver the end before its start
+		query = `
+			INSERT INTO cli_sessions (conversation_id, session_name, working_directory, git_branch, start_source, started_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(conversation_id) DO UPDATE SET
+				session_name = excluded.session_name,
+				working_directory = excluded.working_directory,
+				git_branch = excluded.git_branch,
+				start_source = excluded.start_source,
+				started_at = COALESCE(cli_sessions.started_at, excluded.started_at),
+				updated_at = CURRENT_TIMESTAMP
+		`
+		detail = session.StartSource
+	case SessionLifecycleEnd:
+		query = `
+			INSERT INTO cli_sessions (conversation_id, session_name, working_directory, git_branch, end_reason, ended_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(conversation_id) DO UPDATE SET
+				end_reason = excluded.end_reason,
+				ended_at = excluded.ended_at,
+				updated_at = CURRENT_TIMESTAMP
+		`
+		detail = session.EndReason
+	default:
+		return fmt.Errorf("unknown session lifecycle event: %s", event)
+	}
+
+	if _, err := r.db.db.Exec(query,
+		session.ConversationID,
+		session.SessionName,
+		session.WorkingDirectory,
+		session.GitBranch,
+		detail,
+		at.UTC(),
+	); err != nil {
+		return fmt.Errorf("failed to record session %s: %w", event, err)
+	}
+
+	return nil
+}
+
+// GetCLISessions retrieves recorded CLI sessions, most recently started first
+func (r *Repository) GetCLISessions(limit int) ([]*CLISession, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	if limit <= 0 {
+		limit = 100
+	}
+
+	query := `
+		SELECT conversation_id, COALESCE(session_name, ''), COALESCE(working_directory, ''),
+		       COALESCE(git_branch, ''), COALESCE(start_source, ''), COALESCE(end_reason, ''),
+		       started_at, ended_at
+		FROM cli_sessions
+		ORDER BY COALESCE(started_at, ended_at) DESC
+		LIMIT ?
+	`
+
+	rows, err := r.db.db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cli sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []*CLISession{}
+	for rows.Next() {
+		session := &CLISession{}
+		var startedAt, endedAt sql.NullTime
+		if err := rows.Scan(
+			&session.ConversationID,
+			&session.SessionName,
+			&session.WorkingDirectory,
+			&session.GitBranch,
+			&session.StartSource,
+			&session.EndReason,
+			&startedAt,
+			&endedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan cli session: %w", err)
+		}
+
+		if startedAt.Valid {
+			session.StartedAt = &startedAt.Time
+		}
+		if endedAt.Valid {
+			session.EndedAt = &endedAt.Time
+		}
+		if session.StartedAt != nil && session.EndedAt != nil {
+			duration := session.EndedAt.Sub(*session.StartedAt).Seconds()
+			session.DurationSeconds = &duration
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
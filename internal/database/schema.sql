@@ -108,6 +108,7 @@ CREATE TABLE IF NOT EXISTS notifications (
     git_branch TEXT,
     model_provider TEXT,
     model_name TEXT,
+    acknowledged BOOLEAN NOT NULL DEFAULT 0, -- set once the user has seen the notification
     notified_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
@@ -122,6 +123,49 @@ CREATE TABLE IF NOT EXISTS user_settings (
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Table for conversation tags (user-defined labels)
+CREATE TABLE IF NOT EXISTS conversation_tags (
+    conversation_id TEXT NOT NULL,
+    tag TEXT NOT NULL, -- stored lowercased
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (conversation_id, tag)
+);
+
+-- Table for periodic Claude process samples (process history timeline)
+CREATE TABLE IF NOT EXISTS process_samples (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    sampled_at TIMESTAMP NOT NULL,
+    process_count INTEGER NOT NULL,
+    known_dir_count INTEGER NOT NULL DEFAULT 0
+);
+
+-- Table for background shells observed by the shell sampler (shell history)
+CREATE TABLE IF NOT EXISTS background_shells (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    shell_id TEXT,
+    pid TEXT NOT NULL,
+    command TEXT NOT NULL,
+    status TEXT,
+    working_directory TEXT,
+    first_seen_at TIMESTAMP NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP
+);
+
+-- Table for Claude CLI session lifecycle (SessionStart/SessionEnd hooks)
+CREATE TABLE IF NOT EXISTS cli_sessions (
+    conversation_id TEXT PRIMARY KEY,
+    session_name TEXT,
+    working_directory TEXT,
+    git_branch TEXT,
+    start_source TEXT,
+    end_reason TEXT,
+    started_at TIMESTAMP,
+    ended_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
 -- Insert default settings
 INSERT OR IGNORE INTO user_settings (key, value, value_type, description) VALUES
 ('diff_display_location', 'chat', 'string', 'Where to display file diffs: "chat" or "options"');
@@ -169,6 +213,21 @@ CREATE INDEX IF NOT EXISTS idx_notifications_type
 CREATE INDEX IF NOT EXISTS idx_notifications_tool
     ON notifications(tool_name, notified_at DESC) WHERE tool_name IS NOT NULL;
 
+CREATE INDEX IF NOT EXISTS idx_conversation_tags_tag
+    ON conversation_tags(tag, conversation_id);
+
+CREATE INDEX IF NOT EXISTS idx_process_samples_sampled_at
+    ON process_samples(sampled_at);
+
+CREATE INDEX IF NOT EXISTS idx_background_shells_last_seen
+    ON background_shells(last_seen_at);
+
+CREATE INDEX IF NOT EXISTS idx_background_shells_open
+    ON background_shells(pid, command) WHERE ended_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_cli_sessions_started_at
+    ON cli_sessions(started_at DESC);
+
 -- Indexes for model filtering
 CREATE INDEX IF NOT EXISTS idx_shell_commands_model
     ON shell_commands(model_provider, model_name);
@@ -205,6 +264,7 @@ CREATE TABLE IF NOT EXISTS agent_sessions (
     claude_session_id TEXT,
     git_branch TEXT,
     options TEXT,
+    parent_session_id TEXT,
     CONSTRAINT status_check CHECK (status IN ('idle', 'active', 'processing', 'error', 'ended'))
 );
 
@@ -219,6 +279,8 @@ CREATE TABLE IF NOT EXISTS agent_messages (
     tool_uses TEXT,
     timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     tokens_used INTEGER DEFAULT 0,
+    archived INTEGER NOT NULL DEFAULT 0,
+    flagged INTEGER NOT NULL DEFAULT 0,
     FOREIGN KEY (session_id) REFERENCES agent_sessions(id) ON DELETE CASCADE,
     CONSTRAINT role_check CHECK (role IN ('user', 'assistant', 'system'))
 );
@@ -242,3 +304,10 @@ CREATE INDEX IF NOT EXISTS idx_agent_messages_timestamp
 
 CREATE INDEX IF NOT EXISTS idx_agent_messages_sequence
     ON agent_messages(session_id, sequence DESC);
+
+-- Applied schema migrations (see migrations.go)
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    description TEXT NOT NULL,
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -1,23 +1,45 @@
 package logging
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
+// Log output formats
+const (
+	FormatText = "text" // Human-readable lines (default)
+	FormatJSON = "json" // One JSON object per line for log aggregation
+)
+
+// Fields holds structured key-value data attached to a log entry
+type Fields map[string]interface{}
+
+// Options configures the log output
+type Options struct {
+	Format     string // FormatText or FormatJSON (unknown values fall back to text)
+	MaxSizeMB  int    // Rotate the main and stderr logs at this size; 0 disables rotation
+	MaxBackups int    // Rotated files kept per log (default: 3)
+}
+
 // Logger provides application-wide logging with file output
 type Logger struct {
-	file       *os.File
+	file       io.WriteCloser
 	logger     *log.Logger
 	verbose    bool
+	format     string
 	mu         sync.Mutex
 	logFile    string
 	stderrFile string
+	maxSize    int64 // Rotation size in bytes, 0 = unlimited
+	maxBackups int
 }
 
 var (
@@ -29,9 +51,21 @@ var (
 // logDir: directory where log files will be written
 // verbose: enable debug/verbose logging
 func Initialize(logDir string, verbose bool) (*Logger, error) {
+	return InitializeWithFormat(logDir, verbose, FormatText)
+}
+
+// InitializeWithFormat creates the global logger instance with the given output format.
+// format: FormatText or FormatJSON (unknown values fall back to text)
+func InitializeWithFormat(logDir string, verbose bool, format string) (*Logger, error) {
+	return InitializeWithOptions(logDir, verbose, Options{Format: format})
+}
+
+// InitializeWithOptions creates the global logger instance with the given
+// output format and rotation settings
+func InitializeWithOptions(logDir string, verbose bool, opts Options) (*Logger, error) {
 	var err error
 	once.Do(func() {
-		globalLogger, err = newLogger(logDir, verbose)
+		globalLogger, err = newLogger(logDir, verbose, opts)
 	})
 	return globalLogger, err
 }
@@ -42,7 +76,16 @@ func GetLogger() *Logger {
 }
 
 // newLogger creates a new logger instance
-func newLogger(logDir string, verbose bool) (*Logger, error) {
+func newLogger(logDir string, verbose bool, opts Options) (*Logger, error) {
+	format := opts.Format
+	if format != FormatJSON {
+		format = FormatText
+	}
+	maxSize := int64(opts.MaxSizeMB) * 1024 * 1024
+	if maxSize < 0 {
+		maxSize = 0
+	}
+
 	// Create log directory if it doesn't exist
 	if err := os.MkdirAll(logDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create log directory: %w", err)
@@ -54,26 +97,36 @@ func newLogger(logDir string, verbose bool) (*Logger, error) {
 	stderrFile := filepath.Join(logDir, fmt.Sprintf("sdk_stderr_%s.log", timestamp))
 
 	// Create log file
-	file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	file, err := openRotatingFile(logFile, maxSize, opts.MaxBackups)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create log file: %w", err)
 	}
 
-	// Create logger
-	logger := log.New(file, "", log.LstdFlags|log.Lshortfile)
+	// Create logger (JSON entries carry their own timestamp, so no prefix flags)
+	flags := log.LstdFlags | log.Lshortfile
+	if format == FormatJSON {
+		flags = 0
+	}
+	logger := log.New(file, "", flags)
 
 	l := &Logger{
 		file:       file,
 		logger:     logger,
 		verbose:    verbose,
+		format:     format,
 		logFile:    logFile,
 		stderrFile: stderrFile,
+		maxSize:    maxSize,
+		maxBackups: opts.MaxBackups,
 	}
 
 	// Log initialization
-	l.Info("Logger initialized (verbose=%v)", verbose)
+	l.Info("Logger initialized (verbose=%v, format=%s)", verbose, format)
 	l.Info("Log file: %s", logFile)
 	l.Info("SDK stderr file: %s", stderrFile)
+	if maxSize > 0 {
+		l.Info("Log rotation: %d MB, %d backups", opts.MaxSizeMB, file.maxBackups)
+	}
 
 	return l, nil
 }
@@ -100,8 +153,28 @@ func (l *Logger) Error(format string, args ...interface{}) {
 	l.log("ERROR", format, args...)
 }
 
+// InfoFields logs an informational message with structured fields
+func (l *Logger) InfoFields(msg string, fields Fields) {
+	l.write("INFO", msg, fields)
+}
+
+// WarningFields logs a warning message with structured fields
+func (l *Logger) WarningFields(msg string, fields Fields) {
+	l.write("WARNING", msg, fields)
+}
+
+// ErrorFields logs an error message with structured fields
+func (l *Logger) ErrorFields(msg string, fields Fields) {
+	l.write("ERROR", msg, fields)
+}
+
 // log is the internal logging method
 func (l *Logger) log(level string, format string, args ...interface{}) {
+	l.write(level, fmt.Sprintf(format, args...), nil)
+}
+
+// write formats a single entry according to the logger's output format
+func (l *Logger) write(level string, msg string, fields Fields) {
 	if l == nil || l.logger == nil {
 		return
 	}
@@ -109,8 +182,44 @@ func (l *Logger) log(level string, format string, args ...interface{}) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	msg := fmt.Sprintf(format, args...)
-	l.logger.Printf("[%s] %s", level, msg)
+	if l.format == FormatJSON {
+		entry := make(map[string]interface{}, len(fields)+3)
+		for k, v := range fields {
+			entry[k] = v
+		}
+		entry["time"] = time.Now().Format(time.RFC3339Nano)
+		entry["level"] = strings.ToLower(level)
+		entry["msg"] = msg
+
+		data, err := json.Marshal(entry)
+		if err != nil {
+			l.logger.Printf(`{"level":"error","msg":"failed to marshal log entry: %v"}`, err)
+			return
+		}
+		l.logger.Print(string(data))
+		return
+	}
+
+	l.logger.Printf("[%s] %s%s", level, msg, formatTextFields(fields))
+}
+
+// formatTextFields renders fields as sorted " key=value" pairs for text output
+func formatTextFields(fields Fields) string {
+	if len(fields) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, fields[k])
+	}
+	return b.String()
 }
 
 // GetLogFilePath returns the path to the main log file
@@ -137,7 +246,7 @@ func (l *Logger) RedirectStderr() error {
 	}
 
 	// Create stderr log file
-	stderrFile, err := os.OpenFile(l.stderrFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	stderrFile, err := openRotatingFile(l.stderrFile, l.maxSize, l.maxBackups)
 	if err != nil {
 		return fmt.Errorf("failed to create stderr log file: %w", err)
 	}
@@ -217,3 +326,24 @@ func Error(format string, args ...interface{}) {
 		globalLogger.Error(format, args...)
 	}
 }
+
+// InfoFields logs an info message with structured fields to the global logger
+func InfoFields(msg string, fields Fields) {
+	if globalLogger != nil {
+		globalLogger.InfoFields(msg, fields)
+	}
+}
+
+// WarningFields logs a warning message with structured fields to the global logger
+func WarningFields(msg string, fields Fields) {
+	if globalLogger != nil {
+		globalLogger.WarningFields(msg, fields)
+	}
+}
+
+// ErrorFields logs an error message with structured fields to the global logger
+func ErrorFields(msg string, fields Fields) {
+	if globalLogger != nil {
+		globalLogger.ErrorFields(msg, fields)
+	}
+}
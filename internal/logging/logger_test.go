@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJSONFormat(t *testing.T) {
+	l, err := newLogger(t.TempDir(), false, Options{Format: FormatJSON})
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer l.file.Close()
+
+	l.InfoFields("session created", Fields{"session_id": "abc"})
+
+	data, err := os.ReadFile(l.GetLogFilePath())
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &entry); err != nil {
+		t.Fatalf("last line is not valid JSON: %v", err)
+	}
+
+	if entry["level"] != "info" || entry["msg"] != "session created" || entry["session_id"] != "abc" {
+		t.Errorf("unexpected entry: %v", entry)
+	}
+
+	if _, ok := entry["time"]; !ok {
+		t.Error("expected time field in JSON entry")
+	}
+}
+
+func TestTextFormatWithFields(t *testing.T) {
+	l, err := newLogger(t.TempDir(), false, Options{})
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer l.file.Close()
+
+	l.WarningFields("slow query", Fields{"table": "agent_messages", "ms": 1200})
+
+	data, err := os.ReadFile(l.GetLogFilePath())
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+
+	if !strings.Contains(string(data), "[WARNING] slow query ms=1200 table=agent_messages") {
+		t.Errorf("expected text entry with sorted fields, got:\n%s", data)
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cct.log")
+	r, err := openRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("openRotatingFile failed: %v", err)
+	}
+	defer r.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	want := map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	}
+	for file, content := range want {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+		if string(data) != content {
+			t.Errorf("%s = %q, want %q", filepath.Base(file), data, content)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("expected backups beyond maxBackups to be removed")
+	}
+}
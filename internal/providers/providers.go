@@ -185,7 +185,7 @@ echo "  ANTHROPIC_MODEL=%s"
 			baseURL,
 			model,
 			provider.Name,
-			maskAPIKey(config.APIKey),
+			MaskAPIKey(config.APIKey),
 			baseURL,
 			model,
 		)
@@ -207,7 +207,7 @@ echo "  ANTHROPIC_BASE_URL=%s"
 			config.APIKey,
 			baseURL,
 			provider.Name,
-			maskAPIKey(config.APIKey),
+			MaskAPIKey(config.APIKey),
 			baseURL,
 		)
 	}
@@ -228,8 +228,8 @@ echo "  ANTHROPIC_BASE_URL=%s"
 	return nil
 }
 
-// maskAPIKey returns a masked version of the API key for display
-func maskAPIKey(apiKey string) string {
+// MaskAPIKey returns a masked version of the API key for display (its last 4 characters)
+func MaskAPIKey(apiKey string) string {
 	if len(apiKey) <= 8 {
 		return "****"
 	}
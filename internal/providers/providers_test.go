@@ -110,7 +110,7 @@ func TestMaskAPIKey(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := maskAPIKey(tt.apiKey)
+			result := MaskAPIKey(tt.apiKey)
 			if result != tt.expected {
 				t.Errorf("expected %q, got %q", tt.expected, result)
 			}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -29,6 +30,7 @@ type AgentHandler struct {
 	SessionManager *SessionManager // Exported for server access
 	Mu             sync.Mutex      // Exported for server access
 	Active         int             // Exported for server access
+	Draining       bool            // Reject new connections while shutting down
 }
 
 // NewAgentHandler creates a new agent handler with the given config and database
@@ -94,6 +96,10 @@ func (h *AgentHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 
 		log.Printf("📥 WS INCOMING: type=%s, sessionID=%v, data=%+v", msgType, rawMsg["session_id"], rawMsg)
 
+		if h.rejectInvalidUUIDs(ws, rawMsg) {
+			continue
+		}
+
 		// Route message to appropriate handler
 		if err := h.routeMessage(ws, MessageType(msgType), rawMsg); err != nil {
 			log.Printf("ERROR: Failed to handle message type %s: %v", msgType, err)
@@ -109,6 +115,15 @@ func (h *AgentHandler) HandleFiberWebSocket(c *fiberws.Conn) {
 
 	// Check concurrent session limit
 	h.Mu.Lock()
+	if h.Draining {
+		h.Mu.Unlock()
+		logging.Warning("Rejecting WebSocket connection from %s: server is draining", c.RemoteAddr())
+		c.WriteJSON(map[string]interface{}{
+			"type":    "error",
+			"message": "server is shutting down",
+		})
+		return
+	}
 	if h.Active >= h.Config.MaxConcurrentSessions {
 		h.Mu.Unlock()
 		logging.Warning("Max concurrent sessions reached: %d/%d", h.Active, h.Config.MaxConcurrentSessions)
@@ -119,7 +134,8 @@ func (h *AgentHandler) HandleFiberWebSocket(c *fiberws.Conn) {
 		return
 	}
 	h.Active++
-	log.Printf("HandleFiberWebSocket: Active connections: %d/%d", h.Active, h.Config.MaxConcurrentSessions)
+	active := h.Active
+	log.Printf("HandleFiberWebSocket: Active connections: %d/%d", active, h.Config.MaxConcurrentSessions)
 	h.Mu.Unlock()
 
 	// Track which sessions are connected via this WebSocket
@@ -148,8 +164,9 @@ func (h *AgentHandler) HandleFiberWebSocket(c *fiberws.Conn) {
 	defer func() {
 		h.Mu.Lock()
 		h.Active--
+		remaining := h.Active
 		h.Mu.Unlock()
-		logging.Debug("WebSocket connection closed, active connections: %d", h.Active)
+		logging.Debug("WebSocket connection closed, active connections: %d", remaining)
 
 		// Clean up all sessions connected via this WebSocket
 		connectedSessionsMu.Lock()
@@ -165,7 +182,7 @@ func (h *AgentHandler) HandleFiberWebSocket(c *fiberws.Conn) {
 	}()
 
 	log.Printf("Fiber WebSocket connection established from %s", c.RemoteAddr().String())
-	logging.Info("WebSocket connection established from %s (active: %d)", c.RemoteAddr().String(), h.Active)
+	logging.Info("WebSocket connection established from %s (active: %d)", c.RemoteAddr().String(), active)
 
 	// Main message loop
 	for {
@@ -186,6 +203,10 @@ func (h *AgentHandler) HandleFiberWebSocket(c *fiberws.Conn) {
 
 		log.Printf("📥 WS INCOMING: type=%s, sessionID=%v, data=%+v", msgType, rawMsg["session_id"], rawMsg)
 
+		if h.rejectInvalidUUIDs(c, rawMsg) {
+			continue
+		}
+
 		// Route message to appropriate handler
 		if err := h.routeFiberMessage(c, MessageType(msgType), rawMsg, registerSession); err != nil {
 			log.Printf("ERROR: Failed to handle message type %s: %v", msgType, err)
@@ -195,7 +216,7 @@ func (h *AgentHandler) HandleFiberWebSocket(c *fiberws.Conn) {
 }
 
 // sendFiberError sends an error message via Fiber WebSocket
-func (h *AgentHandler) sendFiberError(c *fiberws.Conn, errMsg string) {
+func (h *AgentHandler) sendFiberError(c EventWriter, errMsg string) {
 	err := c.WriteJSON(map[string]interface{}{
 		"type":    "error",
 		"message": errMsg,
@@ -218,15 +239,24 @@ func (h *AgentHandler) routeFiberMessage(c *fiberws.Conn, msgType MessageType, r
 	case MessageTypeSendPrompt:
 		return h.handleFiberSendPrompt(c, rawMsg, registerSession)
 
+	case MessageTypeRetryLast:
+		return h.handleFiberRetryLast(c, rawMsg, registerSession)
+
 	case MessageTypeEndSession:
 		return h.handleFiberEndSession(c, rawMsg)
 
 	case MessageTypeInterruptSession:
 		return h.handleFiberInterruptSession(c, rawMsg)
 
+	case MessageTypeStopGeneration:
+		return h.handleFiberStopGeneration(c, rawMsg)
+
 	case MessageTypeDeleteSession:
 		return h.handleFiberDeleteSession(c, rawMsg)
 
+	case MessageTypeForkSession:
+		return h.handleFiberForkSession(c, rawMsg, registerSession)
+
 	case MessageTypeListSessions:
 		return h.handleFiberListSessions(c, registerSession)
 
@@ -236,6 +266,9 @@ func (h *AgentHandler) routeFiberMessage(c *fiberws.Conn, msgType MessageType, r
 	case MessageTypeKillAllAgents:
 		return h.handleFiberKillAllAgents(c)
 
+	case MessageTypeForceKillSession:
+		return h.handleFiberForceKillSession(c, rawMsg)
+
 	case MessageTypeDeleteAllSessions:
 		return h.handleFiberDeleteAllSessions(c)
 
@@ -245,6 +278,18 @@ func (h *AgentHandler) routeFiberMessage(c *fiberws.Conn, msgType MessageType, r
 	case MessageTypePermissionResponse:
 		return h.handleFiberPermissionResponse(c, rawMsg)
 
+	case MessageTypeCancelPermission:
+		return h.handleFiberCancelPermission(c, rawMsg)
+
+	case MessageTypeCompactSession:
+		return h.handleFiberCompactSession(c, rawMsg)
+
+	case MessageTypeInjectContext:
+		return h.handleFiberInjectContext(c, rawMsg)
+
+	case MessageTypeFlagMessage:
+		return h.handleFiberFlagMessage(c, rawMsg)
+
 	case MessageTypeAddAlwaysAllowRule:
 		return h.handleFiberAddAlwaysAllowRule(c, rawMsg)
 
@@ -254,6 +299,15 @@ func (h *AgentHandler) routeFiberMessage(c *fiberws.Conn, msgType MessageType, r
 	case MessageTypeListAlwaysAllowRules:
 		return h.handleFiberListAlwaysAllowRules(c, rawMsg)
 
+	case MessageTypeAddAlwaysDenyRule:
+		return h.handleFiberAddAlwaysDenyRule(c, rawMsg)
+
+	case MessageTypeRemoveAlwaysDenyRule:
+		return h.handleFiberRemoveAlwaysDenyRule(c, rawMsg)
+
+	case MessageTypeListAlwaysDenyRules:
+		return h.handleFiberListAlwaysDenyRules(c, rawMsg)
+
 	default:
 		return fmt.Errorf("unknown message type: %s", msgType)
 	}
@@ -389,6 +443,15 @@ func (h *AgentHandler) streamFiberResponses(c *fiberws.Conn, sessionID uuid.UUID
 	}
 }
 
+// includeThinking reports whether the session asked for thinking blocks to be forwarded
+func (h *AgentHandler) includeThinking(sessionID uuid.UUID) bool {
+	session, err := h.SessionManager.GetSession(sessionID)
+	if err != nil {
+		return false
+	}
+	return session.Options.includeThinking()
+}
+
 // sendAgentMessage sends a Claude message to the WebSocket client
 func (h *AgentHandler) sendAgentMessage(ws *websocket.Conn, sessionID uuid.UUID, msg types.Message) error {
 	msgType := msg.GetMessageType()
@@ -403,6 +466,7 @@ func (h *AgentHandler) sendAgentMessage(ws *websocket.Conn, sessionID uuid.UUID,
 		if assistantMsg, ok := msg.(*types.AssistantMessage); ok {
 			log.Printf("Assistant message type assertion succeeded, content blocks: %d", len(assistantMsg.Content))
 			var textContent []string
+			var thinkingContent []string
 			var toolUses []map[string]interface{}
 
 			for i, block := range assistantMsg.Content {
@@ -411,6 +475,8 @@ func (h *AgentHandler) sendAgentMessage(ws *websocket.Conn, sessionID uuid.UUID,
 				if textBlock, ok := block.(*types.TextBlock); ok {
 					log.Printf("TextBlock found with text: %s", textBlock.Text)
 					textContent = append(textContent, textBlock.Text)
+				} else if thinkingBlock, ok := block.(*types.ThinkingBlock); ok {
+					thinkingContent = append(thinkingContent, thinkingBlock.Thinking)
 				} else if toolUseBlock, ok := block.(*types.ToolUseBlock); ok {
 					log.Printf("ToolUseBlock found: name=%s, id=%s", toolUseBlock.Name, toolUseBlock.ID)
 					toolUses = append(toolUses, map[string]interface{}{
@@ -436,11 +502,15 @@ func (h *AgentHandler) sendAgentMessage(ws *websocket.Conn, sessionID uuid.UUID,
 			}
 			log.Printf("Extracted %d text blocks and %d tool uses", len(textContent), len(toolUses))
 
-			response.Content = map[string]interface{}{
+			content := map[string]interface{}{
 				"type":  "assistant",
 				"text":  textContent,
 				"tools": toolUses,
 			}
+			if len(thinkingContent) > 0 && h.includeThinking(sessionID) {
+				content["thinking"] = thinkingContent
+			}
+			response.Content = content
 		} else {
 			log.Printf("Failed to assert message as AssistantMessage (type=%T)", msg)
 		}
@@ -679,7 +749,7 @@ func (h *AgentHandler) handleFiberSendPrompt(c *fiberws.Conn, rawMsg map[string]
 	// before the goroutine is ready to receive it
 	// Only start if not already running to prevent multiple goroutines
 	if session.StartPermissionForwarder() {
-		go h.forwardPermissionRequests(c, msg.SessionID, session)
+		go h.forwardPermissionRequests(c, msg.SessionID, session, nil)
 	}
 
 	// Send prompt or content to session
@@ -710,8 +780,43 @@ func (h *AgentHandler) handleFiberSendPrompt(c *fiberws.Conn, rawMsg map[string]
 	return nil
 }
 
+// handleFiberRetryLast re-sends the last prompt of a failed session and streams
+// the responses like handleFiberSendPrompt (Fiber version)
+func (h *AgentHandler) handleFiberRetryLast(c *fiberws.Conn, rawMsg map[string]interface{}, registerSession func(uuid.UUID)) error {
+	var msg RetryLastMessage
+	msgBytes, _ := json.Marshal(rawMsg)
+	if err := json.Unmarshal(msgBytes, &msg); err != nil {
+		return fmt.Errorf("invalid retry_last message: %w", err)
+	}
+
+	session, err := h.SessionManager.GetSession(msg.SessionID)
+	if err != nil {
+		return err
+	}
+
+	registerSession(msg.SessionID)
+
+	if session.StartPermissionForwarder() {
+		go h.forwardPermissionRequests(c, msg.SessionID, session, nil)
+	}
+
+	log.Printf("Retrying last prompt of session %s", msg.SessionID)
+	if err := h.SessionManager.RetryLastPrompt(msg.SessionID); err != nil {
+		return err
+	}
+
+	responseChan, err := h.SessionManager.GetResponseChannel(msg.SessionID)
+	if err != nil {
+		return err
+	}
+
+	go h.streamFiberResponses(c, msg.SessionID, responseChan)
+
+	return nil
+}
+
 // sendFiberAgentMessage sends a Claude message to the WebSocket client (Fiber version)
-func (h *AgentHandler) sendFiberAgentMessage(c *fiberws.Conn, sessionID uuid.UUID, msg types.Message) error {
+func (h *AgentHandler) sendFiberAgentMessage(c EventWriter, sessionID uuid.UUID, msg types.Message) error {
 	msgType := msg.GetMessageType()
 	log.Printf("sendFiberAgentMessage: msgType=%s, msg=%+v", msgType, msg)
 
@@ -724,6 +829,7 @@ func (h *AgentHandler) sendFiberAgentMessage(c *fiberws.Conn, sessionID uuid.UUI
 		if assistantMsg, ok := msg.(*types.AssistantMessage); ok {
 			log.Printf("Assistant message type assertion succeeded, content blocks: %d", len(assistantMsg.Content))
 			var textContent []string
+			var thinkingContent []string
 			var toolUses []map[string]interface{}
 
 			for i, block := range assistantMsg.Content {
@@ -732,6 +838,8 @@ func (h *AgentHandler) sendFiberAgentMessage(c *fiberws.Conn, sessionID uuid.UUI
 				if textBlock, ok := block.(*types.TextBlock); ok {
 					log.Printf("TextBlock found with text: %s", textBlock.Text)
 					textContent = append(textContent, textBlock.Text)
+				} else if thinkingBlock, ok := block.(*types.ThinkingBlock); ok {
+					thinkingContent = append(thinkingContent, thinkingBlock.Thinking)
 				} else if toolUseBlock, ok := block.(*types.ToolUseBlock); ok {
 					log.Printf("ToolUseBlock found: name=%s, id=%s", toolUseBlock.Name, toolUseBlock.ID)
 					toolUses = append(toolUses, map[string]interface{}{
@@ -757,11 +865,15 @@ func (h *AgentHandler) sendFiberAgentMessage(c *fiberws.Conn, sessionID uuid.UUI
 			}
 			log.Printf("Extracted %d text blocks and %d tool uses", len(textContent), len(toolUses))
 
-			response.Content = map[string]interface{}{
+			content := map[string]interface{}{
 				"type":  "assistant",
 				"text":  textContent,
 				"tools": toolUses,
 			}
+			if len(thinkingContent) > 0 && h.includeThinking(sessionID) {
+				content["thinking"] = thinkingContent
+			}
+			response.Content = content
 		} else {
 			log.Printf("Failed to assert message as AssistantMessage (type=%T)", msg)
 		}
@@ -901,6 +1013,56 @@ func (h *AgentHandler) handleFiberInterruptSession(c *fiberws.Conn, rawMsg map[s
 	return c.WriteJSON(response)
 }
 
+// handleFiberStopGeneration stops the current response but keeps the session
+// and its context, so the next prompt continues normally (Fiber version)
+func (h *AgentHandler) handleFiberStopGeneration(c *fiberws.Conn, rawMsg map[string]interface{}) error {
+	var msg StopGenerationMessage
+	msgBytes, _ := json.Marshal(rawMsg)
+	if err := json.Unmarshal(msgBytes, &msg); err != nil {
+		return fmt.Errorf("invalid stop_generation message: %w", err)
+	}
+
+	if err := h.SessionManager.StopGeneration(msg.SessionID); err != nil {
+		logging.Error("Failed to stop generation for session %s: %v", msg.SessionID, err)
+		h.sendFiberError(c, fmt.Sprintf("failed to stop generation: %v", err))
+		return nil
+	}
+
+	response := GenerationStoppedMessage{
+		BaseMessage: BaseMessage{Type: MessageTypeGenerationStopped},
+		SessionID:   msg.SessionID,
+		Mode:        StopModeClientSide,
+		Message:     "Response stopped by closing the Claude CLI process; the next prompt resumes the conversation",
+	}
+	return c.WriteJSON(response)
+}
+
+// handleFiberForkSession creates a new session continuing from an existing one (Fiber version)
+func (h *AgentHandler) handleFiberForkSession(c *fiberws.Conn, rawMsg map[string]interface{}, registerSession func(uuid.UUID)) error {
+	var msg ForkSessionMessage
+	msgBytes, _ := json.Marshal(rawMsg)
+	if err := json.Unmarshal(msgBytes, &msg); err != nil {
+		return fmt.Errorf("invalid fork_session message: %w", err)
+	}
+
+	session, err := h.SessionManager.ForkSession(msg.SessionID, msg.NewSessionID)
+	if err != nil {
+		logging.Error("Failed to fork session %s: %v", msg.SessionID, err)
+		h.sendFiberError(c, fmt.Sprintf("failed to fork session: %v", err))
+		return nil
+	}
+
+	registerSession(session.ID)
+
+	response := SessionForkedMessage{
+		BaseMessage:     BaseMessage{Type: MessageTypeSessionForked},
+		SessionID:       session.ID,
+		ParentSessionID: msg.SessionID,
+		Session:         *session,
+	}
+	return c.WriteJSON(response)
+}
+
 // handleFiberDeleteSession deletes an agent session (Fiber version)
 func (h *AgentHandler) handleFiberDeleteSession(c *fiberws.Conn, rawMsg map[string]interface{}) error {
 	var msg DeleteSessionMessage
@@ -964,10 +1126,10 @@ func (h *AgentHandler) handleFiberLoadMessages(c *fiberws.Conn, rawMsg map[strin
 		return fmt.Errorf("missing or invalid session_id")
 	}
 
-	sessionID, err := uuid.Parse(sessionIDStr)
+	sessionID, err := ParseUUID("session_id", sessionIDStr)
 	if err != nil {
-		h.sendFiberError(c, "invalid session ID format")
-		return fmt.Errorf("invalid session ID format")
+		h.sendInvalidUUIDError(c, err.(*InvalidUUIDError))
+		return nil
 	}
 
 	// Parse pagination params with defaults
@@ -1028,6 +1190,69 @@ func (h *AgentHandler) handleFiberKillAllAgents(c *fiberws.Conn) error {
 	return c.WriteJSON(response)
 }
 
+// handleFiberForceKillSession force-kills a single session (Fiber version)
+func (h *AgentHandler) handleFiberForceKillSession(c *fiberws.Conn, rawMsg map[string]interface{}) error {
+	var msg ForceKillSessionMessage
+	msgBytes, _ := json.Marshal(rawMsg)
+	if err := json.Unmarshal(msgBytes, &msg); err != nil {
+		return fmt.Errorf("invalid force_kill_session message: %w", err)
+	}
+
+	wasRunning, err := h.SessionManager.ForceKillSession(msg.SessionID)
+	if err != nil {
+		logging.Error("Failed to force-kill session %s: %v", msg.SessionID, err)
+	}
+
+	response := SessionKilledMessage{
+		BaseMessage: BaseMessage{Type: MessageTypeSessionKilled},
+		SessionID:   msg.SessionID,
+		WasRunning:  wasRunning,
+	}
+	return c.WriteJSON(response)
+}
+
+// handleFiberInjectContext queues a context note for a session's next prompt (Fiber version)
+func (h *AgentHandler) handleFiberInjectContext(c *fiberws.Conn, rawMsg map[string]interface{}) error {
+	var msg InjectContextMessage
+	msgBytes, _ := json.Marshal(rawMsg)
+	if err := json.Unmarshal(msgBytes, &msg); err != nil {
+		return fmt.Errorf("invalid inject_context message: %w", err)
+	}
+
+	pending, err := h.SessionManager.InjectContext(msg.SessionID, msg.Content)
+	if err != nil {
+		return fmt.Errorf("failed to inject context: %w", err)
+	}
+
+	response := ContextInjectedMessage{
+		BaseMessage: BaseMessage{Type: MessageTypeContextInjected},
+		SessionID:   msg.SessionID,
+		Pending:     pending,
+	}
+	return c.WriteJSON(response)
+}
+
+// handleFiberFlagMessage flags or unflags a message of a session (Fiber version)
+func (h *AgentHandler) handleFiberFlagMessage(c *fiberws.Conn, rawMsg map[string]interface{}) error {
+	var msg FlagMessageMessage
+	msgBytes, _ := json.Marshal(rawMsg)
+	if err := json.Unmarshal(msgBytes, &msg); err != nil {
+		return fmt.Errorf("invalid flag_message message: %w", err)
+	}
+
+	if err := h.SessionManager.FlagMessage(msg.SessionID, msg.Sequence, msg.Flagged); err != nil {
+		return err
+	}
+
+	response := MessageFlaggedMessage{
+		BaseMessage: BaseMessage{Type: MessageTypeMessageFlagged},
+		SessionID:   msg.SessionID,
+		Sequence:    msg.Sequence,
+		Flagged:     msg.Flagged,
+	}
+	return c.WriteJSON(response)
+}
+
 // handleFiberDeleteAllSessions deletes all sessions from database (Fiber version)
 func (h *AgentHandler) handleFiberDeleteAllSessions(c *fiberws.Conn) error {
 	count, err := h.SessionManager.DeleteAllSessions()
@@ -1044,8 +1269,9 @@ func (h *AgentHandler) handleFiberDeleteAllSessions(c *fiberws.Conn) error {
 }
 
 // forwardPermissionRequests monitors the session's permission request channel
-// and forwards requests to the WebSocket client
-func (h *AgentHandler) forwardPermissionRequests(c *fiberws.Conn, sessionID uuid.UUID, session *AgentSession) {
+// and forwards requests to the WebSocket client. A non-nil done stops it, for
+// writers that are only valid until their caller returns (see StreamPrompt).
+func (h *AgentHandler) forwardPermissionRequests(c EventWriter, sessionID uuid.UUID, session *AgentSession, done <-chan struct{}) {
 	logging.Info("🚀 Permission forwarder started for session %s", sessionID)
 
 	defer func() {
@@ -1126,6 +1352,11 @@ func (h *AgentHandler) forwardPermissionRequests(c *fiberws.Conn, sessionID uuid
 				return
 			}
 
+		case <-done:
+			logging.Info("Stream client for session %s finished, stopping permission forwarder", sessionID)
+			session.CleanupPendingPermissions()
+			return
+
 		case <-session.ctx.Done():
 			logging.Info("Session %s context cancelled, stopping permission request forwarding", sessionID)
 			session.CleanupPendingPermissions()
@@ -1200,6 +1431,65 @@ func (h *AgentHandler) handleFiberPermissionResponse(c *fiberws.Conn, rawMsg map
 	return c.WriteJSON(ack)
 }
 
+// handleFiberCancelPermission cancels a pending permission request (Fiber version)
+func (h *AgentHandler) handleFiberCancelPermission(c *fiberws.Conn, rawMsg map[string]interface{}) error {
+	var msg CancelPermissionMessage
+	msgBytes, _ := json.Marshal(rawMsg)
+	if err := json.Unmarshal(msgBytes, &msg); err != nil {
+		return fmt.Errorf("invalid cancel_permission message: %w", err)
+	}
+
+	if msg.PermissionID == "" {
+		return fmt.Errorf("permission_id is required")
+	}
+
+	logging.Info("Cancelling permission request %s for session %s", msg.PermissionID, msg.SessionID)
+
+	if err := h.SessionManager.CancelPermission(msg.SessionID, msg.PermissionID); err != nil {
+		return err
+	}
+
+	response := PermissionCancelledMessage{
+		BaseMessage:  BaseMessage{Type: MessageTypePermissionCancelled},
+		SessionID:    msg.SessionID,
+		PermissionID: msg.PermissionID,
+	}
+	return c.WriteJSON(response)
+}
+
+// handleFiberCompactSession summarizes a session into a fresh Claude session (Fiber version).
+// Compaction involves two model round-trips, so it runs in the background and
+// replies with session_compacted (or an error) when done.
+func (h *AgentHandler) handleFiberCompactSession(c *fiberws.Conn, rawMsg map[string]interface{}) error {
+	var msg CompactSessionMessage
+	msgBytes, _ := json.Marshal(rawMsg)
+	if err := json.Unmarshal(msgBytes, &msg); err != nil {
+		return fmt.Errorf("invalid compact_session message: %w", err)
+	}
+
+	logging.Info("Compacting session: %s", msg.SessionID)
+
+	go func() {
+		result, err := h.SessionManager.CompactSession(msg.SessionID)
+		if err != nil {
+			logging.Error("Failed to compact session %s: %v", msg.SessionID, err)
+			h.sendFiberError(c, fmt.Sprintf("failed to compact session: %v", err))
+			return
+		}
+
+		response := SessionCompactedMessage{
+			BaseMessage:      BaseMessage{Type: MessageTypeSessionCompacted},
+			SessionID:        msg.SessionID,
+			CompactionResult: result,
+		}
+		if err := c.WriteJSON(response); err != nil {
+			logging.Error("Failed to send session_compacted for %s: %v", msg.SessionID, err)
+		}
+	}()
+
+	return nil
+}
+
 // handleFiberPing responds to ping with pong (Fiber version)
 func (h *AgentHandler) handleFiberPing(c *fiberws.Conn) error {
 	response := BaseMessage{Type: MessageTypePong}
@@ -1430,20 +1720,233 @@ func (h *AgentHandler) handleFiberListAlwaysAllowRules(c *fiberws.Conn, rawMsg m
 	return c.WriteJSON(response)
 }
 
+// handleFiberAddAlwaysDenyRule adds an always-deny rule to a session
+func (h *AgentHandler) handleFiberAddAlwaysDenyRule(c *fiberws.Conn, rawMsg map[string]interface{}) error {
+	var msg AddAlwaysDenyRuleMessage
+	msgBytes, _ := json.Marshal(rawMsg)
+	if err := json.Unmarshal(msgBytes, &msg); err != nil {
+		return fmt.Errorf("invalid add_always_deny_rule message: %w", err)
+	}
+
+	// An empty tool would be written to permissions.deny as "(*)"
+	if strings.TrimSpace(msg.Rule.Tool) == "" {
+		return fmt.Errorf("rule.tool is required")
+	}
+
+	logging.Info("Adding always-deny rule to session %s: %s (mode: %s)", msg.SessionID, msg.Rule.Description, msg.Rule.MatchMode)
+
+	session, err := h.SessionManager.GetSession(msg.SessionID)
+	if err != nil {
+		return fmt.Errorf("session not found: %w", err)
+	}
+
+	workingDir := "."
+	if session.Options.WorkingDirectory != nil {
+		workingDir = *session.Options.WorkingDirectory
+	}
+	settingsManager := NewClaudeSettingsManager(workingDir)
+
+	// Write to permissions.deny so the CLI refuses matches even when an allow entry covers them
+	permissionStr := FormatPermissionString(msg.Rule.Tool, msg.Rule.Pattern)
+	logging.Info("📝 Adding deny permission to settings.local.json: %s", permissionStr)
+	if err := settingsManager.AddDenyPermission(permissionStr); err != nil {
+		logging.Error("Failed to add deny permission to settings: %v", err)
+		return fmt.Errorf("failed to add deny permission: %w", err)
+	}
+
+	if msg.Rule.ID == "" {
+		msg.Rule.ID = uuid.New().String()
+	}
+	msg.Rule.CreatedAt = time.Now()
+
+	// Add rule to in-memory session so the permission callback denies matches immediately
+	h.SessionManager.mu.Lock()
+	session.Options.AlwaysDenyRules = append(session.Options.AlwaysDenyRules, msg.Rule)
+	rules := append([]AlwaysDenyRule(nil), session.Options.AlwaysDenyRules...)
+	processing := session.Status == SessionStatusProcessing
+	session.UpdatedAt = time.Now()
+	h.SessionManager.mu.Unlock()
+
+	if err := h.SessionManager.updateSessionInDB(&session.Session); err != nil {
+		logging.Error("Failed to update session in database: %v", err)
+		// Don't fail the request - the in-memory session is updated
+	}
+
+	// Deny the pending permission request that prompted this rule, if any
+	if msg.PermissionID != "" {
+		session.permMu.Lock()
+		responseChan, exists := session.pendingPermissions[msg.PermissionID]
+		session.permMu.Unlock()
+
+		if exists {
+			select {
+			case responseChan <- PermissionResponse{
+				Approved:    false,
+				DenyMessage: alwaysDenyRuleDeny(msg.Rule.Tool, msg.Rule.Description).Message,
+			}:
+				logging.Info("⛔ Pending permission %s denied by new always-deny rule", msg.PermissionID)
+			case <-time.After(3 * time.Second):
+				logging.Warning("⚠️ Timeout sending permission denial to SDK")
+			}
+		} else {
+			logging.Warning("⚠️ No pending permission found for ID: %s", msg.PermissionID)
+		}
+	}
+
+	// An idle session picks up the new settings on its next prompt. A running turn
+	// is left alone; the permission callback already enforces the rule.
+	if !processing {
+		if err := h.SessionManager.ReloadSessionSettings(msg.SessionID); err != nil {
+			logging.Error("Failed to reload session settings: %v", err)
+		}
+	}
+
+	response := AlwaysDenyRulesListMessage{
+		BaseMessage: BaseMessage{Type: MessageTypeAlwaysDenyRulesList},
+		SessionID:   msg.SessionID,
+		Rules:       rules,
+	}
+
+	return c.WriteJSON(response)
+}
+
+// handleFiberRemoveAlwaysDenyRule removes an always-deny rule from a session
+func (h *AgentHandler) handleFiberRemoveAlwaysDenyRule(c *fiberws.Conn, rawMsg map[string]interface{}) error {
+	var msg RemoveAlwaysDenyRuleMessage
+	msgBytes, _ := json.Marshal(rawMsg)
+	if err := json.Unmarshal(msgBytes, &msg); err != nil {
+		return fmt.Errorf("invalid remove_always_deny_rule message: %w", err)
+	}
+
+	logging.Info("Removing always-deny rule %s from session %s", msg.RuleID, msg.SessionID)
+
+	session, err := h.SessionManager.GetSession(msg.SessionID)
+	if err != nil {
+		return fmt.Errorf("session not found: %w", err)
+	}
+
+	workingDir := "."
+	if session.Options.WorkingDirectory != nil {
+		workingDir = *session.Options.WorkingDirectory
+	}
+	settingsManager := NewClaudeSettingsManager(workingDir)
+
+	h.SessionManager.mu.Lock()
+	var ruleToRemove *AlwaysDenyRule
+	newRules := []AlwaysDenyRule{}
+	for _, rule := range session.Options.AlwaysDenyRules {
+		if rule.ID != msg.RuleID {
+			newRules = append(newRules, rule)
+		} else {
+			ruleToRemove = &rule
+		}
+	}
+	session.Options.AlwaysDenyRules = newRules
+	session.UpdatedAt = time.Now()
+	h.SessionManager.mu.Unlock()
+
+	if ruleToRemove != nil {
+		permissionStr := FormatPermissionString(ruleToRemove.Tool, ruleToRemove.Pattern)
+		logging.Info("🗑️ Removing deny permission from settings.local.json: %s", permissionStr)
+		if err := settingsManager.RemoveDenyPermission(permissionStr); err != nil {
+			logging.Error("Failed to remove deny permission from settings: %v", err)
+		}
+	}
+
+	if err := h.SessionManager.updateSessionInDB(&session.Session); err != nil {
+		logging.Error("Failed to update session in database: %v", err)
+		// Don't fail the request - the in-memory session is updated
+	}
+
+	response := AlwaysDenyRulesListMessage{
+		BaseMessage: BaseMessage{Type: MessageTypeAlwaysDenyRulesList},
+		SessionID:   msg.SessionID,
+		Rules:       newRules,
+	}
+
+	return c.WriteJSON(response)
+}
+
+// handleFiberListAlwaysDenyRules lists all always-deny rules for a session
+func (h *AgentHandler) handleFiberListAlwaysDenyRules(c *fiberws.Conn, rawMsg map[string]interface{}) error {
+	var msg ListAlwaysDenyRulesMessage
+	msgBytes, _ := json.Marshal(rawMsg)
+	if err := json.Unmarshal(msgBytes, &msg); err != nil {
+		return fmt.Errorf("invalid list_always_deny_rules message: %w", err)
+	}
+
+	session, err := h.SessionManager.GetSession(msg.SessionID)
+	if err != nil {
+		return fmt.Errorf("session not found: %w", err)
+	}
+
+	// The add handler appends under the manager lock, so copy the rules under it too
+	h.SessionManager.mu.RLock()
+	rules := append([]AlwaysDenyRule(nil), session.Options.AlwaysDenyRules...)
+	h.SessionManager.mu.RUnlock()
+
+	response := AlwaysDenyRulesListMessage{
+		BaseMessage: BaseMessage{Type: MessageTypeAlwaysDenyRulesList},
+		SessionID:   msg.SessionID,
+		Rules:       rules,
+	}
+
+	return c.WriteJSON(response)
+}
+
 // GetStats returns current handler statistics
+// The session count is read under the session manager's lock before taking
+// h.Mu so the two locks are never held together.
 func (h *AgentHandler) GetStats() map[string]interface{} {
+	sessionCount := h.SessionManager.SessionCount()
+
 	h.Mu.Lock()
 	defer h.Mu.Unlock()
 
-	sessions := h.SessionManager.ListSessions()
-
 	return map[string]interface{}{
 		"active_connections": h.Active,
 		"max_connections":    h.Config.MaxConcurrentSessions,
-		"active_sessions":    len(sessions),
+		"active_sessions":    sessionCount,
 	}
 }
 
+// MaxConcurrentSessions returns the current limit on agent WebSocket connections
+func (h *AgentHandler) MaxConcurrentSessions() int {
+	h.Mu.Lock()
+	defer h.Mu.Unlock()
+	return h.Config.MaxConcurrentSessions
+}
+
+// SetMaxConcurrentSessions changes the connection limit at runtime. Lowering it
+// below the active count keeps existing connections and only rejects new ones.
+func (h *AgentHandler) SetMaxConcurrentSessions(max int) error {
+	if max < 1 {
+		return fmt.Errorf("max concurrent sessions must be at least 1")
+	}
+
+	h.Mu.Lock()
+	defer h.Mu.Unlock()
+
+	logging.Info("Max concurrent sessions changed: %d -> %d (active: %d)", h.Config.MaxConcurrentSessions, max, h.Active)
+	h.Config.MaxConcurrentSessions = max
+	return nil
+}
+
+// StartDraining stops the handler from accepting new WebSocket connections.
+// Existing connections are left open so in-flight responses can complete.
+func (h *AgentHandler) StartDraining() {
+	h.Mu.Lock()
+	defer h.Mu.Unlock()
+	h.Draining = true
+}
+
+// ActiveConnections returns the number of open agent WebSocket connections
+func (h *AgentHandler) ActiveConnections() int {
+	h.Mu.Lock()
+	defer h.Mu.Unlock()
+	return h.Active
+}
+
 // Cleanup ends all active sessions gracefully
 func (h *AgentHandler) Cleanup() error {
 	count := h.SessionManager.EndAllSessions()
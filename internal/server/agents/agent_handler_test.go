@@ -0,0 +1,85 @@
+package agents
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSetMaxConcurrentSessions(t *testing.T) {
+	h := &AgentHandler{Config: &Config{MaxConcurrentSessions: 10}}
+
+	if err := h.SetMaxConcurrentSessions(0); err == nil {
+		t.Error("expected error for a limit below 1")
+	}
+	if got := h.MaxConcurrentSessions(); got != 10 {
+		t.Errorf("expected limit to stay 10 after invalid update, got %d", got)
+	}
+
+	if err := h.SetMaxConcurrentSessions(3); err != nil {
+		t.Fatalf("SetMaxConcurrentSessions failed: %v", err)
+	}
+	if got := h.MaxConcurrentSessions(); got != 3 {
+		t.Errorf("expected limit 3, got %d", got)
+	}
+}
+
+func TestGetStatsConcurrentWithSessionChanges(t *testing.T) {
+	sm := newTestSessionManager(t)
+	h := &AgentHandler{Config: &Config{MaxConcurrentSessions: 10}, SessionManager: sm}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			id := uuid.New()
+			if _, err := sm.CreateSession(id, SessionOptions{}); err != nil {
+				t.Errorf("CreateSession failed: %v", err)
+				return
+			}
+			_ = sm.EndSession(id)
+		}()
+		go func() {
+			defer wg.Done()
+			h.Mu.Lock()
+			h.Active++
+			h.Mu.Unlock()
+			h.Mu.Lock()
+			h.Active--
+			h.Mu.Unlock()
+		}()
+		go func() {
+			defer wg.Done()
+			_ = h.GetStats()
+		}()
+	}
+	wg.Wait()
+
+	stats := h.GetStats()
+	if stats["active_connections"] != 0 || stats["active_sessions"] != 0 {
+		t.Errorf("expected no connections or sessions left, got %v", stats)
+	}
+}
+
+func TestValidateMessageUUIDs(t *testing.T) {
+	valid := map[string]interface{}{"type": "send_prompt", "session_id": uuid.New().String()}
+	if err := validateMessageUUIDs(valid); err != nil {
+		t.Errorf("expected valid session_id to pass, got %v", err)
+	}
+
+	if err := validateMessageUUIDs(map[string]interface{}{"type": "create_session"}); err != nil {
+		t.Errorf("expected missing session_id to be left to the handler, got %v", err)
+	}
+
+	err := validateMessageUUIDs(map[string]interface{}{"type": "fork_session", "parent_session_id": "abc"})
+	var uuidErr *InvalidUUIDError
+	if !errors.As(err, &uuidErr) {
+		t.Fatalf("expected InvalidUUIDError, got %v", err)
+	}
+	if uuidErr.Field != "parent_session_id" || uuidErr.Value != "abc" {
+		t.Errorf("unexpected error details: %+v", uuidErr)
+	}
+}
@@ -5,19 +5,31 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/schlunsen/claude-control-terminal/internal/logging"
 )
 
+// SettingsPermissions is the permissions block of settings.local.json
+type SettingsPermissions struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny,omitempty"` // Takes precedence over Allow
+}
+
 // ClaudeSettings represents the structure of .claude/settings.local.json
 type ClaudeSettings struct {
-	Permissions struct {
-		Allow []string `json:"allow"`
-	} `json:"permissions"`
-	EnableAllProjectMcpServers bool     `json:"enableAllProjectMcpServers,omitempty"`
-	EnabledMcpjsonServers      []string `json:"enabledMcpjsonServers,omitempty"`
-	Hooks                      any      `json:"hooks,omitempty"`
+	Permissions                SettingsPermissions `json:"permissions"`
+	EnableAllProjectMcpServers bool                `json:"enableAllProjectMcpServers,omitempty"`
+	EnabledMcpjsonServers      []string            `json:"enabledMcpjsonServers,omitempty"`
+	Hooks                      any                 `json:"hooks,omitempty"`
+}
+
+// SettingsPermission is a single permissions.allow entry split into its tool and pattern
+type SettingsPermission struct {
+	Rule    string `json:"rule"`              // Raw entry, e.g. "Bash(git:*)"
+	Tool    string `json:"tool"`              // Tool name, e.g. "Bash"
+	Pattern string `json:"pattern,omitempty"` // Text inside the parentheses, e.g. "git:*"
 }
 
 // ClaudeSettingsManager manages .claude/settings.local.json
@@ -63,6 +75,7 @@ func (csm *ClaudeSettingsManager) LoadSettings() (*ClaudeSettings, error) {
 
 	// Deduplicate permissions on load to clean up any existing duplicates
 	settings.Permissions.Allow = deduplicatePermissions(settings.Permissions.Allow)
+	settings.Permissions.Deny = deduplicatePermissions(settings.Permissions.Deny)
 
 	return &settings, nil
 }
@@ -82,6 +95,7 @@ func (csm *ClaudeSettingsManager) SaveSettings(settings *ClaudeSettings) error {
 
 	// Deduplicate permissions before saving
 	settings.Permissions.Allow = deduplicatePermissions(settings.Permissions.Allow)
+	settings.Permissions.Deny = deduplicatePermissions(settings.Permissions.Deny)
 
 	// Marshal with pretty printing
 	data, err := json.MarshalIndent(settings, "", "  ")
@@ -156,11 +170,97 @@ func (csm *ClaudeSettingsManager) GetAllowedPermissions() ([]string, error) {
 	return settings.Permissions.Allow, nil
 }
 
+// AddDenyPermission adds a permission string to the deny list. Claude
+// evaluates deny entries before allow entries, so a match is always refused.
+func (csm *ClaudeSettingsManager) AddDenyPermission(permission string) error {
+	settings, err := csm.LoadSettings()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range settings.Permissions.Deny {
+		if p == permission {
+			logging.Info("Deny permission already exists: %s", permission)
+			return nil
+		}
+	}
+
+	settings.Permissions.Deny = append(settings.Permissions.Deny, permission)
+
+	return csm.SaveSettings(settings)
+}
+
+// RemoveDenyPermission removes a permission string from the deny list
+func (csm *ClaudeSettingsManager) RemoveDenyPermission(permission string) error {
+	settings, err := csm.LoadSettings()
+	if err != nil {
+		return err
+	}
+
+	newDenyList := []string{}
+	found := false
+	for _, p := range settings.Permissions.Deny {
+		if p != permission {
+			newDenyList = append(newDenyList, p)
+		} else {
+			found = true
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("deny permission not found: %s", permission)
+	}
+
+	settings.Permissions.Deny = newDenyList
+
+	return csm.SaveSettings(settings)
+}
+
+// GetDeniedPermissions returns the list of denied permissions
+func (csm *ClaudeSettingsManager) GetDeniedPermissions() ([]string, error) {
+	settings, err := csm.LoadSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	return settings.Permissions.Deny, nil
+}
+
+// ListPermissions returns the on-disk permissions.allow entries as tool/pattern
+// pairs. Entries without parentheses (e.g. "WebSearch") have an empty pattern.
+func (csm *ClaudeSettingsManager) ListPermissions() ([]SettingsPermission, error) {
+	allowed, err := csm.GetAllowedPermissions()
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make([]SettingsPermission, 0, len(allowed))
+	for _, rule := range allowed {
+		permissions = append(permissions, splitPermissionString(rule))
+	}
+
+	return permissions, nil
+}
+
+// splitPermissionString splits "Tool(pattern)" into its tool and pattern
+func splitPermissionString(rule string) SettingsPermission {
+	perm := SettingsPermission{Rule: rule, Tool: rule}
+
+	openParen := strings.Index(rule, "(")
+	if openParen > 0 && strings.HasSuffix(rule, ")") {
+		perm.Tool = rule[:openParen]
+		perm.Pattern = rule[openParen+1 : len(rule)-1]
+	}
+
+	return perm
+}
+
 // FormatPermissionString formats a tool and pattern into Claude Desktop permission format
 // Examples:
 //   - "Bash", "*" -> "Bash(*)"
 //   - "Write", "*" -> "Write(**)"
 //   - "Read", "/path/to/dir/*" -> "Read(//path/to/dir/**)"
+//   - "Read", file pattern "**/.env" -> "Read(**/.env)"
 func FormatPermissionString(toolName string, pattern *RulePattern) string {
 	if pattern == nil {
 		// No pattern, allow all
@@ -187,6 +287,10 @@ func FormatPermissionString(toolName string, pattern *RulePattern) string {
 			// Specific directory path - convert to absolute path with double slash prefix
 			return fmt.Sprintf("%s(//%s/**)", toolName, dirPath)
 		}
+		if pattern.FilePathPattern != nil && *pattern.FilePathPattern != "*" {
+			// File name or path glob, e.g. "Read(**/.env)"
+			return fmt.Sprintf("%s(%s)", toolName, *pattern.FilePathPattern)
+		}
 
 	case "Grep", "Glob":
 		if pattern.PathPattern != nil && *pattern.PathPattern == "*" {
@@ -271,6 +375,12 @@ func ParsePermissionString(permStr string) (toolName string, pattern *RulePatter
 		return toolName, pattern, nil
 	}
 
+	// Handle file name or path globs (e.g., "**/.env")
+	switch toolName {
+	case "Read", "Write", "Edit":
+		pattern.FilePathPattern = &patternStr
+	}
+
 	return toolName, pattern, nil
 }
 
@@ -109,9 +109,7 @@ func TestLoadSettingsDeduplicates(t *testing.T) {
 	// Manually create settings file with duplicates
 	settingsPath := filepath.Join(claudeDir, "settings.local.json")
 	settingsWithDuplicates := ClaudeSettings{
-		Permissions: struct {
-			Allow []string `json:"allow"`
-		}{
+		Permissions: SettingsPermissions{
 			Allow: []string{"Bash(git:*)", "Bash(gh:*)", "Bash(git:*)", "Edit(**)", "Bash(gh:*)"},
 		},
 	}
@@ -159,9 +157,7 @@ func TestSaveSettingsDeduplicates(t *testing.T) {
 
 	// Create settings with duplicates
 	settings := &ClaudeSettings{
-		Permissions: struct {
-			Allow []string `json:"allow"`
-		}{
+		Permissions: SettingsPermissions{
 			Allow: []string{"Bash(git:*)", "Bash(gh:*)", "Bash(git:*)", "Edit(**)", "Bash(gh:*)"},
 		},
 	}
@@ -190,3 +186,118 @@ func TestSaveSettingsDeduplicates(t *testing.T) {
 		}
 	}
 }
+
+// TestListPermissions tests that on-disk rules are split into tool/pattern pairs
+func TestListPermissions(t *testing.T) {
+	tempDir := t.TempDir()
+
+	claudeDir := filepath.Join(tempDir, ".claude")
+	if err := os.MkdirAll(claudeDir, 0755); err != nil {
+		t.Fatalf("Failed to create .claude dir: %v", err)
+	}
+
+	// Written by hand, as if added outside the app
+	raw := `{"permissions": {"allow": ["Bash(git:*)", "Read(//tmp/**)", "WebSearch"]}}`
+	if err := os.WriteFile(filepath.Join(claudeDir, "settings.local.json"), []byte(raw), 0644); err != nil {
+		t.Fatalf("Failed to write settings file: %v", err)
+	}
+
+	permissions, err := NewClaudeSettingsManager(tempDir).ListPermissions()
+	if err != nil {
+		t.Fatalf("ListPermissions failed: %v", err)
+	}
+
+	expected := []SettingsPermission{
+		{Rule: "Bash(git:*)", Tool: "Bash", Pattern: "git:*"},
+		{Rule: "Read(//tmp/**)", Tool: "Read", Pattern: "//tmp/**"},
+		{Rule: "WebSearch", Tool: "WebSearch"},
+	}
+	if len(permissions) != len(expected) {
+		t.Fatalf("Expected %d permissions, got %d", len(expected), len(permissions))
+	}
+	for i, want := range expected {
+		if permissions[i] != want {
+			t.Errorf("Permission[%d] = %+v, want %+v", i, permissions[i], want)
+		}
+	}
+
+	// A missing settings file yields no permissions
+	permissions, err = NewClaudeSettingsManager(t.TempDir()).ListPermissions()
+	if err != nil {
+		t.Fatalf("ListPermissions failed: %v", err)
+	}
+	if len(permissions) != 0 {
+		t.Errorf("Expected no permissions, got %d", len(permissions))
+	}
+}
+
+// TestDenyPermissions tests that deny entries are kept separately from allow entries
+func TestDenyPermissions(t *testing.T) {
+	manager := NewClaudeSettingsManager(t.TempDir())
+
+	if err := manager.AddPermission("Read(**)"); err != nil {
+		t.Fatalf("AddPermission failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := manager.AddDenyPermission("Read(**/.env)"); err != nil {
+			t.Fatalf("AddDenyPermission failed: %v", err)
+		}
+	}
+
+	settings, err := manager.LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings failed: %v", err)
+	}
+	if len(settings.Permissions.Allow) != 1 || settings.Permissions.Allow[0] != "Read(**)" {
+		t.Errorf("Expected allow list [Read(**)], got %v", settings.Permissions.Allow)
+	}
+	if len(settings.Permissions.Deny) != 1 || settings.Permissions.Deny[0] != "Read(**/.env)" {
+		t.Errorf("Expected deny list [Read(**/.env)], got %v", settings.Permissions.Deny)
+	}
+
+	if err := manager.RemoveDenyPermission("Read(**/.env)"); err != nil {
+		t.Fatalf("RemoveDenyPermission failed: %v", err)
+	}
+	denied, err := manager.GetDeniedPermissions()
+	if err != nil {
+		t.Fatalf("GetDeniedPermissions failed: %v", err)
+	}
+	if len(denied) != 0 {
+		t.Errorf("Expected empty deny list, got %v", denied)
+	}
+	if err := manager.RemoveDenyPermission("Read(**/.env)"); err == nil {
+		t.Error("Expected error removing a missing deny permission")
+	}
+}
+
+// TestFilePathPermissionRoundTrip tests file name patterns survive formatting and parsing
+func TestFilePathPermissionRoundTrip(t *testing.T) {
+	permStr := FormatPermissionString("Read", &RulePattern{FilePathPattern: stringPtr("**/.env")})
+	if permStr != "Read(**/.env)" {
+		t.Fatalf("Expected Read(**/.env), got %s", permStr)
+	}
+
+	toolName, pattern, err := ParsePermissionString(permStr)
+	if err != nil {
+		t.Fatalf("ParsePermissionString failed: %v", err)
+	}
+	if toolName != "Read" || pattern.FilePathPattern == nil || *pattern.FilePathPattern != "**/.env" {
+		t.Fatalf("Unexpected parse result: %s %+v", toolName, pattern)
+	}
+
+	tests := []struct {
+		filePath string
+		want     bool
+	}{
+		{".env", true},
+		{"/srv/app/.env", true},
+		{"/srv/app/.env.local", false},
+		{"/srv/app/config.env", false},
+	}
+	for _, tt := range tests {
+		input := map[string]interface{}{"file_path": tt.filePath}
+		if got := parametersMatchPattern(pattern, "Read", input); got != tt.want {
+			t.Errorf("match(%s) = %v, want %v", tt.filePath, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	claude "github.com/schlunsen/claude-agent-sdk-go"
+	"github.com/schlunsen/claude-agent-sdk-go/types"
+	"github.com/schlunsen/claude-control-terminal/internal/logging"
+)
+
+const (
+	// compactionTimeout bounds each of the summarize and seed queries
+	compactionTimeout = 5 * time.Minute
+
+	// charsPerToken is the rough ratio used to estimate token counts from text length
+	charsPerToken = 4
+
+	compactionPrompt = `Summarize our conversation so far so it can be continued in a fresh session.
+Include the goal, key decisions, files and code touched, the current state of the work, and any open questions or next steps.
+Be concise but keep every detail needed to continue. Reply with the summary only.`
+
+	compactionSeedPrefix = `This session continues an earlier conversation that was compacted to save context.
+Here is the summary of that conversation:
+
+`
+	compactionSeedSuffix = `
+
+Acknowledge briefly and wait for the next instruction.`
+)
+
+// CompactionResult describes the outcome of compacting a session
+type CompactionResult struct {
+	Summary                 string `json:"summary"`
+	PreviousClaudeSessionID string `json:"previous_claude_session_id"`
+	ClaudeSessionID         string `json:"claude_session_id"`
+	ArchivedMessages        int64  `json:"archived_messages"`
+	TokensBefore            int64  `json:"estimated_tokens_before"`
+	TokensAfter             int64  `json:"estimated_tokens_after"`
+	EstimatedTokenReduction int64  `json:"estimated_token_reduction"`
+}
+
+// CompactSession asks the model to summarize the conversation, starts a fresh
+// Claude session seeded with that summary, and archives the older messages.
+// Subsequent prompts resume the new Claude session, so context (and cost) resets.
+func (sm *SessionManager) CompactSession(sessionID uuid.UUID) (*CompactionResult, error) {
+	sm.mu.Lock()
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		sm.mu.Unlock()
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+	if session.Status == SessionStatusProcessing {
+		sm.mu.Unlock()
+		return nil, fmt.Errorf("session %s is busy, wait for the current response to finish", sessionID)
+	}
+	if session.ClaudeSessionID == "" {
+		sm.mu.Unlock()
+		return nil, fmt.Errorf("session %s has no conversation to compact", sessionID)
+	}
+	previousStatus := session.Status
+	previousClaudeSessionID := session.ClaudeSessionID
+	sm.setStatus(session, SessionStatusProcessing)
+	sm.mu.Unlock()
+
+	// Restore the previous status if compaction fails part-way
+	compacted := false
+	defer func() {
+		if !compacted {
+			sm.mu.Lock()
+			sm.setStatus(session, previousStatus)
+			sm.mu.Unlock()
+		}
+	}()
+
+	logging.Info("Compacting session %s (Claude session: %s)", sessionID, previousClaudeSessionID)
+
+	// The summary query resumes the same Claude session, so the streaming
+	// client's CLI process must not be writing to it at the same time. The next
+	// prompt reconnects, to the new session or (if compaction fails) the old one.
+	session.mu.Lock()
+	if session.client != nil {
+		session.client.Close(context.Background())
+		session.client = nil
+	}
+	session.mu.Unlock()
+
+	summary, _, err := sm.runOneShotQuery(session, compactionPrompt, previousClaudeSessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize conversation: %w", err)
+	}
+	if summary == "" {
+		return nil, fmt.Errorf("model returned an empty summary")
+	}
+
+	_, newClaudeSessionID, err := sm.runOneShotQuery(session, compactionSeedPrefix+summary+compactionSeedSuffix, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to seed new session: %w", err)
+	}
+	if newClaudeSessionID == "" {
+		return nil, fmt.Errorf("seed query did not return a Claude session ID")
+	}
+
+	archived, archivedChars, err := sm.storage.ArchiveMessages(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	sm.mu.Lock()
+	session.ClaudeSessionID = newClaudeSessionID
+	sm.setStatus(session, SessionStatusIdle)
+	session.UpdatedAt = time.Now()
+	session.MessageCount++
+	summarySequence := session.MessageCount
+	if err := sm.updateSessionInDB(&session.Session); err != nil {
+		logging.Error("Failed to persist compacted session %s: %v", sessionID, err)
+	}
+	sm.mu.Unlock()
+	compacted = true
+
+	// Store the summary as the first non-archived message of the session
+	summaryMeta := map[string]interface{}{
+		"type":                       "compaction_summary",
+		"previous_claude_session_id": previousClaudeSessionID,
+	}
+	if err := sm.saveMessageToDB(sessionID, summarySequence, "system", summary, "", summaryMeta); err != nil {
+		logging.Error("Failed to save compaction summary: %v", err)
+	}
+
+	result := &CompactionResult{
+		Summary:                 summary,
+		PreviousClaudeSessionID: previousClaudeSessionID,
+		ClaudeSessionID:         newClaudeSessionID,
+		ArchivedMessages:        archived,
+		TokensBefore:            estimateTokens(archivedChars),
+		TokensAfter:             estimateTokens(int64(len(summary))),
+	}
+	if result.TokensBefore > result.TokensAfter {
+		result.EstimatedTokenReduction = result.TokensBefore - result.TokensAfter
+	}
+
+	logging.Info("Compacted session %s: archived %d messages, ~%d tokens saved",
+		sessionID, archived, result.EstimatedTokenReduction)
+
+	return result, nil
+}
+
+// runOneShotQuery runs a single non-streaming query with the session's model,
+// provider and working directory. Returns the assistant text and the Claude
+// session ID reported in the result message. There is no permission callback,
+// so tools that need approval are refused, but ones the CLI runs without
+// asking (e.g. Read, Glob, Grep) stay available: the SDK can't restrict them.
+func (sm *SessionManager) runOneShotQuery(session *AgentSession, prompt, resumeID string) (string, string, error) {
+	opts := sm.oneShotOptions(session)
+	if resumeID != "" {
+		opts = opts.WithResume(resumeID)
+	}
+
+	ctx, cancel := context.WithTimeout(session.ctx, compactionTimeout)
+	defer cancel()
+
+	messages, err := claude.Query(ctx, prompt, opts)
+	if err != nil {
+		return "", "", err
+	}
+
+	var text []string
+	var claudeSessionID string
+	for msg := range messages {
+		switch m := msg.(type) {
+		case *types.AssistantMessage:
+			for _, block := range m.Content {
+				if textBlock, ok := block.(*types.TextBlock); ok {
+					text = append(text, textBlock.Text)
+				}
+			}
+		case *types.ResultMessage:
+			if m.IsError {
+				if m.Result != nil {
+					return "", "", fmt.Errorf("query failed: %s", *m.Result)
+				}
+				return "", "", fmt.Errorf("query failed")
+			}
+			claudeSessionID = m.SessionID
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return "", "", err
+	}
+
+	return strings.TrimSpace(strings.Join(text, "\n")), claudeSessionID, nil
+}
+
+// oneShotOptions builds SDK options for a one-shot query using the same
+// model, base URL and API key resolution as SendPrompt
+func (sm *SessionManager) oneShotOptions(session *AgentSession) *types.ClaudeAgentOptions {
+	settings := sm.resolveSettings(session)
+	opts := types.NewClaudeAgentOptions().
+		WithModel(settings.model).
+		WithVerbose(sm.config.Verbose)
+
+	return sm.applyConnectionSettings(opts, settings)
+}
+
+// estimateTokens approximates a token count from a character count
+func estimateTokens(chars int64) int64 {
+	return (chars + charsPerToken - 1) / charsPerToken
+}
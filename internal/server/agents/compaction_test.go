@@ -0,0 +1,180 @@
+package agents
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/schlunsen/claude-control-terminal/internal/database"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		chars int64
+		want  int64
+	}{
+		{0, 0},
+		{1, 1},
+		{4, 1},
+		{5, 2},
+		{4000, 1000},
+	}
+
+	for _, tt := range tests {
+		if got := estimateTokens(tt.chars); got != tt.want {
+			t.Errorf("estimateTokens(%d) = %d, want %d", tt.chars, got, tt.want)
+		}
+	}
+}
+
+func TestArchiveMessages(t *testing.T) {
+	database.ResetInstance()
+	tmpDir, err := os.MkdirTemp("", "cct-agents-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := database.Initialize(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() {
+		db.Close()
+		database.ResetInstance()
+	}()
+
+	storage, err := NewSQLiteSessionStorage(db.GetDB())
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+
+	sessionID := uuid.New()
+	now := time.Now()
+	if err := storage.SaveSession(&SessionMetadata{
+		ID:        sessionID,
+		Status:    string(SessionStatusIdle),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+
+	for i, content := range []string{"hello", "world!!!"} {
+		if err := storage.SaveMessage(&MessageRecord{
+			ID:        uuid.New(),
+			SessionID: sessionID,
+			Sequence:  i + 1,
+			Role:      "user",
+			Content:   content,
+			Timestamp: now,
+		}); err != nil {
+			t.Fatalf("Failed to save message: %v", err)
+		}
+	}
+
+	archived, chars, err := storage.ArchiveMessages(sessionID)
+	if err != nil {
+		t.Fatalf("ArchiveMessages failed: %v", err)
+	}
+	if archived != 2 {
+		t.Errorf("expected 2 archived messages, got %d", archived)
+	}
+	if chars != 13 {
+		t.Errorf("expected 13 archived chars, got %d", chars)
+	}
+
+	messages, _, err := storage.GetMessages(sessionID, 10, 0)
+	if err != nil {
+		t.Fatalf("GetMessages failed: %v", err)
+	}
+	for _, msg := range messages {
+		if !msg.Archived {
+			t.Errorf("expected message %d to be archived", msg.Sequence)
+		}
+	}
+
+	// Archiving again is a no-op
+	archived, _, err = storage.ArchiveMessages(sessionID)
+	if err != nil {
+		t.Fatalf("ArchiveMessages failed: %v", err)
+	}
+	if archived != 0 {
+		t.Errorf("expected 0 newly archived messages, got %d", archived)
+	}
+}
+
+func TestCompactSessionNotifiesStatusChanges(t *testing.T) {
+	// No claude CLI on PATH, so the summary query fails and compaction rolls back
+	t.Setenv("PATH", t.TempDir())
+	sm := newTestSessionManager(t)
+
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	session, _ := sm.GetSession(sessionID)
+	sm.mu.Lock()
+	session.ClaudeSessionID = "claude-session"
+	sm.mu.Unlock()
+
+	var statuses []SessionStatus
+	sm.OnStatusChange(func(id uuid.UUID, status SessionStatus) {
+		if id == sessionID {
+			statuses = append(statuses, status)
+		}
+	})
+
+	if _, err := sm.CompactSession(sessionID); err == nil {
+		t.Fatal("Expected compaction to fail without a CLI")
+	}
+
+	if len(statuses) != 2 || statuses[0] != SessionStatusProcessing || statuses[1] != SessionStatusIdle {
+		t.Errorf("Expected processing then idle status changes, got %v", statuses)
+	}
+	if session.Status != SessionStatusIdle {
+		t.Errorf("Expected status to be restored to idle, got %s", session.Status)
+	}
+}
+
+func TestCompactSessionClosesClientBeforeSummarizing(t *testing.T) {
+	installFakeClaudeCLI(t, nil)
+	sm := newTestSessionManager(t)
+
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	defer sm.EndSession(sessionID)
+	session, _ := sm.GetSession(sessionID)
+
+	if err := sm.SendPrompt(sessionID, "hello"); err != nil {
+		t.Fatalf("SendPrompt failed: %v", err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		sm.mu.RLock()
+		done := session.Status != SessionStatusProcessing && session.ClaudeSessionID != ""
+		sm.mu.RUnlock()
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the prompt to finish")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// The fake CLI returns no summary, so compaction fails after the summary query
+	if _, err := sm.CompactSession(sessionID); err == nil {
+		t.Fatal("Expected compaction to fail without a summary")
+	}
+
+	session.mu.Lock()
+	client := session.client
+	session.mu.Unlock()
+	if client != nil {
+		t.Error("Expected the streaming client to be closed before the summary query resumed its session")
+	}
+}
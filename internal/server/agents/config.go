@@ -1,5 +1,36 @@
 package agents
 
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// defaultPermissionResponseTimeout is how long a permission callback waits for the user
+	defaultPermissionResponseTimeout = 60 * time.Second
+
+	// defaultPermissionSendTimeout is how long a permission callback waits to hand the request to the frontend
+	defaultPermissionSendTimeout = 5 * time.Second
+)
+
+// Permission modes accepted in SessionOptions.PermissionMode
+const (
+	PermissionModeDefault  = "default"   // Ask the user for each tool use
+	PermissionModeAllowAll = "allow-all" // Bypass permission prompts
+	PermissionModeReadOnly = "read-only"
+)
+
+// ValidatePermissionMode returns an error if mode is not a known permission mode
+func ValidatePermissionMode(mode string) error {
+	switch mode {
+	case PermissionModeDefault, PermissionModeAllowAll, PermissionModeReadOnly:
+		return nil
+	default:
+		return fmt.Errorf("invalid permission mode %q (expected %s, %s or %s)",
+			mode, PermissionModeDefault, PermissionModeAllowAll, PermissionModeReadOnly)
+	}
+}
+
 // Config holds configuration for the agent handler
 type Config struct {
 	Model                 string
@@ -10,4 +41,32 @@ type Config struct {
 	SessionRetentionDays  int  // Days to keep ended sessions (default: 30)
 	CleanupEnabled        bool // Enable automatic cleanup (default: true)
 	CleanupIntervalHours  int  // Cleanup interval in hours (default: 24)
+	MessageRetentionDays  int  // Days to keep message content, 0 = keep for session lifetime
+	// DisabledTools are denied by the permission callback, including in allow-all sessions
+	DisabledTools []string
+	// Permission callback timeouts; zero uses the defaults (60s and 5s)
+	PermissionResponseTimeout time.Duration // Wait for the user to answer a permission request
+	PermissionSendTimeout     time.Duration // Wait for the request to be queued for the frontend
+	// DefaultPermissionMode applies to new sessions created without a
+	// permission mode; empty keeps the SDK default
+	DefaultPermissionMode string
+	// StoreThinking persists assistant thinking content for sessions that
+	// don't set SessionOptions.StoreThinking (default: false)
+	StoreThinking bool
+}
+
+// permissionResponseTimeout returns the configured user-response timeout or the default
+func (c *Config) permissionResponseTimeout() time.Duration {
+	if c.PermissionResponseTimeout > 0 {
+		return c.PermissionResponseTimeout
+	}
+	return defaultPermissionResponseTimeout
+}
+
+// permissionSendTimeout returns the configured send timeout or the default
+func (c *Config) permissionSendTimeout() time.Duration {
+	if c.PermissionSendTimeout > 0 {
+		return c.PermissionSendTimeout
+	}
+	return defaultPermissionSendTimeout
 }
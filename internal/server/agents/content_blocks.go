@@ -0,0 +1,92 @@
+package agents
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// supportedImageTypes lists image media types accepted in image content blocks
+var supportedImageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// supportedDocumentTypes lists document media types accepted in document content blocks.
+// PDFs are forwarded as base64; text-like files are decoded and sent as plain text.
+var supportedDocumentTypes = map[string]bool{
+	"application/pdf":  true,
+	"text/plain":       true,
+	"text/markdown":    true,
+	"text/csv":         true,
+	"application/json": true,
+}
+
+// ValidateContentBlocks checks that every block has a supported type and media type
+func ValidateContentBlocks(content []ContentBlock) error {
+	for i, block := range content {
+		switch block.Type {
+		case "text":
+			// Nothing to validate
+		case "image":
+			if block.Source == nil {
+				return fmt.Errorf("content block %d: image is missing source", i)
+			}
+			if !supportedImageTypes[block.Source.MediaType] {
+				return fmt.Errorf("content block %d: unsupported image type %q", i, block.Source.MediaType)
+			}
+		case "document":
+			if block.Source == nil {
+				return fmt.Errorf("content block %d: document is missing source", i)
+			}
+			if !supportedDocumentTypes[block.Source.MediaType] {
+				return fmt.Errorf("content block %d: unsupported document type %q (supported: PDF, plain text, markdown, CSV, JSON)", i, block.Source.MediaType)
+			}
+			if _, err := base64.StdEncoding.DecodeString(block.Source.Data); err != nil {
+				return fmt.Errorf("content block %d: document data is not valid base64: %w", i, err)
+			}
+		default:
+			return fmt.Errorf("content block %d: unsupported content type %q", i, block.Type)
+		}
+	}
+	return nil
+}
+
+// convertContentBlock converts a content block into the SDK's content block format.
+// Blocks must have been validated with ValidateContentBlocks first.
+func convertContentBlock(block ContentBlock) map[string]interface{} {
+	blockMap := map[string]interface{}{"type": block.Type}
+
+	switch block.Type {
+	case "text":
+		blockMap["text"] = block.Text
+	case "image":
+		blockMap["source"] = map[string]interface{}{
+			"type":       block.Source.Type,
+			"media_type": block.Source.MediaType,
+			"data":       block.Source.Data,
+		}
+	case "document":
+		if block.Source.MediaType == "application/pdf" {
+			blockMap["source"] = map[string]interface{}{
+				"type":       "base64",
+				"media_type": block.Source.MediaType,
+				"data":       block.Source.Data,
+			}
+		} else {
+			// Text documents are sent as plain text sources
+			decoded, _ := base64.StdEncoding.DecodeString(block.Source.Data)
+			blockMap["source"] = map[string]interface{}{
+				"type":       "text",
+				"media_type": "text/plain",
+				"data":       string(decoded),
+			}
+		}
+		if block.Filename != "" {
+			blockMap["title"] = block.Filename
+		}
+	}
+
+	return blockMap
+}
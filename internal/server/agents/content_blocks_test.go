@@ -0,0 +1,86 @@
+package agents
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+// TestValidateContentBlocks tests media type validation for prompt content
+func TestValidateContentBlocks(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte("hello"))
+
+	tests := []struct {
+		name    string
+		block   ContentBlock
+		wantErr bool
+	}{
+		{
+			name:  "text block",
+			block: ContentBlock{Type: "text", Text: "hi"},
+		},
+		{
+			name:  "png image",
+			block: ContentBlock{Type: "image", Source: &ImageSource{Type: "base64", MediaType: "image/png", Data: encoded}},
+		},
+		{
+			name:  "pdf document",
+			block: ContentBlock{Type: "document", Filename: "spec.pdf", Source: &ImageSource{Type: "base64", MediaType: "application/pdf", Data: encoded}},
+		},
+		{
+			name:    "unsupported document type",
+			block:   ContentBlock{Type: "document", Source: &ImageSource{Type: "base64", MediaType: "application/zip", Data: encoded}},
+			wantErr: true,
+		},
+		{
+			name:    "document without source",
+			block:   ContentBlock{Type: "document", Filename: "notes.txt"},
+			wantErr: true,
+		},
+		{
+			name:    "document with invalid base64",
+			block:   ContentBlock{Type: "document", Source: &ImageSource{Type: "base64", MediaType: "text/plain", Data: "not base64!"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown block type",
+			block:   ContentBlock{Type: "audio"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateContentBlocks([]ContentBlock{tt.block})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateContentBlocks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestConvertTextDocument tests that text documents are decoded into plain text sources
+func TestConvertTextDocument(t *testing.T) {
+	block := ContentBlock{
+		Type:     "document",
+		Filename: "notes.md",
+		Source: &ImageSource{
+			Type:      "base64",
+			MediaType: "text/markdown",
+			Data:      base64.StdEncoding.EncodeToString([]byte("# Notes")),
+		},
+	}
+
+	converted := convertContentBlock(block)
+	source, ok := converted["source"].(map[string]interface{})
+	if !ok {
+		t.Fatal("expected source map in converted block")
+	}
+
+	if source["type"] != "text" || source["data"] != "# Notes" {
+		t.Errorf("expected decoded text source, got %v", source)
+	}
+
+	if converted["title"] != "notes.md" {
+		t.Errorf("expected title 'notes.md', got %v", converted["title"])
+	}
+}
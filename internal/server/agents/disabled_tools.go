@@ -0,0 +1,26 @@
+package agents
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/schlunsen/claude-agent-sdk-go/types"
+)
+
+// isToolDisabled reports whether the tool is listed in Config.DisabledTools (case-insensitive)
+func (sm *SessionManager) isToolDisabled(toolName string) bool {
+	for _, disabled := range sm.config.DisabledTools {
+		if strings.EqualFold(disabled, toolName) {
+			return true
+		}
+	}
+	return false
+}
+
+// disabledToolDeny is the permission result returned for globally disabled tools
+func disabledToolDeny(toolName string) types.PermissionResultDeny {
+	return types.PermissionResultDeny{
+		Behavior: "deny",
+		Message:  fmt.Sprintf("Tool %s is disabled by the server configuration", toolName),
+	}
+}
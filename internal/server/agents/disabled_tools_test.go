@@ -0,0 +1,21 @@
+package agents
+
+import (
+	"testing"
+)
+
+func TestIsToolDisabled(t *testing.T) {
+	sm := &SessionManager{config: &Config{DisabledTools: []string{"WebFetch"}}}
+
+	if !sm.isToolDisabled("webfetch") {
+		t.Error("expected WebFetch to be disabled")
+	}
+	if sm.isToolDisabled("Read") {
+		t.Error("expected Read to be enabled")
+	}
+
+	deny := disabledToolDeny("WebFetch")
+	if deny.Message == "" {
+		t.Error("expected a deny message")
+	}
+}
@@ -0,0 +1,52 @@
+package agents
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/schlunsen/claude-agent-sdk-go/types"
+)
+
+// applyThinkingOptions configures extended thinking and beta headers for the Claude CLI.
+// The CLI reads the thinking budget from MAX_THINKING_TOKENS and extra request
+// headers from ANTHROPIC_CUSTOM_HEADERS ("Name: Value").
+func applyThinkingOptions(opts *types.ClaudeAgentOptions, options SessionOptions) *types.ClaudeAgentOptions {
+	if options.ThinkingBudget != nil && *options.ThinkingBudget > 0 {
+		opts = opts.WithEnvVar("MAX_THINKING_TOKENS", strconv.Itoa(*options.ThinkingBudget))
+	}
+
+	if betas := normalizeBetaHeaders(options.BetaHeaders); len(betas) > 0 {
+		opts = opts.WithEnvVar("ANTHROPIC_CUSTOM_HEADERS", "anthropic-beta: "+strings.Join(betas, ","))
+	}
+
+	return opts
+}
+
+// normalizeBetaHeaders trims beta feature names and drops empty or duplicate entries
+func normalizeBetaHeaders(betas []string) []string {
+	seen := make(map[string]bool, len(betas))
+	result := make([]string, 0, len(betas))
+	for _, beta := range betas {
+		beta = strings.TrimSpace(beta)
+		if beta == "" || seen[beta] {
+			continue
+		}
+		seen[beta] = true
+		result = append(result, beta)
+	}
+	return result
+}
+
+// includeThinking reports whether thinking blocks should be forwarded to the client
+func (o SessionOptions) includeThinking() bool {
+	return o.IncludeThinking != nil && *o.IncludeThinking
+}
+
+// storeThinking reports whether thinking content should be persisted, falling
+// back to the server-wide setting when the session doesn't choose
+func (o SessionOptions) storeThinking(defaultValue bool) bool {
+	if o.StoreThinking != nil {
+		return *o.StoreThinking
+	}
+	return defaultValue
+}
@@ -0,0 +1,89 @@
+package agents
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/schlunsen/claude-agent-sdk-go/types"
+)
+
+func TestApplyThinkingOptions(t *testing.T) {
+	budget := 8000
+	opts := applyThinkingOptions(types.NewClaudeAgentOptions(), SessionOptions{
+		ThinkingBudget: &budget,
+		BetaHeaders:    []string{" interleaved-thinking-2025-05-14 ", "", "interleaved-thinking-2025-05-14", "context-1m-2025-08-07"},
+	})
+
+	if got := opts.Env["MAX_THINKING_TOKENS"]; got != "8000" {
+		t.Errorf("MAX_THINKING_TOKENS = %q, want %q", got, "8000")
+	}
+
+	want := "anthropic-beta: interleaved-thinking-2025-05-14,context-1m-2025-08-07"
+	if got := opts.Env["ANTHROPIC_CUSTOM_HEADERS"]; got != want {
+		t.Errorf("ANTHROPIC_CUSTOM_HEADERS = %q, want %q", got, want)
+	}
+}
+
+func TestApplyThinkingOptionsUnset(t *testing.T) {
+	opts := applyThinkingOptions(types.NewClaudeAgentOptions(), SessionOptions{})
+
+	if _, ok := opts.Env["MAX_THINKING_TOKENS"]; ok {
+		t.Error("expected MAX_THINKING_TOKENS to be unset")
+	}
+	if _, ok := opts.Env["ANTHROPIC_CUSTOM_HEADERS"]; ok {
+		t.Error("expected ANTHROPIC_CUSTOM_HEADERS to be unset")
+	}
+
+	include := true
+	if !(SessionOptions{IncludeThinking: &include}).includeThinking() {
+		t.Error("expected includeThinking to be true")
+	}
+	if (SessionOptions{}).includeThinking() {
+		t.Error("expected includeThinking to default to false")
+	}
+}
+
+func TestStoreThinking(t *testing.T) {
+	if (SessionOptions{}).storeThinking(false) {
+		t.Error("expected thinking not to be stored by default")
+	}
+	if !(SessionOptions{}).storeThinking(true) {
+		t.Error("expected server setting to apply when the session doesn't choose")
+	}
+	off := false
+	if (SessionOptions{StoreThinking: &off}).storeThinking(true) {
+		t.Error("expected session option to override the server setting")
+	}
+}
+
+func TestPersistSDKMessageThinking(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	now := time.Now()
+	session := &SessionMetadata{ID: uuid.New(), Status: "idle", CreatedAt: now, UpdatedAt: now}
+	if err := sm.storage.SaveSession(session); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+
+	msg := &types.AssistantMessage{Type: "assistant", Content: []types.ContentBlock{
+		&types.ThinkingBlock{Thinking: "private reasoning"},
+		&types.TextBlock{Text: "answer"},
+	}}
+	sm.persistSDKMessage(session.ID, 1, msg, false)
+	sm.persistSDKMessage(session.ID, 2, msg, true)
+
+	messages, _, err := sm.GetMessages(session.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("GetMessages failed: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages))
+	}
+	if messages[0].ThinkingContent != "" || messages[0].Content != "answer" {
+		t.Errorf("expected thinking to be dropped, got %+v", messages[0])
+	}
+	if messages[1].ThinkingContent != "private reasoning" {
+		t.Errorf("expected thinking to be stored, got %q", messages[1].ThinkingContent)
+	}
+}
@@ -0,0 +1,170 @@
+package agents
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Environment variables understood by the fake claude CLI
+const (
+	fakeCLIEnv     = "CCT_FAKE_CLAUDE_CLI"  // Set to "1" when the test binary runs as the CLI
+	fakeCLILogEnv  = "CCT_FAKE_CLAUDE_LOG"  // File the fake CLI appends its events to
+	fakeCLIToolEnv = "CCT_FAKE_CLAUDE_TOOL" // Optional JSON {"tool_name", "input"} to request permission for
+	fakeCLIHangEnv = "CCT_FAKE_CLAUDE_HANG" // Set to "1" to never finish a prompt's turn
+)
+
+// fakeCLIEvent is one line of the fake CLI's event log
+type fakeCLIEvent struct {
+	Event    string                 `json:"event"` // "args", "prompt" or "permission"
+	Args     []string               `json:"args,omitempty"`
+	Message  map[string]interface{} `json:"message,omitempty"`
+	Response map[string]interface{} `json:"response,omitempty"`
+}
+
+// installFakeClaudeCLI puts a "claude" executable on PATH that re-runs this test
+// binary as TestHelperFakeClaudeCLI. It returns the event log path. If tool is
+// non-nil, every prompt triggers a can_use_tool request for it before the result.
+func installFakeClaudeCLI(t *testing.T, tool map[string]interface{}) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	script := fmt.Sprintf("#!/bin/sh\nexec %q -test.run='^TestHelperFakeClaudeCLI$' -- \"$@\"\n", os.Args[0])
+	if err := os.WriteFile(filepath.Join(dir, "claude"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake CLI: %v", err)
+	}
+
+	logPath := filepath.Join(dir, "events.jsonl")
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv(fakeCLIEnv, "1")
+	t.Setenv(fakeCLILogEnv, logPath)
+	if tool != nil {
+		data, _ := json.Marshal(tool)
+		t.Setenv(fakeCLIToolEnv, string(data))
+	} else {
+		t.Setenv(fakeCLIToolEnv, "")
+	}
+	t.Setenv(fakeCLIHangEnv, "")
+
+	return logPath
+}
+
+// waitForFakeCLIEvent polls the fake CLI's event log until an event of the given kind appears
+func waitForFakeCLIEvent(t *testing.T, logPath, event string) fakeCLIEvent {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		data, _ := os.ReadFile(logPath)
+		for _, line := range strings.Split(string(data), "\n") {
+			var e fakeCLIEvent
+			if json.Unmarshal([]byte(line), &e) == nil && e.Event == event {
+				return e
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for fake CLI %q event", event)
+	return fakeCLIEvent{}
+}
+
+// TestHelperFakeClaudeCLI is not a real test. installFakeClaudeCLI runs the
+// test binary through it to speak the CLI's stream-json control protocol.
+func TestHelperFakeClaudeCLI(t *testing.T) {
+	if os.Getenv(fakeCLIEnv) != "1" {
+		return
+	}
+	runFakeClaudeCLI()
+	os.Exit(0)
+}
+
+func runFakeClaudeCLI() {
+	logFile, err := os.OpenFile(os.Getenv(fakeCLILogEnv), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		os.Exit(1)
+	}
+	defer logFile.Close()
+	logEvent := func(e fakeCLIEvent) {
+		data, _ := json.Marshal(e)
+		logFile.Write(append(data, '\n'))
+	}
+	emit := func(msg map[string]interface{}) {
+		data, _ := json.Marshal(msg)
+		os.Stdout.Write(append(data, '\n'))
+	}
+	result := func() {
+		emit(map[string]interface{}{
+			"type":       "result",
+			"subtype":    "success",
+			"session_id": "fake-claude-session",
+			"num_turns":  1,
+		})
+	}
+
+	// Everything after "--" is what the SDK passed to the CLI
+	args := os.Args[1:]
+	for i, arg := range args {
+		if arg == "--" {
+			args = args[i+1:]
+			break
+		}
+	}
+	logEvent(fakeCLIEvent{Event: "args", Args: args})
+
+	hang := os.Getenv(fakeCLIHangEnv) == "1"
+	var tool map[string]interface{}
+	if raw := os.Getenv(fakeCLIToolEnv); raw != "" {
+		json.Unmarshal([]byte(raw), &tool)
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		var msg map[string]interface{}
+		if json.Unmarshal(scanner.Bytes(), &msg) != nil {
+			continue
+		}
+
+		switch msg["type"] {
+		case "control_request":
+			emit(map[string]interface{}{
+				"type": "control_response",
+				"response": map[string]interface{}{
+					"subtype":    "success",
+					"request_id": msg["request_id"],
+					"response":   map[string]interface{}{},
+				},
+			})
+
+		case "control_response":
+			response, _ := msg["response"].(map[string]interface{})
+			inner, _ := response["response"].(map[string]interface{})
+			logEvent(fakeCLIEvent{Event: "permission", Response: inner})
+			result()
+
+		case "user":
+			logEvent(fakeCLIEvent{Event: "prompt", Message: msg})
+			if hang {
+				continue
+			}
+			if tool == nil {
+				result()
+				continue
+			}
+			emit(map[string]interface{}{
+				"type":       "control_request",
+				"request_id": "fake-permission-1",
+				"request": map[string]interface{}{
+					"subtype":   "can_use_tool",
+					"tool_name": tool["tool_name"],
+					"input":     tool["input"],
+				},
+			})
+		}
+	}
+}
@@ -21,15 +21,26 @@ const (
 	MessageTypeSessionEnded  MessageType = "session_ended"
 	MessageTypeInterruptSession MessageType = "interrupt_session"
 	MessageTypeSessionInterrupted MessageType = "session_interrupted"
+	MessageTypeStopGeneration MessageType = "stop_generation"
+	MessageTypeGenerationStopped MessageType = "generation_stopped"
 	MessageTypeDeleteSession MessageType = "delete_session"
 	MessageTypeSessionDeleted MessageType = "session_deleted"
+	MessageTypeForkSession   MessageType = "fork_session"
+	MessageTypeSessionForked MessageType = "session_forked"
 	MessageTypeListSessions  MessageType = "list_sessions"
 	MessageTypeSessionsList  MessageType = "sessions_list"
 	MessageTypeLoadMessages  MessageType = "load_messages"
 	MessageTypeMessagesLoaded MessageType = "messages_loaded"
+	MessageTypeCompactSession MessageType = "compact_session"
+	MessageTypeSessionCompacted MessageType = "session_compacted"
+	MessageTypeInjectContext  MessageType = "inject_context"
+	MessageTypeContextInjected MessageType = "context_injected"
+	MessageTypeFlagMessage    MessageType = "flag_message"
+	MessageTypeMessageFlagged MessageType = "message_flagged"
 
 	// Agent interaction
 	MessageTypeSendPrompt     MessageType = "send_prompt"
+	MessageTypeRetryLast      MessageType = "retry_last"
 	MessageTypeAgentMessage   MessageType = "agent_message"
 	MessageTypeAgentThinking  MessageType = "agent_thinking"
 	MessageTypeAgentToolUse   MessageType = "agent_tool_use"
@@ -39,6 +50,8 @@ const (
 	MessageTypePermissionRequest      MessageType = "permission_request"
 	MessageTypePermissionResponse     MessageType = "permission_response"
 	MessageTypePermissionAcknowledged MessageType = "permission_acknowledged"
+	MessageTypeCancelPermission       MessageType = "cancel_permission"
+	MessageTypePermissionCancelled    MessageType = "permission_cancelled"
 
 	// Always-allow rules
 	MessageTypeAddAlwaysAllowRule    MessageType = "add_always_allow_rule"
@@ -46,14 +59,24 @@ const (
 	MessageTypeListAlwaysAllowRules  MessageType = "list_always_allow_rules"
 	MessageTypeAlwaysAllowRulesList  MessageType = "always_allow_rules_list"
 
+	// Always-deny rules
+	MessageTypeAddAlwaysDenyRule    MessageType = "add_always_deny_rule"
+	MessageTypeRemoveAlwaysDenyRule MessageType = "remove_always_deny_rule"
+	MessageTypeListAlwaysDenyRules  MessageType = "list_always_deny_rules"
+	MessageTypeAlwaysDenyRulesList  MessageType = "always_deny_rules_list"
+
 	// Kill switch
 	MessageTypeKillAllAgents     MessageType = "kill_all_agents"
 	MessageTypeAgentsKilled      MessageType = "agents_killed"
 	MessageTypeDeleteAllSessions MessageType = "delete_all_sessions"
 	MessageTypeAllSessionsDeleted MessageType = "all_sessions_deleted"
+	MessageTypeForceKillSession  MessageType = "force_kill_session"
+	MessageTypeSessionKilled     MessageType = "session_killed"
 
 	// Session updates
-	MessageTypeSessionUpdated MessageType = "session_updated"
+	MessageTypeSessionUpdated       MessageType = "session_updated"
+	MessageTypeSessionStatusChanged MessageType = "session_status_changed"
+	MessageTypeSessionError         MessageType = "session_error"
 
 	// System
 	MessageTypeError MessageType = "error"
@@ -104,6 +127,10 @@ type AlwaysAllowRule struct {
 	CreatedAt   time.Time              `json:"created_at"`
 }
 
+// AlwaysDenyRule represents a rule for auto-denying specific tool requests.
+// It has the same shape as AlwaysAllowRule so both are matched by MatchesRule.
+type AlwaysDenyRule = AlwaysAllowRule
+
 // SessionOptions holds options for creating an agent session
 type SessionOptions struct {
 	SystemPrompt     *string           `json:"system_prompt,omitempty"`
@@ -111,13 +138,21 @@ type SessionOptions struct {
 	Tools            []string          `json:"tools,omitempty"`
 	WorkingDirectory *string           `json:"working_directory,omitempty"`
 	MaxTokens        *int              `json:"max_tokens,omitempty"`
-	Temperature      *float64          `json:"temperature,omitempty"`
+	Temperature      *float64          `json:"temperature,omitempty"` // Not supported: the Claude CLI has no temperature setting, so sessions setting this are rejected
 	PermissionMode   *string           `json:"permission_mode,omitempty"`
 	Provider         *string           `json:"provider,omitempty"`  // Provider ID (e.g., "glm", "deepseek")
 	Model            *string           `json:"model,omitempty"`     // Model name
 	BaseURL          *string           `json:"base_url,omitempty"`  // API base URL for custom providers
 	APIKey           *string           `json:"api_key,omitempty"`   // API key for the provider
 	AlwaysAllowRules []AlwaysAllowRule `json:"always_allow_rules,omitempty"` // Auto-approval rules
+	AlwaysDenyRules  []AlwaysDenyRule  `json:"always_deny_rules,omitempty"`  // Auto-denial rules, checked before AlwaysAllowRules
+	ThinkingBudget   *int              `json:"thinking_budget,omitempty"`   // Extended thinking token budget
+	BetaHeaders      []string          `json:"beta_headers,omitempty"`      // anthropic-beta feature names
+	IncludeThinking  *bool             `json:"include_thinking,omitempty"`  // Forward thinking blocks to the client
+	StoreThinking    *bool             `json:"store_thinking,omitempty"`    // Persist thinking content; nil uses the server setting
+	MaxCostUSD       *float64          `json:"max_cost_usd,omitempty"`      // End the session once its cost reaches this cap
+	EnvVars          map[string]string `json:"env_vars,omitempty"`          // Extra environment variables for the Claude CLI and its tools
+	MCPServers       []string          `json:"mcp_servers,omitempty"`       // Not supported yet: the SDK does not forward MCP config, so sessions setting this are rejected
 }
 
 // Session represents an agent conversation session
@@ -129,12 +164,15 @@ type Session struct {
 	Options        SessionOptions `json:"options"`
 	MessageCount   int            `json:"message_count"`
 	ErrorMessage   *string        `json:"error_message,omitempty"`
+	ErrorCode      string         `json:"error_code,omitempty"` // Classification of ErrorMessage, e.g. "auth_error"
 	CostUSD          float64        `json:"cost_usd"`
 	NumTurns         int            `json:"num_turns"`
 	DurationMS       int64          `json:"duration_ms"`
 	ModelName        string         `json:"model_name,omitempty"`
 	ClaudeSessionID  string         `json:"claude_session_id,omitempty"`  // Claude CLI session ID for resuming conversations
 	GitBranch        string         `json:"git_branch,omitempty"`         // Git branch of working directory (if applicable)
+	ParentSessionID  *uuid.UUID     `json:"parent_session_id,omitempty"`  // Session this one was forked from
+	EndedAt          *time.Time     `json:"ended_at,omitempty"`           // Set for sessions loaded from storage after they ended
 }
 
 // BaseMessage represents a base WebSocket message
@@ -163,18 +201,19 @@ type SessionCreatedMessage struct {
 	Status    string    `json:"status"`
 }
 
-// ContentBlock represents a piece of content (text or image)
+// ContentBlock represents a piece of content (text, image, or document)
 type ContentBlock struct {
-	Type   string       `json:"type"`             // "text" or "image"
-	Text   string       `json:"text,omitempty"`   // For text blocks
-	Source *ImageSource `json:"source,omitempty"` // For image blocks
+	Type     string       `json:"type"`               // "text", "image", or "document"
+	Text     string       `json:"text,omitempty"`     // For text blocks
+	Source   *ImageSource `json:"source,omitempty"`   // For image and document blocks
+	Filename string       `json:"filename,omitempty"` // For document blocks
 }
 
-// ImageSource represents base64-encoded image data
+// ImageSource represents base64-encoded image or document data
 type ImageSource struct {
 	Type      string `json:"type"`       // "base64"
-	MediaType string `json:"media_type"` // "image/png", "image/jpeg", "image/gif", "image/webp"
-	Data      string `json:"data"`       // Base64 encoded image data
+	MediaType string `json:"media_type"` // Images: "image/png", "image/jpeg", "image/gif", "image/webp"; documents: see supportedDocumentTypes
+	Data      string `json:"data"`       // Base64 encoded data
 }
 
 // SendPromptMessage represents sending a prompt to an agent
@@ -204,6 +243,7 @@ type SessionEndedMessage struct {
 	BaseMessage
 	SessionID uuid.UUID `json:"session_id"`
 	Status    string    `json:"status"`
+	Reason    string    `json:"reason,omitempty"` // Set when the server ended the session, e.g. "budget_exceeded"
 }
 
 // InterruptSessionMessage represents interrupting a session
@@ -219,6 +259,40 @@ type SessionInterruptedMessage struct {
 	Status    string    `json:"status"`
 }
 
+// StopGenerationMessage requests that the in-flight response be stopped
+// while keeping the session and its client alive
+type StopGenerationMessage struct {
+	BaseMessage
+	SessionID uuid.UUID `json:"session_id"`
+}
+
+// GenerationStoppedMessage confirms a stop_generation request. Mode is always
+// StopModeClientSide: the SDK can't interrupt a query, so the server closed the
+// Claude CLI process and the next prompt resumes the conversation.
+type GenerationStoppedMessage struct {
+	BaseMessage
+	SessionID uuid.UUID `json:"session_id"`
+	Mode      string    `json:"mode"`
+	Message   string    `json:"message"`
+}
+
+// ForkSessionMessage requests a new session that continues from the parent's
+// conversation. NewSessionID is optional; one is generated when omitted.
+// Forks are rejected until the SDK can pass --fork-session to the CLI.
+type ForkSessionMessage struct {
+	BaseMessage
+	SessionID    uuid.UUID `json:"session_id"`
+	NewSessionID uuid.UUID `json:"new_session_id,omitempty"`
+}
+
+// SessionForkedMessage returns the newly forked session
+type SessionForkedMessage struct {
+	BaseMessage
+	SessionID       uuid.UUID `json:"session_id"`
+	ParentSessionID uuid.UUID `json:"parent_session_id"`
+	Session         Session   `json:"session"`
+}
+
 // DeleteSessionMessage represents deleting a session
 type DeleteSessionMessage struct {
 	BaseMessage
@@ -273,6 +347,20 @@ type AgentsKilledMessage struct {
 	Count int `json:"count"`
 }
 
+// ForceKillSessionMessage represents force-killing a single session
+type ForceKillSessionMessage struct {
+	BaseMessage
+	SessionID uuid.UUID `json:"session_id"`
+}
+
+// SessionKilledMessage confirms a force kill. WasRunning is false when the
+// session was no longer in memory.
+type SessionKilledMessage struct {
+	BaseMessage
+	SessionID  uuid.UUID `json:"session_id"`
+	WasRunning bool      `json:"was_running"`
+}
+
 // DeleteAllSessionsMessage represents deleting all sessions
 type DeleteAllSessionsMessage struct {
 	BaseMessage
@@ -310,6 +398,33 @@ type PermissionResponseMessage struct {
 	Approved     bool      `json:"approved"`
 }
 
+// CancelPermissionMessage represents cancelling a pending permission request
+type CancelPermissionMessage struct {
+	BaseMessage
+	SessionID    uuid.UUID `json:"session_id"`
+	PermissionID string    `json:"permission_id"`
+}
+
+// PermissionCancelledMessage confirms a pending permission request was cancelled
+type PermissionCancelledMessage struct {
+	BaseMessage
+	SessionID    uuid.UUID `json:"session_id"`
+	PermissionID string    `json:"permission_id"`
+}
+
+// CompactSessionMessage requests that a session's conversation be summarized
+type CompactSessionMessage struct {
+	BaseMessage
+	SessionID uuid.UUID `json:"session_id"`
+}
+
+// SessionCompactedMessage reports the outcome of a session compaction
+type SessionCompactedMessage struct {
+	BaseMessage
+	SessionID uuid.UUID `json:"session_id"`
+	*CompactionResult
+}
+
 // SessionUpdatedMessage represents a session update notification
 type SessionUpdatedMessage struct {
 	BaseMessage
@@ -317,6 +432,57 @@ type SessionUpdatedMessage struct {
 	GitBranch *string   `json:"git_branch,omitempty"`
 }
 
+// SessionStatusChangedMessage notifies clients that a session changed status
+type SessionStatusChangedMessage struct {
+	BaseMessage
+	SessionID uuid.UUID     `json:"session_id"`
+	Status    SessionStatus `json:"status"`
+}
+
+// RetryLastMessage re-sends the last prompt of a session that failed
+type RetryLastMessage struct {
+	BaseMessage
+	SessionID uuid.UUID `json:"session_id"`
+}
+
+// InjectContextMessage adds a note to a session without starting a turn; it
+// is delivered to the model with the next prompt
+type InjectContextMessage struct {
+	BaseMessage
+	SessionID uuid.UUID `json:"session_id"`
+	Content   string    `json:"content"`
+}
+
+// ContextInjectedMessage confirms a queued context note
+type ContextInjectedMessage struct {
+	BaseMessage
+	SessionID uuid.UUID `json:"session_id"`
+	Pending   int       `json:"pending"` // Notes waiting for the next prompt
+}
+
+// FlagMessageMessage flags or unflags a message as an important turn
+type FlagMessageMessage struct {
+	BaseMessage
+	SessionID uuid.UUID `json:"session_id"`
+	Sequence  int       `json:"sequence"`
+	Flagged   bool      `json:"flagged"`
+}
+
+// MessageFlaggedMessage confirms a flag_message request
+type MessageFlaggedMessage struct {
+	BaseMessage
+	SessionID uuid.UUID `json:"session_id"`
+	Sequence  int       `json:"sequence"`
+	Flagged   bool      `json:"flagged"`
+}
+
+// SessionErrorMessage is broadcast when a session fails, with a code such as
+// "auth_error" so clients can show a targeted fix
+type SessionErrorMessage struct {
+	BaseMessage
+	SessionError
+}
+
 // AddAlwaysAllowRuleMessage represents adding an always-allow rule
 type AddAlwaysAllowRuleMessage struct {
 	BaseMessage
@@ -344,3 +510,31 @@ type AlwaysAllowRulesListMessage struct {
 	SessionID uuid.UUID         `json:"session_id"`
 	Rules     []AlwaysAllowRule `json:"rules"`
 }
+
+// AddAlwaysDenyRuleMessage represents adding an always-deny rule
+type AddAlwaysDenyRuleMessage struct {
+	BaseMessage
+	SessionID    uuid.UUID      `json:"session_id"`
+	Rule         AlwaysDenyRule `json:"rule"`
+	PermissionID string         `json:"permission_id,omitempty"` // Optional: ID of the pending permission to deny
+}
+
+// RemoveAlwaysDenyRuleMessage represents removing an always-deny rule
+type RemoveAlwaysDenyRuleMessage struct {
+	BaseMessage
+	SessionID uuid.UUID `json:"session_id"`
+	RuleID    string    `json:"rule_id"`
+}
+
+// ListAlwaysDenyRulesMessage represents requesting the list of deny rules
+type ListAlwaysDenyRulesMessage struct {
+	BaseMessage
+	SessionID uuid.UUID `json:"session_id"`
+}
+
+// AlwaysDenyRulesListMessage represents a list of always-deny rules
+type AlwaysDenyRulesListMessage struct {
+	BaseMessage
+	SessionID uuid.UUID        `json:"session_id"`
+	Rules     []AlwaysDenyRule `json:"rules"`
+}
@@ -0,0 +1,83 @@
+package agents
+
+import (
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/schlunsen/claude-control-terminal/internal/logging"
+)
+
+// ProjectSessions groups the sessions that share a working directory
+type ProjectSessions struct {
+	Project      string    `json:"project"` // Working directory; empty when the session has none
+	SessionCount int       `json:"session_count"`
+	TotalCost    float64   `json:"total_cost"`
+	LastActivity time.Time `json:"last_activity"`
+	Sessions     []Session `json:"sessions"`
+}
+
+// GroupSessionsByProject groups sessions by Options.WorkingDirectory.
+// Projects are ordered by their most recently updated session; sessions
+// keep their input order within each project.
+func GroupSessionsByProject(sessions []Session) []ProjectSessions {
+	index := make(map[string]int)
+	groups := make([]ProjectSessions, 0)
+
+	for _, session := range sessions {
+		project := ""
+		if session.Options.WorkingDirectory != nil {
+			project = *session.Options.WorkingDirectory
+		}
+
+		i, ok := index[project]
+		if !ok {
+			i = len(groups)
+			index[project] = i
+			groups = append(groups, ProjectSessions{Project: project, Sessions: []Session{}})
+		}
+
+		group := &groups[i]
+		group.Sessions = append(group.Sessions, session)
+		group.SessionCount++
+		group.TotalCost += session.CostUSD
+		if session.UpdatedAt.After(group.LastActivity) {
+			group.LastActivity = session.UpdatedAt
+		}
+	}
+
+	sort.SliceStable(groups, func(a, b int) bool {
+		return groups[a].LastActivity.After(groups[b].LastActivity)
+	})
+
+	return groups
+}
+
+// EndSessionsInDirectory ends every active session whose working directory is
+// dir (compared after cleaning both paths) and returns the IDs of the ended
+// sessions
+func (sm *SessionManager) EndSessionsInDirectory(dir string) []uuid.UUID {
+	dir = filepath.Clean(dir)
+
+	sm.mu.RLock()
+	var matching []uuid.UUID
+	for sessionID, session := range sm.sessions {
+		workDir := session.Options.WorkingDirectory
+		if workDir != nil && *workDir != "" && filepath.Clean(*workDir) == dir {
+			matching = append(matching, sessionID)
+		}
+	}
+	sm.mu.RUnlock()
+
+	ended := make([]uuid.UUID, 0, len(matching))
+	for _, sessionID := range matching {
+		// A session may have ended on its own since the scan
+		if err := sm.EndSession(sessionID); err != nil {
+			logging.Debug("Skipping session %s: %v", sessionID, err)
+			continue
+		}
+		ended = append(ended, sessionID)
+	}
+	return ended
+}
@@ -0,0 +1,81 @@
+package agents
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestGroupSessionsByProject(t *testing.T) {
+	now := time.Now()
+	dirA := "/work/a"
+	dirB := "/work/b"
+
+	sessions := []Session{
+		{Options: SessionOptions{WorkingDirectory: &dirA}, CostUSD: 1.5, UpdatedAt: now.Add(-2 * time.Hour)},
+		{Options: SessionOptions{WorkingDirectory: &dirB}, CostUSD: 0.5, UpdatedAt: now.Add(-1 * time.Hour)},
+		{Options: SessionOptions{WorkingDirectory: &dirA}, CostUSD: 2.0, UpdatedAt: now.Add(-3 * time.Hour)},
+		{CostUSD: 0.25, UpdatedAt: now.Add(-4 * time.Hour)},
+	}
+
+	groups := GroupSessionsByProject(sessions)
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 projects, got %d", len(groups))
+	}
+
+	// Most recent activity first
+	if groups[0].Project != dirB || groups[1].Project != dirA || groups[2].Project != "" {
+		t.Errorf("unexpected project order: %q, %q, %q", groups[0].Project, groups[1].Project, groups[2].Project)
+	}
+
+	a := groups[1]
+	if a.SessionCount != 2 {
+		t.Errorf("expected 2 sessions for %s, got %d", dirA, a.SessionCount)
+	}
+	if a.TotalCost != 3.5 {
+		t.Errorf("expected total cost 3.5 for %s, got %f", dirA, a.TotalCost)
+	}
+	if !a.LastActivity.Equal(now.Add(-2 * time.Hour)) {
+		t.Errorf("unexpected last activity for %s: %v", dirA, a.LastActivity)
+	}
+}
+
+func TestGroupSessionsByProjectEmpty(t *testing.T) {
+	groups := GroupSessionsByProject(nil)
+	if groups == nil || len(groups) != 0 {
+		t.Errorf("expected empty non-nil slice, got %v", groups)
+	}
+}
+
+func TestEndSessionsInDirectory(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	dirA := t.TempDir()
+	dirB := t.TempDir()
+	dirAWithSlash := dirA + "/"
+
+	inA := []uuid.UUID{uuid.New(), uuid.New()}
+	for i, dir := range []*string{&dirA, &dirAWithSlash} {
+		if _, err := sm.CreateSession(inA[i], SessionOptions{WorkingDirectory: dir}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+	}
+	inB := uuid.New()
+	if _, err := sm.CreateSession(inB, SessionOptions{WorkingDirectory: &dirB}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	ended := sm.EndSessionsInDirectory(dirA)
+	if len(ended) != 2 {
+		t.Fatalf("expected 2 sessions ended, got %d", len(ended))
+	}
+	for _, id := range inA {
+		if _, err := sm.GetSession(id); err == nil {
+			t.Errorf("expected session %s to be ended", id)
+		}
+	}
+	if _, err := sm.GetSession(inB); err != nil {
+		t.Errorf("expected session in another directory to stay active: %v", err)
+	}
+}
@@ -2,9 +2,11 @@ package agents
 
 import (
 	"encoding/json"
+	"fmt"
 	"path/filepath"
 	"strings"
 
+	"github.com/schlunsen/claude-agent-sdk-go/types"
 	"github.com/schlunsen/claude-control-terminal/internal/logging"
 )
 
@@ -114,6 +116,17 @@ func parametersMatchPattern(pattern *RulePattern, toolName string, input map[str
 			// If the relative path starts with "..", it's outside the directory
 			return !strings.HasPrefix(rel, "..")
 		}
+		if pattern.FilePathPattern != nil {
+			if *pattern.FilePathPattern == "*" {
+				return true
+			}
+
+			filePath, ok := input["file_path"].(string)
+			if !ok {
+				return false
+			}
+			return filePathMatchesPattern(*pattern.FilePathPattern, filePath)
+		}
 
 	case "Grep":
 		if pattern.PathPattern != nil {
@@ -149,6 +162,22 @@ func parametersMatchPattern(pattern *RulePattern, toolName string, input map[str
 	return false
 }
 
+// filePathMatchesPattern matches a file path against a glob such as ".env",
+// "**/.env" or "/srv/app/*.pem". Patterns without a directory part match the
+// file name in any directory.
+func filePathMatchesPattern(pattern, filePath string) bool {
+	pattern = strings.TrimPrefix(pattern, "**/")
+	if !strings.Contains(pattern, "/") {
+		matched, _ := filepath.Match(pattern, filepath.Base(filePath))
+		return matched
+	}
+
+	absPath, _ := filepath.Abs(filePath)
+	absPattern, _ := filepath.Abs(pattern)
+	matched, _ := filepath.Match(absPattern, absPath)
+	return matched
+}
+
 // extractPatternBase extracts the base directory from a glob pattern
 func extractPatternBase(pattern string) string {
 	// Find the last directory separator before any wildcard
@@ -251,6 +280,31 @@ func CheckAlwaysAllowRules(rules []AlwaysAllowRule, toolName string, input map[s
 	return false, ""
 }
 
+// CheckAlwaysDenyRules checks if a tool request matches any always-deny rules
+// Returns (matched bool, ruleDescription string)
+func CheckAlwaysDenyRules(rules []AlwaysDenyRule, toolName string, input map[string]interface{}) (bool, string) {
+	for _, rule := range rules {
+		if MatchesRule(rule, toolName, input) {
+			logging.Info("⛔ AUTO-DENIED via always-deny rule: %s (rule: %s, mode: %s)",
+				toolName, rule.Description, rule.MatchMode)
+			return true, rule.Description
+		}
+	}
+	return false, ""
+}
+
+// alwaysDenyRuleDeny is the permission result returned for requests matching an always-deny rule
+func alwaysDenyRuleDeny(toolName, ruleDesc string) types.PermissionResultDeny {
+	message := fmt.Sprintf("Tool %s is blocked by an always-deny rule", toolName)
+	if ruleDesc != "" {
+		message = fmt.Sprintf("%s: %s", message, ruleDesc)
+	}
+	return types.PermissionResultDeny{
+		Behavior: "deny",
+		Message:  message,
+	}
+}
+
 // Helper function to create string pointer
 func stringPtr(s string) *string {
 	return &s
@@ -0,0 +1,68 @@
+package agents
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/schlunsen/claude-control-terminal/internal/logging"
+)
+
+// EndReasonBudgetExceeded marks sessions ended because they reached SessionOptions.MaxCostUSD
+const EndReasonBudgetExceeded = "budget_exceeded"
+
+// SessionEndedFunc is called when the session manager ends a session on its
+// own (e.g. EndReasonBudgetExceeded). It runs without the session manager
+// lock held.
+type SessionEndedFunc func(sessionID uuid.UUID, reason string)
+
+// OnSessionEnded registers fn to be called whenever the session manager ends
+// a session by itself, replacing any previously registered function
+func (sm *SessionManager) OnSessionEnded(fn SessionEndedFunc) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.onSessionEnded = fn
+}
+
+// validateMaxCost rejects budget caps that could never be met
+func validateMaxCost(maxCost *float64) error {
+	if maxCost != nil && *maxCost <= 0 {
+		return fmt.Errorf("max_cost_usd must be greater than 0")
+	}
+	return nil
+}
+
+// overBudget reports whether the session's cost has reached its cap.
+// Must be called with sm.mu held.
+func (s *AgentSession) overBudget() bool {
+	return s.Options.MaxCostUSD != nil && s.CostUSD >= *s.Options.MaxCostUSD
+}
+
+// checkBudget returns an error if the session has used up its budget
+func (sm *SessionManager) checkBudget(session *AgentSession) error {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	if session.overBudget() {
+		return fmt.Errorf("session budget exceeded: spent $%.4f of $%.4f", session.CostUSD, *session.Options.MaxCostUSD)
+	}
+	return nil
+}
+
+// endOverBudget ends a session that reached its budget cap and notifies the
+// OnSessionEnded callback
+func (sm *SessionManager) endOverBudget(sessionID uuid.UUID) {
+	logging.Warning("Session %s reached its budget cap, ending it", sessionID)
+
+	if err := sm.EndSession(sessionID); err != nil {
+		logging.Error("Failed to end session %s over budget: %v", sessionID, err)
+		return
+	}
+
+	sm.mu.RLock()
+	fn := sm.onSessionEnded
+	sm.mu.RUnlock()
+
+	if fn != nil {
+		fn(sessionID, EndReasonBudgetExceeded)
+	}
+}
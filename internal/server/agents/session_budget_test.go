@@ -0,0 +1,85 @@
+package agents
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/schlunsen/claude-agent-sdk-go/types"
+)
+
+func TestValidateMaxCost(t *testing.T) {
+	valid := 2.5
+	if err := validateMaxCost(&valid); err != nil {
+		t.Errorf("expected valid cap, got %v", err)
+	}
+	if err := validateMaxCost(nil); err != nil {
+		t.Errorf("expected no cap to be valid, got %v", err)
+	}
+	zero := 0.0
+	if err := validateMaxCost(&zero); err == nil {
+		t.Error("expected error for a zero cap")
+	}
+}
+
+func TestSessionEndsWhenBudgetExceeded(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	ended := make(chan string, 1)
+	sm.OnSessionEnded(func(sessionID uuid.UUID, reason string) {
+		ended <- reason
+	})
+
+	maxCost := 0.5
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{MaxCostUSD: &maxCost}); err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	under := 0.25
+	sm.persistSDKMessage(sessionID, 1, &types.ResultMessage{Type: "result", NumTurns: 1, TotalCostUSD: &under}, false)
+	if _, err := sm.GetSession(sessionID); err != nil {
+		t.Fatalf("expected session under budget to stay active: %v", err)
+	}
+
+	over := 0.75
+	sm.persistSDKMessage(sessionID, 2, &types.ResultMessage{Type: "result", NumTurns: 2, TotalCostUSD: &over}, false)
+
+	select {
+	case reason := <-ended:
+		if reason != EndReasonBudgetExceeded {
+			t.Errorf("expected reason %q, got %q", EndReasonBudgetExceeded, reason)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected session to be ended over budget")
+	}
+
+	if _, err := sm.GetSession(sessionID); err == nil {
+		t.Error("expected ended session to be removed from memory")
+	}
+}
+
+func TestSendPromptRejectedOverBudget(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	maxCost := 1.0
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{MaxCostUSD: &maxCost}); err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	session, _ := sm.GetSession(sessionID)
+	sm.mu.Lock()
+	session.CostUSD = 1.0
+	sm.mu.Unlock()
+
+	if err := sm.SendPrompt(sessionID, "one more"); err == nil {
+		t.Error("expected prompt to be rejected once the budget is used up")
+	}
+	if err := sm.SendPromptWithContent(sessionID, []ContentBlock{{Type: "text", Text: "one more"}}); err == nil {
+		t.Error("expected content prompt to be rejected once the budget is used up")
+	}
+	if session.Status == SessionStatusProcessing {
+		t.Error("expected rejected prompt not to change the session status")
+	}
+}
@@ -0,0 +1,81 @@
+package agents
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SessionComparisonSide is the aggregate metadata of one session in a comparison
+type SessionComparisonSide struct {
+	ID           uuid.UUID `json:"id"`
+	Status       string    `json:"status"`
+	ModelName    string    `json:"model_name,omitempty"`
+	CostUSD      float64   `json:"cost_usd"`
+	NumTurns     int       `json:"num_turns"`
+	DurationMS   int64     `json:"duration_ms"`
+	MessageCount int       `json:"message_count"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SessionComparisonDiff holds B minus A for each compared metric
+type SessionComparisonDiff struct {
+	CostUSD      float64 `json:"cost_usd"`
+	NumTurns     int     `json:"num_turns"`
+	DurationMS   int64   `json:"duration_ms"`
+	MessageCount int     `json:"message_count"`
+	SameModel    bool    `json:"same_model"`
+}
+
+// SessionComparison compares the aggregates of two sessions side by side
+type SessionComparison struct {
+	A    SessionComparisonSide `json:"a"`
+	B    SessionComparisonSide `json:"b"`
+	Diff SessionComparisonDiff `json:"diff"`
+}
+
+// CompareSessions loads two sessions from storage (including ended ones) and
+// compares their cost, turns, duration, model and message count
+func (sm *SessionManager) CompareSessions(a, b uuid.UUID) (*SessionComparison, error) {
+	metaA, err := sm.storage.GetSession(a)
+	if err != nil {
+		return nil, err
+	}
+	metaB, err := sm.storage.GetSession(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return compareSessionMetadata(metaA, metaB), nil
+}
+
+// compareSessionMetadata builds a comparison of two sessions
+func compareSessionMetadata(a, b *SessionMetadata) *SessionComparison {
+	sideA := comparisonSide(a)
+	sideB := comparisonSide(b)
+
+	return &SessionComparison{
+		A: sideA,
+		B: sideB,
+		Diff: SessionComparisonDiff{
+			CostUSD:      sideB.CostUSD - sideA.CostUSD,
+			NumTurns:     sideB.NumTurns - sideA.NumTurns,
+			DurationMS:   sideB.DurationMS - sideA.DurationMS,
+			MessageCount: sideB.MessageCount - sideA.MessageCount,
+			SameModel:    sideA.ModelName == sideB.ModelName,
+		},
+	}
+}
+
+func comparisonSide(meta *SessionMetadata) SessionComparisonSide {
+	return SessionComparisonSide{
+		ID:           meta.ID,
+		Status:       meta.Status,
+		ModelName:    meta.ModelName,
+		CostUSD:      meta.CostUSD,
+		NumTurns:     meta.NumTurns,
+		DurationMS:   meta.DurationMS,
+		MessageCount: meta.MessageCount,
+		CreatedAt:    meta.CreatedAt,
+	}
+}
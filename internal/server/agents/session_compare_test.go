@@ -0,0 +1,42 @@
+package agents
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCompareSessions(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	a := &SessionMetadata{ID: uuid.New(), Status: "ended", ModelName: "sonnet", CostUSD: 0.25, NumTurns: 4, DurationMS: 5000, MessageCount: 10}
+	b := &SessionMetadata{ID: uuid.New(), Status: "idle", ModelName: "opus", CostUSD: 0.75, NumTurns: 3, DurationMS: 8000, MessageCount: 7}
+	for _, meta := range []*SessionMetadata{a, b} {
+		meta.CreatedAt = time.Now()
+		meta.UpdatedAt = meta.CreatedAt
+		if err := sm.storage.SaveSession(meta); err != nil {
+			t.Fatalf("Failed to save session: %v", err)
+		}
+	}
+
+	comparison, err := sm.CompareSessions(a.ID, b.ID)
+	if err != nil {
+		t.Fatalf("CompareSessions failed: %v", err)
+	}
+
+	if comparison.A.ID != a.ID || comparison.B.ID != b.ID {
+		t.Errorf("expected sides in request order, got %s and %s", comparison.A.ID, comparison.B.ID)
+	}
+	if comparison.Diff.CostUSD != 0.5 || comparison.Diff.NumTurns != -1 ||
+		comparison.Diff.DurationMS != 3000 || comparison.Diff.MessageCount != -3 {
+		t.Errorf("unexpected diff: %+v", comparison.Diff)
+	}
+	if comparison.Diff.SameModel {
+		t.Error("expected models to differ")
+	}
+
+	if _, err := sm.CompareSessions(a.ID, uuid.New()); err == nil {
+		t.Error("expected error for unknown session")
+	}
+}
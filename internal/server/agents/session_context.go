@@ -0,0 +1,88 @@
+package agents
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/schlunsen/claude-control-terminal/internal/logging"
+)
+
+// InjectContext queues a note for a session without starting a turn. The note
+// is stored as a system message right away so it shows in the session history,
+// and is prepended to the next prompt so the model (and any later resume of
+// the Claude session) sees it. It never triggers a response on its own.
+// Returns the number of notes waiting for the next prompt.
+func (sm *SessionManager) InjectContext(sessionID uuid.UUID, note string) (int, error) {
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return 0, fmt.Errorf("context note is empty")
+	}
+
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return 0, err
+	}
+
+	sm.mu.Lock()
+	session.MessageCount++
+	sequence := session.MessageCount
+	session.UpdatedAt = time.Now()
+	sm.mu.Unlock()
+
+	if err := sm.saveMessageToDB(sessionID, sequence, "system", note, "", map[string]interface{}{
+		"injected_context": true,
+	}); err != nil {
+		return 0, fmt.Errorf("failed to save context note: %w", err)
+	}
+
+	session.pendingContextMu.Lock()
+	session.pendingContext = append(session.pendingContext, note)
+	pending := len(session.pendingContext)
+	session.pendingContextMu.Unlock()
+
+	logging.Info("Session %s: Injected context note (%d pending)", sessionID, pending)
+	return pending, nil
+}
+
+// takePendingContext removes and returns the queued context notes. Callers
+// must hand them back with restorePendingContext if the prompt isn't sent.
+func (s *AgentSession) takePendingContext() []string {
+	s.pendingContextMu.Lock()
+	defer s.pendingContextMu.Unlock()
+
+	notes := s.pendingContext
+	s.pendingContext = nil
+	return notes
+}
+
+// restorePendingContext puts notes taken for a prompt that failed to send back
+// at the front of the queue, ahead of any injected since
+func (s *AgentSession) restorePendingContext(notes []string) {
+	if len(notes) == 0 {
+		return
+	}
+
+	s.pendingContextMu.Lock()
+	s.pendingContext = append(append([]string{}, notes...), s.pendingContext...)
+	s.pendingContextMu.Unlock()
+}
+
+// formatContextPreamble formats context notes as a preamble for the next
+// prompt, or "" if there are none
+func formatContextPreamble(notes []string) string {
+	if len(notes) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("Context notes from the user (for your information, not a request):\n")
+	for _, note := range notes {
+		b.WriteString("- ")
+		b.WriteString(note)
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	return b.String()
+}
@@ -0,0 +1,103 @@
+package agents
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	claude "github.com/schlunsen/claude-agent-sdk-go"
+	"github.com/schlunsen/claude-agent-sdk-go/types"
+)
+
+func TestInjectContext(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	if _, err := sm.InjectContext(sessionID, "   "); err == nil {
+		t.Error("Expected error for empty note")
+	}
+	if _, err := sm.InjectContext(uuid.New(), "note"); err == nil {
+		t.Error("Expected error for unknown session")
+	}
+
+	for i, note := range []string{"the user switched to the staging branch", "tests are flaky on CI"} {
+		pending, err := sm.InjectContext(sessionID, note)
+		if err != nil {
+			t.Fatalf("InjectContext failed: %v", err)
+		}
+		if pending != i+1 {
+			t.Errorf("Expected %d pending notes, got %d", i+1, pending)
+		}
+	}
+
+	// Notes are stored as system messages without starting a turn
+	messages, _, err := sm.GetMessages(sessionID, 10, 0)
+	if err != nil {
+		t.Fatalf("GetMessages failed: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 stored notes, got %d", len(messages))
+	}
+	for _, msg := range messages {
+		if msg.Role != "system" {
+			t.Errorf("Expected system role, got %q", msg.Role)
+		}
+	}
+
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+	if session.Status != SessionStatusIdle {
+		t.Errorf("Injecting context must not start a turn, status is %s", session.Status)
+	}
+
+	preamble := formatContextPreamble(session.takePendingContext())
+	if !strings.Contains(preamble, "- the user switched to the staging branch\n") ||
+		!strings.Contains(preamble, "- tests are flaky on CI\n") {
+		t.Errorf("Unexpected preamble: %q", preamble)
+	}
+	if again := session.takePendingContext(); len(again) != 0 {
+		t.Errorf("Expected notes to be delivered once, got %q", again)
+	}
+}
+
+func TestPendingContextRestoredWhenQueryFails(t *testing.T) {
+	installFakeClaudeCLI(t, nil)
+	sm := newTestSessionManager(t)
+
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	session, _ := sm.GetSession(sessionID)
+
+	for _, note := range []string{"first note", "second note"} {
+		if _, err := sm.InjectContext(sessionID, note); err != nil {
+			t.Fatalf("InjectContext failed: %v", err)
+		}
+	}
+
+	// A client that never connected makes Query fail
+	client, err := claude.NewClient(context.Background(), types.NewClaudeAgentOptions())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	session.mu.Lock()
+	session.client = client
+	session.mu.Unlock()
+
+	if err := sm.SendPrompt(sessionID, "hello"); err == nil {
+		t.Fatal("Expected SendPrompt to fail with a disconnected client")
+	}
+
+	notes := session.takePendingContext()
+	if strings.Join(notes, "|") != "first note|second note" {
+		t.Errorf("Expected notes to be kept for the next prompt, got %q", notes)
+	}
+}
@@ -0,0 +1,60 @@
+package agents
+
+import (
+	"github.com/google/uuid"
+	"github.com/schlunsen/claude-control-terminal/internal/providers"
+)
+
+// ResolvedOptions describes the SDK options SendPrompt would use for a session
+type ResolvedOptions struct {
+	SessionID       uuid.UUID         `json:"session_id"`
+	Model           string            `json:"model"`
+	PermissionMode  string            `json:"permission_mode"`
+	SDKPermission   string            `json:"sdk_permission_mode"`
+	DisabledTools   []string          `json:"disabled_tools,omitempty"` // Denied by the permission callback, not sent to the CLI
+	Provider        string            `json:"provider,omitempty"`
+	BaseURL         string            `json:"base_url,omitempty"`
+	APIKey          string            `json:"api_key,omitempty"` // Masked, only the last 4 characters are shown
+	APIKeySource    string            `json:"api_key_source,omitempty"`
+	EnvVars         map[string]string `json:"env_vars,omitempty"` // Secret-looking values are masked
+	SystemPrompt    string            `json:"system_prompt"`
+	WorkingDir      string            `json:"working_directory,omitempty"`
+	ResumeSessionID string            `json:"resume_session_id,omitempty"`
+	StoreThinking   bool              `json:"store_thinking"`
+	ClientActive    bool              `json:"client_active"`
+}
+
+// ResolveOptions reports the options SendPrompt hands to the SDK for a session.
+// Both resolve them through resolveSettings.
+func (sm *SessionManager) ResolveOptions(sessionID uuid.UUID) (*ResolvedOptions, error) {
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := sm.resolveSettings(session)
+	resolved := &ResolvedOptions{
+		SessionID:       sessionID,
+		Model:           settings.model,
+		PermissionMode:  settings.permissionMode,
+		SDKPermission:   string(settings.sdkPermission),
+		DisabledTools:   sm.config.DisabledTools,
+		Provider:        settings.provider,
+		BaseURL:         settings.baseURL,
+		APIKeySource:    settings.apiKeySource,
+		EnvVars:         maskEnvVars(session.Options.EnvVars),
+		SystemPrompt:    "code",
+		WorkingDir:      settings.workingDir,
+		ResumeSessionID: settings.resumeID,
+		StoreThinking:   session.Options.storeThinking(sm.config.StoreThinking),
+	}
+	if settings.apiKey != "" {
+		resolved.APIKey = "***" + providers.MaskAPIKey(settings.apiKey)
+	}
+
+	session.mu.Lock()
+	resolved.ClientActive = session.client != nil
+	session.mu.Unlock()
+
+	return resolved, nil
+}
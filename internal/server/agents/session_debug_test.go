@@ -0,0 +1,111 @@
+package agents
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestResolveOptions(t *testing.T) {
+	sm := newTestSessionManager(t)
+	sm.config.APIKey = "sk-ant-config-key-1234"
+	sm.config.DisabledTools = []string{"WebFetch"}
+
+	if _, err := sm.ResolveOptions(uuid.New()); err == nil {
+		t.Error("Expected error for unknown session")
+	}
+
+	// Defaults come from the manager config
+	defaultID := uuid.New()
+	if _, err := sm.CreateSession(defaultID, SessionOptions{}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	resolved, err := sm.ResolveOptions(defaultID)
+	if err != nil {
+		t.Fatalf("ResolveOptions failed: %v", err)
+	}
+	if resolved.Model != "sonnet" {
+		t.Errorf("Expected config model, got %q", resolved.Model)
+	}
+	if resolved.PermissionMode != PermissionModeDefault || resolved.SDKPermission != "default" {
+		t.Errorf("Unexpected permission mode: %q / %q", resolved.PermissionMode, resolved.SDKPermission)
+	}
+	if resolved.APIKeySource != "config" || resolved.APIKey != "***1234" {
+		t.Errorf("Expected masked config key, got %q from %q", resolved.APIKey, resolved.APIKeySource)
+	}
+	if strings.Join(resolved.DisabledTools, ",") != "WebFetch" {
+		t.Errorf("Expected disabled tools to be reported, got %v", resolved.DisabledTools)
+	}
+	if resolved.SystemPrompt != "code" {
+		t.Errorf("Expected code system prompt, got %q", resolved.SystemPrompt)
+	}
+
+	// Session options override the defaults
+	model, mode, baseURL, key, cwd := "opus", PermissionModeAllowAll, "https://proxy.example.com", "sk-session-key-9876", "/tmp/project"
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{
+		Model:            &model,
+		PermissionMode:   &mode,
+		BaseURL:          &baseURL,
+		APIKey:           &key,
+		WorkingDirectory: &cwd,
+		Tools:            []string{"Read", "Grep"},
+	}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	resolved, err = sm.ResolveOptions(sessionID)
+	if err != nil {
+		t.Fatalf("ResolveOptions failed: %v", err)
+	}
+	if resolved.Model != model || resolved.BaseURL != baseURL || resolved.WorkingDir != cwd {
+		t.Errorf("Session options not applied: %+v", resolved)
+	}
+	// WebFetch is disabled, so allow-all keeps the permission callback in the loop
+	if resolved.SDKPermission != "default" {
+		t.Errorf("Expected default mode while tools are disabled, got %q", resolved.SDKPermission)
+	}
+	if resolved.APIKeySource != "session" || resolved.APIKey != "***9876" {
+		t.Errorf("Expected masked session key, got %q from %q", resolved.APIKey, resolved.APIKeySource)
+	}
+
+	sm.config.DisabledTools = nil
+	resolved, err = sm.ResolveOptions(sessionID)
+	if err != nil {
+		t.Fatalf("ResolveOptions failed: %v", err)
+	}
+	if resolved.SDKPermission != "bypassPermissions" {
+		t.Errorf("Expected bypassPermissions, got %q", resolved.SDKPermission)
+	}
+}
+
+func TestResolveOptionsMatchesSendPrompt(t *testing.T) {
+	logPath := installFakeClaudeCLI(t, nil)
+	sm := newTestSessionManager(t)
+
+	model, mode := "opus", PermissionModeAllowAll
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{Model: &model, PermissionMode: &mode}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	defer sm.EndSession(sessionID)
+
+	resolved, err := sm.ResolveOptions(sessionID)
+	if err != nil {
+		t.Fatalf("ResolveOptions failed: %v", err)
+	}
+	if err := sm.SendPrompt(sessionID, "hello"); err != nil {
+		t.Fatalf("SendPrompt failed: %v", err)
+	}
+
+	args := strings.Join(waitForFakeCLIEvent(t, logPath, "args").Args, " ")
+	for _, want := range []string{
+		"--model " + resolved.Model,
+		"--permission-mode " + resolved.SDKPermission,
+		"--system-prompt " + resolved.SystemPrompt,
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("Expected CLI args to contain %q, got %q", want, args)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package agents
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/schlunsen/claude-agent-sdk-go/types"
+	"github.com/schlunsen/claude-control-terminal/internal/providers"
+)
+
+// envVarNamePattern matches portable environment variable names
+var envVarNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// secretEnvMarkers are name fragments of environment variables treated as secrets
+var secretEnvMarkers = []string{"KEY", "TOKEN", "SECRET", "PASSWORD", "PASSWD", "CREDENTIAL", "AUTH"}
+
+// validateEnvVars rejects environment variable names the Claude CLI process can't receive
+func validateEnvVars(envVars map[string]string) error {
+	names := make([]string, 0, len(envVars))
+	for name := range envVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !envVarNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid environment variable name %q", name)
+		}
+	}
+	return nil
+}
+
+// applyEnvVars passes the session's environment variables to the Claude CLI.
+// Call it before the API key and thinking options so those take precedence.
+func applyEnvVars(opts *types.ClaudeAgentOptions, options SessionOptions) *types.ClaudeAgentOptions {
+	for name, value := range options.EnvVars {
+		opts = opts.WithEnvVar(name, value)
+	}
+	return opts
+}
+
+// maskEnvVars returns a copy of envVars with values of secret-looking names masked
+func maskEnvVars(envVars map[string]string) map[string]string {
+	if len(envVars) == 0 {
+		return nil
+	}
+
+	masked := make(map[string]string, len(envVars))
+	for name, value := range envVars {
+		if isSecretEnvVar(name) && value != "" {
+			value = "***" + providers.MaskAPIKey(value)
+		}
+		masked[name] = value
+	}
+	return masked
+}
+
+// isSecretEnvVar reports whether an environment variable name looks like it holds a secret
+func isSecretEnvVar(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, marker := range secretEnvMarkers {
+		if strings.Contains(upper, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package agents
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/schlunsen/claude-agent-sdk-go/types"
+)
+
+func TestApplyEnvVars(t *testing.T) {
+	opts := applyEnvVars(types.NewClaudeAgentOptions(), SessionOptions{
+		EnvVars: map[string]string{"NODE_ENV": "test", "CI": "1"},
+	})
+
+	if got := opts.Env["NODE_ENV"]; got != "test" {
+		t.Errorf("NODE_ENV = %q, want %q", got, "test")
+	}
+	if got := opts.Env["CI"]; got != "1" {
+		t.Errorf("CI = %q, want %q", got, "1")
+	}
+}
+
+func TestMaskEnvVars(t *testing.T) {
+	masked := maskEnvVars(map[string]string{
+		"NODE_ENV":     "test",
+		"GITHUB_TOKEN": "ghp_abcdefghijkl1234",
+		"db_password":  "hunter2hunter2",
+	})
+
+	if masked["NODE_ENV"] != "test" {
+		t.Errorf("NODE_ENV should not be masked, got %q", masked["NODE_ENV"])
+	}
+	if masked["GITHUB_TOKEN"] != "***1234" {
+		t.Errorf("GITHUB_TOKEN = %q, want masked", masked["GITHUB_TOKEN"])
+	}
+	if masked["db_password"] != "***ter2" {
+		t.Errorf("db_password = %q, want masked", masked["db_password"])
+	}
+	if maskEnvVars(nil) != nil {
+		t.Error("expected nil for no env vars")
+	}
+}
+
+func TestCreateSessionEnvVars(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	if _, err := sm.CreateSession(uuid.New(), SessionOptions{
+		EnvVars: map[string]string{"BAD=NAME": "x"},
+	}); err == nil {
+		t.Error("Expected error for invalid environment variable name")
+	}
+
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{
+		EnvVars: map[string]string{"NODE_ENV": "test", "API_TOKEN": "secret-value-9876"},
+	}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	// Env vars are persisted with the session options
+	meta, err := sm.storage.GetSession(sessionID)
+	if err != nil {
+		t.Fatalf("Failed to load session: %v", err)
+	}
+	var stored SessionOptions
+	if err := json.Unmarshal([]byte(meta.OptionsJSON), &stored); err != nil {
+		t.Fatalf("Failed to decode options: %v", err)
+	}
+	if stored.EnvVars["NODE_ENV"] != "test" {
+		t.Errorf("Expected NODE_ENV to be persisted, got %v", stored.EnvVars)
+	}
+
+	// Debug output masks secrets
+	resolved, err := sm.ResolveOptions(sessionID)
+	if err != nil {
+		t.Fatalf("ResolveOptions failed: %v", err)
+	}
+	if resolved.EnvVars["API_TOKEN"] != "***9876" || resolved.EnvVars["NODE_ENV"] != "test" {
+		t.Errorf("Unexpected debug env vars: %v", resolved.EnvVars)
+	}
+}
@@ -0,0 +1,79 @@
+package agents
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrorCodeAuth marks session errors caused by a missing, invalid or expired API key
+const ErrorCodeAuth = "auth_error"
+
+// SessionError describes why a session failed, so clients can react to
+// specific failures (e.g. prompt the user to fix their API key) instead of
+// showing a generic error
+type SessionError struct {
+	SessionID uuid.UUID `json:"session_id"`
+	Code      string    `json:"code,omitempty"` // e.g. ErrorCodeAuth; empty for unclassified errors
+	Message   string    `json:"message"`
+	Provider  string    `json:"provider,omitempty"` // Provider ID when the session uses a configured provider
+}
+
+// SessionErrorFunc is called when a session fails. Like StatusChangeFunc it
+// runs with the session manager lock held, so it must not call back into the
+// SessionManager and must not block.
+type SessionErrorFunc func(sessErr SessionError)
+
+// authErrorMarkers are lowercase fragments of the errors the CLI and API
+// report when credentials are rejected
+var authErrorMarkers = []string{
+	"invalid api key",
+	"invalid x-api-key",
+	"authentication_error",
+	"authentication failed",
+	"oauth token has expired",
+	"please run /login",
+	"401 unauthorized",
+	"status 401",
+	"status code 401",
+}
+
+// errorCode classifies an error message, returning ErrorCodeAuth for
+// credential failures and "" otherwise
+func errorCode(message string) string {
+	lower := strings.ToLower(message)
+	for _, marker := range authErrorMarkers {
+		if strings.Contains(lower, marker) {
+			return ErrorCodeAuth
+		}
+	}
+	return ""
+}
+
+// OnSessionError registers fn to be called whenever a session moves to the
+// error state, replacing any previously registered function
+func (sm *SessionManager) OnSessionError(fn SessionErrorFunc) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.onSessionError = fn
+}
+
+// failSession records message as the session's error, classifies it and moves
+// the session to the error state. Must be called with sm.mu held.
+func (sm *SessionManager) failSession(session *AgentSession, message string) {
+	session.ErrorMessage = &message
+	session.ErrorCode = errorCode(message)
+	sm.setStatus(session, SessionStatusError)
+
+	if sm.onSessionError != nil {
+		sessErr := SessionError{
+			SessionID: session.ID,
+			Code:      session.ErrorCode,
+			Message:   message,
+		}
+		if session.Options.Provider != nil {
+			sessErr.Provider = *session.Options.Provider
+		}
+		sm.onSessionError(sessErr)
+	}
+}
@@ -0,0 +1,58 @@
+package agents
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// errForkUnsupported is returned for fork_session requests.
+// claude-agent-sdk-go v0.2.4 never passes ClaudeAgentOptions.ForkSession to the
+// CLI (there is no --fork-session), so a fork's first prompt would plain-resume
+// the parent's Claude session and append to its transcript. Reject forks until it does.
+var errForkUnsupported = errors.New("fork_session is not supported: the agent SDK does not pass --fork-session to the Claude CLI, so a fork would write into the parent session's transcript")
+
+// ForkSession would create a new session continuing from the parent's
+// conversation. It is rejected until the SDK can fork a Claude session.
+func (sm *SessionManager) ForkSession(parentID, newID uuid.UUID) (*Session, error) {
+	if _, err := sm.storage.GetSession(parentID); err != nil {
+		return nil, err
+	}
+	return nil, errForkUnsupported
+}
+
+// sessionSource returns a session's options and Claude session ID, whether it is
+// in memory or only in the database
+func (sm *SessionManager) sessionSource(sessionID uuid.UUID) (SessionOptions, string, error) {
+	sm.mu.RLock()
+	session, exists := sm.sessions[sessionID]
+	if exists {
+		options, claudeSessionID := session.Options, session.ClaudeSessionID
+		sm.mu.RUnlock()
+		return options, claudeSessionID, nil
+	}
+	sm.mu.RUnlock()
+
+	meta, err := sm.storage.GetSession(sessionID)
+	if err != nil {
+		return SessionOptions{}, "", err
+	}
+
+	var options SessionOptions
+	if meta.OptionsJSON != "" {
+		if err := json.Unmarshal([]byte(meta.OptionsJSON), &options); err != nil {
+			return SessionOptions{}, "", fmt.Errorf("failed to read options of session %s: %w", sessionID, err)
+		}
+	}
+
+	return options, meta.ClaudeSessionID, nil
+}
+
+// forkPending reports whether the session was forked (before forks were
+// rejected) and has not completed a turn of its own yet. Resuming such a
+// session would extend the parent's Claude session, so it starts a new one.
+func (s *AgentSession) forkPending() bool {
+	return s.ParentSessionID != nil && s.NumTurns == 0
+}
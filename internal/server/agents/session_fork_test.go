@@ -0,0 +1,97 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	claude "github.com/schlunsen/claude-agent-sdk-go"
+	"github.com/schlunsen/claude-agent-sdk-go/types"
+)
+
+func TestForkSessionRejected(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	if _, err := sm.ForkSession(uuid.New(), uuid.New()); err == nil || errors.Is(err, errForkUnsupported) {
+		t.Errorf("expected not found error for unknown parent, got %v", err)
+	}
+
+	parentID := uuid.New()
+	if _, err := sm.CreateSession(parentID, SessionOptions{}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	parent, _ := sm.GetSession(parentID)
+	sm.mu.Lock()
+	parent.ClaudeSessionID = "claude-parent"
+	sm.mu.Unlock()
+
+	forkID := uuid.New()
+	if _, err := sm.ForkSession(parentID, forkID); !errors.Is(err, errForkUnsupported) {
+		t.Fatalf("expected errForkUnsupported, got %v", err)
+	}
+	if _, err := sm.storage.GetSession(forkID); err == nil {
+		t.Error("rejected fork must not create a session")
+	}
+}
+
+// TestLegacyForkDoesNotResumeParent covers sessions forked before forks were
+// rejected: their first prompt must not resume the parent's Claude session.
+func TestLegacyForkDoesNotResumeParent(t *testing.T) {
+	logPath := installFakeClaudeCLI(t, nil)
+	sm := newTestSessionManager(t)
+
+	parentID, forkID := uuid.New(), uuid.New()
+	if _, err := sm.CreateSession(forkID, SessionOptions{}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	defer sm.EndSession(forkID)
+	session, _ := sm.GetSession(forkID)
+	sm.mu.Lock()
+	session.ClaudeSessionID = "claude-parent"
+	session.ParentSessionID = &parentID
+	sm.mu.Unlock()
+
+	if err := sm.SendPrompt(forkID, "hello"); err != nil {
+		t.Fatalf("SendPrompt failed: %v", err)
+	}
+
+	args := strings.Join(waitForFakeCLIEvent(t, logPath, "args").Args, " ")
+	if strings.Contains(args, "--resume") {
+		t.Errorf("forked session resumed its parent's Claude session: %q", args)
+	}
+}
+
+// TestSDKDoesNotForwardForkSession pins the reason fork_session is rejected. If
+// it fails, the SDK now passes --fork-session and forks can be supported.
+func TestSDKDoesNotForwardForkSession(t *testing.T) {
+	logPath := installFakeClaudeCLI(t, nil)
+
+	opts := types.NewClaudeAgentOptions().
+		WithCanUseTool(func(context.Context, string, map[string]interface{}, types.ToolPermissionContext) (interface{}, error) {
+			return types.PermissionResultAllow{}, nil
+		}).
+		WithResume("claude-parent").
+		WithForkSession(true)
+
+	ctx := context.Background()
+	client, err := claude.NewClient(ctx, opts)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer client.Close(ctx)
+
+	args := waitForFakeCLIEvent(t, logPath, "args").Args
+	for _, arg := range args {
+		if strings.Contains(arg, "fork") {
+			t.Errorf("SDK passed the fork flag to the CLI (%q); fork_session can now be supported", args)
+		}
+	}
+	if !strings.Contains(strings.Join(args, " "), "--resume claude-parent") {
+		t.Errorf("expected the CLI to resume the parent session, got %q", args)
+	}
+}
@@ -6,11 +6,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	claude "github.com/schlunsen/claude-agent-sdk-go"
 	"github.com/schlunsen/claude-agent-sdk-go/types"
+	"github.com/schlunsen/claude-control-terminal/internal/database"
 	"github.com/schlunsen/claude-control-terminal/internal/logging"
 )
 
@@ -21,8 +23,19 @@ type SessionManager struct {
 	config   *Config
 	storage  SessionStorage
 	db       *sql.DB // Database connection for loading provider configs
+
+	cleanupPaused atomic.Bool // Skips retention deletion in runCleanup while set
+
+	onStatusChange StatusChangeFunc // Guarded by mu; see OnStatusChange
+	onSessionError SessionErrorFunc // Guarded by mu; see OnSessionError
+	onSessionEnded SessionEndedFunc // Guarded by mu; see OnSessionEnded
 }
 
+// StatusChangeFunc is called whenever a session's status changes. It runs
+// with the session manager lock held, so it must not call back into the
+// SessionManager and must not block.
+type StatusChangeFunc func(sessionID uuid.UUID, status SessionStatus)
+
 // PermissionRequest represents a pending permission request
 type PermissionRequest struct {
 	RequestID   string
@@ -38,6 +51,7 @@ type PermissionResponse struct {
 	UpdatedInput       *map[string]interface{}
 	UpdatedPermissions []types.PermissionUpdate
 	DenyMessage        string
+	Cancelled          bool // Request was dismissed by the user rather than explicitly denied
 }
 
 // AgentSession represents an active agent session
@@ -53,16 +67,29 @@ type AgentSession struct {
 	permForwarderRunning   bool // Track if permission forwarder goroutine is running
 	permForwarderMu        sync.Mutex
 	wsConnected            bool // Track WebSocket connection state
+	streamClient           bool // wsConnected was set by a StreamPrompt client rather than a WebSocket
 	wsConnMu               sync.Mutex
 	active                 bool
 	client                 *claude.Client // Streaming client for this session
 	mu                     sync.Mutex     // Protects client field
 	pendingReload          bool           // Track if we should reload after next message
 	pendingReloadMu        sync.Mutex     // Protects pendingReload field
+	stopGeneration         atomic.Bool    // Discard the rest of the in-flight response (see StopGeneration)
+	pendingContext         []string       // Notes from InjectContext, prepended to the next prompt
+	pendingContextMu       sync.Mutex     // Protects pendingContext
+	lastPrompt             string         // Last prompt sent, for RetryLastPrompt
+	lastContent            []ContentBlock // Last structured content sent, for RetryLastPrompt
+	lastPromptMu           sync.Mutex     // Protects lastPrompt and lastContent
 }
 
 // NewSessionManager creates a new session manager
 func NewSessionManager(config *Config, db *sql.DB) (*SessionManager, error) {
+	if config.DefaultPermissionMode != "" {
+		if err := ValidatePermissionMode(config.DefaultPermissionMode); err != nil {
+			return nil, fmt.Errorf("invalid default permission mode: %w", err)
+		}
+	}
+
 	// Initialize storage
 	storage, err := NewSQLiteSessionStorage(db)
 	if err != nil {
@@ -109,12 +136,22 @@ func (sm *SessionManager) loadSessionsFromDB() error {
 				DurationMS:      sessionMeta.DurationMS,
 				ModelName:       sessionMeta.ModelName,
 				ClaudeSessionID: sessionMeta.ClaudeSessionID,
+				GitBranch:       sessionMeta.GitBranch,
+				ParentSessionID: sessionMeta.ParentSessionID,
 			},
 			active: true,
 		}
 
 		if sessionMeta.ErrorMessage != "" {
 			session.ErrorMessage = &sessionMeta.ErrorMessage
+			session.ErrorCode = errorCode(sessionMeta.ErrorMessage)
+		}
+
+		// Restore options so resumed sessions keep their working directory and settings
+		if sessionMeta.OptionsJSON != "" {
+			if err := json.Unmarshal([]byte(sessionMeta.OptionsJSON), &session.Options); err != nil {
+				logging.Warning("Failed to deserialize session options for session %s: %v", sessionMeta.ID, err)
+			}
 		}
 
 		// Create context for session
@@ -147,8 +184,8 @@ func (sm *SessionManager) StartCleanupJob() {
 		return
 	}
 
-	logging.Info("Starting session cleanup job (retention: %d days, interval: %d hours)",
-		sm.config.SessionRetentionDays, sm.config.CleanupIntervalHours)
+	logging.Info("Starting session cleanup job (retention: %d days, message retention: %d days, interval: %d hours)",
+		sm.config.SessionRetentionDays, sm.config.MessageRetentionDays, sm.config.CleanupIntervalHours)
 
 	go func() {
 		ticker := time.NewTicker(time.Duration(sm.config.CleanupIntervalHours) * time.Hour)
@@ -164,8 +201,38 @@ func (sm *SessionManager) StartCleanupJob() {
 	}()
 }
 
+// PauseCleanup stops the cleanup job from deleting sessions or messages until
+// ResumeCleanup is called. The job keeps ticking but skips each run.
+func (sm *SessionManager) PauseCleanup() {
+	if !sm.cleanupPaused.Swap(true) {
+		logging.Info("Session cleanup paused")
+	}
+}
+
+// ResumeCleanup re-enables retention deletion on the next cleanup run
+func (sm *SessionManager) ResumeCleanup() {
+	if sm.cleanupPaused.Swap(false) {
+		logging.Info("Session cleanup resumed")
+	}
+}
+
+// CleanupPaused reports whether the cleanup job is currently paused
+func (sm *SessionManager) CleanupPaused() bool {
+	return sm.cleanupPaused.Load()
+}
+
+// CleanupEnabled reports whether the cleanup job is enabled in the config
+func (sm *SessionManager) CleanupEnabled() bool {
+	return sm.config.CleanupEnabled
+}
+
 // runCleanup performs the actual cleanup of old sessions
 func (sm *SessionManager) runCleanup() {
+	if sm.cleanupPaused.Load() {
+		logging.Info("Session cleanup paused, skipping run")
+		return
+	}
+
 	deleted, err := sm.storage.DeleteOldSessions(sm.config.SessionRetentionDays)
 	if err != nil {
 		logging.Error("Failed to cleanup old sessions: %v", err)
@@ -175,6 +242,17 @@ func (sm *SessionManager) runCleanup() {
 	if deleted > 0 {
 		logging.Info("Cleaned up %d old sessions (retention: %d days)", deleted, sm.config.SessionRetentionDays)
 	}
+
+	// Prune message content separately so session metadata survives for cost accounting
+	if sm.config.MessageRetentionDays > 0 {
+		pruned, err := sm.storage.DeleteOldMessages(sm.config.MessageRetentionDays)
+		if err != nil {
+			logging.Error("Failed to prune old messages: %v", err)
+			return
+		}
+
+		logging.Info("Pruned %d message rows (retention: %d days)", pruned, sm.config.MessageRetentionDays)
+	}
 }
 
 // CreateSession creates a new agent session
@@ -229,12 +307,14 @@ func (sm *SessionManager) CreateSession(sessionID uuid.UUID, options SessionOpti
 				ModelName:       existingMeta.ModelName,
 				ClaudeSessionID: existingMeta.ClaudeSessionID, // CRITICAL: Restore Claude session ID
 				GitBranch:       gitBranch,
+				ParentSessionID: existingMeta.ParentSessionID,
 			},
 			active: true,
 		}
 
 		if existingMeta.ErrorMessage != "" {
 			session.ErrorMessage = &existingMeta.ErrorMessage
+			session.ErrorCode = errorCode(existingMeta.ErrorMessage)
 		}
 
 		// Create context for restored session
@@ -255,6 +335,19 @@ func (sm *SessionManager) CreateSession(sessionID uuid.UUID, options SessionOpti
 
 	// Session doesn't exist anywhere, create new one
 	logging.Debug("Creating new session: %s", sessionID)
+
+	if err := validateEnvVars(options.EnvVars); err != nil {
+		return nil, err
+	}
+	if err := validateMaxCost(options.MaxCostUSD); err != nil {
+		return nil, err
+	}
+	if err := validateSamplingOptions(options); err != nil {
+		return nil, err
+	}
+	if err := validateMCPServers(options); err != nil {
+		return nil, err
+	}
 	now := time.Now()
 
 	// Detect git branch if working directory is provided
@@ -263,6 +356,12 @@ func (sm *SessionManager) CreateSession(sessionID uuid.UUID, options SessionOpti
 		gitBranch = GetGitBranch(*options.WorkingDirectory)
 	}
 
+	// Apply the configured default when the client didn't choose a mode
+	if options.PermissionMode == nil && sm.config.DefaultPermissionMode != "" {
+		mode := sm.config.DefaultPermissionMode
+		options.PermissionMode = &mode
+	}
+
 	session := &AgentSession{
 		Session: Session{
 			ID:           sessionID,
@@ -318,6 +417,7 @@ func (sm *SessionManager) sessionToMetadata(session *Session) *SessionMetadata {
 		ModelName:       session.ModelName,
 		ClaudeSessionID: session.ClaudeSessionID,
 		GitBranch:       session.GitBranch,
+		ParentSessionID: session.ParentSessionID,
 	}
 
 	if session.ErrorMessage != nil {
@@ -348,6 +448,26 @@ func (sm *SessionManager) updateSessionInDB(session *Session) error {
 	return sm.storage.UpdateSession(sm.sessionToMetadata(session))
 }
 
+// OnStatusChange registers fn to be called on every session status change,
+// replacing any previously registered function
+func (sm *SessionManager) OnStatusChange(fn StatusChangeFunc) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.onStatusChange = fn
+}
+
+// setStatus updates the session status and notifies the status listener if
+// it changed. Must be called with sm.mu held.
+func (sm *SessionManager) setStatus(session *AgentSession, status SessionStatus) {
+	if session.Status == status {
+		return
+	}
+	session.Status = status
+	if sm.onStatusChange != nil {
+		sm.onStatusChange(session.ID, status)
+	}
+}
+
 // GetSession retrieves a session by ID
 func (sm *SessionManager) GetSession(sessionID uuid.UUID) (*AgentSession, error) {
 	sm.mu.RLock()
@@ -374,6 +494,13 @@ func (sm *SessionManager) ListSessions() []Session {
 	return sessions
 }
 
+// SessionCount returns the number of sessions held in memory
+func (sm *SessionManager) SessionCount() int {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return len(sm.sessions)
+}
+
 // ListAllSessions returns all sessions (active and ended) from database
 func (sm *SessionManager) ListAllSessions(statusFilter string) ([]Session, error) {
 	sessionMetas, err := sm.storage.ListSessions(statusFilter)
@@ -381,6 +508,22 @@ func (sm *SessionManager) ListAllSessions(statusFilter string) ([]Session, error
 		return nil, fmt.Errorf("failed to list sessions from storage: %w", err)
 	}
 
+	return metadataToSessions(sessionMetas), nil
+}
+
+// ListSessionsByBranch returns sessions (active and ended) whose stored git
+// branch matches, filtered by status like ListAllSessions
+func (sm *SessionManager) ListSessionsByBranch(branch, statusFilter string) ([]Session, error) {
+	sessionMetas, err := sm.storage.ListSessionsByBranch(branch, statusFilter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions from storage: %w", err)
+	}
+
+	return metadataToSessions(sessionMetas), nil
+}
+
+// metadataToSessions converts stored session metadata to sessions
+func metadataToSessions(sessionMetas []*SessionMetadata) []Session {
 	sessions := make([]Session, 0, len(sessionMetas))
 	for _, meta := range sessionMetas {
 		session := Session{
@@ -395,10 +538,13 @@ func (sm *SessionManager) ListAllSessions(statusFilter string) ([]Session, error
 			ModelName:       meta.ModelName,
 			ClaudeSessionID: meta.ClaudeSessionID,
 			GitBranch:       meta.GitBranch,
+			ParentSessionID: meta.ParentSessionID,
+			EndedAt:         meta.EndedAt,
 		}
 
 		if meta.ErrorMessage != "" {
 			session.ErrorMessage = &meta.ErrorMessage
+			session.ErrorCode = errorCode(meta.ErrorMessage)
 		}
 
 		// Deserialize Options from JSON
@@ -414,7 +560,23 @@ func (sm *SessionManager) ListAllSessions(statusFilter string) ([]Session, error
 		sessions = append(sessions, session)
 	}
 
-	return sessions, nil
+	return sessions
+}
+
+// GetStatsByModel returns cost, turn and message totals of all sessions grouped by model.
+func (sm *SessionManager) GetStatsByModel() ([]ModelStat, error) {
+	return sm.storage.GetStatsByModel()
+}
+
+// GetStatsByProvider returns cost and token totals of all sessions grouped by provider
+func (sm *SessionManager) GetStatsByProvider() ([]ProviderStat, error) {
+	return sm.storage.GetStatsByProvider()
+}
+
+// GetRecentAssistantMessages returns the latest assistant messages across all
+// sessions, newest first, with context from their sessions
+func (sm *SessionManager) GetRecentAssistantMessages(limit int) ([]*RecentAssistantMessage, error) {
+	return sm.storage.GetRecentAssistantMessages(limit)
 }
 
 // InterruptSession interrupts an ongoing session without ending it
@@ -451,7 +613,7 @@ func (sm *SessionManager) InterruptSession(sessionID uuid.UUID) error {
 	session.ctx, session.cancel = context.WithCancel(context.Background())
 
 	// Update status back to idle
-	session.Status = SessionStatusIdle
+	sm.setStatus(session, SessionStatusIdle)
 	session.UpdatedAt = time.Now()
 
 	// Update in database
@@ -463,6 +625,42 @@ func (sm *SessionManager) InterruptSession(sessionID uuid.UUID) error {
 	return nil
 }
 
+// StopModeClientSide is the only way StopGeneration can stop a response: the
+// SDK client has no interrupt, so the server closes the Claude CLI process
+const StopModeClientSide = "client_side"
+
+// StopGeneration stops the in-flight response without ending the session, so
+// the next prompt continues the same conversation. The SDK can't interrupt a
+// query, so the stop is client-side: tool requests are denied from now on and
+// the session's client is closed, which ends the CLI process and its spending.
+// The next prompt creates a new client that resumes the conversation.
+func (sm *SessionManager) StopGeneration(sessionID uuid.UUID) error {
+	sm.mu.RLock()
+	session, exists := sm.sessions[sessionID]
+	processing := exists && session.Status == SessionStatusProcessing
+	sm.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	if !processing {
+		return fmt.Errorf("session %s has no response in progress", sessionID)
+	}
+
+	session.stopGeneration.Store(true)
+
+	session.mu.Lock()
+	if session.client != nil {
+		// Not the session context, which stays alive for the next prompt
+		session.client.Close(context.Background())
+		session.client = nil
+	}
+	session.mu.Unlock()
+
+	logging.Info("Stopped generation for session %s (client closed)", sessionID)
+	return nil
+}
+
 // ReloadSessionSettings closes and recreates the client to reload settings from disk
 // This is useful after adding always-allow rules to settings.local.json
 func (sm *SessionManager) ReloadSessionSettings(sessionID uuid.UUID) error {
@@ -492,6 +690,74 @@ func (sm *SessionManager) ReloadSessionSettings(sessionID uuid.UUID) error {
 	return nil
 }
 
+// ImportAlwaysAllowRules writes a batch of always-allow rules to the session's
+// settings.local.json, adds them to the in-memory session, and reloads settings once.
+// Rules already present on the session are skipped. Returns the full rule list.
+func (sm *SessionManager) ImportAlwaysAllowRules(sessionID uuid.UUID, rules []AlwaysAllowRule) ([]AlwaysAllowRule, error) {
+	for i, rule := range rules {
+		if rule.Tool == "" {
+			return nil, fmt.Errorf("rule %d: tool is required", i)
+		}
+	}
+
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	workingDir := "."
+	if session.Options.WorkingDirectory != nil {
+		workingDir = *session.Options.WorkingDirectory
+	}
+	settingsManager := NewClaudeSettingsManager(workingDir)
+
+	sm.mu.RLock()
+	existing := make(map[string]bool, len(session.Options.AlwaysAllowRules))
+	for _, rule := range session.Options.AlwaysAllowRules {
+		existing[FormatPermissionString(rule.Tool, rule.Pattern)] = true
+	}
+	sm.mu.RUnlock()
+
+	now := time.Now()
+	imported := make([]AlwaysAllowRule, 0, len(rules))
+	for _, rule := range rules {
+		permissionStr := FormatPermissionString(rule.Tool, rule.Pattern)
+		if existing[permissionStr] {
+			continue
+		}
+
+		if err := settingsManager.AddPermission(permissionStr); err != nil {
+			return nil, fmt.Errorf("failed to add permission %s: %w", permissionStr, err)
+		}
+
+		if rule.ID == "" {
+			rule.ID = uuid.New().String()
+		}
+		rule.CreatedAt = now
+		imported = append(imported, rule)
+		existing[permissionStr] = true
+	}
+
+	sm.mu.Lock()
+	session.Options.AlwaysAllowRules = append(session.Options.AlwaysAllowRules, imported...)
+	session.UpdatedAt = now
+	allRules := append([]AlwaysAllowRule(nil), session.Options.AlwaysAllowRules...)
+	if err := sm.updateSessionInDB(&session.Session); err != nil {
+		logging.Error("Failed to update session in database: %v", err)
+	}
+	sm.mu.Unlock()
+
+	logging.Info("Imported %d always-allow rules into session %s (total: %d)", len(imported), sessionID, len(allRules))
+
+	if len(imported) > 0 {
+		if err := sm.ReloadSessionSettings(sessionID); err != nil {
+			return nil, err
+		}
+	}
+
+	return allRules, nil
+}
+
 // EndSession ends a session
 func (sm *SessionManager) EndSession(sessionID uuid.UUID) error {
 	sm.mu.Lock()
@@ -516,7 +782,7 @@ func (sm *SessionManager) EndSession(sessionID uuid.UUID) error {
 	}
 
 	// Update status
-	session.Status = SessionStatusEnded
+	sm.setStatus(session, SessionStatusEnded)
 	session.UpdatedAt = time.Now()
 	session.active = false
 
@@ -570,6 +836,82 @@ func (sm *SessionManager) DeleteSession(sessionID uuid.UUID) error {
 	return nil
 }
 
+// ForceKillSession tears down a single session regardless of its state: it
+// cancels the context, closes the client without waiting on a wedged
+// goroutine, removes the session from memory and marks it as errored in the
+// database. Returns false when the session was not running in memory.
+func (sm *SessionManager) ForceKillSession(sessionID uuid.UUID) (bool, error) {
+	sm.mu.Lock()
+	session, exists := sm.sessions[sessionID]
+	if exists {
+		delete(sm.sessions, sessionID)
+	}
+	sm.mu.Unlock()
+
+	errMsg := "session force-killed"
+
+	if !exists {
+		// Not in memory - still mark a stored session as errored
+		meta, err := sm.storage.GetSession(sessionID)
+		if err != nil {
+			logging.Info("Force kill: session %s already gone", sessionID)
+			return false, nil
+		}
+		meta.Status = string(SessionStatusError)
+		meta.ErrorMessage = errMsg
+		meta.UpdatedAt = time.Now()
+		if err := sm.storage.UpdateSession(meta); err != nil {
+			return false, fmt.Errorf("failed to mark session as errored: %w", err)
+		}
+		sm.mu.RLock()
+		if sm.onStatusChange != nil {
+			sm.onStatusChange(sessionID, SessionStatusError)
+		}
+		sm.mu.RUnlock()
+		return false, nil
+	}
+
+	// Cancel first so anything blocked on the context unwinds
+	if session.cancel != nil {
+		session.cancel()
+	}
+
+	// The wedged goroutine may be holding the session lock, so only close the
+	// client inline if the lock is free and otherwise close it once released
+	closeClient := func() {
+		if session.client != nil {
+			session.client.Close(context.Background())
+			session.client = nil
+		}
+	}
+	if session.mu.TryLock() {
+		closeClient()
+		session.mu.Unlock()
+	} else {
+		go func() {
+			session.mu.Lock()
+			defer session.mu.Unlock()
+			closeClient()
+		}()
+	}
+
+	sm.mu.Lock()
+	sm.setStatus(session, SessionStatusError)
+	session.ErrorMessage = &errMsg
+	session.UpdatedAt = time.Now()
+	session.active = false
+	session.DurationMS = time.Since(session.CreatedAt).Milliseconds()
+	err := sm.updateSessionInDB(&session.Session)
+	sm.mu.Unlock()
+
+	if err != nil {
+		return true, fmt.Errorf("failed to mark session as errored: %w", err)
+	}
+
+	logging.Warning("Session force-killed: %s", sessionID)
+	return true, nil
+}
+
 // EndAllSessions ends all active sessions
 func (sm *SessionManager) EndAllSessions() int {
 	sm.mu.Lock()
@@ -639,10 +981,15 @@ func (sm *SessionManager) SendPrompt(sessionID uuid.UUID, prompt string) error {
 		logging.Error("SendPrompt: Failed to get session: %v", err)
 		return err
 	}
+	if err := sm.checkBudget(session); err != nil {
+		return err
+	}
+
+	session.rememberPrompt(prompt, nil)
 
 	// Update session status
 	sm.mu.Lock()
-	session.Status = SessionStatusProcessing
+	sm.setStatus(session, SessionStatusProcessing)
 	session.UpdatedAt = time.Now()
 	session.MessageCount++
 	userMsgSequence := session.MessageCount
@@ -655,184 +1002,14 @@ func (sm *SessionManager) SendPrompt(sessionID uuid.UUID, prompt string) error {
 
 	logging.Debug("SendPrompt: Executing query for session %s", sessionID)
 
-	// Determine permission mode
-	permMode := types.PermissionModeDefault
-	if session.Options.PermissionMode != nil {
-		switch *session.Options.PermissionMode {
-		case "allow-all":
-			permMode = types.PermissionModeBypassPermissions
-		case "read-only":
-			permMode = types.PermissionModeDefault
-		default:
-			permMode = types.PermissionModeDefault
-		}
-	}
-
-	// Build SDK options
-	logging.Debug("SendPrompt: Building SDK options (model: %s, permMode: %v, verbose: %v)", sm.config.Model, permMode, sm.config.Verbose)
-
-	// Create permission callback
-	canUseTool := func(ctx context.Context, toolName string, input map[string]interface{}, permCtx types.ToolPermissionContext) (interface{}, error) {
-		requestID := uuid.New().String()
-		logging.Info("🔐🔐🔐 CALLBACK INVOKED: tool=%s, requestID=%s, input=%+v", toolName, requestID, input)
-
-		// Check if WebSocket is connected before proceeding
-		if !session.IsWebSocketConnected() {
-			logging.Warning("Permission request rejected: WebSocket not connected (tool=%s, requestID=%s)", toolName, requestID)
-			return types.PermissionResultDeny{Message: "WebSocket connection lost - cannot request permission"}, nil
-		}
-
-		// Create response channel for this specific request
-		responseChan := make(chan PermissionResponse, 1)
-
-		// Store in pending permissions map
-		session.permMu.Lock()
-		session.pendingPermissions[requestID] = responseChan
-		session.permMu.Unlock()
-
-		// Clean up when done
-		defer func() {
-			session.permMu.Lock()
-			delete(session.pendingPermissions, requestID)
-			session.permMu.Unlock()
-		}()
-
-		// Send permission request to frontend via channel
-		permReq := &PermissionRequest{
-			RequestID:    requestID,
-			ToolName:     toolName,
-			Input:        input,
-			Context:      permCtx,
-			ResponseChan: responseChan,
-		}
-
-		logging.Info("⏳ Sending permission request to channel...")
-
-		select {
-		case session.permissionReqChan <- permReq:
-			logging.Info("✅ Permission request sent to channel successfully: %s", requestID)
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case <-session.ctx.Done():
-			logging.Warning("Session context cancelled while sending permission request")
-			return types.PermissionResultDeny{Message: "Session ended"}, nil
-		case <-time.After(5 * time.Second):
-			logging.Warning("Timeout sending permission request to frontend")
-			return types.PermissionResultDeny{Message: "Permission request timeout"}, nil
-		}
-
-		// Wait for response from frontend with reduced timeout (60 seconds instead of 5 minutes)
-		select {
-		case response := <-responseChan:
-			logging.Info("Permission response received: approved=%v, requestID=%s", response.Approved, requestID)
-			if response.Approved {
-				result := types.PermissionResultAllow{
-					Behavior: "allow",
-				}
-				if response.UpdatedInput != nil {
-					result.UpdatedInput = response.UpdatedInput
-				}
-				if len(response.UpdatedPermissions) > 0 {
-					result.UpdatedPermissions = response.UpdatedPermissions
-					logging.Info("✨ Including %d permission update(s) in approval response", len(response.UpdatedPermissions))
-				}
-				return result, nil
-			} else {
-				return types.PermissionResultDeny{
-					Behavior: "deny",
-					Message:  response.DenyMessage,
-				}, nil
-			}
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case <-session.ctx.Done():
-			logging.Warning("Session context cancelled while waiting for permission response")
-			return types.PermissionResultDeny{Message: "Session ended"}, nil
-		case <-time.After(60 * time.Second): // Reduced from 5 minutes to 60 seconds
-			logging.Warning("Timeout waiting for permission response from user (tool=%s, requestID=%s)", toolName, requestID)
-			return types.PermissionResultDeny{Message: "Permission request timed out after 60 seconds"}, nil
-		}
-	}
-
-	// Define available tools (Claude Code standard tools)
-	allowedTools := []string{
-		"Bash", "Read", "Write", "Edit", "Glob", "Grep",
-		"WebSearch", "WebFetch",
-	}
-
-	// If session options specify tools, use those instead
-	if len(session.Options.Tools) > 0 {
-		allowedTools = session.Options.Tools
-	}
-
-	logging.Info("Allowed tools for session %s: %v", sessionID, allowedTools)
-	logging.Info("Permission mode: %v", permMode)
-
-	// Determine model to use: session-specific > config default
-	modelToUse := sm.config.Model
-	if session.Options.Model != nil && *session.Options.Model != "" {
-		modelToUse = *session.Options.Model
-		logging.Info("Using session-specific model: %s", modelToUse)
-	}
-
-	opts := types.NewClaudeAgentOptions().
-		WithModel(modelToUse).
-		WithPermissionMode(permMode).
-		WithVerbose(sm.config.Verbose).
-		WithCanUseTool(canUseTool).
-		// Don't set AllowedTools - let permission mode control tool access
-		// WithAllowedTools(allowedTools...).
-		WithSystemPrompt("code")
-
-	// Set base URL if specified in session options (for custom providers)
-	if session.Options.BaseURL != nil && *session.Options.BaseURL != "" {
-		logging.Info("Using session-specific base URL: %s", *session.Options.BaseURL)
-		opts = opts.WithBaseURL(*session.Options.BaseURL)
-	}
-
-	// Set API key: session-specific > database provider config > config default
-	apiKeyToUse := sm.config.APIKey
-
-	// If session specifies a provider, try to get API key from database
-	if session.Options.Provider != nil && *session.Options.Provider != "" {
-		// Query providers table directly
-		var apiKey string
-		err := sm.db.QueryRow("SELECT api_key FROM providers WHERE provider_id = ? LIMIT 1", *session.Options.Provider).Scan(&apiKey)
-		if err == nil && apiKey != "" {
-			apiKeyToUse = apiKey
-			logging.Info("Using API key from provider database: %s (masked)", *session.Options.Provider)
-		} else if err == sql.ErrNoRows {
-			logging.Warning("No API key found for provider: %s - configure it via TUI first", *session.Options.Provider)
-		} else if err != nil {
-			logging.Warning("Failed to load provider config from database: %v", err)
-		}
-	}
-
-	// Session-specific API key overrides everything
-	if session.Options.APIKey != nil && *session.Options.APIKey != "" {
-		apiKeyToUse = *session.Options.APIKey
-		logging.Info("Using session-specific API key (masked)")
-	}
-
-	// Only set API key if provided (don't override SDK's default detection)
-	if apiKeyToUse != "" {
-		opts = opts.WithEnvVar("ANTHROPIC_API_KEY", apiKeyToUse)
-		logging.Debug("API key configured (length: %d)", len(apiKeyToUse))
-	} else {
+	// Build SDK options. The permission callback is shared with SendPromptWithContent
+	// so disabled tools and always-deny/always-allow rules apply to every prompt.
+	settings := sm.resolveSettings(session)
+	logging.Debug("SendPrompt: Building SDK options (model: %s, permMode: %v, verbose: %v)", settings.model, settings.sdkPermission, sm.config.Verbose)
+	if settings.apiKey == "" {
 		logging.Warning("No API key configured for this session - make sure ANTHROPIC_API_KEY is set in environment or configure provider in TUI")
 	}
-
-	// Set working directory if provided
-	if session.Options.WorkingDirectory != nil && *session.Options.WorkingDirectory != "" {
-		logging.Debug("SendPrompt: Setting working directory: %s", *session.Options.WorkingDirectory)
-		opts = opts.WithCWD(*session.Options.WorkingDirectory)
-	}
-
-	// Resume existing conversation if Claude session ID exists
-	if session.ClaudeSessionID != "" {
-		logging.Debug("SendPrompt: Resuming conversation from Claude session: %s", session.ClaudeSessionID)
-		opts = opts.WithResume(session.ClaudeSessionID)
-	}
+	opts := sm.promptOptions(session, settings)
 
 	// Reuse existing client if available (preserves conversation context)
 	// Otherwise create a new client
@@ -850,15 +1027,13 @@ func (sm *SessionManager) SendPrompt(sessionID uuid.UUID, prompt string) error {
 		}
 		logging.Debug("SendPrompt: API Key length: %d", len(sm.config.APIKey))
 		logging.Debug("Creating streaming client for session %s with options: model=%s, permMode=%v",
-			sessionID, sm.config.Model, permMode)
+			sessionID, settings.model, settings.sdkPermission)
 
 		newClient, err := claude.NewClient(session.ctx, opts)
 		if err != nil {
 			logging.Error("SendPrompt: Failed to create client: %v", err)
 			sm.mu.Lock()
-			errMsg := err.Error()
-			session.ErrorMessage = &errMsg
-			session.Status = SessionStatusError
+			sm.failSession(session, err.Error())
 			sm.mu.Unlock()
 			return fmt.Errorf("failed to create client: %w", err)
 		}
@@ -867,9 +1042,7 @@ func (sm *SessionManager) SendPrompt(sessionID uuid.UUID, prompt string) error {
 		if err := newClient.Connect(session.ctx); err != nil {
 			logging.Error("SendPrompt: Failed to connect client: %v", err)
 			sm.mu.Lock()
-			errMsg := err.Error()
-			session.ErrorMessage = &errMsg
-			session.Status = SessionStatusError
+			sm.failSession(session, err.Error())
 			sm.mu.Unlock()
 			return fmt.Errorf("failed to connect client: %w", err)
 		}
@@ -883,13 +1056,13 @@ func (sm *SessionManager) SendPrompt(sessionID uuid.UUID, prompt string) error {
 		logging.Info("SendPrompt: Reusing existing client for session %s (preserves conversation context)", sessionID)
 	}
 
-	// Send the query
-	if err := client.Query(session.ctx, prompt); err != nil {
+	// Send the query, preceded by any injected context notes
+	notes := session.takePendingContext()
+	if err := client.Query(session.ctx, formatContextPreamble(notes)+prompt); err != nil {
 		logging.Error("SendPrompt: Failed to send query: %v", err)
+		session.restorePendingContext(notes)
 		sm.mu.Lock()
-		errMsg := err.Error()
-		session.ErrorMessage = &errMsg
-		session.Status = SessionStatusError
+		sm.failSession(session, err.Error())
 		sm.mu.Unlock()
 		return fmt.Errorf("failed to send query: %w", err)
 	}
@@ -905,19 +1078,54 @@ func (sm *SessionManager) SendPrompt(sessionID uuid.UUID, prompt string) error {
 	return nil
 }
 
-// SendPromptWithContent sends structured content (text + images) to an agent session
-// This method bypasses the SDK's Query method to support image content blocks
+// providerDefaults holds the per-provider settings stored in the providers table
+type providerDefaults struct {
+	APIKey  string
+	BaseURL string
+	Model   string
+}
+
+// loadProviderDefaults loads the API key, custom URL, and default model for a provider.
+// Returns sql.ErrNoRows if the provider has not been configured.
+func (sm *SessionManager) loadProviderDefaults(providerID string) (*providerDefaults, error) {
+	p := &providerDefaults{}
+	err := sm.db.QueryRow(
+		"SELECT api_key, COALESCE(custom_url, ''), COALESCE(model_name, '') FROM providers WHERE provider_id = ? LIMIT 1",
+		providerID,
+	).Scan(&p.APIKey, &p.BaseURL, &p.Model)
+	if err != nil {
+		return nil, err
+	}
+	if p.APIKey, err = database.DecryptSecret(p.APIKey); err != nil {
+		return nil, fmt.Errorf("failed to decrypt API key for provider %s: %w", providerID, err)
+	}
+	return p, nil
+}
+
+// SendPromptWithContent sends structured content (text, images, documents) to an agent session
+// This method bypasses the SDK's Query method to support image and document content blocks
 func (sm *SessionManager) SendPromptWithContent(sessionID uuid.UUID, content []ContentBlock) error {
+	// Reject unsupported content before touching session state or the SDK
+	if err := ValidateContentBlocks(content); err != nil {
+		logging.Warning("SendPromptWithContent: Invalid content: %v", err)
+		return err
+	}
+
 	logging.Debug("SendPromptWithContent: Getting session %s", sessionID)
 	session, err := sm.GetSession(sessionID)
 	if err != nil {
 		logging.Error("SendPromptWithContent: Failed to get session: %v", err)
 		return err
 	}
+	if err := sm.checkBudget(session); err != nil {
+		return err
+	}
+
+	session.rememberPrompt("", content)
 
 	// Update session status
 	sm.mu.Lock()
-	session.Status = SessionStatusProcessing
+	sm.setStatus(session, SessionStatusProcessing)
 	session.UpdatedAt = time.Now()
 	session.MessageCount++
 	userMsgSequence := session.MessageCount
@@ -949,72 +1157,15 @@ func (sm *SessionManager) SendPromptWithContent(sessionID uuid.UUID, content []C
 		// then send our structured content
 		logging.Info("SendPromptWithContent: No client exists, initializing session first")
 
-		// We need to create the client but not send a query yet
-		// Let's replicate the client creation logic here
-		permMode := types.PermissionModeDefault
-		if session.Options.PermissionMode != nil {
-			switch *session.Options.PermissionMode {
-			case "allow-all":
-				permMode = types.PermissionModeBypassPermissions
-			case "read-only":
-				permMode = types.PermissionModeDefault
-			default:
-				permMode = types.PermissionModeDefault
-			}
-		}
-
-		// Create permission callback (same as SendPrompt)
-		canUseTool := sm.createPermissionCallback(session)
-
-		// Determine model to use
-		modelToUse := sm.config.Model
-		if session.Options.Model != nil && *session.Options.Model != "" {
-			modelToUse = *session.Options.Model
-		}
-
-		opts := types.NewClaudeAgentOptions().
-			WithModel(modelToUse).
-			WithPermissionMode(permMode).
-			WithVerbose(sm.config.Verbose).
-			WithCanUseTool(canUseTool).
-			WithSystemPrompt("code")
-
-		// Set other options (base URL, API key, working directory, resume)
-		if session.Options.BaseURL != nil && *session.Options.BaseURL != "" {
-			opts = opts.WithBaseURL(*session.Options.BaseURL)
-		}
-
-		apiKeyToUse := sm.config.APIKey
-		if session.Options.Provider != nil && *session.Options.Provider != "" {
-			var apiKey string
-			err := sm.db.QueryRow("SELECT api_key FROM providers WHERE provider_id = ? LIMIT 1", *session.Options.Provider).Scan(&apiKey)
-			if err == nil && apiKey != "" {
-				apiKeyToUse = apiKey
-			}
-		}
-		if session.Options.APIKey != nil && *session.Options.APIKey != "" {
-			apiKeyToUse = *session.Options.APIKey
-		}
-		if apiKeyToUse != "" {
-			opts = opts.WithEnvVar("ANTHROPIC_API_KEY", apiKeyToUse)
-		}
-
-		if session.Options.WorkingDirectory != nil && *session.Options.WorkingDirectory != "" {
-			opts = opts.WithCWD(*session.Options.WorkingDirectory)
-		}
-
-		if session.ClaudeSessionID != "" {
-			opts = opts.WithResume(session.ClaudeSessionID)
-		}
+		// Create the client with the same options as SendPrompt, but don't send a query yet
+		opts := sm.promptOptions(session, sm.resolveSettings(session))
 
 		// Create new client
 		newClient, err := claude.NewClient(session.ctx, opts)
 		if err != nil {
 			logging.Error("SendPromptWithContent: Failed to create client: %v", err)
 			sm.mu.Lock()
-			errMsg := err.Error()
-			session.ErrorMessage = &errMsg
-			session.Status = SessionStatusError
+			sm.failSession(session, err.Error())
 			sm.mu.Unlock()
 			return fmt.Errorf("failed to create client: %w", err)
 		}
@@ -1022,9 +1173,7 @@ func (sm *SessionManager) SendPromptWithContent(sessionID uuid.UUID, content []C
 		if err := newClient.Connect(session.ctx); err != nil {
 			logging.Error("SendPromptWithContent: Failed to connect client: %v", err)
 			sm.mu.Lock()
-			errMsg := err.Error()
-			session.ErrorMessage = &errMsg
-			session.Status = SessionStatusError
+			sm.failSession(session, err.Error())
 			sm.mu.Unlock()
 			return fmt.Errorf("failed to connect client: %w", err)
 		}
@@ -1037,22 +1186,13 @@ func (sm *SessionManager) SendPromptWithContent(sessionID uuid.UUID, content []C
 
 	// Convert ContentBlock array to interface{} for SDK
 	// The SDK's QueryWithContent accepts interface{} which can be a content array
-	contentInterface := make([]interface{}, len(content))
-	for i, block := range content {
-		blockMap := make(map[string]interface{})
-		blockMap["type"] = block.Type
-
-		if block.Type == "text" {
-			blockMap["text"] = block.Text
-		} else if block.Type == "image" && block.Source != nil {
-			blockMap["source"] = map[string]interface{}{
-				"type":       block.Source.Type,
-				"media_type": block.Source.MediaType,
-				"data":       block.Source.Data,
-			}
-		}
-
-		contentInterface[i] = blockMap
+	contentInterface := make([]interface{}, 0, len(content)+1)
+	notes := session.takePendingContext()
+	if preamble := formatContextPreamble(notes); preamble != "" {
+		contentInterface = append(contentInterface, convertContentBlock(ContentBlock{Type: "text", Text: preamble}))
+	}
+	for _, block := range content {
+		contentInterface = append(contentInterface, convertContentBlock(block))
 	}
 
 	logging.Info("SendPromptWithContent: Sending %d content blocks to Claude CLI", len(content))
@@ -1060,10 +1200,9 @@ func (sm *SessionManager) SendPromptWithContent(sessionID uuid.UUID, content []C
 	// Use the new QueryWithContent method to send structured content
 	if err := client.QueryWithContent(session.ctx, contentInterface); err != nil {
 		logging.Error("SendPromptWithContent: Failed to send query: %v", err)
+		session.restorePendingContext(notes)
 		sm.mu.Lock()
-		errMsg := err.Error()
-		session.ErrorMessage = &errMsg
-		session.Status = SessionStatusError
+		sm.failSession(session, err.Error())
 		sm.mu.Unlock()
 		return fmt.Errorf("failed to send query: %w", err)
 	}
@@ -1115,10 +1254,36 @@ func (sm *SessionManager) createPermissionCallback(session *AgentSession) types.
 		requestID := uuid.New().String()
 		logging.Info("🔐 PERMISSION CALLBACK: tool=%s, requestID=%s", toolName, requestID)
 
-		// Check always-allow rules first - get latest rules from session manager
+		// Nothing more runs once the user has stopped the response
+		if session.stopGeneration.Load() {
+			logging.Info("Denied %s: generation stopped for session %s", toolName, sessionID)
+			return types.PermissionResultDeny{
+				Behavior:  "deny",
+				Message:   "Generation stopped by the user",
+				Interrupt: true,
+			}, nil
+		}
+
+		// Globally disabled tools are denied before always-allow rules are consulted
+		if sm.isToolDisabled(toolName) {
+			logging.Info("Denied disabled tool: %s", toolName)
+			return disabledToolDeny(toolName), nil
+		}
+
+		// Check always-deny rules, then always-allow rules - get latest rules from session manager
 		sm.mu.RLock()
 		currentSession, exists := sm.sessions[sessionID]
 		if exists {
+			if matched, ruleDesc := CheckAlwaysDenyRules(currentSession.Options.AlwaysDenyRules, toolName, input); matched {
+				sm.mu.RUnlock()
+				return alwaysDenyRuleDeny(toolName, ruleDesc), nil
+			}
+			// Allow-all sessions only reach the callback when tools are disabled
+			// (see resolveSettings); everything else is approved without asking
+			if mode := currentSession.Options.PermissionMode; mode != nil && *mode == PermissionModeAllowAll {
+				sm.mu.RUnlock()
+				return types.PermissionResultAllow{Behavior: "allow"}, nil
+			}
 			logging.Info("📋 Checking %d always-allow rules for tool %s", len(currentSession.Options.AlwaysAllowRules), toolName)
 			if matched, ruleDesc := CheckAlwaysAllowRules(currentSession.Options.AlwaysAllowRules, toolName, input); matched {
 				sm.mu.RUnlock()
@@ -1165,12 +1330,13 @@ func (sm *SessionManager) createPermissionCallback(session *AgentSession) types.
 		case <-session.ctx.Done():
 			logging.Warning("Session context cancelled while sending permission request")
 			return types.PermissionResultDeny{Message: "Session ended"}, nil
-		case <-time.After(5 * time.Second):
+		case <-time.After(sm.config.permissionSendTimeout()):
 			logging.Warning("Timeout sending permission request to channel")
 			return types.PermissionResultDeny{Message: "Permission request timeout"}, nil
 		}
 
-		// Wait for response from frontend with reduced timeout (60 seconds instead of unlimited)
+		// Wait for response from frontend (default 60 seconds, see Config.PermissionResponseTimeout)
+		responseTimeout := sm.config.permissionResponseTimeout()
 		select {
 		case response := <-responseChan:
 			if response.Approved {
@@ -1186,6 +1352,14 @@ func (sm *SessionManager) createPermissionCallback(session *AgentSession) types.
 					logging.Info("✨ Including %d permission update(s) in approval response", len(response.UpdatedPermissions))
 				}
 				return result, nil
+			} else if response.Cancelled {
+				// A dismissed request stops the turn instead of letting the model retry
+				logging.Info("🚫 Permission request CANCELLED for %s (request %s)", toolName, requestID)
+				return types.PermissionResultDeny{
+					Behavior:  "deny",
+					Message:   response.DenyMessage,
+					Interrupt: true,
+				}, nil
 			} else {
 				logging.Info("❌ Permission DENIED for %s (request %s): %s", toolName, requestID, response.DenyMessage)
 				return types.PermissionResultDeny{
@@ -1199,13 +1373,47 @@ func (sm *SessionManager) createPermissionCallback(session *AgentSession) types.
 		case <-session.ctx.Done():
 			logging.Warning("⏱️ Session ended while waiting for permission (tool=%s, request %s)", toolName, requestID)
 			return types.PermissionResultDeny{Message: "Session ended"}, nil
-		case <-time.After(60 * time.Second): // Reduced from unlimited to 60 seconds
+		case <-time.After(responseTimeout):
 			logging.Warning("⏱️ Permission request TIMEOUT for %s (request %s)", toolName, requestID)
-			return types.PermissionResultDeny{Message: "Permission request timed out after 60 seconds"}, nil
+			return types.PermissionResultDeny{Message: fmt.Sprintf("Permission request timed out after %s", responseTimeout)}, nil
 		}
 	}
 }
 
+// CancelPermission cancels a pending permission request without recording a denial.
+// The waiting permission callback denies the tool and interrupts the turn,
+// and the request is removed from the session's pending permissions.
+func (sm *SessionManager) CancelPermission(sessionID uuid.UUID, permissionID string) error {
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	session.permMu.Lock()
+	responseChan, exists := session.pendingPermissions[permissionID]
+	if exists {
+		delete(session.pendingPermissions, permissionID)
+	}
+	session.permMu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("no pending permission request found for ID: %s", permissionID)
+	}
+
+	// Response channel is buffered, so this never blocks unless a response was already sent
+	select {
+	case responseChan <- PermissionResponse{
+		Approved:    false,
+		DenyMessage: "Permission request cancelled by user",
+		Cancelled:   true,
+	}:
+	default:
+		logging.Warning("Permission %s already has a response, cancel ignored", permissionID)
+	}
+
+	return nil
+}
+
 // receiveQueryResponses receives responses from a Query and sends them to the response channel
 func (sm *SessionManager) receiveQueryResponses(session *AgentSession, messages <-chan types.Message) {
 	defer func() {
@@ -1213,9 +1421,13 @@ func (sm *SessionManager) receiveQueryResponses(session *AgentSession, messages
 			logging.Error("Session %s: PANIC in receiveQueryResponses: %v", session.ID, r)
 		}
 		sm.mu.Lock()
-		session.Status = SessionStatusIdle
+		// Keep a failure reported by the response (e.g. rejected API key) visible
+		if session.Status != SessionStatusError {
+			sm.setStatus(session, SessionStatusIdle)
+		}
 		session.UpdatedAt = time.Now()
 		sm.mu.Unlock()
+		session.stopGeneration.Store(false)
 		logging.Debug("Session %s: Query response receiving completed", session.ID)
 	}()
 
@@ -1230,6 +1442,16 @@ func (sm *SessionManager) receiveQueryResponses(session *AgentSession, messages
 			if !ok {
 				logging.Info("Session %s: Messages channel closed after %d messages", session.ID, messageCount)
 
+				// A stopped response ends when StopGeneration closes the client, before
+				// its result arrives. Finish the turn for the clients streaming it.
+				if session.stopGeneration.Load() {
+					select {
+					case session.responseChan <- &types.ResultMessage{Type: "result", Subtype: "stopped"}:
+					default:
+						logging.Warning("Session %s: Response channel full, stop not forwarded", session.ID)
+					}
+				}
+
 				// Refresh git branch after conversation turn completes
 				if _, changed, err := sm.RefreshGitBranch(session.ID); err == nil && changed {
 					logging.Debug("Session %s: Git branch updated after conversation turn", session.ID)
@@ -1252,21 +1474,40 @@ func (sm *SessionManager) receiveQueryResponses(session *AgentSession, messages
 				logging.Debug("Session %s: Failed to refresh git branch: %v", session.ID, err)
 			}
 
-			// Increment session message count atomically and get sequence number
+			// Increment session message count atomically and get sequence number.
+			// UpdatedAt tracks activity, so a long streaming turn doesn't look idle.
 			sm.mu.Lock()
 			session.MessageCount++
+			session.UpdatedAt = time.Now()
 			sequenceNum := session.MessageCount
+			storeThinking := session.Options.storeThinking(sm.config.StoreThinking)
 			sm.mu.Unlock()
 
 			// Save message to database based on type with proper sequence number
-			sm.persistSDKMessage(session.ID, sequenceNum, msg)
+			sm.persistSDKMessage(session.ID, sequenceNum, msg, storeThinking)
 
-			select {
-			case session.responseChan <- msg:
-				logging.Debug("Session %s: Message #%d forwarded to response channel", session.ID, messageCount)
-			case <-session.ctx.Done():
-				logging.Info("Session %s: Context cancelled after %d messages", session.ID, messageCount)
-				return
+			// Surface rejected credentials as a session error rather than a normal reply
+			if result, ok := msg.(*types.ResultMessage); ok && result.IsError && result.Result != nil &&
+				errorCode(*result.Result) == ErrorCodeAuth {
+				sm.mu.Lock()
+				sm.failSession(session, *result.Result)
+				sm.updateSessionInDB(&session.Session)
+				sm.mu.Unlock()
+			}
+
+			// After StopGeneration the rest of the response is still persisted but not
+			// forwarded, except the result message so the client sees the turn finish
+			_, isResult := msg.(*types.ResultMessage)
+			if session.stopGeneration.Load() && !isResult {
+				logging.Debug("Session %s: Discarding message #%d after stop requested", session.ID, messageCount)
+			} else {
+				select {
+				case session.responseChan <- msg:
+					logging.Debug("Session %s: Message #%d forwarded to response channel", session.ID, messageCount)
+				case <-session.ctx.Done():
+					logging.Info("Session %s: Context cancelled after %d messages", session.ID, messageCount)
+					return
+				}
 			}
 
 			// Check if we should reload after this message (from "Allow Similar" flow)
@@ -1320,6 +1561,37 @@ func (sm *SessionManager) GetResponseChannel(sessionID uuid.UUID) (chan types.Me
 	return session.responseChan, nil
 }
 
+// SendPromptDetached sends a prompt to a session that has no WebSocket client
+// streaming its responses, e.g. one started over HTTP. Responses are persisted
+// as usual; the forwarded copies are drained until the turn's result so the
+// session does not stall on a full response channel.
+func (sm *SessionManager) SendPromptDetached(sessionID uuid.UUID, prompt string) error {
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if err := sm.SendPrompt(sessionID, prompt); err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			select {
+			case msg := <-session.responseChan:
+				if _, ok := msg.(*types.ResultMessage); ok {
+					logging.Debug("Session %s: Detached prompt finished", sessionID)
+					return
+				}
+			case <-session.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
 // RefreshGitBranch checks and updates the git branch for a session
 // Returns the new branch name and whether it changed
 func (sm *SessionManager) RefreshGitBranch(sessionID uuid.UUID) (newBranch string, changed bool, err error) {
@@ -1377,6 +1649,7 @@ func (s *AgentSession) SetWebSocketConnected(connected bool) {
 	s.wsConnMu.Lock()
 	defer s.wsConnMu.Unlock()
 	s.wsConnected = connected
+	s.streamClient = false // A WebSocket change supersedes any stream client
 }
 
 // IsWebSocketConnected returns the current WebSocket connection state
@@ -1407,6 +1680,11 @@ func (s *AgentSession) CleanupPendingPermissions() {
 
 // saveMessageToDB persists a message to the database
 func (sm *SessionManager) saveMessageToDB(sessionID uuid.UUID, sequence int, role, content, thinkingContent string, toolUses interface{}) error {
+	return sm.saveMessageWithTokens(sessionID, sequence, role, content, thinkingContent, toolUses, 0)
+}
+
+// saveMessageWithTokens persists a message along with the tokens it used
+func (sm *SessionManager) saveMessageWithTokens(sessionID uuid.UUID, sequence int, role, content, thinkingContent string, toolUses interface{}, tokensUsed int) error {
 	var toolUsesJSON []byte
 	if toolUses != nil {
 		var err error
@@ -1425,19 +1703,73 @@ func (sm *SessionManager) saveMessageToDB(sessionID uuid.UUID, sequence int, rol
 		ThinkingContent: thinkingContent,
 		ToolUses:        toolUsesJSON,
 		Timestamp:       time.Now(),
-		TokensUsed:      0, // TODO: Extract from SDK response if available
+		TokensUsed:      tokensUsed,
 	}
 
 	return sm.storage.SaveMessage(msg)
 }
 
+// usageTokens reads input and output token counts from an SDK usage map.
+// Missing or non-numeric values count as zero.
+func usageTokens(usage map[string]interface{}) (input, output int) {
+	toInt := func(v interface{}) int {
+		switch n := v.(type) {
+		case float64:
+			return int(n)
+		case int:
+			return n
+		case int64:
+			return int(n)
+		}
+		return 0
+	}
+	return toInt(usage["input_tokens"]), toInt(usage["output_tokens"])
+}
+
 // GetMessages retrieves messages for a session with pagination
 func (sm *SessionManager) GetMessages(sessionID uuid.UUID, limit, offset int) ([]*MessageRecord, bool, error) {
 	return sm.storage.GetMessages(sessionID, limit, offset)
 }
 
-// persistSDKMessage saves an SDK message to the database
-func (sm *SessionManager) persistSDKMessage(sessionID uuid.UUID, sequence int, msg types.Message) {
+// GetMessageCount returns how many messages are stored for a session
+func (sm *SessionManager) GetMessageCount(sessionID uuid.UUID) (int, error) {
+	return sm.storage.GetMessageCount(sessionID)
+}
+
+// FlagMessage flags or unflags a stored message so users can jump back to important turns
+func (sm *SessionManager) FlagMessage(sessionID uuid.UUID, sequence int, flagged bool) error {
+	return sm.storage.SetMessageFlagged(sessionID, sequence, flagged)
+}
+
+// GetFlaggedMessages returns the flagged messages of a session in conversation order
+func (sm *SessionManager) GetFlaggedMessages(sessionID uuid.UUID) ([]*MessageRecord, error) {
+	return sm.storage.GetFlaggedMessages(sessionID)
+}
+
+// GetLastMessage returns the most recent message for a session, or nil if it has none
+func (sm *SessionManager) GetLastMessage(sessionID uuid.UUID) (*MessageRecord, error) {
+	count, err := sm.storage.GetMessageCount(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, nil
+	}
+
+	messages, _, err := sm.storage.GetMessages(sessionID, 1, count-1)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, nil
+	}
+
+	return messages[0], nil
+}
+
+// persistSDKMessage saves an SDK message to the database. Thinking content is
+// dropped unless storeThinking is set.
+func (sm *SessionManager) persistSDKMessage(sessionID uuid.UUID, sequence int, msg types.Message, storeThinking bool) {
 	messageType := msg.GetMessageType()
 
 	switch messageType {
@@ -1457,6 +1789,9 @@ func (sm *SessionManager) persistSDKMessage(sessionID uuid.UUID, sequence int, m
 					textContent += b.Text
 
 				case *types.ThinkingBlock:
+					if !storeThinking {
+						continue
+					}
 					if thinkingContent != "" {
 						thinkingContent += "\n"
 					}
@@ -1503,16 +1838,26 @@ func (sm *SessionManager) persistSDKMessage(sessionID uuid.UUID, sequence int, m
 				resultData["usage"] = resultMsg.Usage
 			}
 
-			if err := sm.saveMessageToDB(sessionID, sequence, "system", content, "", resultData); err != nil {
+			// Record the provider next to the usage so tokens can be attributed to it
+			sm.mu.RLock()
+			if session, exists := sm.sessions[sessionID]; exists && session.Options.Provider != nil {
+				resultData["provider"] = *session.Options.Provider
+			}
+			sm.mu.RUnlock()
+
+			inputTokens, outputTokens := usageTokens(resultMsg.Usage)
+			if err := sm.saveMessageWithTokens(sessionID, sequence, "system", content, "", resultData, inputTokens+outputTokens); err != nil {
 				logging.Error("Failed to save result message: %v", err)
 			}
 
 			// Update session with cost and turn info
+			overBudget := false
 			sm.mu.Lock()
 			if session, exists := sm.sessions[sessionID]; exists {
 				if resultMsg.TotalCostUSD != nil {
 					session.CostUSD = *resultMsg.TotalCostUSD
 				}
+				overBudget = session.overBudget()
 				session.NumTurns = resultMsg.NumTurns
 				session.DurationMS = int64(resultMsg.DurationMs)
 
@@ -1529,6 +1874,12 @@ func (sm *SessionManager) persistSDKMessage(sessionID uuid.UUID, sequence int, m
 				}
 			}
 			sm.mu.Unlock()
+
+			// End asynchronously: closing the client waits on the stream
+			// this message is being read from
+			if overBudget {
+				go sm.endOverBudget(sessionID)
+			}
 		}
 
 	case "user":
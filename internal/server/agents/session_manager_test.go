@@ -0,0 +1,875 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/schlunsen/claude-agent-sdk-go/types"
+	"github.com/schlunsen/claude-control-terminal/internal/database"
+)
+
+// newTestSessionManager creates a session manager backed by a temporary database
+func newTestSessionManager(t *testing.T) *SessionManager {
+	t.Helper()
+
+	database.ResetInstance()
+	tmpDir, err := os.MkdirTemp("", "cct-agents-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+
+	db, err := database.Initialize(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	t.Cleanup(func() {
+		database.ResetInstance()
+		os.RemoveAll(tmpDir)
+	})
+
+	sm, err := NewSessionManager(&Config{Model: "sonnet"}, db.GetDB())
+	if err != nil {
+		t.Fatalf("Failed to create session manager: %v", err)
+	}
+	return sm
+}
+
+func TestImportAlwaysAllowRules(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	workDir := t.TempDir()
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{WorkingDirectory: &workDir}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	prefix := "git"
+	rules := []AlwaysAllowRule{
+		{Tool: "Bash", MatchMode: "pattern", Pattern: &RulePattern{CommandPrefix: &prefix}},
+		{Tool: "Read"},
+		{Tool: "Read"}, // duplicate is skipped
+	}
+
+	allRules, err := sm.ImportAlwaysAllowRules(sessionID, rules)
+	if err != nil {
+		t.Fatalf("ImportAlwaysAllowRules failed: %v", err)
+	}
+	if len(allRules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(allRules))
+	}
+	for _, rule := range allRules {
+		if rule.ID == "" || rule.CreatedAt.IsZero() {
+			t.Errorf("expected rule %s to get an ID and timestamp", rule.Tool)
+		}
+	}
+
+	permissions, err := NewClaudeSettingsManager(workDir).GetAllowedPermissions()
+	if err != nil {
+		t.Fatalf("Failed to read settings: %v", err)
+	}
+	if len(permissions) != 2 || permissions[0] != "Bash(git:*)" || permissions[1] != "Read(*)" {
+		t.Errorf("unexpected permissions in settings.local.json: %v", permissions)
+	}
+
+	// Importing again adds nothing
+	allRules, err = sm.ImportAlwaysAllowRules(sessionID, rules[:1])
+	if err != nil {
+		t.Fatalf("ImportAlwaysAllowRules failed: %v", err)
+	}
+	if len(allRules) != 2 {
+		t.Errorf("expected 2 rules after re-import, got %d", len(allRules))
+	}
+
+	if _, err := sm.ImportAlwaysAllowRules(sessionID, []AlwaysAllowRule{{}}); err == nil {
+		t.Error("expected error for rule without tool")
+	}
+
+	if _, err := sm.ImportAlwaysAllowRules(uuid.New(), rules); err == nil {
+		t.Error("expected error for unknown session")
+	}
+}
+
+func TestGetStatsByModel(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	now := time.Now()
+	sessions := []*SessionMetadata{
+		{ID: uuid.New(), Status: "ended", CreatedAt: now, UpdatedAt: now, CostUSD: 1.5, NumTurns: 3, MessageCount: 6, ModelName: "opus"},
+		{ID: uuid.New(), Status: "ended", CreatedAt: now, UpdatedAt: now, CostUSD: 0.5, NumTurns: 2, MessageCount: 4, ModelName: "opus"},
+		{ID: uuid.New(), Status: "idle", CreatedAt: now, UpdatedAt: now, CostUSD: 0.25, NumTurns: 1, MessageCount: 2, ModelName: "sonnet"},
+	}
+	for _, session := range sessions {
+		if err := sm.storage.SaveSession(session); err != nil {
+			t.Fatalf("Failed to save session: %v", err)
+		}
+	}
+
+	stats, err := sm.GetStatsByModel()
+	if err != nil {
+		t.Fatalf("GetStatsByModel failed: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("Expected 2 models, got %d: %+v", len(stats), stats)
+	}
+
+	opus := stats[0]
+	if opus.ModelName != "opus" || opus.SessionCount != 2 || opus.TotalCostUSD != 2.0 ||
+		opus.TotalTurns != 5 || opus.TotalMessages != 10 {
+		t.Errorf("Unexpected opus stats: %+v", opus)
+	}
+	if stats[1].ModelName != "sonnet" || stats[1].SessionCount != 1 {
+		t.Errorf("Unexpected sonnet stats: %+v", stats[1])
+	}
+}
+
+func TestGetStatsByProvider(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	now := time.Now()
+	glm := "glm"
+	glmOptions, _ := json.Marshal(SessionOptions{Provider: &glm})
+
+	glmSession := &SessionMetadata{ID: uuid.New(), Status: "ended", CreatedAt: now, UpdatedAt: now, CostUSD: 0.5, OptionsJSON: string(glmOptions)}
+	defaultSession := &SessionMetadata{ID: uuid.New(), Status: "ended", CreatedAt: now, UpdatedAt: now, CostUSD: 2.0}
+	for _, session := range []*SessionMetadata{glmSession, defaultSession} {
+		if err := sm.storage.SaveSession(session); err != nil {
+			t.Fatalf("Failed to save session: %v", err)
+		}
+	}
+
+	result := func(input, output float64) *types.ResultMessage {
+		return &types.ResultMessage{
+			Type:  "result",
+			Usage: map[string]interface{}{"input_tokens": input, "output_tokens": output},
+		}
+	}
+	sm.persistSDKMessage(glmSession.ID, 1, result(100, 400), false)
+	sm.persistSDKMessage(glmSession.ID, 2, result(50, 100), false)
+	sm.persistSDKMessage(defaultSession.ID, 1, result(200, 300), false)
+
+	stats, err := sm.GetStatsByProvider()
+	if err != nil {
+		t.Fatalf("GetStatsByProvider failed: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("Expected 2 providers, got %d: %+v", len(stats), stats)
+	}
+
+	if stats[0].ProviderID != "" || stats[0].TotalCostUSD != 2.0 || stats[0].OutputTokens != 300 {
+		t.Errorf("Unexpected default provider stats: %+v", stats[0])
+	}
+	glmStat := stats[1]
+	if glmStat.ProviderID != "glm" || glmStat.SessionCount != 1 || glmStat.InputTokens != 150 ||
+		glmStat.OutputTokens != 500 || glmStat.OutputTokensPerUSD != 1000 {
+		t.Errorf("Unexpected glm stats: %+v", glmStat)
+	}
+
+	messages, _, err := sm.GetMessages(glmSession.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("GetMessages failed: %v", err)
+	}
+	if len(messages) != 2 || messages[0].TokensUsed != 500 {
+		t.Errorf("Expected result message to record 500 tokens, got %+v", messages)
+	}
+}
+
+func TestGetRecentAssistantMessages(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	now := time.Now()
+	agentName := "reviewer"
+	optionsJSON, _ := json.Marshal(SessionOptions{AgentName: &agentName})
+	first := &SessionMetadata{ID: uuid.New(), Status: "idle", CreatedAt: now, UpdatedAt: now, ModelName: "opus", OptionsJSON: string(optionsJSON)}
+	second := &SessionMetadata{ID: uuid.New(), Status: "ended", CreatedAt: now, UpdatedAt: now, GitBranch: "main"}
+	for _, session := range []*SessionMetadata{first, second} {
+		if err := sm.storage.SaveSession(session); err != nil {
+			t.Fatalf("Failed to save session: %v", err)
+		}
+	}
+
+	records := []*MessageRecord{
+		{ID: uuid.New(), SessionID: first.ID, Sequence: 1, Role: "user", Content: "question", Timestamp: now.Add(-4 * time.Minute)},
+		{ID: uuid.New(), SessionID: first.ID, Sequence: 2, Role: "assistant", Content: "oldest answer", Timestamp: now.Add(-3 * time.Minute)},
+		{ID: uuid.New(), SessionID: second.ID, Sequence: 1, Role: "assistant", Content: "newest answer", Timestamp: now.Add(-1 * time.Minute)},
+		{ID: uuid.New(), SessionID: second.ID, Sequence: 2, Role: "assistant", Content: "", Timestamp: now},
+		{ID: uuid.New(), SessionID: first.ID, Sequence: 3, Role: "assistant", Content: "middle answer", Timestamp: now.Add(-2 * time.Minute)},
+	}
+	for _, record := range records {
+		if err := sm.storage.SaveMessage(record); err != nil {
+			t.Fatalf("Failed to save message: %v", err)
+		}
+	}
+
+	recent, err := sm.GetRecentAssistantMessages(2)
+	if err != nil {
+		t.Fatalf("GetRecentAssistantMessages failed: %v", err)
+	}
+	if len(recent) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(recent))
+	}
+	if recent[0].Content != "newest answer" || recent[0].SessionID != second.ID || recent[0].GitBranch != "main" {
+		t.Errorf("Unexpected newest message: %+v", recent[0])
+	}
+	if recent[1].Content != "middle answer" || recent[1].ModelName != "opus" || recent[1].AgentName != "reviewer" {
+		t.Errorf("Unexpected second message: %+v", recent[1])
+	}
+}
+
+func TestPauseCleanup(t *testing.T) {
+	sm := newTestSessionManager(t)
+	sm.config.SessionRetentionDays = 7
+
+	sessionID := uuid.New()
+	ended := time.Now().AddDate(0, 0, -30)
+	if err := sm.storage.SaveSession(&SessionMetadata{
+		ID:        sessionID,
+		Status:    string(SessionStatusEnded),
+		CreatedAt: ended,
+		UpdatedAt: ended,
+		EndedAt:   &ended,
+	}); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+
+	sm.PauseCleanup()
+	if !sm.CleanupPaused() {
+		t.Fatal("expected cleanup to be paused")
+	}
+
+	sm.runCleanup()
+	if meta, err := sm.storage.GetSession(sessionID); err != nil || meta == nil {
+		t.Fatalf("expected session to survive paused cleanup, got %v, %v", meta, err)
+	}
+
+	sm.ResumeCleanup()
+	if sm.CleanupPaused() {
+		t.Fatal("expected cleanup to be resumed")
+	}
+
+	sm.runCleanup()
+	if meta, _ := sm.storage.GetSession(sessionID); meta != nil {
+		t.Error("expected session to be deleted after cleanup resumed")
+	}
+}
+
+func TestStopGeneration(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	if err := sm.StopGeneration(uuid.New()); err == nil {
+		t.Error("expected error for unknown session")
+	}
+
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+
+	if err := sm.StopGeneration(sessionID); err == nil {
+		t.Error("expected error when no response is in progress")
+	}
+
+	sm.mu.Lock()
+	session.Status = SessionStatusProcessing
+	sm.mu.Unlock()
+
+	if err := sm.StopGeneration(sessionID); err != nil {
+		t.Fatalf("StopGeneration failed: %v", err)
+	}
+	if !session.stopGeneration.Load() {
+		t.Error("expected stop flag to be set")
+	}
+	if session.ctx.Err() != nil {
+		t.Error("expected session context to stay alive")
+	}
+
+	// Tool requests are refused while the stop is in effect
+	result, _ := sm.createPermissionCallback(session)(context.Background(), "Bash", map[string]interface{}{"command": "ls"}, types.ToolPermissionContext{})
+	if deny, ok := result.(types.PermissionResultDeny); !ok || !deny.Interrupt {
+		t.Errorf("expected interrupting deny after stop, got %#v", result)
+	}
+}
+
+func TestStopGenerationClosesClient(t *testing.T) {
+	logPath := installFakeClaudeCLI(t, nil)
+	t.Setenv(fakeCLIHangEnv, "1")
+	sm := newTestSessionManager(t)
+
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	defer sm.EndSession(sessionID)
+	session, _ := sm.GetSession(sessionID)
+
+	if err := sm.SendPrompt(sessionID, "write a long essay"); err != nil {
+		t.Fatalf("SendPrompt failed: %v", err)
+	}
+	waitForFakeCLIEvent(t, logPath, "prompt")
+
+	if err := sm.StopGeneration(sessionID); err != nil {
+		t.Fatalf("StopGeneration failed: %v", err)
+	}
+
+	// Closing the client ends the SDK's message stream, and stream consumers see the turn finish
+	select {
+	case msg := <-session.responseChan:
+		if result, ok := msg.(*types.ResultMessage); !ok || result.Subtype != "stopped" {
+			t.Errorf("expected stopped result, got %#v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the stopped result")
+	}
+
+	session.mu.Lock()
+	client := session.client
+	session.mu.Unlock()
+	if client != nil {
+		t.Error("expected the client to be closed")
+	}
+	if session.ctx.Err() != nil {
+		t.Error("expected session context to stay alive")
+	}
+}
+
+func TestForceKillSession(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	// Unknown sessions are confirmed without error
+	wasRunning, err := sm.ForceKillSession(uuid.New())
+	if err != nil {
+		t.Fatalf("ForceKillSession failed for unknown session: %v", err)
+	}
+	if wasRunning {
+		t.Error("expected unknown session to be reported as not running")
+	}
+
+	killedID := uuid.New()
+	otherID := uuid.New()
+	for _, id := range []uuid.UUID{killedID, otherID} {
+		if _, err := sm.CreateSession(id, SessionOptions{}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+	}
+	session, err := sm.GetSession(killedID)
+	if err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+
+	// A wedged goroutine holding the session lock must not block the kill
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	wasRunning, err = sm.ForceKillSession(killedID)
+	if err != nil {
+		t.Fatalf("ForceKillSession failed: %v", err)
+	}
+	if !wasRunning {
+		t.Error("expected session to be reported as running")
+	}
+	if session.ctx.Err() == nil {
+		t.Error("expected session context to be cancelled")
+	}
+	if _, err := sm.GetSession(killedID); err == nil {
+		t.Error("expected killed session to be removed from memory")
+	}
+	if _, err := sm.GetSession(otherID); err != nil {
+		t.Errorf("expected other session to be untouched: %v", err)
+	}
+
+	meta, err := sm.storage.GetSession(killedID)
+	if err != nil {
+		t.Fatalf("Failed to load killed session: %v", err)
+	}
+	if meta.Status != string(SessionStatusError) {
+		t.Errorf("expected stored status %q, got %q", SessionStatusError, meta.Status)
+	}
+
+	// Killing again is confirmed and leaves the session errored
+	if wasRunning, err := sm.ForceKillSession(killedID); err != nil || wasRunning {
+		t.Errorf("expected repeat kill to succeed as not running, got %v, %v", wasRunning, err)
+	}
+}
+
+func TestOnStatusChange(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	var changes []SessionStatus
+	sm.OnStatusChange(func(sessionID uuid.UUID, status SessionStatus) {
+		changes = append(changes, status)
+	})
+
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+
+	sm.mu.Lock()
+	sm.setStatus(session, SessionStatusProcessing)
+	sm.setStatus(session, SessionStatusProcessing) // unchanged, no event
+	sm.mu.Unlock()
+
+	if err := sm.InterruptSession(sessionID); err != nil {
+		t.Fatalf("InterruptSession failed: %v", err)
+	}
+	if err := sm.EndSession(sessionID); err != nil {
+		t.Fatalf("EndSession failed: %v", err)
+	}
+
+	want := []SessionStatus{SessionStatusProcessing, SessionStatusIdle, SessionStatusEnded}
+	if len(changes) != len(want) {
+		t.Fatalf("expected status changes %v, got %v", want, changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("change %d: expected %s, got %s", i, want[i], changes[i])
+		}
+	}
+}
+
+func TestPermissionCallbackTimeouts(t *testing.T) {
+	sm := newTestSessionManager(t)
+	sm.config.PermissionResponseTimeout = 20 * time.Millisecond
+	sm.config.PermissionSendTimeout = 20 * time.Millisecond
+
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+	session.SetWebSocketConnected(true)
+	callback := sm.createPermissionCallback(session)
+
+	// The request is queued but nobody answers it
+	result, err := callback(context.Background(), "Bash", map[string]interface{}{"command": "ls"}, types.ToolPermissionContext{})
+	if err != nil {
+		t.Fatalf("callback failed: %v", err)
+	}
+	deny, ok := result.(types.PermissionResultDeny)
+	if !ok || !strings.Contains(deny.Message, "timed out after 20ms") {
+		t.Errorf("expected response timeout deny, got %#v", result)
+	}
+
+	// Fill the request channel so the request cannot be handed to the frontend
+	for len(session.permissionReqChan) < cap(session.permissionReqChan) {
+		session.permissionReqChan <- &PermissionRequest{}
+	}
+	result, _ = callback(context.Background(), "Bash", map[string]interface{}{"command": "ls"}, types.ToolPermissionContext{})
+	if deny, ok := result.(types.PermissionResultDeny); !ok || deny.Message != "Permission request timeout" {
+		t.Errorf("expected send timeout deny, got %#v", result)
+	}
+}
+
+func TestPermissionCallbackAlwaysDenyRules(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	sessionID := uuid.New()
+	opts := SessionOptions{
+		AlwaysAllowRules: []AlwaysAllowRule{
+			{Tool: "Read", MatchMode: RuleMatchPattern, Pattern: GeneratePattern("Read", nil)},
+		},
+		AlwaysDenyRules: []AlwaysDenyRule{
+			{Tool: "Read", MatchMode: RuleMatchPattern, Pattern: &RulePattern{FilePathPattern: stringPtr("**/.env")}, Description: "Never read .env"},
+		},
+	}
+	if _, err := sm.CreateSession(sessionID, opts); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+	callback := sm.createPermissionCallback(session)
+
+	// Deny rules win over a matching allow rule
+	result, err := callback(context.Background(), "Read", map[string]interface{}{"file_path": "/srv/app/.env"}, types.ToolPermissionContext{})
+	if err != nil {
+		t.Fatalf("callback failed: %v", err)
+	}
+	deny, ok := result.(types.PermissionResultDeny)
+	if !ok || !strings.Contains(deny.Message, "Never read .env") {
+		t.Errorf("expected always-deny result, got %#v", result)
+	}
+
+	// Other files still fall through to the allow rule
+	result, _ = callback(context.Background(), "Read", map[string]interface{}{"file_path": "/srv/app/main.go"}, types.ToolPermissionContext{})
+	if _, ok := result.(types.PermissionResultAllow); !ok {
+		t.Errorf("expected allow result, got %#v", result)
+	}
+}
+
+func TestPermissionCallbackCancel(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+	session.SetWebSocketConnected(true)
+	callback := sm.createPermissionCallback(session)
+
+	results := make(chan interface{}, 1)
+	go func() {
+		result, _ := callback(context.Background(), "Bash", map[string]interface{}{"command": "ls"}, types.ToolPermissionContext{})
+		results <- result
+	}()
+
+	var req *PermissionRequest
+	select {
+	case req = <-session.permissionReqChan:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for permission request")
+	}
+
+	if err := sm.CancelPermission(sessionID, req.RequestID); err != nil {
+		t.Fatalf("CancelPermission failed: %v", err)
+	}
+	if err := sm.CancelPermission(sessionID, req.RequestID); err == nil {
+		t.Error("Expected error cancelling an already cancelled request")
+	}
+
+	select {
+	case result := <-results:
+		deny, ok := result.(types.PermissionResultDeny)
+		if !ok || !deny.Interrupt {
+			t.Errorf("Expected interrupting deny for a cancelled request, got %#v", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for permission callback")
+	}
+}
+
+func TestSendPromptAlwaysDenyRules(t *testing.T) {
+	logPath := installFakeClaudeCLI(t, map[string]interface{}{
+		"tool_name": "Read",
+		"input":     map[string]interface{}{"file_path": "/srv/app/.env"},
+	})
+	sm := newTestSessionManager(t)
+
+	sessionID := uuid.New()
+	workDir := t.TempDir()
+	opts := SessionOptions{
+		WorkingDirectory: &workDir,
+		AlwaysDenyRules: []AlwaysDenyRule{
+			{Tool: "Read", MatchMode: RuleMatchPattern, Pattern: &RulePattern{FilePathPattern: stringPtr(".env")}, Description: "Never read .env"},
+		},
+	}
+	if _, err := sm.CreateSession(sessionID, opts); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	defer sm.EndSession(sessionID)
+
+	// The WebSocket is connected, so only the deny rule can refuse without prompting
+	session, _ := sm.GetSession(sessionID)
+	session.SetWebSocketConnected(true)
+
+	if err := sm.SendPrompt(sessionID, "show me the secrets"); err != nil {
+		t.Fatalf("SendPrompt failed: %v", err)
+	}
+
+	event := waitForFakeCLIEvent(t, logPath, "permission")
+	if event.Response["behavior"] != "deny" {
+		t.Fatalf("Expected deny, got %v", event.Response)
+	}
+	if msg, _ := event.Response["message"].(string); !strings.Contains(msg, "Never read .env") {
+		t.Errorf("Expected always-deny message, got %q", msg)
+	}
+	if len(session.permissionReqChan) != 0 {
+		t.Error("Expected no permission request to reach the user")
+	}
+}
+
+func TestPermissionTimeoutDefaults(t *testing.T) {
+	config := &Config{}
+	if got := config.permissionResponseTimeout(); got != defaultPermissionResponseTimeout {
+		t.Errorf("expected default response timeout %s, got %s", defaultPermissionResponseTimeout, got)
+	}
+	if got := config.permissionSendTimeout(); got != defaultPermissionSendTimeout {
+		t.Errorf("expected default send timeout %s, got %s", defaultPermissionSendTimeout, got)
+	}
+}
+
+func TestListSessionsByBranch(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	branches := map[uuid.UUID]string{
+		uuid.New(): "main",
+		uuid.New(): "feature/x",
+		uuid.New(): "main",
+	}
+	for id, branch := range branches {
+		err := sm.storage.SaveSession(&SessionMetadata{
+			ID:        id,
+			Status:    string(SessionStatusIdle),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			GitBranch: branch,
+		})
+		if err != nil {
+			t.Fatalf("SaveSession failed: %v", err)
+		}
+	}
+
+	sessions, err := sm.ListSessionsByBranch("main", "all")
+	if err != nil {
+		t.Fatalf("ListSessionsByBranch failed: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions on main, got %d", len(sessions))
+	}
+	for _, s := range sessions {
+		if s.GitBranch != "main" {
+			t.Errorf("Expected branch main, got %q", s.GitBranch)
+		}
+	}
+
+	sessions, err = sm.ListSessionsByBranch("main", "ended")
+	if err != nil {
+		t.Fatalf("ListSessionsByBranch failed: %v", err)
+	}
+	if len(sessions) != 0 {
+		t.Errorf("Expected no ended sessions on main, got %d", len(sessions))
+	}
+
+	sessions, err = sm.ListSessionsByBranch("missing", "all")
+	if err != nil {
+		t.Fatalf("ListSessionsByBranch failed: %v", err)
+	}
+	if len(sessions) != 0 {
+		t.Errorf("Expected no sessions for unknown branch, got %d", len(sessions))
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"Invalid API key · Please run /login", ErrorCodeAuth},
+		{`API Error: 401 {"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`, ErrorCodeAuth},
+		{"OAuth token has expired", ErrorCodeAuth},
+		{"failed to connect: claude CLI not found", ""},
+		{"context deadline exceeded", ""},
+	}
+
+	for _, tt := range tests {
+		if got := errorCode(tt.message); got != tt.want {
+			t.Errorf("errorCode(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestFailSessionReportsAuthError(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	var reported []SessionError
+	sm.OnSessionError(func(sessErr SessionError) {
+		reported = append(reported, sessErr)
+	})
+
+	provider := "glm"
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{Provider: &provider}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+
+	sm.mu.Lock()
+	sm.failSession(session, "Invalid API key · Please run /login")
+	sm.updateSessionInDB(&session.Session)
+	sm.mu.Unlock()
+
+	if session.Status != SessionStatusError || session.ErrorCode != ErrorCodeAuth {
+		t.Errorf("Expected error status with auth code, got %s / %q", session.Status, session.ErrorCode)
+	}
+	if len(reported) != 1 {
+		t.Fatalf("Expected 1 reported error, got %d", len(reported))
+	}
+	if reported[0].Code != ErrorCodeAuth || reported[0].Provider != provider || reported[0].SessionID != sessionID {
+		t.Errorf("Unexpected reported error: %+v", reported[0])
+	}
+
+	// The code is recovered when the session is read back from storage
+	sessions, err := sm.ListAllSessions("error")
+	if err != nil {
+		t.Fatalf("ListAllSessions failed: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ErrorCode != ErrorCodeAuth {
+		t.Errorf("Expected stored session with auth code, got %+v", sessions)
+	}
+}
+
+func TestDefaultPermissionMode(t *testing.T) {
+	sm := newTestSessionManager(t)
+	sm.config.DefaultPermissionMode = PermissionModeAllowAll
+
+	defaulted := uuid.New()
+	session, err := sm.CreateSession(defaulted, SessionOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if session.Options.PermissionMode == nil || *session.Options.PermissionMode != PermissionModeAllowAll {
+		t.Errorf("Expected default permission mode %q, got %v", PermissionModeAllowAll, session.Options.PermissionMode)
+	}
+
+	// An explicit mode from the client wins
+	readOnly := PermissionModeReadOnly
+	session, err = sm.CreateSession(uuid.New(), SessionOptions{PermissionMode: &readOnly})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if *session.Options.PermissionMode != PermissionModeReadOnly {
+		t.Errorf("Expected explicit mode to be kept, got %q", *session.Options.PermissionMode)
+	}
+
+	if err := ValidatePermissionMode("bypass"); err == nil {
+		t.Error("Expected error for unknown permission mode")
+	}
+	if _, err := NewSessionManager(&Config{DefaultPermissionMode: "bypass"}, sm.db); err == nil {
+		t.Error("Expected NewSessionManager to reject an invalid default permission mode")
+	}
+}
+
+func TestFlagMessage(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	for i, role := range []string{"user", "assistant", "user", "assistant"} {
+		if err := sm.saveMessageToDB(sessionID, i+1, role, "message", "", nil); err != nil {
+			t.Fatalf("Failed to save message: %v", err)
+		}
+	}
+
+	if err := sm.FlagMessage(sessionID, 4, true); err != nil {
+		t.Fatalf("FlagMessage failed: %v", err)
+	}
+	if err := sm.FlagMessage(sessionID, 2, true); err != nil {
+		t.Fatalf("FlagMessage failed: %v", err)
+	}
+	if err := sm.FlagMessage(sessionID, 99, true); err == nil {
+		t.Error("Expected error for unknown sequence")
+	}
+
+	flagged, err := sm.GetFlaggedMessages(sessionID)
+	if err != nil {
+		t.Fatalf("GetFlaggedMessages failed: %v", err)
+	}
+	if len(flagged) != 2 || flagged[0].Sequence != 2 || flagged[1].Sequence != 4 || !flagged[0].Flagged {
+		t.Fatalf("Unexpected flagged messages: %+v", flagged)
+	}
+
+	if err := sm.FlagMessage(sessionID, 2, false); err != nil {
+		t.Fatalf("FlagMessage (unflag) failed: %v", err)
+	}
+	messages, _, err := sm.GetMessages(sessionID, 10, 0)
+	if err != nil {
+		t.Fatalf("GetMessages failed: %v", err)
+	}
+	for _, msg := range messages {
+		if want := msg.Sequence == 4; msg.Flagged != want {
+			t.Errorf("Message %d: expected flagged=%v, got %v", msg.Sequence, want, msg.Flagged)
+		}
+	}
+}
+
+func TestReceiveQueryResponsesTracksActivity(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	session, _ := sm.GetSession(sessionID)
+
+	stale := time.Now().Add(-time.Hour)
+	sm.mu.Lock()
+	session.UpdatedAt = stale
+	sm.mu.Unlock()
+
+	messages := make(chan types.Message)
+	done := make(chan struct{})
+	go func() {
+		sm.receiveQueryResponses(session, messages)
+		close(done)
+	}()
+	defer func() {
+		close(messages)
+		<-done
+	}()
+
+	// Mid-turn, each message counts as activity
+	messages <- &types.AssistantMessage{Type: "assistant"}
+	<-session.responseChan
+
+	sm.mu.RLock()
+	updated := session.UpdatedAt
+	sm.mu.RUnlock()
+	if !updated.After(stale) {
+		t.Errorf("expected UpdatedAt to advance on a received message, still %s", updated)
+	}
+}
+
+func TestAllowAllSessionStillDeniesDisabledTools(t *testing.T) {
+	for _, tt := range []struct {
+		tool     string
+		behavior string
+	}{
+		{"Bash", "deny"},
+		{"Read", "allow"},
+	} {
+		t.Run(tt.tool, func(t *testing.T) {
+			logPath := installFakeClaudeCLI(t, map[string]interface{}{
+				"tool_name": tt.tool,
+				"input":     map[string]interface{}{},
+			})
+			sm := newTestSessionManager(t)
+			sm.config.DisabledTools = []string{"Bash"}
+
+			// No WebSocket is attached: an allowed tool must not need a prompt
+			mode := PermissionModeAllowAll
+			sessionID := uuid.New()
+			if _, err := sm.CreateSession(sessionID, SessionOptions{PermissionMode: &mode}); err != nil {
+				t.Fatalf("Failed to create session: %v", err)
+			}
+			defer sm.EndSession(sessionID)
+
+			if err := sm.SendPrompt(sessionID, "run it"); err != nil {
+				t.Fatalf("SendPrompt failed: %v", err)
+			}
+
+			args := strings.Join(waitForFakeCLIEvent(t, logPath, "args").Args, " ")
+			if !strings.Contains(args, "--permission-mode default") {
+				t.Errorf("Expected allow-all with disabled tools to run in default mode, got %q", args)
+			}
+			event := waitForFakeCLIEvent(t, logPath, "permission")
+			if event.Response["behavior"] != tt.behavior {
+				t.Errorf("Expected %s for %s, got %v", tt.behavior, tt.tool, event.Response)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package agents
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/schlunsen/claude-control-terminal/internal/logging"
+)
+
+// transcriptPageSize is how many messages are read per storage query when
+// loading a whole transcript
+const transcriptPageSize = 500
+
+// ExportMarkdown renders a session and its persisted messages as markdown.
+// Works for ended sessions too. Messages are persisted as soon as they arrive
+// from the SDK, so the transcript is current up to the last received message.
+//
+// With includePending, the in-memory state of a live session is flushed to
+// storage first and the export ends with a section describing what is still
+// in flight (a turn being generated, context notes not yet delivered). That
+// content reflects the moment of the export and may change as the turn finishes.
+func (sm *SessionManager) ExportMarkdown(sessionID uuid.UUID, includePending bool) (string, error) {
+	var live *AgentSession
+	var liveSnapshot Session
+	if includePending {
+		if session, err := sm.GetSession(sessionID); err == nil {
+			live = session
+			sm.mu.Lock()
+			if err := sm.updateSessionInDB(&session.Session); err != nil {
+				logging.Warning("Failed to flush session %s before export: %v", sessionID, err)
+			}
+			liveSnapshot = session.Session
+			sm.mu.Unlock()
+		}
+	}
+
+	meta, err := sm.storage.GetSession(sessionID)
+	if err != nil {
+		return "", err
+	}
+
+	messages, err := sm.loadTranscript(sessionID)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	writeMarkdownHeader(&b, meta)
+	for _, msg := range messages {
+		writeMarkdownMessage(&b, msg)
+	}
+
+	if live != nil {
+		live.pendingContextMu.Lock()
+		queuedNotes := len(live.pendingContext)
+		live.pendingContextMu.Unlock()
+		writeMarkdownPending(&b, liveSnapshot, queuedNotes, time.Now())
+	}
+
+	return b.String(), nil
+}
+
+// loadTranscript reads every stored message of a session in conversation order
+func (sm *SessionManager) loadTranscript(sessionID uuid.UUID) ([]*MessageRecord, error) {
+	var messages []*MessageRecord
+	for offset := 0; ; offset += transcriptPageSize {
+		page, hasMore, err := sm.storage.GetMessages(sessionID, transcriptPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, page...)
+		if !hasMore {
+			return messages, nil
+		}
+	}
+}
+
+// writeMarkdownHeader writes the session title and metadata list
+func writeMarkdownHeader(b *strings.Builder, meta *SessionMetadata) {
+	fmt.Fprintf(b, "# Agent session %s\n\n", meta.ID)
+	fmt.Fprintf(b, "- **Status:** %s\n", meta.Status)
+	if meta.ModelName != "" {
+		fmt.Fprintf(b, "- **Model:** %s\n", meta.ModelName)
+	}
+	fmt.Fprintf(b, "- **Created:** %s\n", meta.CreatedAt.Format(time.RFC3339))
+	if meta.EndedAt != nil {
+		fmt.Fprintf(b, "- **Ended:** %s\n", meta.EndedAt.Format(time.RFC3339))
+	}
+	if meta.GitBranch != "" {
+		fmt.Fprintf(b, "- **Branch:** %s\n", meta.GitBranch)
+	}
+	fmt.Fprintf(b, "- **Turns:** %d\n", meta.NumTurns)
+	fmt.Fprintf(b, "- **Cost:** $%.4f\n", meta.CostUSD)
+	b.WriteString("\n")
+}
+
+// writeMarkdownMessage writes one persisted message as a markdown section
+func writeMarkdownMessage(b *strings.Builder, msg *MessageRecord) {
+	title := "System"
+	switch msg.Role {
+	case "user":
+		title = "User"
+	case "assistant":
+		title = "Assistant"
+	}
+	fmt.Fprintf(b, "## %s (%s)\n\n", title, msg.Timestamp.Format("2006-01-02 15:04:05"))
+
+	if msg.ThinkingContent != "" {
+		b.WriteString("<details><summary>Thinking</summary>\n\n")
+		b.WriteString(msg.ThinkingContent)
+		b.WriteString("\n\n</details>\n\n")
+	}
+
+	if msg.Content != "" {
+		b.WriteString(msg.Content)
+		b.WriteString("\n\n")
+	}
+
+	if msg.Role == "assistant" && len(msg.ToolUses) > 0 {
+		var toolUses []struct {
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
+		}
+		if err := json.Unmarshal(msg.ToolUses, &toolUses); err == nil {
+			for _, tool := range toolUses {
+				fmt.Fprintf(b, "**Tool:** `%s`\n\n```json\n%s\n```\n\n", tool.Name, tool.Input)
+			}
+		}
+	}
+}
+
+// writeMarkdownPending writes the in-flight state of a live session
+func writeMarkdownPending(b *strings.Builder, session Session, queuedNotes int, now time.Time) {
+	b.WriteString("---\n\n## Pending\n\n")
+	fmt.Fprintf(b, "_Live state as of %s. This section may change once the current turn finishes._\n\n", now.Format(time.RFC3339))
+	fmt.Fprintf(b, "- **Status:** %s\n", session.Status)
+	if session.Status == SessionStatusProcessing {
+		b.WriteString("- A response is still being generated; messages after the last one above have not arrived yet.\n")
+	}
+	if queuedNotes > 0 {
+		fmt.Fprintf(b, "- %d context note(s) will be sent with the next prompt.\n", queuedNotes)
+	}
+	b.WriteString("\n")
+}
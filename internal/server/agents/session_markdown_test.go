@@ -0,0 +1,60 @@
+package agents
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestExportMarkdown(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	if _, err := sm.ExportMarkdown(uuid.New(), false); err == nil {
+		t.Error("Expected error for unknown session")
+	}
+
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	if err := sm.saveMessageToDB(sessionID, 1, "user", "List the files", "", nil); err != nil {
+		t.Fatalf("Failed to save message: %v", err)
+	}
+	if err := sm.saveMessageToDB(sessionID, 2, "assistant", "Here they are", "Checking the directory", []map[string]interface{}{
+		{"id": "tool_1", "name": "Glob", "input": map[string]interface{}{"pattern": "*"}},
+	}); err != nil {
+		t.Fatalf("Failed to save message: %v", err)
+	}
+	if _, err := sm.InjectContext(sessionID, "ignore the vendor directory"); err != nil {
+		t.Fatalf("InjectContext failed: %v", err)
+	}
+
+	markdown, err := sm.ExportMarkdown(sessionID, false)
+	if err != nil {
+		t.Fatalf("ExportMarkdown failed: %v", err)
+	}
+	for _, want := range []string{
+		"# Agent session " + sessionID.String(),
+		"## User", "List the files",
+		"## Assistant", "Here they are", "Checking the directory",
+		"**Tool:** `Glob`", `"pattern":"*"`,
+		"ignore the vendor directory",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Expected export to contain %q:\n%s", want, markdown)
+		}
+	}
+	if strings.Contains(markdown, "## Pending") {
+		t.Error("Pending section should only be included on request")
+	}
+
+	markdown, err = sm.ExportMarkdown(sessionID, true)
+	if err != nil {
+		t.Fatalf("ExportMarkdown failed: %v", err)
+	}
+	if !strings.Contains(markdown, "## Pending") || !strings.Contains(markdown, "1 context note(s)") {
+		t.Errorf("Expected pending section with the queued note:\n%s", markdown)
+	}
+}
@@ -0,0 +1,17 @@
+package agents
+
+import "errors"
+
+// errMCPServersUnsupported is returned for sessions that name MCP servers.
+// claude-agent-sdk-go v0.2.4 never passes ClaudeAgentOptions.McpServers to the
+// CLI (there is no --mcp-config or --strict-mcp-config), so the session would
+// still get every configured MCP server. Reject the option until it does.
+var errMCPServersUnsupported = errors.New("mcp_servers is not supported: the agent SDK does not forward MCP configuration to the Claude CLI, so a session cannot be limited to specific MCP servers")
+
+// validateMCPServers rejects sessions that try to restrict their MCP servers
+func validateMCPServers(options SessionOptions) error {
+	if len(options.MCPServers) > 0 {
+		return errMCPServersUnsupported
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	claude "github.com/schlunsen/claude-agent-sdk-go"
+	"github.com/schlunsen/claude-agent-sdk-go/types"
+)
+
+func TestSessionMCPServersRejected(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	_, err := sm.CreateSession(uuid.New(), SessionOptions{MCPServers: []string{"postgres"}})
+	if !errors.Is(err, errMCPServersUnsupported) {
+		t.Errorf("expected mcp_servers to be rejected as unsupported, got %v", err)
+	}
+
+	if _, err := sm.CreateSession(uuid.New(), SessionOptions{}); err != nil {
+		t.Errorf("expected session without mcp_servers to be created, got %v", err)
+	}
+}
+
+// TestSDKDoesNotForwardMCPServers pins the reason mcp_servers is rejected. If it
+// fails, the SDK now passes MCP config to the CLI and the option can be supported.
+func TestSDKDoesNotForwardMCPServers(t *testing.T) {
+	logPath := installFakeClaudeCLI(t, nil)
+
+	opts := types.NewClaudeAgentOptions().
+		WithCanUseTool(func(context.Context, string, map[string]interface{}, types.ToolPermissionContext) (interface{}, error) {
+			return types.PermissionResultAllow{}, nil
+		}).
+		WithMcpServers(map[string]interface{}{
+			"postgres": map[string]interface{}{"command": "pg-mcp"},
+		})
+
+	ctx := context.Background()
+	client, err := claude.NewClient(ctx, opts)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer client.Close(ctx)
+
+	args := waitForFakeCLIEvent(t, logPath, "args").Args
+	for _, arg := range args {
+		if strings.Contains(arg, "mcp") {
+			t.Errorf("SDK passed MCP config to the CLI (%q); mcp_servers can now be supported", args)
+		}
+	}
+}
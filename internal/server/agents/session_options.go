@@ -0,0 +1,137 @@
+package agents
+
+import (
+	"database/sql"
+
+	"github.com/schlunsen/claude-agent-sdk-go/types"
+	"github.com/schlunsen/claude-control-terminal/internal/logging"
+)
+
+// sessionSettings are a session's effective settings after applying provider
+// defaults and the manager config. Prompts and the debug view both use them.
+type sessionSettings struct {
+	permissionMode string
+	sdkPermission  types.PermissionMode
+	provider       string
+	model          string
+	baseURL        string
+	apiKey         string
+	apiKeySource   string // "session", "provider" or "config"
+	workingDir     string
+	resumeID       string
+}
+
+// resolveSettings computes a session's settings from its SessionOptions,
+// provider defaults and the manager config
+func (sm *SessionManager) resolveSettings(session *AgentSession) *sessionSettings {
+	settings := &sessionSettings{
+		permissionMode: PermissionModeDefault,
+		sdkPermission:  types.PermissionModeDefault,
+	}
+
+	if session.Options.PermissionMode != nil && *session.Options.PermissionMode != "" {
+		settings.permissionMode = *session.Options.PermissionMode
+		// Bypass mode never calls the permission callback, so disabled tools would
+		// run. With tools disabled, allow-all stays in default mode and the
+		// callback approves everything that isn't disabled.
+		if settings.permissionMode == PermissionModeAllowAll && len(sm.config.DisabledTools) == 0 {
+			settings.sdkPermission = types.PermissionModeBypassPermissions
+		}
+	}
+
+	var provider *providerDefaults
+	if session.Options.Provider != nil && *session.Options.Provider != "" {
+		settings.provider = *session.Options.Provider
+		p, err := sm.loadProviderDefaults(settings.provider)
+		if err == sql.ErrNoRows {
+			logging.Warning("No API key found for provider: %s - configure it via TUI first", settings.provider)
+		} else if err != nil {
+			logging.Warning("Failed to load provider config from database: %v", err)
+		}
+		provider = p
+	}
+
+	// Model: session-specific > provider default > config default
+	settings.model = sm.config.Model
+	if session.Options.Model != nil && *session.Options.Model != "" {
+		settings.model = *session.Options.Model
+	} else if provider != nil && provider.Model != "" {
+		settings.model = provider.Model
+	}
+
+	// Base URL: session-specific > provider custom URL
+	if session.Options.BaseURL != nil && *session.Options.BaseURL != "" {
+		settings.baseURL = *session.Options.BaseURL
+	} else if provider != nil && provider.BaseURL != "" {
+		settings.baseURL = provider.BaseURL
+	}
+
+	// API key: session-specific > provider > config default
+	switch {
+	case session.Options.APIKey != nil && *session.Options.APIKey != "":
+		settings.apiKey, settings.apiKeySource = *session.Options.APIKey, "session"
+	case provider != nil && provider.APIKey != "":
+		settings.apiKey, settings.apiKeySource = provider.APIKey, "provider"
+	case sm.config.APIKey != "":
+		settings.apiKey, settings.apiKeySource = sm.config.APIKey, "config"
+	}
+
+	if session.Options.WorkingDirectory != nil {
+		settings.workingDir = *session.Options.WorkingDirectory
+	}
+
+	// A session forked before forks were rejected must not resume its parent's
+	// Claude session: the SDK can't fork it, so start a new conversation instead
+	if !session.forkPending() {
+		session.mu.Lock()
+		settings.resumeID = session.ClaudeSessionID
+		session.mu.Unlock()
+	}
+
+	return settings
+}
+
+// promptOptions builds the SDK options used to stream prompts for a session.
+// Disabled tools are enforced by the permission callback, which resolveSettings
+// keeps in the loop for allow-all sessions: the SDK does not forward tool
+// allow/deny lists to the CLI.
+func (sm *SessionManager) promptOptions(session *AgentSession, settings *sessionSettings) *types.ClaudeAgentOptions {
+	opts := types.NewClaudeAgentOptions().
+		WithModel(settings.model).
+		WithPermissionMode(settings.sdkPermission).
+		WithVerbose(sm.config.Verbose).
+		WithCanUseTool(sm.createPermissionCallback(session)).
+		WithSystemPrompt("code")
+
+	// Session environment variables, then sampling limits, extended thinking and beta features
+	opts = applyEnvVars(opts, session.Options)
+	opts = applySamplingOptions(opts, session.Options)
+	opts = applyThinkingOptions(opts, session.Options)
+	opts = sm.applyConnectionSettings(opts, settings)
+
+	// Resume existing conversation if Claude session ID exists
+	if settings.resumeID != "" {
+		opts = opts.WithResume(settings.resumeID)
+	}
+
+	return opts
+}
+
+// applyConnectionSettings sets the base URL, API key and working directory
+// shared by streamed prompts and one-shot queries
+func (sm *SessionManager) applyConnectionSettings(opts *types.ClaudeAgentOptions, settings *sessionSettings) *types.ClaudeAgentOptions {
+	if settings.baseURL != "" {
+		opts = opts.WithBaseURL(settings.baseURL)
+	}
+
+	// Only set API key if provided (don't override SDK's default detection)
+	if settings.apiKey != "" {
+		opts = opts.WithEnvVar("ANTHROPIC_API_KEY", settings.apiKey)
+	}
+
+	if settings.workingDir != "" {
+		opts = opts.WithCWD(settings.workingDir)
+	}
+
+	return opts
+}
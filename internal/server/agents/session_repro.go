@@ -0,0 +1,90 @@
+package agents
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ReproScript renders a shell script that recreates a session's configuration
+// (model, working directory and options) on a server at serverURL: it creates a
+// new session over the agent WebSocket with websocat, then prints the options
+// the server resolved for it with curl. Works for ended sessions too.
+//
+// Secrets are masked: the session API key is replaced by a placeholder and
+// secret-looking environment variables keep only their last 4 characters.
+func (sm *SessionManager) ReproScript(sessionID uuid.UUID, serverURL string) (string, error) {
+	options, _, err := sm.sessionSource(sessionID)
+	if err != nil {
+		return "", err
+	}
+
+	// Pin the model the session ran with so the server default doesn't change it
+	if options.Model == nil || *options.Model == "" {
+		if meta, err := sm.storage.GetSession(sessionID); err == nil && meta.ModelName != "" {
+			model := meta.ModelName
+			options.Model = &model
+		}
+	}
+
+	options = maskReproOptions(options)
+	// Keep the <API_KEY> placeholder readable instead of \u003c-escaped
+	var optionsJSON bytes.Buffer
+	encoder := json.NewEncoder(&optionsJSON)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(options); err != nil {
+		return "", fmt.Errorf("failed to marshal session options: %w", err)
+	}
+
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&b, "# Reproduce agent session %s\n", sessionID)
+	fmt.Fprintf(&b, "#   model:             %s\n", valueOrDefault(options.Model, "(server default)"))
+	fmt.Fprintf(&b, "#   working directory: %s\n", valueOrDefault(options.WorkingDirectory, "(server default)"))
+	fmt.Fprintf(&b, "#   provider:          %s\n", valueOrDefault(options.Provider, "(default)"))
+	b.WriteString("#\n")
+	b.WriteString("# Secrets are masked: replace <API_KEY> and masked env_vars values before running.\n")
+	b.WriteString("# CCT_API_KEY is the server API key (~/.claude/analytics/.secret) when auth is enabled.\n")
+	b.WriteString("# Requires websocat (https://github.com/vi/websocat), curl and uuidgen.\n")
+	b.WriteString("set -e\n\n")
+	fmt.Fprintf(&b, "CCT_URL=\"${CCT_URL:-%s}\"\n", serverURL)
+	b.WriteString("SESSION_ID=\"$(uuidgen | tr 'A-Z' 'a-z')\"\n")
+	b.WriteString("WS_URL=\"$(printf '%s' \"$CCT_URL\" | sed 's/^http/ws/')/agent/ws\"\n\n")
+	b.WriteString("# 1. Create the session (-k accepts the server's self-signed certificate)\n")
+	fmt.Fprintf(&b, "OPTIONS=%s\n", shellQuote(strings.TrimSpace(optionsJSON.String())))
+	b.WriteString("printf '{\"type\":\"create_session\",\"session_id\":\"%s\",\"options\":%s}\\n' \"$SESSION_ID\" \"$OPTIONS\" |\n")
+	b.WriteString("  websocat -k -1 -H \"Authorization: Bearer $CCT_API_KEY\" \"$WS_URL\"\n\n")
+	b.WriteString("# 2. Show the options the server resolved for the new session\n")
+	b.WriteString("curl -sk -H \"Authorization: Bearer $CCT_API_KEY\" \"$CCT_URL/api/agent/sessions/$SESSION_ID/debug\"\n")
+
+	return b.String(), nil
+}
+
+// maskReproOptions returns a copy of options that is safe to share in a bug report.
+// Temperature is dropped: sessions persisted before it was rejected may still
+// carry one, and create_session would refuse the script's options.
+func maskReproOptions(options SessionOptions) SessionOptions {
+	options.Temperature = nil
+	if options.APIKey != nil && *options.APIKey != "" {
+		placeholder := "<API_KEY>"
+		options.APIKey = &placeholder
+	}
+	options.EnvVars = maskEnvVars(options.EnvVars)
+	return options
+}
+
+// valueOrDefault dereferences s, or returns fallback when it is nil or empty
+func valueOrDefault(s *string, fallback string) string {
+	if s == nil || *s == "" {
+		return fallback
+	}
+	return *s
+}
+
+// shellQuote wraps s in single quotes for POSIX shells
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,89 @@
+package agents
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestReproScript(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	if _, err := sm.ReproScript(uuid.New(), "https://127.0.0.1:3333"); err == nil {
+		t.Error("Expected error for unknown session")
+	}
+
+	model := "opus"
+	workDir := "/tmp/it's a project"
+	apiKey := "sk-live-1234567890abcd"
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{
+		Model:            &model,
+		WorkingDirectory: &workDir,
+		APIKey:           &apiKey,
+		EnvVars:          map[string]string{"GITHUB_TOKEN": "ghp_secretvalue9876", "LOG_LEVEL": "debug"},
+	}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	script, err := sm.ReproScript(sessionID, "https://127.0.0.1:3333")
+	if err != nil {
+		t.Fatalf("ReproScript failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"# Reproduce agent session " + sessionID.String(),
+		"#   model:             opus",
+		`CCT_URL="${CCT_URL:-https://127.0.0.1:3333}"`,
+		`"api_key":"<API_KEY>"`,
+		`"LOG_LEVEL":"debug"`,
+		"websocat",
+		"/api/agent/sessions/$SESSION_ID/debug",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected script to contain %q\n%s", want, script)
+		}
+	}
+	for _, secret := range []string{apiKey, "ghp_secretvalue9876"} {
+		if strings.Contains(script, secret) {
+			t.Errorf("Script leaks secret %q", secret)
+		}
+	}
+
+	// The quoted options must survive the shell unchanged
+	if _, err := exec.LookPath("sh"); err == nil {
+		path := filepath.Join(t.TempDir(), "repro.sh")
+		lines := strings.Split(script, "\n")
+		var optionsLine string
+		for _, line := range lines {
+			if strings.HasPrefix(line, "OPTIONS=") {
+				optionsLine = line
+			}
+		}
+		if err := os.WriteFile(path, []byte(optionsLine+"\nprintf '%s' \"$OPTIONS\"\n"), 0600); err != nil {
+			t.Fatalf("Failed to write script: %v", err)
+		}
+		out, err := exec.Command("sh", path).Output()
+		if err != nil {
+			t.Fatalf("Failed to run options line: %v", err)
+		}
+		if !strings.Contains(string(out), `"working_directory":"/tmp/it's a project"`) {
+			t.Errorf("Options were not quoted correctly: %s", out)
+		}
+	}
+}
+
+func TestMaskReproOptionsDropsTemperature(t *testing.T) {
+	temperature := 0.7
+	masked := maskReproOptions(SessionOptions{Temperature: &temperature})
+	if masked.Temperature != nil {
+		t.Errorf("Expected temperature to be dropped, got %v", *masked.Temperature)
+	}
+	if err := validateSamplingOptions(masked); err != nil {
+		t.Errorf("Masked options would be rejected by create_session: %v", err)
+	}
+}
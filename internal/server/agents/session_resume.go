@@ -0,0 +1,44 @@
+package agents
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/google/uuid"
+)
+
+// ResumeCheck reports whether a session can be resumed, and why not
+type ResumeCheck struct {
+	Resumable bool   `json:"resumable"`
+	Reason    string `json:"reason"` // Empty when resumable
+}
+
+// CheckResumable reports whether a session can be resumed: it needs a Claude
+// session ID to resume from, and its working directory (if any) must still
+// exist. Works for both live sessions and sessions only found in storage.
+func (sm *SessionManager) CheckResumable(sessionID uuid.UUID) (*ResumeCheck, error) {
+	options, claudeSessionID, err := sm.sessionSource(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if claudeSessionID == "" {
+		return &ResumeCheck{Reason: "session has no Claude session ID to resume"}, nil
+	}
+
+	if options.WorkingDirectory != nil && *options.WorkingDirectory != "" {
+		dir := *options.WorkingDirectory
+		info, err := os.Stat(dir)
+		if os.IsNotExist(err) {
+			return &ResumeCheck{Reason: fmt.Sprintf("working directory %s no longer exists", dir)}, nil
+		}
+		if err != nil {
+			return &ResumeCheck{Reason: fmt.Sprintf("working directory %s is not accessible: %v", dir, err)}, nil
+		}
+		if !info.IsDir() {
+			return &ResumeCheck{Reason: fmt.Sprintf("working directory %s is not a directory", dir)}, nil
+		}
+	}
+
+	return &ResumeCheck{Resumable: true}, nil
+}
@@ -0,0 +1,49 @@
+package agents
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCheckResumable(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	workDir := t.TempDir()
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{WorkingDirectory: &workDir}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	check, err := sm.CheckResumable(sessionID)
+	if err != nil {
+		t.Fatalf("CheckResumable failed: %v", err)
+	}
+	if check.Resumable || check.Reason == "" {
+		t.Errorf("expected a session without a Claude session ID to be unresumable, got %+v", check)
+	}
+
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+	sm.mu.Lock()
+	session.ClaudeSessionID = "claude-session"
+	sm.mu.Unlock()
+
+	if check, _ := sm.CheckResumable(sessionID); !check.Resumable {
+		t.Errorf("expected session to be resumable, got %+v", check)
+	}
+
+	if err := os.RemoveAll(workDir); err != nil {
+		t.Fatalf("Failed to remove working directory: %v", err)
+	}
+	if check, _ := sm.CheckResumable(sessionID); check.Resumable {
+		t.Error("expected session with a deleted working directory to be unresumable")
+	}
+
+	if _, err := sm.CheckResumable(uuid.New()); err == nil {
+		t.Error("expected error for unknown session")
+	}
+}
@@ -0,0 +1,60 @@
+package agents
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/schlunsen/claude-control-terminal/internal/logging"
+)
+
+// rememberPrompt records the prompt (or structured content) being sent so it
+// can be re-sent by RetryLastPrompt
+func (s *AgentSession) rememberPrompt(prompt string, content []ContentBlock) {
+	s.lastPromptMu.Lock()
+	defer s.lastPromptMu.Unlock()
+	s.lastPrompt = prompt
+	s.lastContent = content
+}
+
+// RetryLastPrompt re-sends the last prompt of a session in the error state.
+// The error is cleared, the session goes back to idle and its client is
+// closed so the retry reconnects to the Claude CLI from scratch.
+func (sm *SessionManager) RetryLastPrompt(sessionID uuid.UUID) error {
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	session.lastPromptMu.Lock()
+	prompt, content := session.lastPrompt, session.lastContent
+	session.lastPromptMu.Unlock()
+
+	sm.mu.Lock()
+	if session.Status != SessionStatusError {
+		status := session.Status
+		sm.mu.Unlock()
+		return fmt.Errorf("only sessions in the error state can be retried (session %s is %s)", sessionID, status)
+	}
+	if prompt == "" && len(content) == 0 {
+		sm.mu.Unlock()
+		return fmt.Errorf("no prompt to retry for session %s", sessionID)
+	}
+	session.ErrorMessage = nil
+	session.ErrorCode = ""
+	sm.setStatus(session, SessionStatusIdle)
+	sm.mu.Unlock()
+
+	// Rebuild the client: the failed one may be disconnected or mid-response
+	session.mu.Lock()
+	if session.client != nil {
+		session.client.Close(session.ctx)
+		session.client = nil
+	}
+	session.mu.Unlock()
+
+	logging.Info("Retrying last prompt for session %s", sessionID)
+	if len(content) > 0 {
+		return sm.SendPromptWithContent(sessionID, content)
+	}
+	return sm.SendPrompt(sessionID, prompt)
+}
@@ -0,0 +1,41 @@
+package agents
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRetryLastPromptGuards(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	session, err := sm.GetSession(sessionID)
+	if err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+
+	session.rememberPrompt("fix the build", nil)
+	if err := sm.RetryLastPrompt(sessionID); err == nil || !strings.Contains(err.Error(), "error state") {
+		t.Errorf("expected retry outside the error state to be rejected, got %v", err)
+	}
+
+	session.rememberPrompt("", nil)
+	sm.mu.Lock()
+	sm.failSession(session, "connection reset")
+	sm.mu.Unlock()
+	if err := sm.RetryLastPrompt(sessionID); err == nil || !strings.Contains(err.Error(), "no prompt") {
+		t.Errorf("expected retry without a prompt to be rejected, got %v", err)
+	}
+	if session.Status != SessionStatusError {
+		t.Errorf("expected a rejected retry to leave the session in the error state, got %s", session.Status)
+	}
+
+	if err := sm.RetryLastPrompt(uuid.New()); err == nil {
+		t.Error("expected error for unknown session")
+	}
+}
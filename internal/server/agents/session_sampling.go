@@ -0,0 +1,34 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/schlunsen/claude-agent-sdk-go/types"
+)
+
+// errTemperatureUnsupported is returned for sessions that set a temperature.
+// The Claude CLI has no temperature setting, so it could never be applied.
+var errTemperatureUnsupported = errors.New("temperature is not supported: the Claude CLI has no temperature setting, so it cannot be applied to the session")
+
+// validateSamplingOptions checks the session's sampling settings; a nil
+// max tokens keeps the CLI default
+func validateSamplingOptions(options SessionOptions) error {
+	if options.Temperature != nil {
+		return errTemperatureUnsupported
+	}
+	if options.MaxTokens != nil && *options.MaxTokens <= 0 {
+		return fmt.Errorf("max_tokens must be greater than 0")
+	}
+	return nil
+}
+
+// applySamplingOptions passes the session's output token limit to the Claude
+// CLI, which reads it from CLAUDE_CODE_MAX_OUTPUT_TOKENS
+func applySamplingOptions(opts *types.ClaudeAgentOptions, options SessionOptions) *types.ClaudeAgentOptions {
+	if options.MaxTokens != nil && *options.MaxTokens > 0 {
+		opts = opts.WithEnvVar("CLAUDE_CODE_MAX_OUTPUT_TOKENS", strconv.Itoa(*options.MaxTokens))
+	}
+	return opts
+}
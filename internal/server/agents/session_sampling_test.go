@@ -0,0 +1,47 @@
+package agents
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/schlunsen/claude-agent-sdk-go/types"
+)
+
+func TestApplySamplingOptions(t *testing.T) {
+	maxTokens := 4096
+	opts := applySamplingOptions(types.NewClaudeAgentOptions(), SessionOptions{
+		MaxTokens: &maxTokens,
+	})
+	if got := opts.Env["CLAUDE_CODE_MAX_OUTPUT_TOKENS"]; got != "4096" {
+		t.Errorf("CLAUDE_CODE_MAX_OUTPUT_TOKENS = %q, want %q", got, "4096")
+	}
+
+	opts = applySamplingOptions(types.NewClaudeAgentOptions(), SessionOptions{})
+	if _, ok := opts.Env["CLAUDE_CODE_MAX_OUTPUT_TOKENS"]; ok {
+		t.Error("expected CLAUDE_CODE_MAX_OUTPUT_TOKENS to be unset")
+	}
+}
+
+func TestCreateSessionValidatesSamplingOptions(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	temperature := 0.2
+	if _, err := sm.CreateSession(uuid.New(), SessionOptions{Temperature: &temperature}); !errors.Is(err, errTemperatureUnsupported) {
+		t.Errorf("expected temperature to be rejected as unsupported, got %v", err)
+	}
+
+	zero := 0
+	if _, err := sm.CreateSession(uuid.New(), SessionOptions{MaxTokens: &zero}); err == nil {
+		t.Error("expected non-positive max_tokens to be rejected")
+	}
+
+	maxTokens := 1024
+	session, err := sm.CreateSession(uuid.New(), SessionOptions{MaxTokens: &maxTokens})
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	if session.Options.MaxTokens == nil || *session.Options.MaxTokens != maxTokens {
+		t.Errorf("expected max_tokens to be kept, got %v", session.Options.MaxTokens)
+	}
+}
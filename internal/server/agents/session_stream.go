@@ -0,0 +1,122 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/schlunsen/claude-agent-sdk-go/types"
+	"github.com/schlunsen/claude-control-terminal/internal/logging"
+)
+
+// EventWriter receives agent events as JSON values. *fiberws.Conn satisfies it,
+// as do one-way transports such as Server-Sent Events.
+type EventWriter interface {
+	WriteJSON(v interface{}) error
+}
+
+// ErrSessionAttached is returned by StreamPrompt when a WebSocket client is
+// attached to the session. Both would consume the session's response channel,
+// splitting the frames between them.
+var ErrSessionAttached = errors.New("session is attached to a WebSocket client")
+
+// CheckStreamable reports whether StreamPrompt can currently take the session,
+// returning ErrSessionAttached if a WebSocket client holds it
+func (h *AgentHandler) CheckStreamable(sessionID uuid.UUID) error {
+	session, err := h.SessionManager.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if session.IsWebSocketConnected() {
+		return ErrSessionAttached
+	}
+	return nil
+}
+
+// claimStream marks the session connected on behalf of a stream client and
+// claims its permission forwarder. It fails if a WebSocket client is attached
+// or a previous client's forwarder is still running.
+func (s *AgentSession) claimStream() bool {
+	s.wsConnMu.Lock()
+	defer s.wsConnMu.Unlock()
+
+	if s.wsConnected || !s.StartPermissionForwarder() {
+		return false
+	}
+	s.wsConnected = true
+	s.streamClient = true
+	return true
+}
+
+// releaseStream undoes claimStream, unless a WebSocket client has since taken over
+func (s *AgentSession) releaseStream() {
+	s.wsConnMu.Lock()
+	defer s.wsConnMu.Unlock()
+
+	if s.streamClient {
+		s.wsConnected = false
+		s.streamClient = false
+	}
+}
+
+// StreamPrompt sends a prompt to an existing session and writes the session's
+// events (agent messages, permission requests and the final result) to w until
+// the turn's result arrives. The session counts as connected while streaming,
+// so permission requests are forwarded instead of denied. Nothing is written
+// to w after StreamPrompt returns.
+//
+// If w stops accepting events, the remaining responses are drained so the
+// session does not stall on a full response channel.
+func (h *AgentHandler) StreamPrompt(w EventWriter, sessionID uuid.UUID, prompt string) error {
+	session, err := h.SessionManager.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	// Claim the session and start forwarding permission requests BEFORE sending
+	// the prompt, so an early request isn't denied for lack of a client
+	if !session.claimStream() {
+		h.sendFiberError(w, ErrSessionAttached.Error())
+		return ErrSessionAttached
+	}
+	defer session.releaseStream()
+
+	done := make(chan struct{})
+	var forwarder sync.WaitGroup
+	forwarder.Add(1)
+	go func() {
+		defer forwarder.Done()
+		h.forwardPermissionRequests(w, sessionID, session, done)
+	}()
+	defer func() {
+		close(done)
+		forwarder.Wait()
+	}()
+
+	if err := h.SessionManager.SendPrompt(sessionID, prompt); err != nil {
+		h.sendFiberError(w, fmt.Sprintf("failed to send prompt: %v", err))
+		return err
+	}
+
+	writing := true
+	for {
+		select {
+		case msg := <-session.responseChan:
+			if writing {
+				if err := h.sendFiberAgentMessage(w, sessionID, msg); err != nil {
+					logging.Warning("Session %s: stream client gone, draining remaining responses: %v", sessionID, err)
+					writing = false
+				}
+			}
+
+			if _, ok := msg.(*types.ResultMessage); ok {
+				logging.Debug("Session %s: Streamed prompt finished", sessionID)
+				return nil
+			}
+
+		case <-session.ctx.Done():
+			return fmt.Errorf("session %s ended while streaming", sessionID)
+		}
+	}
+}
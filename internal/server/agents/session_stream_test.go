@@ -0,0 +1,100 @@
+package agents
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/schlunsen/claude-agent-sdk-go/types"
+)
+
+// recordingWriter is an EventWriter that keeps every event written to it
+type recordingWriter struct {
+	mu     sync.Mutex
+	events []interface{}
+}
+
+func (w *recordingWriter) WriteJSON(v interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.events = append(w.events, v)
+	return nil
+}
+
+func (w *recordingWriter) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.events)
+}
+
+func TestStreamPromptRefusesAttachedWebSocket(t *testing.T) {
+	sm := newTestSessionManager(t)
+	h := &AgentHandler{Config: &Config{MaxConcurrentSessions: 10}, SessionManager: sm}
+
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	session, _ := sm.GetSession(sessionID)
+
+	// A WebSocket client is attached and has a response waiting for it
+	session.SetWebSocketConnected(true)
+	session.responseChan <- &types.ResultMessage{Type: "result"}
+
+	if err := h.CheckStreamable(sessionID); !errors.Is(err, ErrSessionAttached) {
+		t.Errorf("Expected CheckStreamable to report ErrSessionAttached, got %v", err)
+	}
+
+	w := &recordingWriter{}
+	if err := h.StreamPrompt(w, sessionID, "hello"); !errors.Is(err, ErrSessionAttached) {
+		t.Fatalf("Expected ErrSessionAttached, got %v", err)
+	}
+	if len(session.responseChan) != 1 {
+		t.Error("Stream must not consume the WebSocket client's responses")
+	}
+	if !session.IsWebSocketConnected() {
+		t.Error("Refused stream must leave the WebSocket client attached")
+	}
+	if w.count() != 1 {
+		t.Errorf("Expected only an error event, got %d events", w.count())
+	}
+}
+
+func TestStreamPromptStopsForwarderOnReturn(t *testing.T) {
+	installFakeClaudeCLI(t, nil)
+	sm := newTestSessionManager(t)
+	h := &AgentHandler{Config: &Config{MaxConcurrentSessions: 10}, SessionManager: sm}
+
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	defer sm.EndSession(sessionID)
+	session, _ := sm.GetSession(sessionID)
+
+	w := &recordingWriter{}
+	if err := h.StreamPrompt(w, sessionID, "hello"); err != nil {
+		t.Fatalf("StreamPrompt failed: %v", err)
+	}
+
+	if session.IsWebSocketConnected() {
+		t.Error("Expected the stream client to be detached after StreamPrompt returns")
+	}
+	if !session.StartPermissionForwarder() {
+		t.Fatal("Expected the stream's permission forwarder to have stopped")
+	}
+	session.StopPermissionForwarder()
+
+	// A late permission request must not reach the finished stream's writer
+	written := w.count()
+	session.permissionReqChan <- &PermissionRequest{RequestID: "late", ResponseChan: make(chan PermissionResponse, 1)}
+	time.Sleep(50 * time.Millisecond)
+	if w.count() != written {
+		t.Error("Permission request was written after StreamPrompt returned")
+	}
+	if err := h.CheckStreamable(sessionID); err != nil {
+		t.Errorf("Expected session to be streamable again, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package agents
+
+import "sort"
+
+// SessionSummary holds averages across agent sessions for the session health panel
+type SessionSummary struct {
+	SessionCount         int     `json:"session_count"`
+	AvgSessionDurationMS float64 `json:"avg_session_duration_ms"`
+	AvgTurnsPerSession   float64 `json:"avg_turns_per_session"`
+	AvgCost              float64 `json:"avg_cost"`
+	MedianCost           float64 `json:"median_cost"`
+	TotalCost            float64 `json:"total_cost"`
+}
+
+// SummarizeSessions computes duration, turn and cost averages for the given
+// sessions. All averages are zero when there are no sessions.
+func SummarizeSessions(sessions []Session) SessionSummary {
+	summary := SessionSummary{SessionCount: len(sessions)}
+	if len(sessions) == 0 {
+		return summary
+	}
+
+	var totalDuration int64
+	var totalTurns int
+	costs := make([]float64, 0, len(sessions))
+	for _, session := range sessions {
+		totalDuration += session.DurationMS
+		totalTurns += session.NumTurns
+		summary.TotalCost += session.CostUSD
+		costs = append(costs, session.CostUSD)
+	}
+
+	count := float64(len(sessions))
+	summary.AvgSessionDurationMS = float64(totalDuration) / count
+	summary.AvgTurnsPerSession = float64(totalTurns) / count
+	summary.AvgCost = summary.TotalCost / count
+
+	sort.Float64s(costs)
+	mid := len(costs) / 2
+	if len(costs)%2 == 0 {
+		summary.MedianCost = (costs[mid-1] + costs[mid]) / 2
+	} else {
+		summary.MedianCost = costs[mid]
+	}
+
+	return summary
+}
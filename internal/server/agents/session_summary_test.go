@@ -0,0 +1,36 @@
+package agents
+
+import "testing"
+
+func TestSummarizeSessions(t *testing.T) {
+	empty := SummarizeSessions(nil)
+	if empty.SessionCount != 0 || empty.AvgSessionDurationMS != 0 || empty.MedianCost != 0 {
+		t.Errorf("expected zero summary for no sessions, got %+v", empty)
+	}
+
+	sessions := []Session{
+		{DurationMS: 1000, NumTurns: 2, CostUSD: 0.10},
+		{DurationMS: 3000, NumTurns: 4, CostUSD: 0.50},
+		{DurationMS: 2000, NumTurns: 6, CostUSD: 0.30},
+	}
+
+	summary := SummarizeSessions(sessions)
+	if summary.SessionCount != 3 {
+		t.Errorf("expected 3 sessions, got %d", summary.SessionCount)
+	}
+	if summary.AvgSessionDurationMS != 2000 {
+		t.Errorf("expected avg duration 2000ms, got %v", summary.AvgSessionDurationMS)
+	}
+	if summary.AvgTurnsPerSession != 4 {
+		t.Errorf("expected avg turns 4, got %v", summary.AvgTurnsPerSession)
+	}
+	if summary.MedianCost != 0.30 {
+		t.Errorf("expected median cost 0.30, got %v", summary.MedianCost)
+	}
+
+	// Even count averages the two middle costs
+	summary = SummarizeSessions(append(sessions, Session{CostUSD: 0.70}))
+	if summary.MedianCost < 0.3999 || summary.MedianCost > 0.4001 {
+		t.Errorf("expected median cost 0.40, got %v", summary.MedianCost)
+	}
+}
@@ -0,0 +1,104 @@
+package agents
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ToolInvocation is one tool call made by the agent, with its result once the
+// matching tool_result has been stored
+type ToolInvocation struct {
+	Sequence  int             `json:"sequence"` // Sequence of the assistant message that made the call
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Tool      string          `json:"tool"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	Result    interface{}     `json:"result"` // nil while the tool has no stored result
+	IsError   bool            `json:"is_error"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// storedToolUse is an entry of an assistant message's tool_uses column
+type storedToolUse struct {
+	ID    string          `json:"id"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input"`
+}
+
+// storedToolResult is a tool_result block of a stored user message
+type storedToolResult struct {
+	Type      string      `json:"type"`
+	ToolUseID string      `json:"tool_use_id"`
+	Content   interface{} `json:"content"`
+	IsError   *bool       `json:"is_error"`
+}
+
+// GetToolTimeline returns the session's tool calls in the order they were
+// made, each matched with the result from the tool_result block that answered
+// it. Works for ended sessions too.
+func (sm *SessionManager) GetToolTimeline(sessionID uuid.UUID) ([]ToolInvocation, error) {
+	if _, err := sm.storage.GetSession(sessionID); err != nil {
+		return nil, err
+	}
+
+	messages, err := sm.loadTranscript(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildToolTimeline(messages), nil
+}
+
+// buildToolTimeline extracts tool calls from assistant messages and attaches
+// results from user messages by tool_use_id
+func buildToolTimeline(messages []*MessageRecord) []ToolInvocation {
+	timeline := []ToolInvocation{}
+	byID := make(map[string]int)
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case "assistant":
+			if len(msg.ToolUses) == 0 {
+				continue
+			}
+			var toolUses []storedToolUse
+			if err := json.Unmarshal(msg.ToolUses, &toolUses); err != nil {
+				continue
+			}
+			for _, tool := range toolUses {
+				if tool.ID != "" {
+					byID[tool.ID] = len(timeline)
+				}
+				timeline = append(timeline, ToolInvocation{
+					Sequence:  msg.Sequence,
+					ToolUseID: tool.ID,
+					Tool:      tool.Name,
+					Input:     tool.Input,
+					Timestamp: msg.Timestamp,
+				})
+			}
+
+		case "user":
+			// Tool results are stored as a JSON array of content blocks;
+			// plain-text prompts don't parse and are skipped
+			var blocks []storedToolResult
+			if err := json.Unmarshal([]byte(msg.Content), &blocks); err != nil {
+				continue
+			}
+			for _, block := range blocks {
+				if block.Type != "tool_result" {
+					continue
+				}
+				i, ok := byID[block.ToolUseID]
+				if !ok {
+					continue
+				}
+				timeline[i].Result = block.Content
+				timeline[i].IsError = block.IsError != nil && *block.IsError
+			}
+		}
+	}
+
+	return timeline
+}
@@ -0,0 +1,57 @@
+package agents
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestGetToolTimeline(t *testing.T) {
+	sm := newTestSessionManager(t)
+
+	sessionID := uuid.New()
+	if _, err := sm.CreateSession(sessionID, SessionOptions{}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	toolUses := []map[string]interface{}{
+		{"id": "toolu_1", "name": "Bash", "input": map[string]interface{}{"command": "go test ./..."}},
+		{"id": "toolu_2", "name": "Read", "input": map[string]interface{}{"file_path": "main.go"}},
+	}
+	results := `[{"type":"tool_result","tool_use_id":"toolu_1","content":"ok","is_error":false},` +
+		`{"type":"tool_result","tool_use_id":"toolu_2","content":"no such file","is_error":true}]`
+
+	saves := []struct {
+		role, content string
+		toolUses      interface{}
+	}{
+		{"user", "run the tests", nil},
+		{"assistant", "Running tests", toolUses},
+		{"user", results, nil},
+		{"assistant", "Done", nil},
+	}
+	for i, save := range saves {
+		if err := sm.saveMessageToDB(sessionID, i+1, save.role, save.content, "", save.toolUses); err != nil {
+			t.Fatalf("Failed to save message: %v", err)
+		}
+	}
+
+	timeline, err := sm.GetToolTimeline(sessionID)
+	if err != nil {
+		t.Fatalf("GetToolTimeline failed: %v", err)
+	}
+	if len(timeline) != 2 {
+		t.Fatalf("expected 2 tool invocations, got %d", len(timeline))
+	}
+
+	if timeline[0].Tool != "Bash" || timeline[0].Sequence != 2 || timeline[0].Result != "ok" || timeline[0].IsError {
+		t.Errorf("unexpected first invocation: %+v", timeline[0])
+	}
+	if timeline[1].Tool != "Read" || timeline[1].Result != "no such file" || !timeline[1].IsError {
+		t.Errorf("unexpected second invocation: %+v", timeline[1])
+	}
+
+	if _, err := sm.GetToolTimeline(uuid.New()); err == nil {
+		t.Error("expected error for unknown session")
+	}
+}
@@ -16,15 +16,44 @@ type SessionStorage interface {
 	UpdateSession(session *SessionMetadata) error
 	GetSession(sessionID uuid.UUID) (*SessionMetadata, error)
 	ListSessions(statusFilter string) ([]*SessionMetadata, error)
+	ListSessionsByBranch(branch, statusFilter string) ([]*SessionMetadata, error)
 	DeleteSession(sessionID uuid.UUID) error
 
 	// Message operations
 	SaveMessage(msg *MessageRecord) error
 	GetMessages(sessionID uuid.UUID, limit, offset int) ([]*MessageRecord, bool, error)
 	GetMessageCount(sessionID uuid.UUID) (int, error)
+	SetMessageFlagged(sessionID uuid.UUID, sequence int, flagged bool) error
+	GetFlaggedMessages(sessionID uuid.UUID) ([]*MessageRecord, error)
+	ArchiveMessages(sessionID uuid.UUID) (archived int64, contentChars int64, err error)
+	GetRecentAssistantMessages(limit int) ([]*RecentAssistantMessage, error)
+
+	// Aggregates
+	GetStatsByModel() ([]ModelStat, error)
+	GetStatsByProvider() ([]ProviderStat, error)
 
 	// Cleanup
 	DeleteOldSessions(retentionDays int) (int64, error)
+	DeleteOldMessages(retentionDays int) (int64, error)
+}
+
+// ModelStat aggregates session cost and usage for a single model
+type ModelStat struct {
+	ModelName     string  `json:"model_name"` // Empty when the session had no model recorded
+	SessionCount  int     `json:"session_count"`
+	TotalCostUSD  float64 `json:"total_cost_usd"`
+	TotalTurns    int     `json:"total_turns"`
+	TotalMessages int     `json:"total_messages"`
+}
+
+// ProviderStat aggregates session cost and token usage for a single provider
+type ProviderStat struct {
+	ProviderID         string  `json:"provider_id"` // Empty when the session used the default provider
+	SessionCount       int     `json:"session_count"`
+	TotalCostUSD       float64 `json:"total_cost_usd"`
+	InputTokens        int64   `json:"input_tokens"`
+	OutputTokens       int64   `json:"output_tokens"`
+	OutputTokensPerUSD float64 `json:"output_tokens_per_usd"` // 0 when the provider has no recorded cost
 }
 
 // SessionMetadata represents a persisted agent session
@@ -43,6 +72,7 @@ type SessionMetadata struct {
 	ClaudeSessionID string          `json:"claude_session_id,omitempty"`  // Claude CLI session ID for resuming
 	GitBranch       string          `json:"git_branch,omitempty"`         // Git branch of working directory
 	OptionsJSON     string          `json:"options_json,omitempty"`       // JSON-serialized SessionOptions
+	ParentSessionID *uuid.UUID      `json:"parent_session_id,omitempty"`  // Session this one was forked from
 }
 
 // MessageRecord represents a persisted message
@@ -56,6 +86,21 @@ type MessageRecord struct {
 	ToolUses        json.RawMessage `json:"tool_uses,omitempty"`
 	Timestamp       time.Time       `json:"timestamp"`
 	TokensUsed      int             `json:"tokens_used"`
+	Archived        bool            `json:"archived,omitempty"` // Superseded by a compaction summary
+	Flagged         bool            `json:"flagged,omitempty"`  // Bookmarked by the user as an important turn
+}
+
+// RecentAssistantMessage is an assistant message with context from its session
+type RecentAssistantMessage struct {
+	MessageID        uuid.UUID `json:"message_id"`
+	SessionID        uuid.UUID `json:"session_id"`
+	Content          string    `json:"content"`
+	Timestamp        time.Time `json:"timestamp"`
+	SessionStatus    string    `json:"session_status"`
+	ModelName        string    `json:"model_name,omitempty"`
+	GitBranch        string    `json:"git_branch,omitempty"`
+	AgentName        string    `json:"agent_name,omitempty"`
+	WorkingDirectory string    `json:"working_directory,omitempty"`
 }
 
 // SQLiteSessionStorage implements SessionStorage using SQLite
@@ -79,14 +124,23 @@ func NewSQLiteSessionStorage(db *sql.DB) (*SQLiteSessionStorage, error) {
 	return storage, nil
 }
 
+// nullableUUID converts an optional UUID to a value storable in a TEXT column
+func nullableUUID(id *uuid.UUID) interface{} {
+	if id == nil {
+		return nil
+	}
+	return id.String()
+}
+
 // SaveSession inserts a new session into the database
 func (s *SQLiteSessionStorage) SaveSession(session *SessionMetadata) error {
 	query := `
 		INSERT INTO agent_sessions (
 			id, status, created_at, updated_at, ended_at,
 			message_count, cost_usd, num_turns, duration_ms,
-			error_message, model_name, claude_session_id, git_branch, options
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			error_message, model_name, claude_session_id, git_branch, options,
+			parent_session_id
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	_, err := s.db.Exec(
@@ -105,6 +159,7 @@ func (s *SQLiteSessionStorage) SaveSession(session *SessionMetadata) error {
 		session.ClaudeSessionID,
 		session.GitBranch,
 		session.OptionsJSON,
+		nullableUUID(session.ParentSessionID),
 	)
 
 	if err != nil {
@@ -121,7 +176,8 @@ func (s *SQLiteSessionStorage) UpdateSession(session *SessionMetadata) error {
 		SET status = ?, updated_at = ?, ended_at = ?,
 		    message_count = ?, cost_usd = ?, num_turns = ?,
 		    duration_ms = ?, error_message = ?, model_name = ?,
-		    claude_session_id = ?, git_branch = ?, options = ?
+		    claude_session_id = ?, git_branch = ?, options = ?,
+		    parent_session_id = ?
 		WHERE id = ?
 	`
 
@@ -139,6 +195,7 @@ func (s *SQLiteSessionStorage) UpdateSession(session *SessionMetadata) error {
 		session.ClaudeSessionID,
 		session.GitBranch,
 		session.OptionsJSON,
+		nullableUUID(session.ParentSessionID),
 		session.ID.String(),
 	)
 
@@ -163,7 +220,8 @@ func (s *SQLiteSessionStorage) GetSession(sessionID uuid.UUID) (*SessionMetadata
 	query := `
 		SELECT id, status, created_at, updated_at, ended_at,
 		       message_count, cost_usd, num_turns, duration_ms,
-		       error_message, model_name, claude_session_id, git_branch, options
+		       error_message, model_name, claude_session_id, git_branch, options,
+		       parent_session_id
 		FROM agent_sessions
 		WHERE id = ?
 	`
@@ -171,7 +229,7 @@ func (s *SQLiteSessionStorage) GetSession(sessionID uuid.UUID) (*SessionMetadata
 	session := &SessionMetadata{}
 	var idStr string
 	var endedAt sql.NullTime
-	var errorMsg, modelName, claudeSessionID, gitBranch, optionsJSON sql.NullString
+	var errorMsg, modelName, claudeSessionID, gitBranch, optionsJSON, parentID sql.NullString
 
 	err := s.db.QueryRow(query, sessionID.String()).Scan(
 		&idStr,
@@ -188,6 +246,7 @@ func (s *SQLiteSessionStorage) GetSession(sessionID uuid.UUID) (*SessionMetadata
 		&claudeSessionID,
 		&gitBranch,
 		&optionsJSON,
+		&parentID,
 	)
 
 	if err == sql.ErrNoRows {
@@ -223,46 +282,45 @@ func (s *SQLiteSessionStorage) GetSession(sessionID uuid.UUID) (*SessionMetadata
 	if optionsJSON.Valid {
 		session.OptionsJSON = optionsJSON.String
 	}
+	if parentID.Valid {
+		if parsed, err := uuid.Parse(parentID.String); err == nil {
+			session.ParentSessionID = &parsed
+		}
+	}
 
 	return session, nil
 }
 
-// ListSessions retrieves sessions filtered by status
-// statusFilter can be: "all", "active", "idle", "processing", "error", "ended"
-func (s *SQLiteSessionStorage) ListSessions(statusFilter string) ([]*SessionMetadata, error) {
-	var query string
-	var args []interface{}
+// sessionColumns lists the agent_sessions columns read by scanSessionRows
+const sessionColumns = `id, status, created_at, updated_at, ended_at,
+		       message_count, cost_usd, num_turns, duration_ms,
+		       error_message, model_name, claude_session_id, git_branch, options,
+		       parent_session_id`
 
+// sessionStatusClause returns the WHERE condition for a status filter.
+// statusFilter can be: "all", "active", "idle", "processing", "error", "ended"
+func sessionStatusClause(statusFilter string) (string, []interface{}) {
 	switch statusFilter {
 	case "all", "":
-		query = `
-			SELECT id, status, created_at, updated_at, ended_at,
-			       message_count, cost_usd, num_turns, duration_ms,
-			       error_message, model_name, claude_session_id, git_branch, options
-			FROM agent_sessions
-			ORDER BY updated_at DESC
-		`
+		return "1=1", nil
 	case "active":
 		// Active means any session that hasn't ended
-		query = `
-			SELECT id, status, created_at, updated_at, ended_at,
-			       message_count, cost_usd, num_turns, duration_ms,
-			       error_message, model_name, claude_session_id, git_branch, options
-			FROM agent_sessions
-			WHERE status != 'ended'
-			ORDER BY updated_at DESC
-		`
+		return "status != 'ended'", nil
 	default:
-		query = `
-			SELECT id, status, created_at, updated_at, ended_at,
-			       message_count, cost_usd, num_turns, duration_ms,
-			       error_message, model_name, claude_session_id, git_branch, options
-			FROM agent_sessions
-			WHERE status = ?
-			ORDER BY updated_at DESC
-		`
-		args = append(args, statusFilter)
+		return "status = ?", []interface{}{statusFilter}
 	}
+}
+
+// ListSessions retrieves sessions filtered by status
+// statusFilter can be: "all", "active", "idle", "processing", "error", "ended"
+func (s *SQLiteSessionStorage) ListSessions(statusFilter string) ([]*SessionMetadata, error) {
+	where, args := sessionStatusClause(statusFilter)
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM agent_sessions
+		WHERE ` + where + `
+		ORDER BY updated_at DESC
+	`
 
 	rows, err := s.db.Query(query, args...)
 	if err != nil {
@@ -270,12 +328,37 @@ func (s *SQLiteSessionStorage) ListSessions(statusFilter string) ([]*SessionMeta
 	}
 	defer rows.Close()
 
+	return scanSessionRows(rows)
+}
+
+// ListSessionsByBranch retrieves sessions whose stored git branch matches,
+// additionally filtered by status like ListSessions
+func (s *SQLiteSessionStorage) ListSessionsByBranch(branch, statusFilter string) ([]*SessionMetadata, error) {
+	where, args := sessionStatusClause(statusFilter)
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM agent_sessions
+		WHERE git_branch = ? AND ` + where + `
+		ORDER BY updated_at DESC
+	`
+
+	rows, err := s.db.Query(query, append([]interface{}{branch}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions for branch %s: %w", branch, err)
+	}
+	defer rows.Close()
+
+	return scanSessionRows(rows)
+}
+
+// scanSessionRows reads sessions selected with sessionColumns
+func scanSessionRows(rows *sql.Rows) ([]*SessionMetadata, error) {
 	var sessions []*SessionMetadata
 	for rows.Next() {
 		session := &SessionMetadata{}
 		var idStr string
 		var endedAt sql.NullTime
-		var errorMsg, modelName, claudeSessionID, gitBranch, optionsJSON sql.NullString
+		var errorMsg, modelName, claudeSessionID, gitBranch, optionsJSON, parentID sql.NullString
 
 		err := rows.Scan(
 			&idStr,
@@ -292,6 +375,7 @@ func (s *SQLiteSessionStorage) ListSessions(statusFilter string) ([]*SessionMeta
 			&claudeSessionID,
 			&gitBranch,
 			&optionsJSON,
+			&parentID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan session: %w", err)
@@ -323,11 +407,16 @@ func (s *SQLiteSessionStorage) ListSessions(statusFilter string) ([]*SessionMeta
 		if optionsJSON.Valid {
 			session.OptionsJSON = optionsJSON.String
 		}
+		if parentID.Valid {
+			if parsed, err := uuid.Parse(parentID.String); err == nil {
+				session.ParentSessionID = &parsed
+			}
+		}
 
 		sessions = append(sessions, session)
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating sessions: %w", err)
 	}
 
@@ -397,7 +486,7 @@ func (s *SQLiteSessionStorage) GetMessages(sessionID uuid.UUID, limit, offset in
 	// Query limit+1 to check if there are more messages
 	query := `
 		SELECT id, session_id, sequence, role, content,
-		       thinking_content, tool_uses, timestamp, tokens_used
+		       thinking_content, tool_uses, timestamp, tokens_used, archived, flagged
 		FROM agent_messages
 		WHERE session_id = ?
 		ORDER BY sequence ASC, timestamp ASC
@@ -410,6 +499,62 @@ func (s *SQLiteSessionStorage) GetMessages(sessionID uuid.UUID, limit, offset in
 	}
 	defer rows.Close()
 
+	messages, err := scanMessageRecords(rows)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Check if there are more messages
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit] // Trim to requested limit
+	}
+
+	return messages, hasMore, nil
+}
+
+// SetMessageFlagged flags or unflags the message at a sequence in a session
+func (s *SQLiteSessionStorage) SetMessageFlagged(sessionID uuid.UUID, sequence int, flagged bool) error {
+	result, err := s.db.Exec(
+		`UPDATE agent_messages SET flagged = ? WHERE session_id = ? AND sequence = ?`,
+		flagged, sessionID.String(), sequence,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to flag message: %w", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("message %d not found in session %s", sequence, sessionID)
+	}
+
+	return nil
+}
+
+// GetFlaggedMessages returns the flagged messages of a session in conversation order
+func (s *SQLiteSessionStorage) GetFlaggedMessages(sessionID uuid.UUID) ([]*MessageRecord, error) {
+	query := `
+		SELECT id, session_id, sequence, role, content,
+		       thinking_content, tool_uses, timestamp, tokens_used, archived, flagged
+		FROM agent_messages
+		WHERE session_id = ? AND flagged = 1
+		ORDER BY sequence ASC, timestamp ASC
+	`
+
+	rows, err := s.db.Query(query, sessionID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flagged messages: %w", err)
+	}
+	defer rows.Close()
+
+	return scanMessageRecords(rows)
+}
+
+// scanMessageRecords reads message rows selected with the column list used by GetMessages
+func scanMessageRecords(rows *sql.Rows) ([]*MessageRecord, error) {
 	var messages []*MessageRecord
 	for rows.Next() {
 		msg := &MessageRecord{}
@@ -427,21 +572,23 @@ func (s *SQLiteSessionStorage) GetMessages(sessionID uuid.UUID, limit, offset in
 			&toolUses,
 			&msg.Timestamp,
 			&msg.TokensUsed,
+			&msg.Archived,
+			&msg.Flagged,
 		)
 		if err != nil {
-			return nil, false, fmt.Errorf("failed to scan message: %w", err)
+			return nil, fmt.Errorf("failed to scan message: %w", err)
 		}
 
 		// Parse UUIDs
 		parsedID, err := uuid.Parse(idStr)
 		if err != nil {
-			return nil, false, fmt.Errorf("invalid message ID in database: %w", err)
+			return nil, fmt.Errorf("invalid message ID in database: %w", err)
 		}
 		msg.ID = parsedID
 
 		parsedSessionID, err := uuid.Parse(sessionIDStr)
 		if err != nil {
-			return nil, false, fmt.Errorf("invalid session ID in database: %w", err)
+			return nil, fmt.Errorf("invalid session ID in database: %w", err)
 		}
 		msg.SessionID = parsedSessionID
 
@@ -456,17 +603,11 @@ func (s *SQLiteSessionStorage) GetMessages(sessionID uuid.UUID, limit, offset in
 		messages = append(messages, msg)
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, false, fmt.Errorf("error iterating messages: %w", err)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating messages: %w", err)
 	}
 
-	// Check if there are more messages
-	hasMore := len(messages) > limit
-	if hasMore {
-		messages = messages[:limit] // Trim to requested limit
-	}
-
-	return messages, hasMore, nil
+	return messages, nil
 }
 
 // GetMessageCount returns the total number of messages for a session
@@ -482,6 +623,86 @@ func (s *SQLiteSessionStorage) GetMessageCount(sessionID uuid.UUID) (int, error)
 	return count, nil
 }
 
+// ArchiveMessages marks all non-archived messages of a session as archived.
+// Returns the number of messages archived and their combined content length.
+func (s *SQLiteSessionStorage) ArchiveMessages(sessionID uuid.UUID) (int64, int64, error) {
+	var contentChars int64
+	err := s.db.QueryRow(`
+		SELECT COALESCE(SUM(LENGTH(content) + COALESCE(LENGTH(thinking_content), 0) + COALESCE(LENGTH(tool_uses), 0)), 0)
+		FROM agent_messages
+		WHERE session_id = ? AND archived = 0
+	`, sessionID.String()).Scan(&contentChars)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to measure messages: %w", err)
+	}
+
+	result, err := s.db.Exec(`UPDATE agent_messages SET archived = 1 WHERE session_id = ? AND archived = 0`, sessionID.String())
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to archive messages: %w", err)
+	}
+
+	archived, err := result.RowsAffected()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get archived count: %w", err)
+	}
+
+	return archived, contentChars, nil
+}
+
+// GetRecentAssistantMessages returns the latest assistant messages across all
+// sessions, newest first. Messages without text (tool-only turns) are skipped.
+func (s *SQLiteSessionStorage) GetRecentAssistantMessages(limit int) ([]*RecentAssistantMessage, error) {
+	query := `
+		SELECT m.id, m.session_id, m.content, m.timestamp,
+		       s.status, COALESCE(s.model_name, ''), COALESCE(s.git_branch, ''), COALESCE(s.options, '')
+		FROM agent_messages m
+		JOIN agent_sessions s ON s.id = m.session_id
+		WHERE m.role = 'assistant' AND m.content != ''
+		ORDER BY m.timestamp DESC
+		LIMIT ?
+	`
+
+	rows, err := s.db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent assistant messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []*RecentAssistantMessage{}
+	for rows.Next() {
+		msg := &RecentAssistantMessage{}
+		var idStr, sessionIDStr, optionsJSON string
+
+		if err := rows.Scan(&idStr, &sessionIDStr, &msg.Content, &msg.Timestamp,
+			&msg.SessionStatus, &msg.ModelName, &msg.GitBranch, &optionsJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan recent assistant message: %w", err)
+		}
+
+		if msg.MessageID, err = uuid.Parse(idStr); err != nil {
+			return nil, fmt.Errorf("invalid message ID in database: %w", err)
+		}
+		if msg.SessionID, err = uuid.Parse(sessionIDStr); err != nil {
+			return nil, fmt.Errorf("invalid session ID in database: %w", err)
+		}
+
+		if optionsJSON != "" {
+			var options SessionOptions
+			if err := json.Unmarshal([]byte(optionsJSON), &options); err == nil {
+				if options.AgentName != nil {
+					msg.AgentName = *options.AgentName
+				}
+				if options.WorkingDirectory != nil {
+					msg.WorkingDirectory = *options.WorkingDirectory
+				}
+			}
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, rows.Err()
+}
+
 // DeleteOldSessions removes sessions older than retentionDays
 func (s *SQLiteSessionStorage) DeleteOldSessions(retentionDays int) (int64, error) {
 	query := `
@@ -503,6 +724,98 @@ func (s *SQLiteSessionStorage) DeleteOldSessions(retentionDays int) (int64, erro
 	return rowsAffected, nil
 }
 
+// DeleteOldMessages removes messages older than retentionDays while keeping
+// their session records (and cost/turn accounting) intact
+func (s *SQLiteSessionStorage) DeleteOldMessages(retentionDays int) (int64, error) {
+	query := `
+		DELETE FROM agent_messages
+		WHERE timestamp < datetime('now', '-' || ? || ' days')
+	`
+
+	result, err := s.db.Exec(query, retentionDays)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cleanup old messages: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+// GetStatsByModel sums cost, turns and messages of all sessions grouped by model,
+// ordered by total cost (highest first)
+func (s *SQLiteSessionStorage) GetStatsByModel() ([]ModelStat, error) {
+	query := `
+		SELECT COALESCE(model_name, '') AS model, COUNT(*), COALESCE(SUM(cost_usd), 0),
+		       COALESCE(SUM(num_turns), 0), COALESCE(SUM(message_count), 0)
+		FROM agent_sessions
+		GROUP BY model
+		ORDER BY SUM(cost_usd) DESC, model ASC
+	`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query model stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []ModelStat{}
+	for rows.Next() {
+		var stat ModelStat
+		if err := rows.Scan(&stat.ModelName, &stat.SessionCount, &stat.TotalCostUSD, &stat.TotalTurns, &stat.TotalMessages); err != nil {
+			return nil, fmt.Errorf("failed to scan model stat: %w", err)
+		}
+		stats = append(stats, stat)
+	}
+
+	return stats, rows.Err()
+}
+
+// GetStatsByProvider sums cost and token usage of all sessions grouped by the
+// provider in their options, ordered by total cost (highest first). Tokens come
+// from the usage recorded with each result message.
+func (s *SQLiteSessionStorage) GetStatsByProvider() ([]ProviderStat, error) {
+	query := `
+		SELECT CASE WHEN json_valid(s.options) THEN COALESCE(json_extract(s.options, '$.provider'), '') ELSE '' END AS provider,
+		       COUNT(*), COALESCE(SUM(s.cost_usd), 0),
+		       COALESCE(SUM(u.input_tokens), 0), COALESCE(SUM(u.output_tokens), 0)
+		FROM agent_sessions s
+		LEFT JOIN (
+			SELECT session_id,
+			       SUM(COALESCE(json_extract(tool_uses, '$.usage.input_tokens'), 0)) AS input_tokens,
+			       SUM(COALESCE(json_extract(tool_uses, '$.usage.output_tokens'), 0)) AS output_tokens
+			FROM agent_messages
+			WHERE role = 'system' AND json_valid(tool_uses)
+			GROUP BY session_id
+		) u ON u.session_id = s.id
+		GROUP BY provider
+		ORDER BY SUM(s.cost_usd) DESC, provider ASC
+	`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query provider stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []ProviderStat{}
+	for rows.Next() {
+		var stat ProviderStat
+		if err := rows.Scan(&stat.ProviderID, &stat.SessionCount, &stat.TotalCostUSD, &stat.InputTokens, &stat.OutputTokens); err != nil {
+			return nil, fmt.Errorf("failed to scan provider stat: %w", err)
+		}
+		if stat.TotalCostUSD > 0 {
+			stat.OutputTokensPerUSD = float64(stat.OutputTokens) / stat.TotalCostUSD
+		}
+		stats = append(stats, stat)
+	}
+
+	return stats, rows.Err()
+}
+
 // FixMessageSequences resequences all messages based on timestamp order
 // This is an idempotent migration that can be run multiple times safely
 func (s *SQLiteSessionStorage) FixMessageSequences() error {
@@ -0,0 +1,87 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+// ErrorCodeInvalidUUID marks errors caused by a client-supplied ID that is not a valid UUID
+const ErrorCodeInvalidUUID = "invalid_uuid"
+
+// InvalidUUIDError reports a client-supplied value that could not be parsed as
+// a UUID, keeping the offending value so it can be echoed back to the client
+type InvalidUUIDError struct {
+	Field string // Where the value came from, e.g. "id" or "session_id"
+	Value string
+}
+
+func (e *InvalidUUIDError) Error() string {
+	return fmt.Sprintf("%s: %s %q is not a valid UUID", ErrorCodeInvalidUUID, e.Field, e.Value)
+}
+
+// ParseUUID parses a UUID received from a client. Any failure is reported as
+// an *InvalidUUIDError naming field.
+func ParseUUID(field, value string) (uuid.UUID, error) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, &InvalidUUIDError{Field: field, Value: value}
+	}
+	return id, nil
+}
+
+// uuidMessageFields are the WebSocket message fields that carry session IDs
+var uuidMessageFields = []string{"session_id", "parent_session_id"}
+
+// validateMessageUUIDs checks the session ID fields of an incoming WebSocket
+// message before it is routed, so malformed IDs are rejected with the same
+// invalid_uuid error regardless of message type. Absent or empty fields are
+// left to the individual handlers.
+func validateMessageUUIDs(rawMsg map[string]interface{}) error {
+	for _, field := range uuidMessageFields {
+		raw, ok := rawMsg[field]
+		if !ok || raw == nil {
+			continue
+		}
+		value, ok := raw.(string)
+		if !ok {
+			return &InvalidUUIDError{Field: field, Value: fmt.Sprint(raw)}
+		}
+		if value == "" {
+			continue
+		}
+		if _, err := ParseUUID(field, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendInvalidUUIDError reports an invalid UUID to the client with its error
+// code and offending value
+func (h *AgentHandler) sendInvalidUUIDError(c EventWriter, uuidErr *InvalidUUIDError) {
+	err := c.WriteJSON(map[string]interface{}{
+		"type":    "error",
+		"code":    ErrorCodeInvalidUUID,
+		"message": uuidErr.Error(),
+		"field":   uuidErr.Field,
+		"value":   uuidErr.Value,
+	})
+	if err != nil {
+		log.Printf("Failed to send error message: %v", err)
+	}
+}
+
+// rejectInvalidUUIDs validates rawMsg's session ID fields, replying with an
+// invalid_uuid error and returning true if any are malformed
+func (h *AgentHandler) rejectInvalidUUIDs(c EventWriter, rawMsg map[string]interface{}) bool {
+	var uuidErr *InvalidUUIDError
+	if err := validateMessageUUIDs(rawMsg); errors.As(err, &uuidErr) {
+		log.Printf("ERROR: Rejecting message with %v", uuidErr)
+		h.sendInvalidUUIDError(c, uuidErr)
+		return true
+	}
+	return false
+}
@@ -38,6 +38,7 @@ type SessionAuthMiddleware struct {
 	userStore    *UserStore
 	enabled      bool
 	requireLogin bool
+	publicPaths  map[string]bool // Extra GET paths that never require login
 }
 
 // NewSessionAuthMiddleware creates a new session authentication middleware
@@ -49,6 +50,14 @@ func NewSessionAuthMiddleware(userStore *UserStore, enabled bool, requireLogin b
 	}
 }
 
+// AllowPublicPath lets GET requests to path bypass session authentication
+func (sam *SessionAuthMiddleware) AllowPublicPath(path string) {
+	if sam.publicPaths == nil {
+		sam.publicPaths = make(map[string]bool)
+	}
+	sam.publicPaths[path] = true
+}
+
 // Handler returns the Fiber middleware handler
 func (sam *SessionAuthMiddleware) Handler() fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -68,6 +77,11 @@ func (sam *SessionAuthMiddleware) Handler() fiber.Handler {
 			return c.Next()
 		}
 
+		// Allow explicitly public paths (e.g. metrics scraping)
+		if sam.publicPaths[path] && c.Method() == "GET" {
+			return c.Next()
+		}
+
 		method := c.Method()
 
 		// If require_login is false, allow GET/OPTIONS requests without auth
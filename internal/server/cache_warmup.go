@@ -0,0 +1,22 @@
+package server
+
+import (
+	"time"
+
+	"github.com/schlunsen/claude-control-terminal/internal/logging"
+)
+
+// warmCaches loads conversations once so the analyzer's cache is populated
+// before the first request. Process and shell detection are not warmed: their
+// caches expire within a second, long before anyone opens the dashboard.
+// Errors are logged and otherwise ignored; the next request simply pays the
+// cold-start cost.
+func (s *Server) warmCaches() {
+	start := time.Now()
+
+	if _, err := s.conversationAnalyzer.LoadConversations(s.stateCalculator); err != nil {
+		logging.Warning("Cache warm-up failed to load conversations: %v", err)
+	}
+
+	logging.Debug("Cache warm-up finished in %s", time.Since(start))
+}
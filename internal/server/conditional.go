@@ -0,0 +1,50 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// conversationsNotModified sets an ETag derived from the conversation files,
+// the request URL and any extra response inputs (e.g. statuses stored in the
+// database), and reports whether the client's If-None-Match already matches.
+// Handlers should respond 304 without loading conversations when it does.
+func (s *Server) conversationsNotModified(c *fiber.Ctx, extra ...interface{}) (bool, error) {
+	fingerprint, err := s.conversationAnalyzer.ConversationsFingerprint()
+	if err != nil {
+		return false, fmt.Errorf("failed to fingerprint conversations: %w", err)
+	}
+
+	// Maps marshal with sorted keys, so equal inputs produce equal ETags
+	extraJSON, err := json.Marshal(extra)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode ETag inputs: %w", err)
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n%s", fingerprint, c.OriginalURL(), extraJSON)
+	etag := `"` + hex.EncodeToString(hash.Sum(nil))[:32] + `"`
+
+	c.Set(fiber.HeaderETag, etag)
+	return etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag), nil
+}
+
+// etagMatches reports whether an If-None-Match header matches the ETag,
+// using weak comparison as required for If-None-Match
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -18,6 +18,9 @@ type Config struct {
 	Server  ServerSettings  `json:"server"`
 	CORS    CORSSettings    `json:"cors"`
 	Agent   AgentSettings   `json:"agent"`
+	Notifications NotificationSettings `json:"notifications"`
+	Recording     RecordingSettings    `json:"recording"`
+	Metrics       MetricsSettings      `json:"metrics"`
 }
 
 // TLSSettings holds TLS configuration
@@ -42,6 +45,15 @@ type ServerSettings struct {
 	Host      string `json:"host"`
 	Quiet     bool   `json:"quiet"`
 	Verbose   bool   `json:"verbose"`
+	LogFormat string `json:"log_format,omitempty"` // "text" (default) or "json"
+	LogMaxSizeMB  int `json:"log_max_size_mb,omitempty"` // Rotate the verbose log and SDK stderr log at this size (default: 0, no rotation)
+	LogMaxBackups int `json:"log_max_backups,omitempty"` // Rotated log files kept per log (default: 3)
+	DrainTimeoutSeconds int `json:"drain_timeout_seconds,omitempty"` // Max wait for active agent connections on SIGTERM (default: 10)
+	UnixSocket          string `json:"unix_socket,omitempty"`          // Listen on this Unix socket path instead of host:port
+	WarmCacheOnStart    bool   `json:"warm_cache_on_start,omitempty"`  // Pre-load conversations in the background after setup
+	WatchConversations  bool   `json:"watch_conversations,omitempty"`  // Watch ~/.claude/projects and push conversations_updated when Claude CLI writes a conversation (default: false)
+	BroadcastCoalesceMS     int      `json:"broadcast_coalesce_ms,omitempty"`     // Batch WebSocket events of the same type within this window, e.g. 100 (default: 0, disabled)
+	BroadcastCoalesceEvents []string `json:"broadcast_coalesce_events,omitempty"` // Event types to batch, a trailing * matches a prefix (default: all)
 }
 
 // CORSSettings holds CORS configuration
@@ -49,13 +61,41 @@ type CORSSettings struct {
 	AllowedOrigins []string `json:"allowed_origins"`
 }
 
+
 // AgentSettings holds agent configuration
 type AgentSettings struct {
-	Model                 string `json:"model"`
-	MaxConcurrentSessions int    `json:"max_concurrent_sessions"`
-	SessionRetentionDays  int    `json:"session_retention_days"`
-	CleanupEnabled        bool   `json:"cleanup_enabled"`
-	CleanupIntervalHours  int    `json:"cleanup_interval_hours"`
+	Model                 string   `json:"model"`
+	MaxConcurrentSessions int      `json:"max_concurrent_sessions"`
+	SessionRetentionDays  int      `json:"session_retention_days"`
+	CleanupEnabled        bool     `json:"cleanup_enabled"`
+	CleanupIntervalHours  int      `json:"cleanup_interval_hours"`
+	MessageRetentionDays  int      `json:"message_retention_days,omitempty"` // 0 = keep messages as long as their session
+	DisabledTools         []string `json:"disabled_tools,omitempty"`         // Tools denied for every agent session (e.g. WebFetch)
+	PermissionResponseTimeoutSeconds int `json:"permission_response_timeout_seconds,omitempty"` // Wait for the user to answer a permission request (default: 60)
+	PermissionSendTimeoutSeconds     int `json:"permission_send_timeout_seconds,omitempty"`     // Wait to hand a permission request to the frontend (default: 5)
+	DefaultPermissionMode            string `json:"default_permission_mode,omitempty"`         // Mode for new sessions that don't set one: "default", "allow-all" or "read-only"
+	StoreThinking                    bool   `json:"store_thinking,omitempty"`                  // Persist assistant thinking content (default: false)
+}
+
+// NotificationSettings holds outbound notification configuration
+type NotificationSettings struct {
+	WebhookURL            string `json:"webhook_url,omitempty"`             // POST target for recorded notifications (Slack, Discord, custom)
+	WebhookTimeoutSeconds int    `json:"webhook_timeout_seconds,omitempty"` // Per-attempt timeout (default: 5)
+	IdleAlertMinutes      int    `json:"idle_alert_minutes,omitempty"`      // Record an idle_alert when a live agent session has no activity this long (0 = disabled)
+}
+
+// RecordingSettings holds limits for data recorded by hooks
+type RecordingSettings struct {
+	MaxPromptLength      int  `json:"max_prompt_length,omitempty"`       // Max prompt size in bytes (default: 100000)
+	TruncatePrompts      bool `json:"truncate_prompts,omitempty"`        // Truncate oversized prompts instead of rejecting them
+	MaxSessionNameLength int  `json:"max_session_name_length,omitempty"` // Max session name length (default: 200)
+	PromptDedupWindowMS  int  `json:"prompt_dedup_window_ms,omitempty"`  // Skip identical consecutive prompts within this window (default: 2000, negative disables)
+	MaxCommandOutputBytes int `json:"max_command_output_bytes,omitempty"` // Max stored stdout/stderr per shell command in bytes (default: 65536, negative disables)
+}
+
+// MetricsSettings holds Prometheus metrics configuration
+type MetricsSettings struct {
+	Public bool `json:"public,omitempty"` // Allow scraping /metrics without logging in
 }
 
 // ConfigManager handles configuration loading and saving
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/schlunsen/claude-control-terminal/internal/analytics"
+	"github.com/schlunsen/claude-control-terminal/internal/logging"
+)
+
+// startConversationWatcher watches the Claude projects directory with fsnotify
+// and tells clients when a conversation file changes. Failures are logged and
+// leave the server running without the watcher.
+func (s *Server) startConversationWatcher() {
+	projectsDir := filepath.Join(s.claudeDir, "projects")
+
+	watcher, err := analytics.NewFileWatcherWithOptions(projectsDir, s.onConversationFilesChanged, s.quiet)
+	if err != nil {
+		logging.Warning("Failed to create conversation watcher: %v", err)
+		return
+	}
+
+	if err := watcher.Start(); err != nil {
+		logging.Warning("Failed to watch %s: %v", projectsDir, err)
+		watcher.Stop()
+		return
+	}
+
+	s.fileWatcher = watcher
+	logging.Info("Watching conversations in %s", projectsDir)
+}
+
+// onConversationFilesChanged drops cached conversation state and broadcasts
+// conversations_updated so dashboards reload
+func (s *Server) onConversationFilesChanged() error {
+	s.stateCalculator.ClearCache()
+	s.wsHub.BroadcastData("conversations_updated", fiber.Map{
+		"timestamp": time.Now(),
+	})
+	return nil
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/schlunsen/claude-control-terminal/internal/analytics"
+)
+
+const (
+	// defaultDashboardConversations is the number of recent conversations returned by /api/dashboard
+	defaultDashboardConversations = 20
+
+	// maxDashboardConversations caps the ?conversations= query parameter
+	maxDashboardConversations = 200
+)
+
+// Handler: Get stats, process/shell counts and recent conversations in one response.
+// Each source is gathered concurrently so the dashboard needs a single round-trip.
+func (s *Server) handleGetDashboard(c *fiber.Ctx) error {
+	limit := c.QueryInt("conversations", defaultDashboardConversations)
+	if limit <= 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "conversations must be a positive integer",
+		})
+	}
+	if limit > maxDashboardConversations {
+		limit = maxDashboardConversations
+	}
+
+	var (
+		wg                 sync.WaitGroup
+		errMu              sync.Mutex
+		errs               []error
+		stats              fiber.Map
+		recent             []analytics.Conversation
+		totalConversations int
+		processCount       int
+		shellCount         int
+	)
+
+	addErr := func(err error) {
+		errMu.Lock()
+		errs = append(errs, err)
+		errMu.Unlock()
+	}
+
+	wg.Add(3)
+
+	go func() {
+		defer wg.Done()
+		conversations, err := s.conversationAnalyzer.LoadConversations(s.stateCalculator)
+		if err != nil {
+			addErr(fmt.Errorf("failed to load conversations: %w", err))
+			return
+		}
+		stats = s.buildStats(conversations)
+		totalConversations = len(conversations)
+		recent = recentConversations(conversations, limit)
+	}()
+
+	go func() {
+		defer wg.Done()
+		processes, err := s.processDetector.DetectRunningClaudeProcesses()
+		if err != nil {
+			addErr(fmt.Errorf("failed to detect processes: %w", err))
+			return
+		}
+		processCount = len(processes)
+	}()
+
+	go func() {
+		defer wg.Done()
+		shells, err := s.shellDetector.DetectBackgroundShells()
+		if err != nil {
+			addErr(fmt.Errorf("failed to detect shells: %w", err))
+			return
+		}
+		shellCount = len(shells)
+	}()
+
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"stats":              stats,
+		"activeProcessCount": processCount,
+		"shellCount":         shellCount,
+		"conversations":      recent,
+		"totalConversations": totalConversations,
+		"conversationsLimit": limit,
+		"timestamp":          time.Now(),
+	})
+}
+
+// recentConversations returns up to limit conversations, most recently modified first
+func recentConversations(conversations []analytics.Conversation, limit int) []analytics.Conversation {
+	sorted := make([]analytics.Conversation, len(conversations))
+	copy(sorted, conversations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].LastModified.After(sorted[j].LastModified)
+	})
+
+	if len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+	return sorted
+}
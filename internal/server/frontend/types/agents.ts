@@ -40,7 +40,6 @@ export interface Session {
     tools?: string[]
     working_directory?: string
     max_tokens?: number
-    temperature?: number
     permission_mode?: string
   }
   message_count: number
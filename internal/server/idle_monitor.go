@@ -0,0 +1,146 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/schlunsen/claude-control-terminal/internal/database"
+	"github.com/schlunsen/claude-control-terminal/internal/logging"
+	"github.com/schlunsen/claude-control-terminal/internal/server/agents"
+)
+
+// idleCheckInterval is how often agent sessions are checked for inactivity
+const idleCheckInterval = time.Minute
+
+// IdleMonitor records an idle_alert notification when a live agent session
+// has had no activity for the configured duration. Each idle period is
+// reported once; new activity re-arms the alert.
+type IdleMonitor struct {
+	sessions func() []agents.Session
+	notify   func(*database.Notification) error
+	idleFor  time.Duration
+	interval time.Duration
+	alerted  map[uuid.UUID]time.Time // session ID -> UpdatedAt when the alert was sent
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewIdleMonitor creates a monitor that reads live sessions from the given
+// function and publishes alerts through notify. Returns nil when idleFor is not positive.
+func NewIdleMonitor(sessions func() []agents.Session, notify func(*database.Notification) error, idleFor time.Duration) *IdleMonitor {
+	if idleFor <= 0 {
+		return nil
+	}
+
+	return &IdleMonitor{
+		sessions: sessions,
+		notify:   notify,
+		idleFor:  idleFor,
+		interval: idleCheckInterval,
+		alerted:  make(map[uuid.UUID]time.Time),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start begins checking in a background goroutine
+func (m *IdleMonitor) Start() {
+	if m == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				m.check(now)
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the monitoring goroutine. Safe to call more than once.
+func (m *IdleMonitor) Stop() {
+	if m == nil {
+		return
+	}
+	m.stopOnce.Do(func() { close(m.stop) })
+}
+
+// check alerts on every live session idle for at least idleFor that hasn't
+// been alerted since its last activity. Only called from the monitor goroutine.
+func (m *IdleMonitor) check(now time.Time) {
+	live := make(map[uuid.UUID]bool)
+
+	for _, session := range m.sessions() {
+		if session.Status != agents.SessionStatusIdle && session.Status != agents.SessionStatusProcessing {
+			continue
+		}
+		live[session.ID] = true
+
+		idle := now.Sub(session.UpdatedAt)
+		if idle < m.idleFor {
+			continue
+		}
+		if alertedAt, ok := m.alerted[session.ID]; ok && alertedAt.Equal(session.UpdatedAt) {
+			continue
+		}
+
+		if err := m.notify(idleNotification(session, idle, now)); err != nil {
+			logging.Warning("Failed to record idle alert for session %s: %v", session.ID, err)
+			continue
+		}
+		m.alerted[session.ID] = session.UpdatedAt
+	}
+
+	// Forget sessions that ended or were removed
+	for id := range m.alerted {
+		if !live[id] {
+			delete(m.alerted, id)
+		}
+	}
+}
+
+// idleNotification builds the idle_alert notification for a session
+func idleNotification(session agents.Session, idle time.Duration, now time.Time) *database.Notification {
+	idle = idle.Truncate(time.Second)
+
+	message := fmt.Sprintf("Agent session has been waiting for input for %s", idle)
+	if session.Status == agents.SessionStatusProcessing {
+		message = fmt.Sprintf("Agent session has produced no new messages for %s", idle)
+	}
+
+	notif := &database.Notification{
+		ConversationID:   session.ID.String(),
+		NotificationType: "idle_alert",
+		Message:          message,
+		CommandDetails:   fmt.Sprintf("idle_seconds=%d", int64(idle.Seconds())),
+		GitBranch:        session.GitBranch,
+		ModelProvider:    "Unknown",
+		ModelName:        session.ModelName,
+		NotifiedAt:       now,
+	}
+	if session.Options.AgentName != nil {
+		notif.SessionName = *session.Options.AgentName
+	}
+	if session.Options.WorkingDirectory != nil {
+		notif.WorkingDirectory = *session.Options.WorkingDirectory
+	}
+	if session.Options.Provider != nil && *session.Options.Provider != "" {
+		notif.ModelProvider = *session.Options.Provider
+	}
+	if notif.ModelName == "" && session.Options.Model != nil {
+		notif.ModelName = *session.Options.Model
+	}
+	if notif.ModelName == "" {
+		notif.ModelName = "Unknown"
+	}
+
+	return notif
+}
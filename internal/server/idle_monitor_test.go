@@ -0,0 +1,64 @@
+package server
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/schlunsen/claude-control-terminal/internal/database"
+	"github.com/schlunsen/claude-control-terminal/internal/server/agents"
+)
+
+func TestIdleMonitorDisabled(t *testing.T) {
+	if m := NewIdleMonitor(nil, nil, 0); m != nil {
+		t.Error("expected nil monitor when idle alerts are disabled")
+	}
+	// Nil monitors are safe to start and stop
+	var m *IdleMonitor
+	m.Start()
+	m.Stop()
+}
+
+func TestIdleMonitorCheck(t *testing.T) {
+	now := time.Now()
+	idleID, busyID, endedID := uuid.New(), uuid.New(), uuid.New()
+
+	sessions := []agents.Session{
+		{ID: idleID, Status: agents.SessionStatusIdle, UpdatedAt: now.Add(-20 * time.Minute)},
+		{ID: busyID, Status: agents.SessionStatusProcessing, UpdatedAt: now.Add(-2 * time.Minute)},
+		{ID: endedID, Status: agents.SessionStatusEnded, UpdatedAt: now.Add(-time.Hour)},
+	}
+
+	var recorded []*database.Notification
+	m := NewIdleMonitor(
+		func() []agents.Session { return sessions },
+		func(n *database.Notification) error { recorded = append(recorded, n); return nil },
+		15*time.Minute,
+	)
+
+	m.check(now)
+	if len(recorded) != 1 {
+		t.Fatalf("expected 1 idle alert, got %d", len(recorded))
+	}
+	alert := recorded[0]
+	if alert.ConversationID != idleID.String() || alert.NotificationType != "idle_alert" {
+		t.Errorf("unexpected alert: %+v", alert)
+	}
+	if !strings.Contains(alert.Message, "20m0s") || alert.CommandDetails != "idle_seconds=1200" {
+		t.Errorf("expected idle duration in alert, got %q / %q", alert.Message, alert.CommandDetails)
+	}
+
+	// The same idle period is reported only once
+	m.check(now.Add(time.Minute))
+	if len(recorded) != 1 {
+		t.Fatalf("expected no repeat alert, got %d alerts", len(recorded))
+	}
+
+	// New activity re-arms the alert
+	sessions[0].UpdatedAt = now
+	m.check(now.Add(16 * time.Minute))
+	if len(recorded) != 3 {
+		t.Fatalf("expected alerts for both idle sessions, got %d", len(recorded))
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// metric is a single Prometheus sample with its metadata
+type metric struct {
+	name   string
+	help   string
+	kind   string // "gauge" or "counter"
+	labels string // Pre-formatted label set, e.g. `type="agent"`
+	value  float64
+}
+
+// writeMetrics renders metrics in the Prometheus text exposition format.
+// HELP and TYPE lines are emitted once per metric name.
+func writeMetrics(metrics []metric) string {
+	var b strings.Builder
+	seen := make(map[string]bool)
+
+	for _, m := range metrics {
+		if !seen[m.name] {
+			fmt.Fprintf(&b, "# HELP %s %s\n", m.name, m.help)
+			fmt.Fprintf(&b, "# TYPE %s %s\n", m.name, m.kind)
+			seen[m.name] = true
+		}
+
+		if m.labels != "" {
+			fmt.Fprintf(&b, "%s{%s} %v\n", m.name, m.labels, m.value)
+		} else {
+			fmt.Fprintf(&b, "%s %v\n", m.name, m.value)
+		}
+	}
+
+	return b.String()
+}
+
+// Handler: Prometheus metrics
+func (s *Server) handleMetrics(c *fiber.Ctx) error {
+	var metrics []metric
+
+	if s.agentHandler != nil {
+		metrics = append(metrics, metric{
+			name:  "cct_active_agent_sessions",
+			help:  "Number of agent sessions currently held in memory.",
+			kind:  "gauge",
+			value: float64(len(s.agentHandler.SessionManager.ListSessions())),
+		})
+	}
+
+	if s.repo != nil {
+		totals, err := s.repo.GetMetricTotals()
+		if err != nil {
+			return c.Status(500).SendString(fmt.Sprintf("# failed to collect metrics: %v\n", err))
+		}
+
+		metrics = append(metrics,
+			metric{
+				name:  "cct_total_prompts",
+				help:  "Total user prompts recorded.",
+				kind:  "counter",
+				value: float64(totals.TotalPrompts),
+			},
+			metric{
+				name:  "cct_total_tokens",
+				help:  "Total tokens across recorded conversations.",
+				kind:  "counter",
+				value: float64(totals.TotalTokens),
+			},
+			metric{
+				name:  "cct_agent_cost_usd_total",
+				help:  "Total cost in USD of all persisted agent sessions.",
+				kind:  "counter",
+				value: totals.AgentCostUSD,
+			},
+		)
+	}
+
+	if s.wsHub != nil {
+		metrics = append(metrics, metric{
+			name:   "cct_websocket_connections",
+			help:   "Number of open WebSocket connections.",
+			kind:   "gauge",
+			labels: `type="analytics"`,
+			value:  float64(s.wsHub.ClientCount()),
+		})
+	}
+
+	if s.agentHandler != nil {
+		metrics = append(metrics, metric{
+			name:   "cct_websocket_connections",
+			help:   "Number of open WebSocket connections.",
+			kind:   "gauge",
+			labels: `type="agent"`,
+			value:  float64(s.agentHandler.ActiveConnections()),
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	return c.SendString(writeMetrics(metrics))
+}
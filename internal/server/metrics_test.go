@@ -0,0 +1,32 @@
+package server
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	output := writeMetrics([]metric{
+		{name: "cct_total_prompts", help: "Total prompts.", kind: "counter", value: 42},
+		{name: "cct_websocket_connections", help: "Open connections.", kind: "gauge", labels: `type="analytics"`, value: 2},
+		{name: "cct_websocket_connections", help: "Open connections.", kind: "gauge", labels: `type="agent"`, value: 1},
+	})
+
+	expected := []string{
+		"# HELP cct_total_prompts Total prompts.",
+		"# TYPE cct_total_prompts counter",
+		"cct_total_prompts 42",
+		`cct_websocket_connections{type="analytics"} 2`,
+		`cct_websocket_connections{type="agent"} 1`,
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("expected output to contain %q, got:\n%s", line, output)
+		}
+	}
+
+	if strings.Count(output, "# TYPE cct_websocket_connections") != 1 {
+		t.Error("expected TYPE line to be emitted once per metric name")
+	}
+}
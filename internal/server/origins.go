@@ -0,0 +1,117 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/schlunsen/claude-control-terminal/internal/logging"
+)
+
+// originAllowlist is the live set of origins trusted by CORS and the API key
+// endpoint. It starts from CORS.AllowedOrigins and can grow at runtime, e.g.
+// when a tunnel's public hostname only becomes known after startup.
+type originAllowlist struct {
+	mu      sync.RWMutex
+	origins []string
+}
+
+// newOriginAllowlist creates an allowlist seeded with the configured origins
+func newOriginAllowlist(origins []string) *originAllowlist {
+	a := &originAllowlist{}
+	for _, origin := range origins {
+		a.add(origin)
+	}
+	return a
+}
+
+// allowed reports whether origin is in the allowlist (case-insensitive)
+func (a *originAllowlist) allowed(origin string) bool {
+	if a == nil {
+		return false
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for _, allowed := range a.origins {
+		if strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// add appends origin if not already present, reporting whether it was added
+func (a *originAllowlist) add(origin string) bool {
+	origin = strings.ToLower(strings.TrimSpace(origin))
+	if origin == "" || a.allowed(origin) {
+		return false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.origins = append(a.origins, origin)
+	return true
+}
+
+// list returns a copy of the allowed origins
+func (a *originAllowlist) list() []string {
+	if a == nil {
+		return []string{}
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return append([]string{}, a.origins...)
+}
+
+// AllowOrigin trusts the origin of rawURL (scheme://host[:port]) for CORS and
+// the API key endpoint for the lifetime of the server, without touching the
+// config file. Remote access integrations call this once their public URL is
+// known (in-process, or through POST /api/cors/origins), so browsers loading
+// the dashboard through that URL are not rejected with 403. Must be called
+// after Setup.
+func (s *Server) AllowOrigin(rawURL string) error {
+	if s.allowedOrigins == nil {
+		return fmt.Errorf("server is not set up")
+	}
+
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return fmt.Errorf("invalid origin URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid origin URL %q: expected http(s)://host", rawURL)
+	}
+
+	origin := u.Scheme + "://" + u.Host
+	if s.allowedOrigins.add(origin) {
+		logging.Info("Allowed origin registered: %s", origin)
+	}
+	return nil
+}
+
+// Handler: Trust a public URL's origin at runtime (e.g. a tunnel started by
+// another process). Like other write endpoints, this requires authentication.
+func (s *Server) handleAllowOrigin(c *fiber.Ctx) error {
+	var req struct {
+		URL string `json:"url"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if err := s.AllowOrigin(req.URL); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"allowed_origins": s.allowedOrigins.list(),
+	})
+}
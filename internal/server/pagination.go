@@ -0,0 +1,44 @@
+package server
+
+import "github.com/gofiber/fiber/v2"
+
+// PageResult is the pagination envelope shared by list endpoints, so a
+// frontend pager can read every paged response the same way.
+type PageResult struct {
+	Items   interface{} `json:"items"`
+	Total   int         `json:"total"`
+	Limit   int         `json:"limit"`
+	Offset  int         `json:"offset"`
+	HasMore bool        `json:"has_more"`
+}
+
+// newPageResult builds a page from the returned items and the total number
+// of matching rows. A limit of 0 means the page is unbounded.
+func newPageResult(items interface{}, returned, total, limit, offset int) PageResult {
+	return PageResult{
+		Items:   items,
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+		HasMore: offset+returned < total,
+	}
+}
+
+// withLegacy merges the page into a response that still carries the keys
+// the endpoint returned before the envelope existed (e.g. "count" and
+// "query"). Those keys are deprecated and will be removed in a later release.
+func (p PageResult) withLegacy(legacy fiber.Map) fiber.Map {
+	response := fiber.Map{
+		"items":    p.Items,
+		"total":    p.Total,
+		"limit":    p.Limit,
+		"offset":   p.Offset,
+		"has_more": p.HasMore,
+	}
+	for key, value := range legacy {
+		if _, ok := response[key]; !ok {
+			response[key] = value
+		}
+	}
+	return response
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/schlunsen/claude-control-terminal/internal/analytics"
+	"github.com/schlunsen/claude-control-terminal/internal/database"
+	"github.com/schlunsen/claude-control-terminal/internal/logging"
+)
+
+const (
+	// processSampleInterval is how often running Claude processes are sampled
+	processSampleInterval = time.Minute
+
+	// processSampleRetention is how long process samples are kept
+	processSampleRetention = 30 * 24 * time.Hour
+)
+
+// ProcessSampler periodically records detected Claude process counts
+type ProcessSampler struct {
+	detector *analytics.ProcessDetector
+	repo     *database.Repository
+	interval time.Duration
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewProcessSampler creates a sampler that records into the given repository
+func NewProcessSampler(detector *analytics.ProcessDetector, repo *database.Repository) *ProcessSampler {
+	return &ProcessSampler{
+		detector: detector,
+		repo:     repo,
+		interval: processSampleInterval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start begins sampling in a background goroutine
+func (ps *ProcessSampler) Start() {
+	go func() {
+		ps.sample()
+
+		ticker := time.NewTicker(ps.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ps.sample()
+			case <-ps.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the sampling goroutine. Safe to call more than once.
+func (ps *ProcessSampler) Stop() {
+	ps.stopOnce.Do(func() { close(ps.stop) })
+}
+
+// sample records the current process count and prunes expired samples
+func (ps *ProcessSampler) sample() {
+	stats, err := ps.detector.GetProcessStats()
+	if err != nil {
+		logging.Debug("Process sampling failed: %v", err)
+		return
+	}
+
+	now := time.Now()
+	if err := ps.repo.RecordProcessSample(&database.ProcessSample{
+		SampledAt:     now,
+		ProcessCount:  stats.Total,
+		KnownDirCount: stats.WithKnownWorkingDir,
+	}); err != nil {
+		logging.Warning("Failed to record process sample: %v", err)
+		return
+	}
+
+	if _, err := ps.repo.DeleteProcessSamplesBefore(now.Add(-processSampleRetention)); err != nil {
+		logging.Warning("Failed to prune process samples: %v", err)
+	}
+}
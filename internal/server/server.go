@@ -4,14 +4,19 @@
 package server
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/schlunsen/claude-control-terminal/internal/analytics"
@@ -36,6 +41,9 @@ type Server struct {
 	conversationParser    *analytics.ConversationParser
 	stateCalculator       *analytics.StateCalculator
 	processDetector       *analytics.ProcessDetector
+	processSampler        *ProcessSampler
+	idleMonitor           *IdleMonitor
+	shellSampler          *ShellSampler
 	shellDetector         *analytics.ShellDetector
 	fileWatcher           *analytics.FileWatcher
 	wsHub                 *ws.Hub
@@ -44,25 +52,34 @@ type Server struct {
 	db                    *database.Database
 	repo                  *database.Repository
 	config                *Config
+	allowedOrigins        *originAllowlist // CORS origins, extended at runtime by AllowOrigin
 	tlsConfig             *TLSConfig
 	authMiddleware        *AuthMiddleware
 	sessionAuthMiddleware *SessionAuthMiddleware
 	userStore             *UserStore
 	agentHandler          *agents.AgentHandler
 	agentConfig           *agents.Config
+	notificationDispatcher *NotificationDispatcher
+	versionChecker        *VersionChecker
 	claudeDir             string
+	host                  string // Bind host override; empty uses config
 	port                  int
 	quiet                 bool // Suppress output when running in TUI
 	verbose               bool // Enable verbose/debug logging
+
+	// WarmCacheOnStart pre-loads conversations in the background after
+	// Setup so the first dashboard request is fast.
+	WarmCacheOnStart bool
 }
 
 // NewServer creates a new Fiber server instance
 func NewServer(claudeDir string, port int) *Server {
-	return NewServerWithOptions(claudeDir, port, false, false)
+	return NewServerWithOptions(claudeDir, "", port, false, false)
 }
 
-// NewServerWithOptions creates a new Fiber server instance with options
-func NewServerWithOptions(claudeDir string, port int, quiet bool, verbose bool) *Server {
+// NewServerWithOptions creates a new Fiber server instance with options.
+// A non-empty host and non-zero port override the values from config.
+func NewServerWithOptions(claudeDir string, host string, port int, quiet bool, verbose bool) *Server {
 	app := fiber.New(fiber.Config{
 		AppName: "Claude Code Analytics",
 		ServerHeader: "go-claude-templates",
@@ -70,11 +87,13 @@ func NewServerWithOptions(claudeDir string, port int, quiet bool, verbose bool)
 	})
 
 	return &Server{
-		app:       app,
-		claudeDir: claudeDir,
-		port:      port,
-		quiet:     quiet,
-		verbose:   verbose,
+		app:            app,
+		claudeDir:      claudeDir,
+		host:           host,
+		port:           port,
+		quiet:          quiet,
+		verbose:        verbose,
+		versionChecker: NewVersionChecker(),
 	}
 }
 
@@ -104,10 +123,19 @@ func (s *Server) Setup() error {
 		s.verbose = config.Server.Verbose
 	}
 
+	// Use cache warm-up from config if not set explicitly
+	if !s.WarmCacheOnStart && config.Server.WarmCacheOnStart {
+		s.WarmCacheOnStart = true
+	}
+
 	// Initialize logging if verbose is enabled
 	if s.verbose {
 		logDir := filepath.Join(s.claudeDir, "analytics", "logs")
-		logger, err := logging.Initialize(logDir, s.verbose)
+		logger, err := logging.InitializeWithOptions(logDir, s.verbose, logging.Options{
+			Format:     config.Server.LogFormat,
+			MaxSizeMB:  config.Server.LogMaxSizeMB,
+			MaxBackups: config.Server.LogMaxBackups,
+		})
 		if err != nil {
 			return fmt.Errorf("failed to initialize logging: %w", err)
 		}
@@ -155,6 +183,9 @@ func (s *Server) Setup() error {
 
 		// Create session auth middleware
 		s.sessionAuthMiddleware = NewSessionAuthMiddleware(s.userStore, true, config.Auth.RequireLogin)
+		if config.Metrics.Public {
+			s.sessionAuthMiddleware.AllowPublicPath("/metrics")
+		}
 
 		if !s.quiet {
 			if s.userStore.HasUsers() {
@@ -208,17 +239,28 @@ func (s *Server) Setup() error {
 		SessionRetentionDays:  retentionDays,
 		CleanupEnabled:        cleanupEnabled,
 		CleanupIntervalHours:  cleanupInterval,
+		MessageRetentionDays:  config.Agent.MessageRetentionDays,
+		DisabledTools:         config.Agent.DisabledTools,
+		PermissionResponseTimeout: time.Duration(config.Agent.PermissionResponseTimeoutSeconds) * time.Second,
+		PermissionSendTimeout:     time.Duration(config.Agent.PermissionSendTimeoutSeconds) * time.Second,
+		DefaultPermissionMode:     config.Agent.DefaultPermissionMode,
+		StoreThinking:             config.Agent.StoreThinking,
 	}
 	s.agentConfig = agentConfig
 
 	// Note: Agent handler will be initialized after database is ready
 
-	// Configure CORS middleware
+	// Configure CORS middleware. Origins are checked against the live
+	// allowlist so origins registered after startup (AllowOrigin) take effect;
+	// an empty configured list keeps Fiber's allow-all default.
+	s.allowedOrigins = newOriginAllowlist(config.CORS.AllowedOrigins)
 	corsConfig := cors.Config{
-		AllowOrigins: strings.Join(config.CORS.AllowedOrigins, ","),
 		AllowMethods: "GET,POST,PUT,DELETE,OPTIONS",
 		AllowHeaders: "Origin,Content-Type,Accept,Authorization",
 	}
+	if len(config.CORS.AllowedOrigins) > 0 {
+		corsConfig.AllowOriginsFunc = s.allowedOrigins.allowed
+	}
 	s.app.Use(cors.New(corsConfig))
 
 	// Only add logger middleware if not in quiet mode
@@ -242,41 +284,67 @@ func (s *Server) Setup() error {
 	}
 	s.db = db
 	s.repo = database.NewRepository(db)
+	s.repo.SetPromptDedupWindow(s.promptDedupWindow())
 
-	// Initialize agent handler (requires database)
+	// Initialize agent handler (requires database). A failure is not fatal:
+	// agentHandler stays nil, agent endpoints answer 503 and the
+	// conversation analytics keep working.
 	agentHandler, err := agents.NewAgentHandler(agentConfig, db.GetDB())
 	if err != nil {
-		return fmt.Errorf("failed to initialize agent handler: %w", err)
-	}
-	s.agentHandler = agentHandler
+		logging.Error("Failed to initialize agent handler, agent features disabled: %v", err)
+		if !s.quiet {
+			fmt.Printf("⚠️  Agent features disabled: failed to initialize agent handler: %v\n", err)
+		}
+	} else {
+		s.agentHandler = agentHandler
 
-	if !s.quiet {
-		fmt.Printf("🤖 Agent handler initialized (model: %s, max sessions: %d, verbose: %v)\n",
-			agentConfig.Model, agentConfig.MaxConcurrentSessions, agentConfig.Verbose)
-	}
+		if !s.quiet {
+			fmt.Printf("🤖 Agent handler initialized (model: %s, max sessions: %d, verbose: %v)\n",
+				agentConfig.Model, agentConfig.MaxConcurrentSessions, agentConfig.Verbose)
+		}
 
-	if s.verbose {
-		logging.Info("Agent handler initialized: model=%s, maxSessions=%d, verbose=%v, apiKeySet=%v",
-			agentConfig.Model, agentConfig.MaxConcurrentSessions, agentConfig.Verbose, agentAPIKey != "")
-	}
+		if s.verbose {
+			logging.Info("Agent handler initialized: model=%s, maxSessions=%d, verbose=%v, apiKeySet=%v",
+				agentConfig.Model, agentConfig.MaxConcurrentSessions, agentConfig.Verbose, agentAPIKey != "")
+		}
 
-	// Start session cleanup job
-	s.agentHandler.SessionManager.StartCleanupJob()
+		// Start session cleanup job
+		s.agentHandler.SessionManager.StartCleanupJob()
+	}
 
 	// Initialize analytics components
 	s.conversationAnalyzer = analytics.NewConversationAnalyzer(s.claudeDir)
 	s.conversationParser = analytics.NewConversationParser(s.repo)
 	s.stateCalculator = analytics.NewStateCalculator()
 	s.processDetector = analytics.NewProcessDetector()
+	s.processSampler = NewProcessSampler(s.processDetector, s.repo)
+	s.processSampler.Start()
 	s.shellDetector = analytics.NewShellDetector()
+	s.shellSampler = NewShellSampler(s.shellDetector, s.repo)
+	s.shellSampler.Start()
 	s.resetTracker = analytics.NewResetTracker(s.claudeDir)
 	s.modelProviderLookup = analytics.NewModelProviderLookup()
 
+	// Initialize notification webhook dispatcher (nil when no webhook configured)
+	s.notificationDispatcher = NewNotificationDispatcher(config.Notifications)
+
 	// Initialize WebSocket hub
 	s.wsHub = ws.NewHub()
+	if config.Server.BroadcastCoalesceMS > 0 {
+		s.wsHub.SetCoalescing(time.Duration(config.Server.BroadcastCoalesceMS)*time.Millisecond, config.Server.BroadcastCoalesceEvents)
+	}
 	go s.wsHub.Run()
 
-	// File watcher removed - WebSocket updates triggered by database operations only
+	// Agent idle alerts and session event broadcasts
+	if s.agentHandler != nil {
+		s.setupAgentEvents(config)
+	}
+
+	// WebSocket updates are triggered by database operations. Watching the
+	// conversation files written by the Claude CLI is opt-in.
+	if config.Server.WatchConversations {
+		s.startConversationWatcher()
+	}
 
 	// Setup API routes
 	s.setupRoutes()
@@ -284,9 +352,54 @@ func (s *Server) Setup() error {
 	// Serve static files
 	s.ServeStaticFiles()
 
+	// Warm caches in the background so startup isn't delayed
+	if s.WarmCacheOnStart {
+		go s.warmCaches()
+	}
+
 	return nil
 }
 
+// setupAgentEvents starts the idle alert monitor and pushes agent session
+// events to WebSocket clients. Requires the agent handler.
+func (s *Server) setupAgentEvents(config *Config) {
+	// Start idle alert monitor for agent sessions (nil when disabled)
+	s.idleMonitor = NewIdleMonitor(
+		s.agentHandler.SessionManager.ListSessions,
+		s.publishNotification,
+		time.Duration(config.Notifications.IdleAlertMinutes)*time.Minute,
+	)
+	s.idleMonitor.Start()
+
+	// Push agent session status transitions so clients don't need to poll
+	s.agentHandler.SessionManager.OnStatusChange(func(sessionID uuid.UUID, status agents.SessionStatus) {
+		s.wsHub.BroadcastData(string(agents.MessageTypeSessionStatusChanged), agents.SessionStatusChangedMessage{
+			BaseMessage: agents.BaseMessage{Type: agents.MessageTypeSessionStatusChanged},
+			SessionID:   sessionID,
+			Status:      status,
+		})
+	})
+
+	// Push session failures with their error code (e.g. auth_error) so the UI
+	// can prompt the user to fix their API key
+	s.agentHandler.SessionManager.OnSessionError(func(sessErr agents.SessionError) {
+		s.wsHub.BroadcastData(string(agents.MessageTypeSessionError), agents.SessionErrorMessage{
+			BaseMessage:  agents.BaseMessage{Type: agents.MessageTypeSessionError},
+			SessionError: sessErr,
+		})
+	})
+
+	// Push sessions the manager ended by itself (e.g. budget cap reached)
+	s.agentHandler.SessionManager.OnSessionEnded(func(sessionID uuid.UUID, reason string) {
+		s.wsHub.BroadcastData(string(agents.MessageTypeSessionEnded), agents.SessionEndedMessage{
+			BaseMessage: agents.BaseMessage{Type: agents.MessageTypeSessionEnded},
+			SessionID:   sessionID,
+			Status:      "ended",
+			Reason:      reason,
+		})
+	})
+}
+
 // setupRoutes configures all API endpoints
 func (s *Server) setupRoutes() {
 	api := s.app.Group("/api")
@@ -341,13 +454,23 @@ func (s *Server) setupRoutes() {
 
 	// Version info
 	api.Get("/version", s.handleGetVersion)
+	api.Get("/version/check", s.handleCheckVersion)
 
 	// Data endpoints
 	api.Get("/data", s.handleGetData)
 	api.Get("/conversations", s.handleGetConversations)
+	api.Get("/conversations/search", s.handleSearchConversations)
+	api.Post("/conversations/:id/status", s.handleSetConversationStatus)
+	api.Delete("/conversations/:id", s.handleDeleteConversation)
+	api.Get("/conversations/:id/tags", s.handleGetConversationTags)
+	api.Post("/conversations/:id/tags", s.handleAddConversationTag)
+	api.Delete("/conversations/:id/tags", s.handleRemoveConversationTag)
 	api.Get("/processes", s.handleGetProcesses)
+	api.Get("/processes/history", s.handleGetProcessHistory)
 	api.Get("/shells", s.handleGetShells)
+	api.Get("/shells/history", s.handleGetBackgroundShellHistory)
 	api.Get("/stats", s.handleGetStats)
+	api.Get("/dashboard", s.handleGetDashboard)
 
 	// Refresh endpoint
 	api.Post("/refresh", s.handleRefresh)
@@ -361,52 +484,105 @@ func (s *Server) setupRoutes() {
 
 	// Command history endpoints
 	api.Get("/history/all", s.handleGetAllHistory)
+	api.Get("/history/stream", s.handleStreamHistory)
 	api.Get("/history/shell", s.handleGetShellHistory)
 	api.Get("/history/claude", s.handleGetClaudeHistory)
+	api.Get("/history/claude/tools", s.handleGetClaudeToolNames)
 	api.Get("/history/stats", s.handleGetCommandStats)
 	api.Post("/commands/shell", s.handleRecordShellCommand)
 	api.Post("/commands/claude", s.handleRecordClaudeCommand)
 	api.Delete("/history", s.handleClearAllHistory)
 	api.Get("/db/stats", s.handleGetDBStats)
+	api.Get("/db/version", s.handleGetDBVersion)
 
 	// User prompts endpoints
 	api.Get("/prompts", s.handleGetUserPrompts)
 	api.Get("/prompts/stats", s.handleGetPromptStats)
 	api.Get("/prompts/sessions", s.handleGetUniqueSessions)
 	api.Post("/prompts", s.handleRecordUserPrompt)
+	api.Post("/prompts/:id/replay", s.handleReplayUserPrompt)
+	api.Get("/directories", s.handleGetWorkingDirectories)
 	api.Delete("/prompts", s.handleClearAllHistory) // Alias for backward compatibility
 
 	// Notification endpoints
 	api.Post("/notifications", s.handleRecordNotification)
 	api.Get("/notifications", s.handleGetNotifications)
 	api.Get("/notifications/stats", s.handleGetNotificationStats)
+	api.Get("/notifications/:id", s.handleGetNotification)
+	api.Post("/notifications/acknowledge", s.handleAcknowledgeNotifications)
 	api.Delete("/notifications", s.handleClearNotifications)
 
+	// Session lifecycle endpoints (session-logger hook)
+	api.Post("/sessions/lifecycle", s.handleRecordSessionLifecycle)
+	api.Get("/sessions/lifecycle", s.handleGetSessionLifecycle)
+
 	// Session resume endpoint
 	api.Get("/sessions/:conversation_id/resume-data", s.handleGetSessionResumeData)
+	api.Get("/sessions/:conversation_id/shell-log", s.handleGetSessionShellLog)
+	api.Get("/sessions/:id/settings/permissions", s.handleGetSessionSettingsPermissions)
 
 	// WebSocket endpoint
 	s.app.Get("/ws", websocket.New(s.wsHub.HandleWebSocket()))
 
+	// Prometheus metrics endpoint
+	s.app.Get("/metrics", s.handleMetrics)
+
 	// Config endpoints (for frontend to get API key securely)
+	api.Get("/config", s.handleGetPublicConfig)
 	api.Get("/config/api-key", s.handleGetAPIKey)
 	api.Get("/config/cwd", s.handleGetCWD)
 	api.Get("/config/permissions", s.handleGetProjectPermissions)
 
+	// Trust an origin that only became known at runtime (e.g. a tunnel URL)
+	api.Post("/cors/origins", s.handleAllowOrigin)
+
 	// Agent endpoints (serve agents from project directory)
 	api.Get("/agents", s.handleListAgents)
 	api.Get("/agents/:name", s.handleGetAgentDetail)
 
 	// Agent session endpoints (for persistence)
 	api.Get("/agent/sessions", s.handleGetAgentSessions)
+	api.Get("/agent/sessions/by-project", s.handleGetAgentSessionsByProject)
+	api.Get("/agent/sessions/by-model", s.handleGetAgentSessionsByModel)
+	api.Get("/agent/recent-responses", s.handleGetRecentAgentResponses)
+	api.Get("/agent/sessions/summary", s.handleGetAgentSessionsSummary)
+	api.Get("/agent/sessions/export.csv", s.handleExportAgentSessionsCSV)
+	api.Get("/agent/sessions/compare", s.handleCompareAgentSessions)
 	api.Get("/agent/sessions/:id/messages", s.handleGetAgentMessages)
+	api.Get("/agent/sessions/:id/flagged", s.handleGetAgentFlaggedMessages)
+	api.Get("/agent/sessions/:id/tools", s.handleGetAgentToolTimeline)
+	api.Get("/agent/sessions/:id/stream", s.handleStreamAgentPrompt)
+	api.Post("/agent/sessions/:id/rules/import", s.handleImportAgentRules)
+	api.Post("/agent/sessions/kill-by-dir", s.handleKillAgentSessionsByDir)
+	api.Post("/agent/sessions/:id/kill", s.handleForceKillAgentSession)
+	api.Get("/agent/sessions/:id/debug", s.handleGetAgentSessionDebug)
+	api.Get("/agent/sessions/:id/export.md", s.handleExportAgentSessionMarkdown)
+	api.Get("/agent/sessions/:id/repro", s.handleGetAgentSessionRepro)
+	api.Get("/agent/sessions/:id/resumable", s.handleGetAgentSessionResumable)
+	api.Get("/agent/config", s.handleGetAgentRuntimeConfig)
+	api.Put("/agent/config", s.handleUpdateAgentRuntimeConfig)
+	api.Get("/agent/cleanup", s.handleGetAgentCleanupState)
+	api.Post("/agent/cleanup/pause", s.handlePauseAgentCleanup)
+	api.Post("/agent/cleanup/resume", s.handleResumeAgentCleanup)
 
 	// Agent WebSocket endpoint (direct, not proxied)
 	// Use Fiber's WebSocket middleware with our Fiber-compatible handler
-	s.app.Get("/agent/ws", websocket.New(s.agentHandler.HandleFiberWebSocket))
+	if s.agentHandler != nil {
+		s.app.Get("/agent/ws", websocket.New(s.agentHandler.HandleFiberWebSocket))
+	} else {
+		s.app.Get("/agent/ws", func(c *fiber.Ctx) error {
+			return c.Status(503).JSON(fiber.Map{
+				"error": "agent handler not initialized",
+			})
+		})
+	}
 
 	// Providers endpoint (serve providers.json for unified configuration)
 	api.Get("/providers", s.handleGetProviders)
+	api.Get("/providers/usage", s.handleGetProviderUsage)
+	api.Post("/providers", s.handleSaveProvider)
+	api.Put("/providers/:id/activate", s.handleActivateProvider)
+	api.Delete("/providers/:id", s.handleDeleteProvider)
 
 	// System info endpoint (system metrics and runtime information)
 	api.Get("/system-info", s.handleGetSystemInfo)
@@ -435,16 +611,31 @@ func (s *Server) handleGetVersion(c *fiber.Ctx) error {
 	})
 }
 
-// Handler: Get all data
+// Handler: Check running version against the latest GitHub release (cached for an hour)
+func (s *Server) handleCheckVersion(c *fiber.Ctx) error {
+	return c.JSON(s.versionChecker.Check(c.Context()))
+}
+
+// Handler: Get all data. Supports If-None-Match like /conversations.
 func (s *Server) handleGetData(c *fiber.Ctx) error {
-	conversations, err := s.conversationAnalyzer.LoadConversations(s.stateCalculator)
+	processes, _ := s.processDetector.DetectRunningClaudeProcesses()
+
+	notModified, err := s.conversationsNotModified(c, len(processes))
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
+	if notModified {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
 
-	processes, _ := s.processDetector.DetectRunningClaudeProcesses()
+	conversations, err := s.conversationAnalyzer.LoadConversations(s.stateCalculator)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
 	return c.JSON(fiber.Map{
 		"conversations":      conversations,
@@ -454,8 +645,44 @@ func (s *Server) handleGetData(c *fiber.Ctx) error {
 	})
 }
 
-// Handler: Get conversations
+// Handler: Get conversations. Supports If-None-Match so periodic refreshes
+// get 304 Not Modified without re-parsing when nothing changed.
 func (s *Server) handleGetConversations(c *fiber.Ctx) error {
+	// Optional tag filter
+	var tagged map[string]bool
+	if tag := c.Query("tag"); tag != "" {
+		ids, err := s.repo.GetConversationIDsByTag(tag)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		tagged = make(map[string]bool, len(ids))
+		for _, id := range ids {
+			tagged[id] = true
+		}
+	}
+
+	// Statuses set by users (archived/completed) take precedence over the
+	// activity status computed from the conversation file
+	statuses, err := s.repo.GetConversationStatuses()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	notModified, err := s.conversationsNotModified(c, tagged, statuses)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if notModified {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
 	conversations, err := s.conversationAnalyzer.LoadConversations(s.stateCalculator)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
@@ -463,9 +690,254 @@ func (s *Server) handleGetConversations(c *fiber.Ctx) error {
 		})
 	}
 
+	if tagged != nil {
+		filtered := make([]analytics.Conversation, 0, len(tagged))
+		for _, conv := range conversations {
+			if tagged[conv.ID] {
+				filtered = append(filtered, conv)
+			}
+		}
+		conversations = filtered
+	}
+
+	for i := range conversations {
+		if status := statuses[conversations[i].ID]; status != "" && status != database.ConversationStatusActive {
+			conversations[i].Status = status
+		}
+	}
+
+	// Optional status filter
+	if status := c.Query("status"); status != "" {
+		filtered := make([]analytics.Conversation, 0, len(conversations))
+		for _, conv := range conversations {
+			if conv.Status == status {
+				filtered = append(filtered, conv)
+			}
+		}
+		conversations = filtered
+	}
+
 	return c.JSON(conversations)
 }
 
+// Handler: Search conversations by working directory, date range and text
+func (s *Server) handleSearchConversations(c *fiber.Ctx) error {
+	query := &database.ConversationSearchQuery{
+		WorkingDirectory: c.Query("dir"),
+		Text:             strings.TrimSpace(c.Query("q")),
+		Limit:            c.QueryInt("limit", 50),
+		Offset:           c.QueryInt("offset", 0),
+	}
+
+	start, err := parseDateParam(c.Query("start_date"), false)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("invalid start_date: %v", err),
+		})
+	}
+	end, err := parseDateParam(c.Query("end_date"), true)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("invalid end_date: %v", err),
+		})
+	}
+	if !start.IsZero() {
+		query.StartDate = &start
+	}
+	if !end.IsZero() {
+		query.EndDate = &end
+	}
+
+	conversations, err := s.repo.SearchConversations(query)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"conversations": conversations,
+		"count":         len(conversations),
+	})
+}
+
+// Handler: Delete all recorded data of one conversation
+func (s *Server) handleDeleteConversation(c *fiber.Ctx) error {
+	conversationID := c.Params("id")
+
+	result, err := s.repo.DeleteConversation(conversationID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to delete conversation: %v", err),
+		})
+	}
+	if result.Total == 0 {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "conversation not found",
+		})
+	}
+
+	// Broadcast update to WebSocket clients
+	s.wsHub.BroadcastData("conversation_deleted", result)
+
+	return c.JSON(result)
+}
+
+// Handler: Set the status of a conversation (active, archived or completed)
+func (s *Server) handleSetConversationStatus(c *fiber.Ctx) error {
+	conversationID := c.Params("id")
+
+	type SetStatusRequest struct {
+		Status string `json:"status"`
+	}
+
+	var req SetStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	status := strings.ToLower(strings.TrimSpace(req.Status))
+	if !database.IsValidConversationStatus(status) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "status must be one of: active, archived, completed",
+		})
+	}
+
+	conv, err := s.repo.GetConversation(conversationID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if conv == nil {
+		now := time.Now()
+		conv = &database.Conversation{
+			ID:             conversationID,
+			StartedAt:      now,
+			LastActivityAt: now,
+		}
+	}
+	conv.Status = status
+
+	if err := s.repo.UpsertConversation(conv); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Broadcast update to WebSocket clients
+	s.wsHub.BroadcastData("conversation_status_updated", fiber.Map{
+		"conversation_id": conversationID,
+		"status":          status,
+	})
+
+	return c.JSON(fiber.Map{
+		"conversation_id": conversationID,
+		"status":          status,
+	})
+}
+
+// Handler: Get tags for a conversation
+func (s *Server) handleGetConversationTags(c *fiber.Ctx) error {
+	conversationID := c.Params("id")
+
+	tags, err := s.repo.GetTags(conversationID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"conversation_id": conversationID,
+		"tags":            tags,
+	})
+}
+
+// Handler: Add a tag to a conversation
+func (s *Server) handleAddConversationTag(c *fiber.Ctx) error {
+	conversationID := c.Params("id")
+
+	type AddTagRequest struct {
+		Tag string `json:"tag"`
+	}
+
+	var req AddTagRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if strings.TrimSpace(req.Tag) == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "tag is required",
+		})
+	}
+
+	if err := s.repo.AddTag(conversationID, req.Tag); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	tags, err := s.repo.GetTags(conversationID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Broadcast update to WebSocket clients
+	s.wsHub.BroadcastData("conversation_tags_updated", fiber.Map{
+		"conversation_id": conversationID,
+		"tags":            tags,
+	})
+
+	return c.JSON(fiber.Map{
+		"conversation_id": conversationID,
+		"tags":            tags,
+	})
+}
+
+// Handler: Remove a tag from a conversation (tag passed as ?tag=...)
+func (s *Server) handleRemoveConversationTag(c *fiber.Ctx) error {
+	conversationID := c.Params("id")
+
+	tag := c.Query("tag")
+	if strings.TrimSpace(tag) == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "tag query parameter is required",
+		})
+	}
+
+	if err := s.repo.RemoveTag(conversationID, tag); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	tags, err := s.repo.GetTags(conversationID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Broadcast update to WebSocket clients
+	s.wsHub.BroadcastData("conversation_tags_updated", fiber.Map{
+		"conversation_id": conversationID,
+		"tags":            tags,
+	})
+
+	return c.JSON(fiber.Map{
+		"conversation_id": conversationID,
+		"tags":            tags,
+	})
+}
+
 // Handler: Get running processes
 func (s *Server) handleGetProcesses(c *fiber.Ctx) error {
 	processes, err := s.processDetector.DetectRunningClaudeProcesses()
@@ -483,8 +955,53 @@ func (s *Server) handleGetProcesses(c *fiber.Ctx) error {
 	})
 }
 
+// Handler: Get sampled process counts over time
+func (s *Server) handleGetProcessHistory(c *fiber.Ctx) error {
+	bucket := c.Query("bucket", "hour")
+	if !database.IsValidProcessBucket(bucket) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "bucket must be one of: minute, hour, day",
+		})
+	}
+
+	hours := c.QueryInt("hours", 24)
+	if hours <= 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "hours must be a positive integer",
+		})
+	}
+
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	series, err := s.repo.GetProcessHistory(bucket, since)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to get process history: %v", err),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"bucket": bucket,
+		"since":  since,
+		"series": series,
+		"count":  len(series),
+	})
+}
+
 // Handler: Get statistics
 func (s *Server) handleGetStats(c *fiber.Ctx) error {
+	start, err := parseDateParam(c.Query("start_date"), false)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("invalid start_date: %v", err),
+		})
+	}
+	end, err := parseDateParam(c.Query("end_date"), true)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("invalid end_date: %v", err),
+		})
+	}
+
 	// Get CLI conversation stats
 	conversations, err := s.conversationAnalyzer.LoadConversations(s.stateCalculator)
 	if err != nil {
@@ -493,6 +1010,22 @@ func (s *Server) handleGetStats(c *fiber.Ctx) error {
 		})
 	}
 
+	return c.JSON(s.buildStatsInRange(conversations, start, end))
+}
+
+// buildStats combines CLI conversation stats with agent session stats,
+// applying any soft reset delta
+func (s *Server) buildStats(conversations []analytics.Conversation) fiber.Map {
+	return s.buildStatsInRange(conversations, time.Time{}, time.Time{})
+}
+
+// buildStatsInRange is buildStats limited to conversations last modified and
+// agent sessions created within [from, to]; zero bounds are open. The soft
+// reset delta only applies when the window includes the reset point, since
+// a window entirely before or after it doesn't contain the pre-reset totals.
+func (s *Server) buildStatsInRange(conversations []analytics.Conversation, from, to time.Time) fiber.Map {
+	conversations = filterConversationsByModified(conversations, from, to)
+
 	cliTotalTokens := 0
 	cliActiveCount := 0
 
@@ -512,8 +1045,8 @@ func (s *Server) handleGetStats(c *fiber.Ctx) error {
 	if s.agentHandler != nil {
 		allSessions, err := s.agentHandler.SessionManager.ListAllSessions("all")
 		if err == nil {
-			agentSessions = allSessions
-			for _, session := range allSessions {
+			agentSessions = filterSessionsByCreatedAt(allSessions, from, to)
+			for _, session := range agentSessions {
 				// Estimate tokens from message count (rough approximation)
 				// TODO: Track actual tokens in messages
 				agentTotalTokens += int64(session.MessageCount * 100)
@@ -532,8 +1065,11 @@ func (s *Server) handleGetStats(c *fiber.Ctx) error {
 	totalConversations := len(conversations) + len(agentSessions)
 	activeCount := cliActiveCount + agentActiveCount
 
-	// Apply soft reset delta if present
-	adjustedTokens, adjustedConversations := s.resetTracker.ApplyDelta(totalTokens, totalConversations)
+	// Apply soft reset delta if present and within the window
+	adjustedTokens, adjustedConversations := totalTokens, totalConversations
+	if resetPoint := s.resetTracker.GetResetPoint(); resetPoint == nil || timeInRange(resetPoint.Timestamp, from, to) {
+		adjustedTokens, adjustedConversations = s.resetTracker.ApplyDelta(totalTokens, totalConversations)
+	}
 
 	avgTokens := 0
 	if adjustedConversations > 0 {
@@ -567,7 +1103,22 @@ func (s *Server) handleGetStats(c *fiber.Ctx) error {
 		response["resetActive"] = false
 	}
 
-	return c.JSON(response)
+	return response
+}
+
+// filterConversationsByModified keeps conversations last modified within [from, to]; zero bounds are open
+func filterConversationsByModified(conversations []analytics.Conversation, from, to time.Time) []analytics.Conversation {
+	if from.IsZero() && to.IsZero() {
+		return conversations
+	}
+
+	filtered := make([]analytics.Conversation, 0, len(conversations))
+	for _, conv := range conversations {
+		if timeInRange(conv.LastModified, from, to) {
+			filtered = append(filtered, conv)
+		}
+	}
+	return filtered
 }
 
 // Handler: Get background shells
@@ -587,6 +1138,30 @@ func (s *Server) handleGetShells(c *fiber.Ctx) error {
 	})
 }
 
+// Handler: Get background shells seen over time, including ones that have exited
+func (s *Server) handleGetBackgroundShellHistory(c *fiber.Ctx) error {
+	hours := c.QueryInt("hours", 24)
+	if hours <= 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "hours must be a positive integer",
+		})
+	}
+
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	shells, err := s.repo.GetShellHistory(since)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to get shell history: %v", err),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"since":  since,
+		"shells": shells,
+		"count":  len(shells),
+	})
+}
+
 // Handler: Refresh data
 func (s *Server) handleRefresh(c *fiber.Ctx) error {
 	// Clear caches
@@ -755,9 +1330,27 @@ func (s *Server) Start() error {
 		protocol = "https"
 	}
 
-	// Bind address - use from config or default to 127.0.0.1
+	// Serve on a Unix socket instead of TCP when configured
+	if socketPath := s.unixSocketPath(); socketPath != "" {
+		ln, err := listenUnix(socketPath, s.tlsConfig)
+		if err != nil {
+			return err
+		}
+
+		if !s.quiet {
+			fmt.Printf("🚀 Starting server on unix socket %s\n", socketPath)
+			fmt.Printf("🔗 API endpoint: curl --unix-socket %s %s://localhost/api/data\n", socketPath, protocol)
+			s.printSecurityBanner()
+		}
+
+		return s.app.Listener(ln)
+	}
+
+	// Bind address - use CLI override, then config, then default to 127.0.0.1
 	bindHost := "127.0.0.1"
-	if s.config != nil && s.config.Server.Host != "" {
+	if s.host != "" {
+		bindHost = s.host
+	} else if s.config != nil && s.config.Server.Host != "" {
 		bindHost = s.config.Server.Host
 	}
 	addr := fmt.Sprintf("%s:%d", bindHost, s.port)
@@ -766,15 +1359,7 @@ func (s *Server) Start() error {
 		fmt.Printf("🚀 Starting server on %s://%s\n", protocol, addr)
 		fmt.Printf("📊 Analytics dashboard: %s://localhost:%d/\n", protocol, s.port)
 		fmt.Printf("🔗 API endpoint: %s://localhost:%d/api/data\n", protocol, s.port)
-
-		if s.tlsConfig != nil && s.tlsConfig.Enabled {
-			fmt.Printf("🔒 TLS enabled (self-signed certificate)\n")
-		}
-
-		if s.authMiddleware != nil {
-			configManager := NewConfigManager(s.claudeDir)
-			fmt.Printf("🔑 Authentication enabled (API key in %s)\n", configManager.GetSecretPath())
-		}
+		s.printSecurityBanner()
 	}
 
 	// Start server with TLS if enabled
@@ -786,6 +1371,85 @@ func (s *Server) Start() error {
 	return s.app.Listen(addr)
 }
 
+// printSecurityBanner prints the TLS and authentication lines of the startup banner
+func (s *Server) printSecurityBanner() {
+	if s.tlsConfig != nil && s.tlsConfig.Enabled {
+		fmt.Printf("🔒 TLS enabled (self-signed certificate)\n")
+	}
+
+	if s.authMiddleware != nil {
+		configManager := NewConfigManager(s.claudeDir)
+		fmt.Printf("🔑 Authentication enabled (API key in %s)\n", configManager.GetSecretPath())
+	}
+}
+
+// ServerStats is a lightweight snapshot of server activity for status displays
+type ServerStats struct {
+	Healthy            bool `json:"healthy"`
+	ActiveSessions     int  `json:"active_sessions"`
+	ActiveConnections  int  `json:"active_connections"`
+	TotalConversations int  `json:"total_conversations"`
+}
+
+// GetStats returns live server statistics.
+// Components that are not initialized yet are reported as zero values.
+func (s *Server) GetStats() ServerStats {
+	stats := ServerStats{}
+
+	if s.agentHandler != nil {
+		stats.ActiveSessions = len(s.agentHandler.SessionManager.ListSessions())
+		stats.ActiveConnections = s.agentHandler.ActiveConnections()
+	}
+
+	if s.db != nil {
+		stats.Healthy = s.db.HealthCheck() == nil
+		if dbStats, err := s.db.Stats(); err == nil {
+			if count, ok := dbStats["conversations_count"].(int); ok {
+				stats.TotalConversations = count
+			}
+		}
+	}
+
+	return stats
+}
+
+// defaultDrainTimeout is how long Drain waits for agent connections when not configured
+const defaultDrainTimeout = 10 * time.Second
+
+// DrainTimeout returns the configured drain timeout
+func (s *Server) DrainTimeout() time.Duration {
+	if s.config != nil && s.config.Server.DrainTimeoutSeconds > 0 {
+		return time.Duration(s.config.Server.DrainTimeoutSeconds) * time.Second
+	}
+	return defaultDrainTimeout
+}
+
+// Drain stops accepting new agent WebSocket connections, waits up to timeout
+// for active connections to finish, then shuts the server down.
+// This gives in-flight agent responses a chance to complete on SIGTERM.
+func (s *Server) Drain(timeout time.Duration) error {
+	if s.agentHandler != nil {
+		s.agentHandler.StartDraining()
+
+		if !s.quiet {
+			fmt.Printf("⏳ Draining %d active agent connection(s) (timeout: %s)...\n",
+				s.agentHandler.ActiveConnections(), timeout)
+		}
+		logging.Info("Draining agent connections (timeout: %s)", timeout)
+
+		deadline := time.Now().Add(timeout)
+		for s.agentHandler.ActiveConnections() > 0 && time.Now().Before(deadline) {
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		if remaining := s.agentHandler.ActiveConnections(); remaining > 0 {
+			logging.Warning("Drain timeout reached with %d active agent connection(s)", remaining)
+		}
+	}
+
+	return s.Shutdown()
+}
+
 // Shutdown gracefully shuts down the server and all its components.
 // It stops the file watcher, WebSocket hub, and closes the database.
 func (s *Server) Shutdown() error {
@@ -800,6 +1464,19 @@ func (s *Server) Shutdown() error {
 		}
 	}
 
+	// Stop idle alert monitor
+	s.idleMonitor.Stop()
+
+	// Stop process sampling
+	if s.processSampler != nil {
+		s.processSampler.Stop()
+	}
+
+	// Stop shell sampling
+	if s.shellSampler != nil {
+		s.shellSampler.Stop()
+	}
+
 	// Stop file watcher
 	if s.fileWatcher != nil {
 		if err := s.fileWatcher.Stop(); err != nil && !s.quiet {
@@ -840,11 +1517,19 @@ func (s *Server) handleGetShellHistory(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(fiber.Map{
+	total, err := s.repo.CountShellCommands(query)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	page := newPageResult(commands, len(commands), total, query.Limit, query.Offset)
+	return c.JSON(page.withLegacy(fiber.Map{
 		"commands": commands,
 		"count":    len(commands),
 		"query":    query,
-	})
+	}))
 }
 
 // Handler: Get Claude command history
@@ -856,6 +1541,20 @@ func (s *Server) handleGetClaudeHistory(c *fiber.Ctx) error {
 		Offset:         c.QueryInt("offset", 0),
 	}
 
+	// Optional success filter (error_only=true is a shortcut for success=false)
+	if c.QueryBool("error_only", false) {
+		success := false
+		query.Success = &success
+	} else if successParam := c.Query("success"); successParam != "" {
+		success, err := strconv.ParseBool(successParam)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "invalid success value, expected true or false",
+			})
+		}
+		query.Success = &success
+	}
+
 	commands, err := s.repo.GetClaudeCommands(query)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
@@ -863,11 +1562,19 @@ func (s *Server) handleGetClaudeHistory(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(fiber.Map{
+	total, err := s.repo.CountClaudeCommands(query)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	page := newPageResult(commands, len(commands), total, query.Limit, query.Offset)
+	return c.JSON(page.withLegacy(fiber.Map{
 		"commands": commands,
 		"count":    len(commands),
 		"query":    query,
-	})
+	}))
 }
 
 // Handler: Get command statistics
@@ -902,6 +1609,32 @@ func formatBytes(bytes int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
+// Handler: Get database schema version
+func (s *Server) handleGetDBVersion(c *fiber.Ctx) error {
+	version, err := s.db.SchemaVersion()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	applied, err := s.db.AppliedMigrations()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	latest := database.LatestSchemaVersion()
+
+	return c.JSON(fiber.Map{
+		"version":    version,
+		"latest":     latest,
+		"up_to_date": version >= latest,
+		"migrations": applied,
+	})
+}
+
 // Handler: Get database statistics
 func (s *Server) handleGetDBStats(c *fiber.Ctx) error {
 	stats, err := s.db.Stats()
@@ -939,11 +1672,19 @@ func (s *Server) handleGetUserPrompts(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(fiber.Map{
+	total, err := s.repo.CountUserMessages(query)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	page := newPageResult(messages, len(messages), total, query.Limit, query.Offset)
+	return c.JSON(page.withLegacy(fiber.Map{
 		"prompts": messages,
 		"count":   len(messages),
 		"query":   query,
-	})
+	}))
 }
 
 // Handler: Get prompt statistics
@@ -1040,6 +1781,39 @@ func (s *Server) handleRecordUserPrompt(c *fiber.Ctx) error {
 		})
 	}
 
+	// Enforce size and content limits to protect the database from runaway hook output
+	var limits RecordingSettings
+	if s.config != nil {
+		limits = s.config.Recording
+	}
+	maxPromptLength := limits.MaxPromptLength
+	if maxPromptLength <= 0 {
+		maxPromptLength = defaultMaxPromptLength
+	}
+	maxSessionNameLength := limits.MaxSessionNameLength
+	if maxSessionNameLength <= 0 {
+		maxSessionNameLength = defaultMaxSessionNameLength
+	}
+
+	if containsControlChars(req.Prompt) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "prompt contains control characters",
+		})
+	}
+
+	if len(req.Prompt) > maxPromptLength {
+		if !limits.TruncatePrompts {
+			return c.Status(413).JSON(fiber.Map{
+				"error":      fmt.Sprintf("prompt exceeds maximum length of %d bytes", maxPromptLength),
+				"length":     len(req.Prompt),
+				"max_length": maxPromptLength,
+			})
+		}
+		req.Prompt = truncateUTF8(req.Prompt, maxPromptLength)
+	}
+
+	req.SessionName = truncateUTF8(req.SessionName, maxSessionNameLength)
+
 	// Use model info from request, fallback to Unknown if not provided
 	modelProvider := req.ModelProvider
 	modelName := req.ModelName
@@ -1075,6 +1849,15 @@ func (s *Server) handleRecordUserPrompt(c *fiber.Ctx) error {
 		})
 	}
 
+	// A duplicate hook firing was folded into the existing message
+	if msg.Deduplicated {
+		return c.JSON(fiber.Map{
+			"status": "duplicate",
+			"id":     msg.ID,
+			"length": msg.MessageLength,
+		})
+	}
+
 	// Broadcast update to WebSocket clients with data
 	s.wsHub.BroadcastData("prompt_recorded", msg)
 
@@ -1086,6 +1869,49 @@ func (s *Server) handleRecordUserPrompt(c *fiber.Ctx) error {
 	})
 }
 
+// Default limits for recorded user prompts
+const (
+	defaultMaxPromptLength      = 100000
+	defaultMaxSessionNameLength = 200
+	defaultPromptDedupWindow    = 2 * time.Second
+)
+
+// promptDedupWindow returns the configured window for skipping duplicate prompts
+func (s *Server) promptDedupWindow() time.Duration {
+	if s.config == nil || s.config.Recording.PromptDedupWindowMS == 0 {
+		return defaultPromptDedupWindow
+	}
+	if s.config.Recording.PromptDedupWindowMS < 0 {
+		return 0
+	}
+	return time.Duration(s.config.Recording.PromptDedupWindowMS) * time.Millisecond
+}
+
+// containsControlChars reports whether s contains control characters other than
+// common whitespace (newline, carriage return, tab)
+func containsControlChars(s string) bool {
+	for _, r := range s {
+		if r == '\n' || r == '\r' || r == '\t' {
+			continue
+		}
+		if unicode.IsControl(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncateUTF8 shortens s to at most maxBytes without splitting a multi-byte character
+func truncateUTF8(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	for maxBytes > 0 && !utf8.RuneStart(s[maxBytes]) {
+		maxBytes--
+	}
+	return s[:maxBytes]
+}
+
 // Handler: Clear all history (user prompts, shell commands, and claude commands)
 func (s *Server) handleClearAllHistory(c *fiber.Ctx) error {
 	// Get database size before clearing
@@ -1202,6 +2028,12 @@ func (s *Server) handleRecordShellCommand(c *fiber.Ctx) error {
 		ExecutedAt:       time.Now(),
 	}
 
+	// Cap stored output so noisy commands (e.g. npm install) don't bloat the database
+	if maxOutput := s.maxCommandOutputBytes(); maxOutput > 0 {
+		cmd.Stdout = truncateCommandOutput(cmd.Stdout, maxOutput)
+		cmd.Stderr = truncateCommandOutput(cmd.Stderr, maxOutput)
+	}
+
 	// Record the command
 	if err := s.repo.RecordShellCommand(cmd); err != nil {
 		return c.Status(500).JSON(fiber.Map{
@@ -1222,6 +2054,30 @@ func (s *Server) handleRecordShellCommand(c *fiber.Ctx) error {
 	})
 }
 
+// defaultMaxCommandOutputBytes caps stored stdout/stderr per shell command
+const defaultMaxCommandOutputBytes = 64 * 1024
+
+// maxCommandOutputBytes returns the configured stdout/stderr cap, or 0 when disabled
+func (s *Server) maxCommandOutputBytes() int {
+	if s.config == nil || s.config.Recording.MaxCommandOutputBytes == 0 {
+		return defaultMaxCommandOutputBytes
+	}
+	if s.config.Recording.MaxCommandOutputBytes < 0 {
+		return 0
+	}
+	return s.config.Recording.MaxCommandOutputBytes
+}
+
+// truncateCommandOutput cuts output to maxBytes on a UTF-8 boundary and notes
+// how many bytes were dropped
+func truncateCommandOutput(output string, maxBytes int) string {
+	if len(output) <= maxBytes {
+		return output
+	}
+	kept := truncateUTF8(output, maxBytes)
+	return fmt.Sprintf("%s\n[truncated %d bytes]", kept, len(output)-len(kept))
+}
+
 // Handler: Record a Claude command
 func (s *Server) handleRecordClaudeCommand(c *fiber.Ctx) error {
 	type RecordClaudeCommandRequest struct {
@@ -1346,6 +2202,32 @@ func (s *Server) handleGetAllHistory(c *fiber.Ctx) error {
 		})
 	}
 
+	// Totals across all four types; limit and offset apply to each type,
+	// so there is more to fetch while any one of them has more.
+	total := 0
+	hasMore := false
+	for _, counted := range []struct {
+		what     string
+		returned int
+		count    func(*database.CommandHistoryQuery) (int, error)
+	}{
+		{"shell commands", len(shellCommands), s.repo.CountShellCommands},
+		{"claude commands", len(claudeCommands), s.repo.CountClaudeCommands},
+		{"user messages", len(userMessages), s.repo.CountUserMessages},
+		{"notifications", len(notifications), s.repo.CountNotifications},
+	} {
+		count, err := counted.count(query)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": fmt.Sprintf("failed to count %s: %v", counted.what, err),
+			})
+		}
+		total += count
+		if offset+counted.returned < count {
+			hasMore = true
+		}
+	}
+
 	// Combine into unified response with type field
 	type HistoryItem struct {
 		Type             string      `json:"type"`
@@ -1426,11 +2308,136 @@ func (s *Server) handleGetAllHistory(c *fiber.Ctx) error {
 		}
 	}
 
-	return c.JSON(fiber.Map{
+	page := PageResult{
+		Items:   allHistory,
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+		HasMore: hasMore,
+	}
+	return c.JSON(page.withLegacy(fiber.Map{
 		"history": allHistory,
 		"count":   len(allHistory),
 		"query":   query,
+	}))
+}
+
+// Handler: Download a conversation's shell commands with their output as a plain-text log, oldest first
+func (s *Server) handleGetSessionShellLog(c *fiber.Ctx) error {
+	conversationID := c.Params("conversation_id")
+
+	commands, err := s.repo.GetShellCommands(&database.CommandHistoryQuery{
+		ConversationID: conversationID,
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to get shell commands: %v", err),
+		})
+	}
+
+	if len(commands) == 0 {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "no shell commands found for this conversation",
+		})
+	}
+
+	c.Set("Content-Type", "text/plain; charset=utf-8")
+	c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="shell-log-%s.txt"`, sanitizeFilename(conversationID)))
+
+	return c.SendString(formatShellLog(conversationID, commands))
+}
+
+// formatShellLog renders shell commands (newest first, as returned by the
+// repository) as a chronological plain-text log
+func formatShellLog(conversationID string, commands []*database.ShellCommand) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Shell log for conversation %s (%d commands)\n", conversationID, len(commands))
+
+	for i := len(commands) - 1; i >= 0; i-- {
+		cmd := commands[i]
+
+		b.WriteString("\n")
+		fmt.Fprintf(&b, "=== [%s] $ %s\n", cmd.ExecutedAt.Format("2006-01-02 15:04:05"), cmd.Command)
+		if cmd.Description != "" {
+			fmt.Fprintf(&b, "# %s\n", cmd.Description)
+		}
+		if cmd.WorkingDirectory != "" {
+			fmt.Fprintf(&b, "cwd: %s\n", cmd.WorkingDirectory)
+		}
+
+		exitCode := "unknown"
+		if cmd.ExitCode != nil {
+			exitCode = strconv.Itoa(*cmd.ExitCode)
+		}
+		duration := "unknown"
+		if cmd.DurationMs != nil {
+			duration = (time.Duration(*cmd.DurationMs) * time.Millisecond).String()
+		}
+		fmt.Fprintf(&b, "exit: %s, duration: %s\n", exitCode, duration)
+
+		writeShellLogStream(&b, "stdout", cmd.Stdout)
+		writeShellLogStream(&b, "stderr", cmd.Stderr)
+	}
+
+	return b.String()
+}
+
+// writeShellLogStream writes one captured output stream, if any, ending in a newline
+func writeShellLogStream(b *strings.Builder, name, output string) {
+	if output == "" {
+		return
+	}
+	fmt.Fprintf(b, "--- %s ---\n", name)
+	b.WriteString(output)
+	if !strings.HasSuffix(output, "\n") {
+		b.WriteString("\n")
+	}
+}
+
+// sanitizeFilename keeps only characters that are safe in a Content-Disposition filename
+func sanitizeFilename(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// historyStreamFlushEvery is how many NDJSON rows are buffered before flushing
+const historyStreamFlushEvery = 100
+
+// Handler: Stream all history as newline-delimited JSON, oldest first
+func (s *Server) handleStreamHistory(c *fiber.Ctx) error {
+	conversationID := c.Query("conversation_id")
+
+	c.Set("Content-Type", "application/x-ndjson")
+	c.Set("Content-Disposition", `attachment; filename="cct-history.ndjson"`)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		encoder := json.NewEncoder(w)
+		count := 0
+
+		err := s.repo.StreamHistory(conversationID, func(row *database.HistoryRow) error {
+			if err := encoder.Encode(row); err != nil {
+				return err
+			}
+			count++
+			if count%historyStreamFlushEvery == 0 {
+				return w.Flush()
+			}
+			return nil
+		})
+		if err != nil {
+			// Headers are already sent, so report the failure as a final row
+			logging.Error("History stream failed after %d rows: %v", count, err)
+			encoder.Encode(fiber.Map{"error": err.Error()})
+		}
+
+		w.Flush()
 	})
+
+	return nil
 }
 
 // Handler: Record a notification
@@ -1492,16 +2499,13 @@ func (s *Server) handleRecordNotification(c *fiber.Ctx) error {
 		NotifiedAt:       time.Now(),
 	}
 
-	// Record the notification
-	if err := s.repo.RecordNotification(notif); err != nil {
+	// Record, broadcast and forward the notification
+	if err := s.publishNotification(notif); err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": fmt.Sprintf("failed to record notification: %v", err),
 		})
 	}
 
-	// Broadcast update to WebSocket clients with data
-	s.wsHub.BroadcastData("notification_recorded", notif)
-
 	return c.JSON(fiber.Map{
 		"status": "recorded",
 		"id":     notif.ID,
@@ -1509,41 +2513,251 @@ func (s *Server) handleRecordNotification(c *fiber.Ctx) error {
 	})
 }
 
-// Handler: Get notifications
-func (s *Server) handleGetNotifications(c *fiber.Ctx) error {
-	query := &database.CommandHistoryQuery{
-		ConversationID: c.Query("conversation_id"),
-		Limit:          c.QueryInt("limit", 100),
-		Offset:         c.QueryInt("offset", 0),
+// publishNotification records a notification, broadcasts it to WebSocket
+// clients and forwards it to the webhook if one is configured
+func (s *Server) publishNotification(notif *database.Notification) error {
+	if err := s.repo.RecordNotification(notif); err != nil {
+		return err
 	}
 
-	notifications, err := s.repo.GetNotifications(query)
+	// Broadcast update to WebSocket clients with data
+	s.wsHub.BroadcastData("notification_recorded", notif)
+
+	// Forward to external webhook if configured (async, never blocks recording)
+	s.notificationDispatcher.Dispatch(notif)
+
+	return nil
+}
+
+// Handler: Get working directories that have recorded history
+func (s *Server) handleGetWorkingDirectories(c *fiber.Ctx) error {
+	directories, err := s.repo.GetWorkingDirectories()
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
+			"error": fmt.Sprintf("failed to get directories: %v", err),
 		})
 	}
 
 	return c.JSON(fiber.Map{
-		"notifications": notifications,
-		"count":         len(notifications),
-		"query":         query,
+		"directories": directories,
+		"count":       len(directories),
 	})
 }
 
-// Handler: Get notification statistics
-func (s *Server) handleGetNotificationStats(c *fiber.Ctx) error {
-	stats, err := s.repo.GetNotificationStats()
+// Handler: Get distinct Claude tool names with usage counts
+func (s *Server) handleGetClaudeToolNames(c *fiber.Ctx) error {
+	query := &database.ToolNameQuery{
+		Text:   strings.TrimSpace(c.Query("q")),
+		Limit:  c.QueryInt("limit", 100),
+		Offset: c.QueryInt("offset", 0),
+	}
+
+	tools, err := s.repo.GetDistinctToolNames(query)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
+			"error": fmt.Sprintf("failed to get tool names: %v", err),
 		})
 	}
 
-	return c.JSON(stats)
-}
-
-// Handler: Clear all notifications
+	total, err := s.repo.CountDistinctToolNames(query)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to count tool names: %v", err),
+		})
+	}
+
+	page := newPageResult(tools, len(tools), total, query.Limit, query.Offset)
+	return c.JSON(page.withLegacy(fiber.Map{
+		"tools": tools,
+		"count": len(tools),
+	}))
+}
+
+// Handler: Record a session start/end event
+func (s *Server) handleRecordSessionLifecycle(c *fiber.Ctx) error {
+	type RecordSessionLifecycleRequest struct {
+		SessionID        string `json:"session_id"`
+		SessionName      string `json:"session_name"`
+		Event            string `json:"event"` // "start" or "end"
+		Source           string `json:"source"`
+		Reason           string `json:"reason"`
+		WorkingDirectory string `json:"cwd"`
+		GitBranch        string `json:"branch"`
+	}
+
+	var req RecordSessionLifecycleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	// Validate required fields
+	if req.SessionID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "session_id is required",
+		})
+	}
+	if req.Event != database.SessionLifecycleStart && req.Event != database.SessionLifecycleEnd {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "event must be 'start' or 'end'",
+		})
+	}
+
+	session := &database.CLISession{
+		ConversationID:   req.SessionID,
+		SessionName:      req.SessionName,
+		WorkingDirectory: req.WorkingDirectory,
+		GitBranch:        req.GitBranch,
+		StartSource:      req.Source,
+		EndReason:        req.Reason,
+	}
+
+	now := time.Now()
+	if err := s.repo.RecordSessionLifecycle(session, req.Event, now); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to record session lifecycle: %v", err),
+		})
+	}
+
+	// Broadcast update to WebSocket clients
+	s.wsHub.BroadcastData("session_lifecycle", fiber.Map{
+		"conversation_id": req.SessionID,
+		"session_name":    req.SessionName,
+		"event":           req.Event,
+		"time":            now,
+	})
+
+	return c.JSON(fiber.Map{
+		"status": "recorded",
+		"event":  req.Event,
+		"time":   now,
+	})
+}
+
+// Handler: Get recorded CLI session lifecycles with durations
+func (s *Server) handleGetSessionLifecycle(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 100)
+
+	sessions, err := s.repo.GetCLISessions(limit)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to get sessions: %v", err),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"sessions": sessions,
+		"count":    len(sessions),
+	})
+}
+
+// Handler: Get notifications
+func (s *Server) handleGetNotifications(c *fiber.Ctx) error {
+	query := &database.CommandHistoryQuery{
+		ConversationID: c.Query("conversation_id"),
+		Limit:          c.QueryInt("limit", 100),
+		Offset:         c.QueryInt("offset", 0),
+		UnreadOnly:     c.QueryBool("unread_only", false),
+	}
+
+	notifications, err := s.repo.GetNotifications(query)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	total, err := s.repo.CountNotifications(query)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	page := newPageResult(notifications, len(notifications), total, query.Limit, query.Offset)
+	return c.JSON(page.withLegacy(fiber.Map{
+		"notifications": notifications,
+		"count":         len(notifications),
+		"query":         query,
+	}))
+}
+
+// Handler: Get a single notification with its full details
+func (s *Server) handleGetNotification(c *fiber.Ctx) error {
+	notificationID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid notification ID",
+		})
+	}
+
+	notification, err := s.repo.GetNotification(notificationID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to get notification: %v", err),
+		})
+	}
+	if notification == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "notification not found",
+		})
+	}
+
+	return c.JSON(notification)
+}
+
+// Handler: Get notification statistics
+func (s *Server) handleGetNotificationStats(c *fiber.Ctx) error {
+	stats, err := s.repo.GetNotificationStats()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(stats)
+}
+
+// maxAcknowledgeIDs caps how many notification IDs one acknowledge request may list
+const maxAcknowledgeIDs = 500
+
+// Handler: Mark notifications as read. An empty or missing "ids" list
+// acknowledges every unread notification.
+func (s *Server) handleAcknowledgeNotifications(c *fiber.Ctx) error {
+	var req struct {
+		IDs []int64 `json:"ids"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "invalid request body",
+			})
+		}
+	}
+	if len(req.IDs) > maxAcknowledgeIDs {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("at most %d ids can be acknowledged per request", maxAcknowledgeIDs),
+		})
+	}
+
+	count, err := s.repo.AcknowledgeNotifications(req.IDs)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	result := fiber.Map{
+		"acknowledged": count,
+		"ids":          req.IDs,
+	}
+	s.wsHub.BroadcastData("notifications_acknowledged", result)
+
+	return c.JSON(result)
+}
+
+// Handler: Clear all notifications
 func (s *Server) handleClearNotifications(c *fiber.Ctx) error {
 	err := s.repo.DeleteAllNotifications()
 	if err != nil {
@@ -1660,15 +2874,7 @@ func (s *Server) handleGetAPIKey(c *fiber.Ctx) error {
 	}
 
 	// If Origin is present, it must match allowed origins
-	allowed := false
-	for _, allowedOrigin := range s.config.CORS.AllowedOrigins {
-		if origin == allowedOrigin {
-			allowed = true
-			break
-		}
-	}
-
-	if !allowed {
+	if !s.allowedOrigins.allowed(origin) {
 		return c.Status(403).JSON(fiber.Map{
 			"error": "Forbidden",
 		})
@@ -1701,6 +2907,65 @@ func (s *Server) handleGetCWD(c *fiber.Ctx) error {
 	})
 }
 
+// Handler: Get non-secret server configuration so the UI can adapt to it.
+// Key paths, certificate paths and webhook URLs are never included.
+func (s *Server) handleGetPublicConfig(c *fiber.Ctx) error {
+	if s.config == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "configuration not loaded",
+		})
+	}
+
+	return c.JSON(s.publicConfig())
+}
+
+// publicConfig returns the configuration values that are safe to expose
+func (s *Server) publicConfig() fiber.Map {
+	model := s.config.Agent.Model
+	maxSessions := s.config.Agent.MaxConcurrentSessions
+	if s.agentConfig != nil && s.agentConfig.Model != "" {
+		model = s.agentConfig.Model
+	}
+	if s.agentHandler != nil {
+		maxSessions = s.agentHandler.MaxConcurrentSessions()
+	}
+
+	allowedOrigins := s.config.CORS.AllowedOrigins
+	if s.allowedOrigins != nil {
+		allowedOrigins = s.allowedOrigins.list()
+	} else if allowedOrigins == nil {
+		allowedOrigins = []string{}
+	}
+
+	return fiber.Map{
+		"agent": fiber.Map{
+			"enabled":                 s.agentHandler != nil,
+			"model":                   model,
+			"max_concurrent_sessions": maxSessions,
+			"session_retention_days":  s.config.Agent.SessionRetentionDays,
+			"message_retention_days":  s.config.Agent.MessageRetentionDays,
+			"cleanup_enabled":         s.config.Agent.CleanupEnabled,
+			"default_permission_mode": s.config.Agent.DefaultPermissionMode,
+			"store_thinking":          s.config.Agent.StoreThinking,
+		},
+		"tls": fiber.Map{
+			"enabled": s.config.TLS.Enabled,
+		},
+		"auth": fiber.Map{
+			"enabled":           s.config.Auth.Enabled,
+			"user_auth_enabled": s.config.Auth.UserAuthEnabled,
+			"require_login":     s.config.Auth.RequireLogin,
+		},
+		"cors": fiber.Map{
+			"allowed_origins": allowedOrigins,
+		},
+		"notifications": fiber.Map{
+			"webhook_enabled":    s.config.Notifications.WebhookURL != "",
+			"idle_alert_minutes": s.config.Notifications.IdleAlertMinutes,
+		},
+	}
+}
+
 // Handler: Get project permissions from .claude/settings.local.json
 func (s *Server) handleGetProjectPermissions(c *fiber.Ctx) error {
 	// Get current working directory
@@ -1755,6 +3020,49 @@ func (s *Server) handleGetProjectPermissions(c *fiber.Ctx) error {
 	})
 }
 
+// Handler: Get the permissions.allow rules from settings.local.json in an agent
+// session's working directory, including rules added outside the app
+func (s *Server) handleGetSessionSettingsPermissions(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
+
+	sessionID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return invalidUUIDResponse(c, err)
+	}
+
+	session, err := s.agentHandler.SessionManager.GetSession(sessionID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if session.Options.WorkingDirectory == nil || *session.Options.WorkingDirectory == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "session has no working directory",
+		})
+	}
+	workingDir := *session.Options.WorkingDirectory
+
+	permissions, err := agents.NewClaudeSettingsManager(workingDir).ListPermissions()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to read settings permissions: %v", err),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"session_id":  sessionID,
+		"file_path":   filepath.Join(workingDir, ".claude", "settings.local.json"),
+		"permissions": permissions,
+		"count":       len(permissions),
+	})
+}
+
 // Helper function to categorize permissions
 func categorizePermissions(permissions []string) map[string]interface{} {
 	categories := map[string][]string{
@@ -1898,11 +3206,6 @@ func (s *Server) handleGetAgentDetail(c *fiber.Ctx) error {
 
 	// Parse frontmatter and system prompt
 	agentData := parseFrontmatterWithPrompt(string(content))
-	if agentData == nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to parse agent file",
-		})
-	}
 
 	return c.JSON(fiber.Map{
 		"agent": agentData,
@@ -1957,12 +3260,14 @@ func parseFrontmatter(content string) map[string]interface{} {
 	return data
 }
 
-// parseFrontmatterWithPrompt extracts frontmatter AND system prompt from markdown
+// parseFrontmatterWithPrompt extracts frontmatter AND system prompt from markdown.
+// Content without complete frontmatter (e.g. a hand-written file missing the
+// closing ---) is returned as the system prompt with no metadata.
 func parseFrontmatterWithPrompt(content string) map[string]interface{} {
 	// Look for frontmatter between --- markers
 	lines := strings.Split(content, "\n")
 	if len(lines) < 3 || lines[0] != "---" {
-		return nil
+		return promptWithoutFrontmatter(content)
 	}
 
 	// Find closing ---
@@ -1975,7 +3280,8 @@ func parseFrontmatterWithPrompt(content string) map[string]interface{} {
 	}
 
 	if endLine == 0 {
-		return nil
+		logging.Warning("Agent file frontmatter has no closing ---, treating the whole file as the system prompt")
+		return promptWithoutFrontmatter(content)
 	}
 
 	// Extract YAML lines
@@ -2021,6 +3327,16 @@ func parseFrontmatterWithPrompt(content string) map[string]interface{} {
 	return data
 }
 
+// promptWithoutFrontmatter returns agent data for content that has no
+// frontmatter: the whole content is the system prompt
+func promptWithoutFrontmatter(content string) map[string]interface{} {
+	systemPrompt := strings.TrimSpace(content)
+	return map[string]interface{}{
+		"system_prompt":        systemPrompt,
+		"system_prompt_length": len(systemPrompt),
+	}
+}
+
 // Handler: Get agent sessions (with optional status filter)
 func (s *Server) handleGetAgentSessions(c *fiber.Ctx) error {
 	if s.agentHandler == nil {
@@ -2032,8 +3348,17 @@ func (s *Server) handleGetAgentSessions(c *fiber.Ctx) error {
 	// Get status filter from query params (default: "all")
 	statusFilter := c.Query("status", "all")
 
+	// Optional git branch filter
+	branch := c.Query("branch")
+
 	// Get sessions from storage
-	sessions, err := s.agentHandler.SessionManager.ListAllSessions(statusFilter)
+	var sessions []agents.Session
+	var err error
+	if branch != "" {
+		sessions, err = s.agentHandler.SessionManager.ListSessionsByBranch(branch, statusFilter)
+	} else {
+		sessions, err = s.agentHandler.SessionManager.ListAllSessions(statusFilter)
+	}
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": fmt.Sprintf("failed to list sessions: %v", err),
@@ -2044,81 +3369,867 @@ func (s *Server) handleGetAgentSessions(c *fiber.Ctx) error {
 		"sessions": sessions,
 		"count":    len(sessions),
 		"filter":   statusFilter,
+		"branch":   branch,
 	})
 }
 
-// Handler: Get messages for an agent session (with pagination)
-func (s *Server) handleGetAgentMessages(c *fiber.Ctx) error {
+// Handler: Get agent sessions grouped by working directory
+func (s *Server) handleGetAgentSessionsByProject(c *fiber.Ctx) error {
 	if s.agentHandler == nil {
 		return c.Status(503).JSON(fiber.Map{
 			"error": "agent handler not initialized",
 		})
 	}
 
-	// Parse session ID from URL params
-	sessionIDStr := c.Params("id")
-	sessionID, err := uuid.Parse(sessionIDStr)
+	statusFilter := c.Query("status", "all")
+
+	sessions, err := s.agentHandler.SessionManager.ListAllSessions(statusFilter)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "invalid session ID",
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to list sessions: %v", err),
 		})
 	}
 
-	// Parse pagination params
-	limit := c.QueryInt("limit", 50)
-	offset := c.QueryInt("offset", 0)
+	projects := agents.GroupSessionsByProject(sessions)
 
-	// Validate pagination params
-	if limit < 1 || limit > 500 {
-		limit = 50
-	}
-	if offset < 0 {
-		offset = 0
+	return c.JSON(fiber.Map{
+		"projects": projects,
+		"count":    len(projects),
+		"filter":   statusFilter,
+	})
+}
+
+// Handler: Get agent session cost and usage grouped by model
+func (s *Server) handleGetAgentSessionsByModel(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
 	}
 
-	// Get messages from storage
-	messages, hasMore, err := s.agentHandler.SessionManager.GetMessages(sessionID, limit, offset)
+	stats, err := s.agentHandler.SessionManager.GetStatsByModel()
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
-			"error": fmt.Sprintf("failed to get messages: %v", err),
+			"error": fmt.Sprintf("failed to get model stats: %v", err),
 		})
 	}
 
 	return c.JSON(fiber.Map{
-		"session_id": sessionID,
-		"messages":   messages,
-		"count":      len(messages),
-		"limit":      limit,
-		"offset":     offset,
-		"has_more":   hasMore,
+		"models": stats,
+		"count":  len(stats),
 	})
 }
 
-// Handler: Get available AI providers (from providers.json)
-func (s *Server) handleGetProviders(c *fiber.Ctx) error {
-	availableProviders := providers.GetAvailableProviders()
+// recentResponseSnippetBytes caps the content returned per recent response
+const recentResponseSnippetBytes = 280
 
-	// Get current provider configuration
-	var currentProvider *database.ProviderConfig
-	if s.repo != nil {
-		currentProvider, _ = s.repo.GetCurrentProvider()
+// Handler: Get the latest assistant responses across all agent sessions
+func (s *Server) handleGetRecentAgentResponses(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
+
+	limit := c.QueryInt("limit", 20)
+	if limit <= 0 || limit > 200 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "limit must be between 1 and 200",
+		})
+	}
+
+	messages, err := s.agentHandler.SessionManager.GetRecentAssistantMessages(limit)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to get recent responses: %v", err),
+		})
+	}
+
+	responses := make([]fiber.Map, 0, len(messages))
+	for _, msg := range messages {
+		snippet := truncateUTF8(msg.Content, recentResponseSnippetBytes)
+		responses = append(responses, fiber.Map{
+			"message_id":        msg.MessageID,
+			"session_id":        msg.SessionID,
+			"snippet":           snippet,
+			"truncated":         len(snippet) < len(msg.Content),
+			"timestamp":         msg.Timestamp,
+			"session_status":    msg.SessionStatus,
+			"model_name":        msg.ModelName,
+			"git_branch":        msg.GitBranch,
+			"agent_name":        msg.AgentName,
+			"working_directory": msg.WorkingDirectory,
+		})
 	}
 
 	return c.JSON(fiber.Map{
-		"providers": availableProviders,
-		"count":     len(availableProviders),
-		"current":   currentProvider,
-		"timestamp": time.Now(),
+		"responses": responses,
+		"count":     len(responses),
 	})
 }
 
-// Handler: Get system information (runtime metrics and server stats)
-func (s *Server) handleGetSystemInfo(c *fiber.Ctx) error {
-	// Get working directory
-	cwd, _ := os.Getwd()
+// Handler: Get average duration, turns and cost across agent sessions
+func (s *Server) handleGetAgentSessionsSummary(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
 
-	// Get hostname
-	hostname, _ := os.Hostname()
+	sessions, err := s.agentHandler.SessionManager.ListAllSessions(c.Query("status", "all"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to list sessions: %v", err),
+		})
+	}
+
+	return c.JSON(agents.SummarizeSessions(sessions))
+}
+
+// Handler: Compare cost, turns, duration, model and message count of two sessions (?a=<id>&b=<id>)
+func (s *Server) handleCompareAgentSessions(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
+
+	a, err := parseUUIDQuery(c, "a")
+	if err != nil {
+		return invalidUUIDResponse(c, err)
+	}
+	b, err := parseUUIDQuery(c, "b")
+	if err != nil {
+		return invalidUUIDResponse(c, err)
+	}
+
+	comparison, err := s.agentHandler.SessionManager.CompareSessions(a, b)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(comparison)
+}
+
+// Handler: Get the runtime agent connection limit and current usage
+func (s *Server) handleGetAgentRuntimeConfig(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
+
+	return c.JSON(s.agentRuntimeConfig())
+}
+
+// Handler: Change the agent connection limit without restarting.
+// The change is not written to the config file.
+func (s *Server) handleUpdateAgentRuntimeConfig(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
+
+	type UpdateAgentConfigRequest struct {
+		MaxConcurrentSessions *int `json:"max_concurrent_sessions"`
+	}
+
+	var req UpdateAgentConfigRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if req.MaxConcurrentSessions == nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "max_concurrent_sessions is required",
+		})
+	}
+
+	if err := s.agentHandler.SetMaxConcurrentSessions(*req.MaxConcurrentSessions); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	config := s.agentRuntimeConfig()
+	s.wsHub.BroadcastData("agent_config_updated", config)
+
+	return c.JSON(config)
+}
+
+// agentRuntimeConfig describes the agent connection limit and current usage
+func (s *Server) agentRuntimeConfig() fiber.Map {
+	stats := s.agentHandler.GetStats()
+	return fiber.Map{
+		"max_concurrent_sessions": stats["max_connections"],
+		"active_connections":      stats["active_connections"],
+		"active_sessions":         stats["active_sessions"],
+	}
+}
+
+// Handler: Get whether the session cleanup job is paused
+func (s *Server) handleGetAgentCleanupState(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
+
+	return c.JSON(s.agentCleanupState())
+}
+
+// Handler: Pause retention deletion by the session cleanup job
+func (s *Server) handlePauseAgentCleanup(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
+
+	s.agentHandler.SessionManager.PauseCleanup()
+
+	state := s.agentCleanupState()
+	s.wsHub.BroadcastData("agent_cleanup_updated", state)
+
+	return c.JSON(state)
+}
+
+// Handler: Resume retention deletion by the session cleanup job
+func (s *Server) handleResumeAgentCleanup(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
+
+	s.agentHandler.SessionManager.ResumeCleanup()
+
+	state := s.agentCleanupState()
+	s.wsHub.BroadcastData("agent_cleanup_updated", state)
+
+	return c.JSON(state)
+}
+
+// agentCleanupState describes the current state of the session cleanup job
+func (s *Server) agentCleanupState() fiber.Map {
+	sm := s.agentHandler.SessionManager
+	return fiber.Map{
+		"enabled": sm.CleanupEnabled(),
+		"paused":  sm.CleanupPaused(),
+	}
+}
+
+// Handler: Force-kill a single agent session, even if it is stuck
+func (s *Server) handleForceKillAgentSession(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
+
+	sessionID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return invalidUUIDResponse(c, err)
+	}
+
+	wasRunning, err := s.agentHandler.SessionManager.ForceKillSession(sessionID)
+	if err != nil {
+		// The session is already torn down; only the status update failed
+		logging.Error("Failed to force-kill session %s: %v", sessionID, err)
+	}
+
+	result := fiber.Map{
+		"session_id":  sessionID,
+		"status":      "killed",
+		"was_running": wasRunning,
+	}
+	s.wsHub.BroadcastData("agent_session_killed", result)
+
+	return c.JSON(result)
+}
+
+// Handler: End all active agent sessions in a working directory
+func (s *Server) handleKillAgentSessionsByDir(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
+
+	var req struct {
+		WorkingDirectory string `json:"working_directory"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+	if strings.TrimSpace(req.WorkingDirectory) == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "working_directory is required",
+		})
+	}
+
+	ended := s.agentHandler.SessionManager.EndSessionsInDirectory(req.WorkingDirectory)
+
+	result := fiber.Map{
+		"working_directory": req.WorkingDirectory,
+		"count":             len(ended),
+		"session_ids":       ended,
+	}
+	if len(ended) > 0 {
+		s.wsHub.BroadcastData("agent_sessions_killed", result)
+	}
+
+	return c.JSON(result)
+}
+
+// Handler: Show the SDK options an agent session's prompts are sent with
+func (s *Server) handleGetAgentSessionDebug(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
+
+	sessionID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return invalidUUIDResponse(c, err)
+	}
+
+	resolved, err := s.agentHandler.SessionManager.ResolveOptions(sessionID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(resolved)
+}
+
+// Handler: Get a shell script that recreates an agent session's configuration,
+// with secrets masked, for attaching to bug reports
+func (s *Server) handleGetAgentSessionRepro(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
+
+	sessionID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return invalidUUIDResponse(c, err)
+	}
+
+	script, err := s.agentHandler.SessionManager.ReproScript(sessionID, c.BaseURL())
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	c.Set("Content-Type", "text/x-shellscript; charset=utf-8")
+	c.Set("Content-Disposition", fmt.Sprintf(`inline; filename="repro-%s.sh"`, sessionID))
+
+	return c.SendString(script)
+}
+
+// Handler: Get the ordered tool invocations of an agent session with their results
+func (s *Server) handleGetAgentToolTimeline(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
+
+	sessionID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return invalidUUIDResponse(c, err)
+	}
+
+	timeline, err := s.agentHandler.SessionManager.GetToolTimeline(sessionID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"session_id": sessionID,
+		"tools":      timeline,
+		"count":      len(timeline),
+	})
+}
+
+// Handler: Check whether an agent session can be resumed
+func (s *Server) handleGetAgentSessionResumable(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
+
+	sessionID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return invalidUUIDResponse(c, err)
+	}
+
+	check, err := s.agentHandler.SessionManager.CheckResumable(sessionID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(check)
+}
+
+// Handler: Replay a logged user prompt in a new agent session
+func (s *Server) handleReplayUserPrompt(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
+
+	promptID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid prompt ID",
+		})
+	}
+
+	prompt, err := s.repo.GetUserMessage(promptID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to get prompt: %v", err),
+		})
+	}
+	if prompt == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "prompt not found",
+		})
+	}
+
+	var options agents.SessionOptions
+	if prompt.WorkingDirectory != "" {
+		workingDir := prompt.WorkingDirectory
+		options.WorkingDirectory = &workingDir
+	}
+
+	sessionID := uuid.New()
+	session, err := s.agentHandler.SessionManager.CreateSession(sessionID, options)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to create session: %v", err),
+		})
+	}
+	// Snapshot before the prompt starts updating the live session
+	created := *session
+
+	if err := s.agentHandler.SessionManager.SendPromptDetached(sessionID, prompt.Message); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error":      fmt.Sprintf("failed to send prompt: %v", err),
+			"session_id": sessionID,
+		})
+	}
+
+	result := fiber.Map{
+		"session_id": sessionID,
+		"prompt_id":  prompt.ID,
+		"session":    created,
+	}
+	s.wsHub.BroadcastData("agent_prompt_replayed", result)
+
+	return c.Status(201).JSON(result)
+}
+
+// Handler: Import a batch of always-allow rules into an agent session
+func (s *Server) handleImportAgentRules(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
+
+	sessionID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return invalidUUIDResponse(c, err)
+	}
+
+	var rules []agents.AlwaysAllowRule
+	if err := c.BodyParser(&rules); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "request body must be an array of rules",
+		})
+	}
+
+	for i, rule := range rules {
+		if rule.Tool == "" {
+			return c.Status(400).JSON(fiber.Map{
+				"error": fmt.Sprintf("rule %d: tool is required", i),
+			})
+		}
+	}
+
+	if _, err := s.agentHandler.SessionManager.GetSession(sessionID); err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	allRules, err := s.agentHandler.SessionManager.ImportAlwaysAllowRules(sessionID, rules)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to import rules: %v", err),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"session_id": sessionID,
+		"rules":      allRules,
+		"count":      len(allRules),
+	})
+}
+
+// Handler: Send a prompt to an existing agent session and stream its responses
+// as Server-Sent Events, for clients and proxies that can't use the WebSocket
+func (s *Server) handleStreamAgentPrompt(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
+
+	sessionID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return invalidUUIDResponse(c, err)
+	}
+
+	prompt := c.Query("prompt")
+	if strings.TrimSpace(prompt) == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "prompt is required",
+		})
+	}
+
+	// A WebSocket client attached to the session would compete for its responses
+	if err := s.agentHandler.CheckStreamable(sessionID); errors.Is(err, agents.ErrSessionAttached) {
+		return c.Status(409).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	} else if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no") // Keep nginx from buffering the stream
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := s.agentHandler.StreamPrompt(&sseWriter{w: w}, sessionID, prompt); err != nil {
+			logging.Warning("Agent stream for session %s ended with error: %v", sessionID, err)
+		}
+	})
+
+	return nil
+}
+
+// Handler: Get messages for an agent session (with pagination)
+func (s *Server) handleGetAgentMessages(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
+
+	// Parse session ID from URL params
+	sessionID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return invalidUUIDResponse(c, err)
+	}
+
+	// Parse pagination params
+	limit := c.QueryInt("limit", 50)
+	offset := c.QueryInt("offset", 0)
+
+	// Validate pagination params
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	// Get messages from storage
+	messages, hasMore, err := s.agentHandler.SessionManager.GetMessages(sessionID, limit, offset)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to get messages: %v", err),
+		})
+	}
+
+	total, err := s.agentHandler.SessionManager.GetMessageCount(sessionID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to count messages: %v", err),
+		})
+	}
+
+	// Thinking content is only returned on request
+	if !c.QueryBool("include_thinking", false) {
+		for _, msg := range messages {
+			msg.ThinkingContent = ""
+		}
+	}
+
+	page := newPageResult(messages, len(messages), total, limit, offset)
+	page.HasMore = hasMore
+	return c.JSON(page.withLegacy(fiber.Map{
+		"session_id": sessionID,
+		"messages":   messages,
+		"count":      len(messages),
+	}))
+}
+
+// Handler: Get the messages of an agent session flagged as important
+func (s *Server) handleGetAgentFlaggedMessages(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
+
+	sessionID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return invalidUUIDResponse(c, err)
+	}
+
+	messages, err := s.agentHandler.SessionManager.GetFlaggedMessages(sessionID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to get flagged messages: %v", err),
+		})
+	}
+	if messages == nil {
+		messages = []*agents.MessageRecord{}
+	}
+
+	// Thinking content is only returned on request
+	if !c.QueryBool("include_thinking", false) {
+		for _, msg := range messages {
+			msg.ThinkingContent = ""
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"session_id": sessionID,
+		"messages":   messages,
+		"count":      len(messages),
+	})
+}
+
+// Handler: Get available AI providers (from providers.json) and saved
+// provider configurations. API keys are masked.
+func (s *Server) handleGetProviders(c *fiber.Ctx) error {
+	availableProviders := providers.GetAvailableProviders()
+
+	// Get current and saved provider configurations
+	var currentProvider *database.ProviderConfig
+	configured := []*database.ProviderConfig{}
+	if s.repo != nil {
+		if current, _ := s.repo.GetCurrentProvider(); current != nil {
+			currentProvider = maskProviderConfig(current)
+		}
+		if saved, err := s.repo.GetAllProviders(); err == nil {
+			for _, config := range saved {
+				configured = append(configured, maskProviderConfig(config))
+			}
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"providers":  availableProviders,
+		"count":      len(availableProviders),
+		"current":    currentProvider,
+		"configured": configured,
+		"timestamp":  time.Now(),
+	})
+}
+
+// Handler: Get agent session cost and token usage grouped by provider
+func (s *Server) handleGetProviderUsage(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
+
+	stats, err := s.agentHandler.SessionManager.GetStatsByProvider()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to get provider usage: %v", err),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"providers": stats,
+		"count":     len(stats),
+	})
+}
+
+// Handler: Save a provider configuration and make it the current provider
+func (s *Server) handleSaveProvider(c *fiber.Ctx) error {
+	var req struct {
+		ProviderID string `json:"provider_id"`
+		APIKey     string `json:"api_key"`
+		CustomURL  string `json:"custom_url"`
+		ModelName  string `json:"model_name"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if providers.GetProviderByID(req.ProviderID) == nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("unknown provider: %s", req.ProviderID),
+		})
+	}
+
+	// Omitting the key keeps the saved one, so settings can change without re-entering it
+	if req.APIKey == "" {
+		existing, err := s.repo.GetProvider(req.ProviderID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": fmt.Sprintf("failed to get provider: %v", err),
+			})
+		}
+		if existing != nil {
+			req.APIKey = existing.APIKey
+		}
+	}
+
+	config := &database.ProviderConfig{
+		ProviderID: req.ProviderID,
+		APIKey:     req.APIKey,
+		CustomURL:  req.CustomURL,
+		ModelName:  req.ModelName,
+	}
+	if err := providers.SaveProviderConfig(s.repo, config); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to save provider: %v", err),
+		})
+	}
+
+	return s.activatedProviderResponse(c, req.ProviderID)
+}
+
+// Handler: Make a saved provider the current provider
+func (s *Server) handleActivateProvider(c *fiber.Ctx) error {
+	providerID := c.Params("id")
+
+	found, err := s.repo.SetCurrentProvider(providerID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to activate provider: %v", err),
+		})
+	}
+	if !found {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "provider not configured",
+		})
+	}
+
+	return s.activatedProviderResponse(c, providerID)
+}
+
+// activatedProviderResponse regenerates the provider env script for the now
+// current provider, broadcasts the change and responds with the masked config
+func (s *Server) activatedProviderResponse(c *fiber.Ctx, providerID string) error {
+	config, err := s.repo.GetProvider(providerID)
+	if err != nil || config == nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to reload provider: %v", err),
+		})
+	}
+
+	// Keep the shell env script in sync, as the TUI does when saving
+	if err := providers.GenerateEnvScript(config); err != nil {
+		logging.Warning("Failed to generate provider env script: %v", err)
+	}
+
+	masked := maskProviderConfig(config)
+	s.wsHub.BroadcastData("provider_changed", masked)
+
+	return c.JSON(fiber.Map{
+		"provider": masked,
+	})
+}
+
+// Handler: Delete a saved provider configuration
+func (s *Server) handleDeleteProvider(c *fiber.Ctx) error {
+	providerID := c.Params("id")
+
+	existing, err := s.repo.GetProvider(providerID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to get provider: %v", err),
+		})
+	}
+	if existing == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "provider not configured",
+		})
+	}
+
+	if err := s.repo.DeleteProvider(providerID); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to delete provider: %v", err),
+		})
+	}
+
+	// The env script exports the current provider's credentials
+	if existing.IsCurrent {
+		if err := os.Remove(providers.GetEnvScriptPath()); err != nil && !os.IsNotExist(err) {
+			logging.Warning("Failed to remove provider env script: %v", err)
+		}
+	}
+
+	s.wsHub.BroadcastData("provider_deleted", fiber.Map{"provider_id": providerID})
+
+	return c.JSON(fiber.Map{
+		"success":     true,
+		"provider_id": providerID,
+	})
+}
+
+// maskProviderConfig returns a copy of the provider config safe to send to
+// clients, with only the last characters of the API key
+func maskProviderConfig(config *database.ProviderConfig) *database.ProviderConfig {
+	masked := *config
+	if masked.APIKey != "" {
+		masked.APIKey = "***" + providers.MaskAPIKey(config.APIKey)
+	}
+	return &masked
+}
+
+// Handler: Get system information (runtime metrics and server stats)
+func (s *Server) handleGetSystemInfo(c *fiber.Ctx) error {
+	// Get working directory
+	cwd, _ := os.Getwd()
+
+	// Get hostname
+	hostname, _ := os.Hostname()
 
 	// Get Go runtime information
 	var memStats struct {
@@ -2157,7 +4268,9 @@ func (s *Server) handleGetSystemInfo(c *fiber.Ctx) error {
 
 	if s.agentConfig != nil {
 		agentConfig["model"] = s.agentConfig.Model
-		agentConfig["max_sessions"] = s.agentConfig.MaxConcurrentSessions
+		if s.agentHandler != nil {
+			agentConfig["max_sessions"] = s.agentHandler.MaxConcurrentSessions()
+		}
 		agentConfig["session_retention"] = s.agentConfig.SessionRetentionDays
 		agentConfig["cleanup_enabled"] = s.agentConfig.CleanupEnabled
 		agentConfig["cleanup_interval"] = s.agentConfig.CleanupIntervalHours
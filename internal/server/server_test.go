@@ -1,13 +1,23 @@
 package server
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"io"
+	"net"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/schlunsen/claude-control-terminal/internal/analytics"
+	"github.com/schlunsen/claude-control-terminal/internal/database"
+	"github.com/schlunsen/claude-control-terminal/internal/server/agents"
 	ws "github.com/schlunsen/claude-control-terminal/internal/websocket"
 )
 
@@ -39,6 +49,7 @@ func TestNewServerWithOptions(t *testing.T) {
 	tests := []struct {
 		name      string
 		claudeDir string
+		host      string
 		port      int
 		quiet     bool
 	}{
@@ -48,6 +59,13 @@ func TestNewServerWithOptions(t *testing.T) {
 			port:      3333,
 			quiet:     false,
 		},
+		{
+			name:      "custom host",
+			claudeDir: "/test/claude",
+			host:      "0.0.0.0",
+			port:      3333,
+			quiet:     false,
+		},
 		{
 			name:      "quiet mode",
 			claudeDir: "/test/claude",
@@ -64,7 +82,7 @@ func TestNewServerWithOptions(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			server := NewServerWithOptions(tt.claudeDir, tt.port, tt.quiet, false)
+			server := NewServerWithOptions(tt.claudeDir, tt.host, tt.port, tt.quiet, false)
 
 			if server == nil {
 				t.Fatal("NewServerWithOptions returned nil")
@@ -74,6 +92,10 @@ func TestNewServerWithOptions(t *testing.T) {
 				t.Errorf("expected claudeDir %q, got %q", tt.claudeDir, server.claudeDir)
 			}
 
+			if server.host != tt.host {
+				t.Errorf("expected host %q, got %q", tt.host, server.host)
+			}
+
 			if server.port != tt.port {
 				t.Errorf("expected port %d, got %d", tt.port, server.port)
 			}
@@ -120,13 +142,13 @@ func TestServerDefaultPort(t *testing.T) {
 
 func TestServerQuietModeFlag(t *testing.T) {
 	// Test non-quiet mode
-	server1 := NewServerWithOptions("/test", 3333, false, false)
+	server1 := NewServerWithOptions("/test", "", 3333, false, false)
 	if server1.quiet {
 		t.Error("expected quiet to be false")
 	}
 
 	// Test quiet mode
-	server2 := NewServerWithOptions("/test", 3333, true, false)
+	server2 := NewServerWithOptions("/test", "", 3333, true, false)
 	if !server2.quiet {
 		t.Error("expected quiet to be true")
 	}
@@ -252,7 +274,7 @@ func TestServerMultipleInstances(t *testing.T) {
 
 func TestServerQuietModeSuppressesLogger(t *testing.T) {
 	// Create server in quiet mode
-	server := NewServerWithOptions("/test", 3333, true, false)
+	server := NewServerWithOptions("/test", "", 3333, true, false)
 
 	if !server.quiet {
 		t.Error("server should be in quiet mode")
@@ -555,6 +577,33 @@ func TestHandleGetStats(t *testing.T) {
 	}
 }
 
+func TestBuildStatsInRange(t *testing.T) {
+	tmpDir := t.TempDir()
+	server := NewServer(tmpDir, 3333)
+	server.resetTracker = analytics.NewResetTracker(tmpDir)
+
+	now := time.Now()
+	conversations := []analytics.Conversation{
+		{ID: "old", Tokens: 1000, LastModified: now.AddDate(0, 0, -30)},
+		{ID: "recent", Tokens: 200, LastModified: now.Add(-time.Hour)},
+	}
+
+	// The reset point is "now", so a window ending before it ignores the delta
+	if err := server.resetTracker.SetResetPoint(500, 1, "test"); err != nil {
+		t.Fatalf("SetResetPoint failed: %v", err)
+	}
+
+	allTime := server.buildStatsInRange(conversations, time.Time{}, time.Time{})
+	if allTime["totalTokens"] != 700 || allTime["cliConversations"] != 2 {
+		t.Errorf("expected all-time stats with the reset delta, got %v", allTime)
+	}
+
+	lastWeek := server.buildStatsInRange(conversations, now.AddDate(0, 0, -7), now.Add(-time.Minute))
+	if lastWeek["totalTokens"] != 200 || lastWeek["cliConversations"] != 1 {
+		t.Errorf("expected only the recent conversation without the reset delta, got %v", lastWeek)
+	}
+}
+
 // Note: Tests for database-dependent handlers (shell history, claude history, command stats, db stats)
 // require a fully initialized database setup. These handlers currently panic with nil repos/db,
 // so they cannot be tested without proper initialization. These are tested during integration testing.
@@ -627,3 +676,656 @@ func TestServerFieldAccess(t *testing.T) {
 		t.Error("app should be initialized")
 	}
 }
+
+func TestContainsControlChars(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"plain prompt", false},
+		{"multi\nline\twith\r\nwhitespace", false},
+		{"bell\x07char", true},
+		{"escape\x1b[31m", true},
+	}
+
+	for _, tt := range tests {
+		if got := containsControlChars(tt.input); got != tt.expected {
+			t.Errorf("containsControlChars(%q) = %v, expected %v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	if got := truncateUTF8("short", 10); got != "short" {
+		t.Errorf("expected unchanged string, got %q", got)
+	}
+
+	if got := truncateUTF8("abcdef", 3); got != "abc" {
+		t.Errorf("expected 'abc', got %q", got)
+	}
+
+	// "é" is two bytes; cutting in the middle must drop the whole character
+	if got := truncateUTF8("aé", 2); got != "a" {
+		t.Errorf("expected 'a', got %q", got)
+	}
+}
+
+func TestTruncateCommandOutput(t *testing.T) {
+	if got := truncateCommandOutput("ok", 10); got != "ok" {
+		t.Errorf("expected unchanged output, got %q", got)
+	}
+
+	if got := truncateCommandOutput("0123456789", 4); got != "0123\n[truncated 6 bytes]" {
+		t.Errorf("unexpected truncated output %q", got)
+	}
+
+	server := NewServer("/test", 3333)
+	if got := server.maxCommandOutputBytes(); got != defaultMaxCommandOutputBytes {
+		t.Errorf("expected default cap without config, got %d", got)
+	}
+	server.config = &Config{Recording: RecordingSettings{MaxCommandOutputBytes: -1}}
+	if got := server.maxCommandOutputBytes(); got != 0 {
+		t.Errorf("expected negative setting to disable the cap, got %d", got)
+	}
+}
+
+func TestHandleGetDashboardInvalidLimit(t *testing.T) {
+	server := NewServer("/test", 3333)
+	server.app.Get("/dashboard", server.handleGetDashboard)
+
+	req := httptest.NewRequest("GET", "/dashboard?conversations=0", nil)
+	resp, err := server.app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+
+	if resp.StatusCode != 400 {
+		t.Errorf("Expected status 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestRecentConversations(t *testing.T) {
+	now := time.Now()
+	conversations := []analytics.Conversation{
+		{ID: "old", LastModified: now.Add(-2 * time.Hour)},
+		{ID: "newest", LastModified: now},
+		{ID: "middle", LastModified: now.Add(-time.Hour)},
+	}
+
+	recent := recentConversations(conversations, 2)
+	if len(recent) != 2 {
+		t.Fatalf("expected 2 conversations, got %d", len(recent))
+	}
+	if recent[0].ID != "newest" || recent[1].ID != "middle" {
+		t.Errorf("expected [newest middle], got [%s %s]", recent[0].ID, recent[1].ID)
+	}
+
+	// The input slice must not be reordered
+	if conversations[0].ID != "old" {
+		t.Errorf("expected input order to be preserved, got %s first", conversations[0].ID)
+	}
+
+	if got := recentConversations(conversations, 10); len(got) != 3 {
+		t.Errorf("expected all 3 conversations when under the limit, got %d", len(got))
+	}
+}
+
+func TestFormatShellLog(t *testing.T) {
+	exitOK, exitFail := 0, 2
+	duration := 1500
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	// Repository returns newest first
+	commands := []*database.ShellCommand{
+		{Command: "make test", ExitCode: &exitFail, Stderr: "FAIL", ExecutedAt: start.Add(time.Minute)},
+		{Command: "make build", ExitCode: &exitOK, DurationMs: &duration, Stdout: "ok\n", ExecutedAt: start},
+	}
+
+	log := formatShellLog("conv-1", commands)
+
+	build := strings.Index(log, "$ make build")
+	test := strings.Index(log, "$ make test")
+	if build < 0 || test < 0 || build > test {
+		t.Fatalf("Expected commands in chronological order, got:\n%s", log)
+	}
+
+	for _, want := range []string{"exit: 0, duration: 1.5s", "exit: 2, duration: unknown", "--- stdout ---\nok\n", "--- stderr ---\nFAIL\n"} {
+		if !strings.Contains(log, want) {
+			t.Errorf("Expected log to contain %q, got:\n%s", want, log)
+		}
+	}
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	if got := sanitizeFilename(`abc-123_"x/y`); got != "abc-123__x_y" {
+		t.Errorf("Unexpected sanitized filename %q", got)
+	}
+}
+
+func TestHandleGetPublicConfig(t *testing.T) {
+	server := NewServer("/test", 3333)
+	server.app.Get("/config", server.handleGetPublicConfig)
+
+	server.config = &Config{
+		TLS:  TLSSettings{Enabled: true, CertPath: "/secret/cert.pem", KeyPath: "/secret/key.pem"},
+		Auth: AuthSettings{Enabled: true, APIKeyPath: "/secret/.secret"},
+		CORS: CORSSettings{AllowedOrigins: []string{"https://example.com"}},
+		Agent: AgentSettings{
+			Model:                 "sonnet",
+			MaxConcurrentSessions: 7,
+			SessionRetentionDays:  30,
+		},
+		Notifications: NotificationSettings{WebhookURL: "https://hooks.example.com/secret-token"},
+	}
+
+	req := httptest.NewRequest("GET", "/config", nil)
+	resp, err := server.app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %v", err)
+	}
+	bodyStr := string(body)
+
+	for _, secret := range []string{"/secret/", "secret-token"} {
+		if strings.Contains(bodyStr, secret) {
+			t.Errorf("Response must not contain %q: %s", secret, bodyStr)
+		}
+	}
+
+	var config struct {
+		Agent struct {
+			Model                 string `json:"model"`
+			MaxConcurrentSessions int    `json:"max_concurrent_sessions"`
+			SessionRetentionDays  int    `json:"session_retention_days"`
+		} `json:"agent"`
+		TLS struct {
+			Enabled bool `json:"enabled"`
+		} `json:"tls"`
+		Auth struct {
+			Enabled bool `json:"enabled"`
+		} `json:"auth"`
+		CORS struct {
+			AllowedOrigins []string `json:"allowed_origins"`
+		} `json:"cors"`
+		Notifications struct {
+			WebhookEnabled bool `json:"webhook_enabled"`
+		} `json:"notifications"`
+	}
+	if err := json.Unmarshal(body, &config); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if config.Agent.Model != "sonnet" || config.Agent.MaxConcurrentSessions != 7 || config.Agent.SessionRetentionDays != 30 {
+		t.Errorf("Unexpected agent config: %+v", config.Agent)
+	}
+	if !config.TLS.Enabled || !config.Auth.Enabled || !config.Notifications.WebhookEnabled {
+		t.Errorf("Expected TLS, auth and webhook to be reported enabled: %s", bodyStr)
+	}
+	if len(config.CORS.AllowedOrigins) != 1 || config.CORS.AllowedOrigins[0] != "https://example.com" {
+		t.Errorf("Unexpected CORS origins: %v", config.CORS.AllowedOrigins)
+	}
+}
+
+func TestConversationsNotModified(t *testing.T) {
+	claudeDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(claudeDir, "conv.jsonl"), []byte(`{"type":"user"}`+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write conversation: %v", err)
+	}
+	old := time.Now().Add(-3 * time.Hour)
+	if err := os.Chtimes(filepath.Join(claudeDir, "conv.jsonl"), old, old); err != nil {
+		t.Fatalf("Failed to set mtime: %v", err)
+	}
+
+	server := NewServer(claudeDir, 3333)
+	server.conversationAnalyzer = analytics.NewConversationAnalyzer(claudeDir)
+	server.app.Get("/conversations", func(c *fiber.Ctx) error {
+		notModified, err := server.conversationsNotModified(c, map[string]string{"conv": "archived"})
+		if err != nil {
+			return err
+		}
+		if notModified {
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+		return c.SendString("full response")
+	})
+
+	resp, err := server.app.Test(httptest.NewRequest("GET", "/conversations", nil))
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != 200 || etag == "" {
+		t.Fatalf("Expected 200 with an ETag, got %d %q", resp.StatusCode, etag)
+	}
+
+	req := httptest.NewRequest("GET", "/conversations", nil)
+	req.Header.Set("If-None-Match", etag)
+	resp, err = server.app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	if resp.StatusCode != 304 {
+		t.Errorf("Expected 304 for matching ETag, got %d", resp.StatusCode)
+	}
+
+	// A different query string gets a different ETag
+	req = httptest.NewRequest("GET", "/conversations?status=active", nil)
+	req.Header.Set("If-None-Match", etag)
+	resp, err = server.app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected 200 for a different query, got %d", resp.StatusCode)
+	}
+
+	// Changing a conversation file invalidates the ETag
+	if err := os.WriteFile(filepath.Join(claudeDir, "conv.jsonl"), []byte(`{"type":"user"}`+"\n"+`{"type":"assistant"}`+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write conversation: %v", err)
+	}
+	req = httptest.NewRequest("GET", "/conversations", nil)
+	req.Header.Set("If-None-Match", etag)
+	resp, err = server.app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to test request: %v", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected 200 after the conversation changed, got %d", resp.StatusCode)
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{`"abc"`, true},
+		{`W/"abc"`, true},
+		{`"other", "abc"`, true},
+		{"*", true},
+		{`"other"`, false},
+	}
+
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, `"abc"`); got != tt.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestListenUnix(t *testing.T) {
+	dir, err := os.MkdirTemp("", "cct-sock-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "cct.sock")
+
+	ln, err := listenUnix(socketPath, nil)
+	if err != nil {
+		t.Fatalf("listenUnix failed: %v", err)
+	}
+
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatalf("Socket not created: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected socket permissions 0600, got %o", info.Mode().Perm())
+	}
+
+	// A socket that is still being served must not be replaced
+	if _, err := listenUnix(socketPath, nil); err == nil {
+		t.Error("Expected error for socket already in use")
+	}
+	ln.Close()
+
+	// A stale socket file is replaced
+	stale, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to create stale socket: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, err = listenUnix(socketPath, nil)
+	if err != nil {
+		t.Fatalf("listenUnix should replace stale socket: %v", err)
+	}
+	ln.Close()
+
+	// Regular files are never removed
+	filePath := filepath.Join(dir, "regular")
+	if err := os.WriteFile(filePath, []byte("data"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := listenUnix(filePath, nil); err == nil {
+		t.Error("Expected error for non-socket path")
+	}
+}
+
+func TestAllowOrigin(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "cct-origins-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if err := os.MkdirAll(filepath.Dir(NewConfigManager(tempDir).GetSecretPath()), 0700); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+
+	server := NewServer(tempDir, 3333)
+	server.app.Get("/api-key", server.handleGetAPIKey)
+
+	if err := server.AllowOrigin("https://example.trycloudflare.com"); err == nil {
+		t.Error("Expected error before setup")
+	}
+
+	server.allowedOrigins = newOriginAllowlist([]string{"https://localhost:3333"})
+
+	request := func(origin string) int {
+		req := httptest.NewRequest("GET", "/api-key", nil)
+		req.Header.Set("Origin", origin)
+		resp, err := server.app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	tunnelOrigin := "https://example.trycloudflare.com"
+	if status := request(tunnelOrigin); status != 403 {
+		t.Errorf("Expected 403 for unknown origin, got %d", status)
+	}
+
+	for _, invalid := range []string{"", "example.com", "ftp://example.com"} {
+		if err := server.AllowOrigin(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+
+	if err := server.AllowOrigin(tunnelOrigin + "/some/path"); err != nil {
+		t.Fatalf("AllowOrigin failed: %v", err)
+	}
+	if status := request(tunnelOrigin); status != 200 {
+		t.Errorf("Expected 200 for registered origin, got %d", status)
+	}
+	if status := request("https://localhost:3333"); status != 200 {
+		t.Errorf("Expected 200 for configured origin, got %d", status)
+	}
+
+	// Registering again must not duplicate the entry
+	server.AllowOrigin(tunnelOrigin)
+	if origins := server.allowedOrigins.list(); len(origins) != 2 {
+		t.Errorf("Expected 2 origins, got %v", origins)
+	}
+
+	// The endpoint registers origins for integrations running in another process
+	server.app.Post("/cors/origins", server.handleAllowOrigin)
+	post := func(body string) int {
+		req := httptest.NewRequest("POST", "/cors/origins", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to test request: %v", err)
+		}
+		return resp.StatusCode
+	}
+	if status := post(`{"url":"example.com"}`); status != 400 {
+		t.Errorf("Expected 400 for invalid URL, got %d", status)
+	}
+	ngrokOrigin := "https://abc123.ngrok.app"
+	if status := post(`{"url":"` + ngrokOrigin + `/"}`); status != 200 {
+		t.Errorf("Expected 200 registering origin, got %d", status)
+	}
+	if status := request(ngrokOrigin); status != 200 {
+		t.Errorf("Expected 200 for origin registered over HTTP, got %d", status)
+	}
+}
+
+func TestMaskProviderConfig(t *testing.T) {
+	config := &database.ProviderConfig{ProviderID: "glm", APIKey: "sk-secret-abcd1234"}
+
+	masked := maskProviderConfig(config)
+	if masked.APIKey != "***1234" {
+		t.Errorf("Expected masked key ***1234, got %q", masked.APIKey)
+	}
+	if config.APIKey != "sk-secret-abcd1234" {
+		t.Error("maskProviderConfig must not modify the original config")
+	}
+
+	if empty := maskProviderConfig(&database.ProviderConfig{ProviderID: "claude"}); empty.APIKey != "" {
+		t.Errorf("Expected empty key to stay empty, got %q", empty.APIKey)
+	}
+}
+
+func TestWriteSessionsCSV(t *testing.T) {
+	created := time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC)
+	ended := created.Add(10 * time.Minute)
+	agentName := "reviewer, strict"
+	optionModel := "opus"
+
+	sessions := []agents.Session{
+		{
+			ID:         uuid.MustParse("11111111-1111-1111-1111-111111111111"),
+			CreatedAt:  created,
+			EndedAt:    &ended,
+			ModelName:  "claude-sonnet-4",
+			NumTurns:   3,
+			DurationMS: 4200,
+			CostUSD:    0.125,
+			GitBranch:  "main",
+			Options:    agents.SessionOptions{AgentName: &agentName},
+		},
+		{
+			ID:        uuid.MustParse("22222222-2222-2222-2222-222222222222"),
+			CreatedAt: created,
+			Options:   agents.SessionOptions{Model: &optionModel},
+		},
+	}
+
+	var buf strings.Builder
+	if err := writeSessionsCSV(&buf, sessions); err != nil {
+		t.Fatalf("writeSessionsCSV failed: %v", err)
+	}
+
+	expected := "id,name,model,created_at,ended_at,num_turns,duration_ms,cost_usd,git_branch\n" +
+		`11111111-1111-1111-1111-111111111111,"reviewer, strict",claude-sonnet-4,2025-03-01T09:30:00Z,2025-03-01T09:40:00Z,3,4200,0.125000,main` + "\n" +
+		"22222222-2222-2222-2222-222222222222,,opus,2025-03-01T09:30:00Z,,0,0,0.000000,\n"
+	if buf.String() != expected {
+		t.Errorf("unexpected CSV:\n%s\nwant:\n%s", buf.String(), expected)
+	}
+}
+
+func TestFilterSessionsByCreatedAt(t *testing.T) {
+	day := func(d int) agents.Session {
+		return agents.Session{CreatedAt: time.Date(2025, 3, d, 12, 0, 0, 0, time.Local)}
+	}
+	sessions := []agents.Session{day(1), day(2), day(3)}
+
+	from, err := parseDateParam("2025-03-02", false)
+	if err != nil {
+		t.Fatalf("parseDateParam failed: %v", err)
+	}
+	to, err := parseDateParam("2025-03-02", true)
+	if err != nil {
+		t.Fatalf("parseDateParam failed: %v", err)
+	}
+
+	filtered := filterSessionsByCreatedAt(sessions, from, to)
+	if len(filtered) != 1 || filtered[0].CreatedAt.Day() != 2 {
+		t.Errorf("expected only the session from March 2, got %v", filtered)
+	}
+
+	if got := filterSessionsByCreatedAt(sessions, time.Time{}, time.Time{}); len(got) != 3 {
+		t.Errorf("expected no filtering without bounds, got %d sessions", len(got))
+	}
+
+	if _, err := parseDateParam("yesterday", false); err == nil {
+		t.Error("expected error for invalid date")
+	}
+	if _, err := parseDateParam("2025-03-02T10:00:00Z", false); err != nil {
+		t.Errorf("expected RFC3339 to parse: %v", err)
+	}
+}
+
+func TestWarmCachesCompletes(t *testing.T) {
+	tmpDir := t.TempDir()
+	server := NewServer(tmpDir, 3333)
+	server.conversationAnalyzer = analytics.NewConversationAnalyzer(tmpDir)
+	server.stateCalculator = analytics.NewStateCalculator()
+
+	done := make(chan struct{})
+	go func() {
+		server.warmCaches()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("warmCaches did not finish")
+	}
+}
+
+func TestPageResultWithLegacy(t *testing.T) {
+	page := newPageResult([]string{"a", "b"}, 2, 5, 2, 2)
+	if !page.HasMore {
+		t.Error("expected has_more with 4 of 5 items seen")
+	}
+
+	response := page.withLegacy(fiber.Map{"prompts": []string{"a", "b"}, "count": 2, "total": -1})
+	if response["total"] != 5 {
+		t.Errorf("envelope keys must win over legacy keys, got total %v", response["total"])
+	}
+	if response["count"] != 2 || response["prompts"] == nil {
+		t.Errorf("expected legacy keys to be kept, got %v", response)
+	}
+
+	last := newPageResult([]string{"e"}, 1, 5, 2, 4)
+	if last.HasMore {
+		t.Error("expected no more items on the last page")
+	}
+}
+
+func TestAgentRoutesUnavailableWithoutAgentHandler(t *testing.T) {
+	tmpDir := t.TempDir()
+	server := NewServer(tmpDir, 3333)
+
+	configManager := NewConfigManager(tmpDir)
+	config, err := configManager.LoadOrCreateConfig()
+	if err != nil {
+		t.Fatalf("failed to load configuration: %v", err)
+	}
+	server.config = config
+
+	// agentHandler stays nil, as after a failed agent initialization
+	server.setupRoutes()
+
+	for _, path := range []string{"/agent/ws", "/api/agent/sessions", "/api/agent/config", "/api/agent/cleanup", "/api/providers/usage", "/api/agent/sessions/" + uuid.NewString() + "/stream?prompt=hi"} {
+		resp, err := server.app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatalf("Failed to test %s: %v", path, err)
+		}
+		if resp.StatusCode != 503 {
+			t.Errorf("Expected 503 for %s, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestSSEWriterFrames(t *testing.T) {
+	var buf bytes.Buffer
+	w := &sseWriter{w: bufio.NewWriter(&buf)}
+
+	events := []interface{}{
+		map[string]interface{}{"type": "agent_message", "content": map[string]interface{}{"type": "assistant"}},
+		map[string]interface{}{"type": "agent_message", "content": map[string]interface{}{"type": "result"}},
+		map[string]interface{}{"type": "permission_request", "permission_id": "p1"},
+		map[string]interface{}{"content": "no type"},
+	}
+	for _, event := range events {
+		if err := w.WriteJSON(event); err != nil {
+			t.Fatalf("WriteJSON failed: %v", err)
+		}
+	}
+
+	frames := strings.Split(strings.TrimSuffix(buf.String(), "\n\n"), "\n\n")
+	if len(frames) != len(events) {
+		t.Fatalf("expected %d frames, got %d: %q", len(events), len(frames), buf.String())
+	}
+
+	wantEvents := []string{"agent_message", "result", "permission_request", "message"}
+	for i, frame := range frames {
+		lines := strings.Split(frame, "\n")
+		if len(lines) != 2 || lines[0] != "event: "+wantEvents[i] || !strings.HasPrefix(lines[1], "data: {") {
+			t.Errorf("frame %d: unexpected %q", i, frame)
+		}
+	}
+}
+
+func TestInvalidUUIDResponse(t *testing.T) {
+	app := fiber.New()
+	app.Get("/sessions/:id", func(c *fiber.Ctx) error {
+		sessionID, err := parseUUIDParam(c, "id")
+		if err != nil {
+			return invalidUUIDResponse(c, err)
+		}
+		return c.SendString(sessionID.String())
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/sessions/not-a-uuid", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != 400 {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if body["error"] != "invalid_uuid" || body["field"] != "id" || body["value"] != "not-a-uuid" {
+		t.Errorf("unexpected response body: %v", body)
+	}
+
+	valid := uuid.New().String()
+	resp, err = app.Test(httptest.NewRequest("GET", "/sessions/"+valid, nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Errorf("expected 200 for a valid UUID, got %d", resp.StatusCode)
+	}
+}
+
+func TestParseFrontmatterWithPromptPartial(t *testing.T) {
+	complete := parseFrontmatterWithPrompt("---\nname: reviewer\ndescription: \"Reviews code\"\n---\n\nYou review code.")
+	if complete["name"] != "reviewer" || complete["system_prompt"] != "You review code." {
+		t.Errorf("unexpected parse of complete frontmatter: %v", complete)
+	}
+
+	partial := parseFrontmatterWithPrompt("---\nname: reviewer\nYou review code.")
+	if partial == nil {
+		t.Fatal("expected partial frontmatter to be parsed")
+	}
+	if _, ok := partial["name"]; ok {
+		t.Errorf("expected no metadata for partial frontmatter, got %v", partial)
+	}
+	if partial["system_prompt"] != "---\nname: reviewer\nYou review code." {
+		t.Errorf("expected whole content as system prompt, got %q", partial["system_prompt"])
+	}
+
+	if plain := parseFrontmatterWithPrompt("Just a prompt"); plain["system_prompt"] != "Just a prompt" {
+		t.Errorf("expected content without frontmatter as system prompt, got %v", plain)
+	}
+}
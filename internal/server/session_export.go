@@ -0,0 +1,183 @@
+package server
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/schlunsen/claude-control-terminal/internal/logging"
+	"github.com/schlunsen/claude-control-terminal/internal/server/agents"
+)
+
+// sessionCSVHeader is the column order of the agent session cost report
+var sessionCSVHeader = []string{
+	"id", "name", "model", "created_at", "ended_at",
+	"num_turns", "duration_ms", "cost_usd", "git_branch",
+}
+
+// Handler: Export agent sessions as a CSV cost report
+// (?status=, ?branch=, ?from= and ?to= filter like the session list; dates are YYYY-MM-DD or RFC3339)
+func (s *Server) handleExportAgentSessionsCSV(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
+
+	from, err := parseDateParam(c.Query("from"), false)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("invalid from date: %v", err),
+		})
+	}
+	to, err := parseDateParam(c.Query("to"), true)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("invalid to date: %v", err),
+		})
+	}
+
+	statusFilter := c.Query("status", "all")
+	branch := c.Query("branch")
+
+	var sessions []agents.Session
+	if branch != "" {
+		sessions, err = s.agentHandler.SessionManager.ListSessionsByBranch(branch, statusFilter)
+	} else {
+		sessions, err = s.agentHandler.SessionManager.ListAllSessions(statusFilter)
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": fmt.Sprintf("failed to list sessions: %v", err),
+		})
+	}
+
+	sessions = filterSessionsByCreatedAt(sessions, from, to)
+
+	c.Set("Content-Type", "text/csv; charset=utf-8")
+	c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="agent-sessions-%s.csv"`, time.Now().Format("2006-01-02")))
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := writeSessionsCSV(w, sessions); err != nil {
+			logging.Error("Agent session CSV export failed: %v", err)
+		}
+		w.Flush()
+	})
+
+	return nil
+}
+
+// Handler: Export an agent session transcript as markdown
+// (?include_pending=true adds the live in-memory state; that section may change while a turn is running)
+func (s *Server) handleExportAgentSessionMarkdown(c *fiber.Ctx) error {
+	if s.agentHandler == nil {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "agent handler not initialized",
+		})
+	}
+
+	sessionID, err := parseUUIDParam(c, "id")
+	if err != nil {
+		return invalidUUIDResponse(c, err)
+	}
+
+	markdown, err := s.agentHandler.SessionManager.ExportMarkdown(sessionID, c.QueryBool("include_pending", false))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	c.Set("Content-Type", "text/markdown; charset=utf-8")
+	c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="agent-session-%s.md"`, sessionID))
+	return c.SendString(markdown)
+}
+
+// parseDateParam parses a YYYY-MM-DD or RFC3339 query value. A date-only end
+// bound covers the whole day. Empty values return the zero time.
+func parseDateParam(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected YYYY-MM-DD or RFC3339, got %q", value)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
+
+// filterSessionsByCreatedAt keeps sessions created within [from, to]; zero bounds are open
+func filterSessionsByCreatedAt(sessions []agents.Session, from, to time.Time) []agents.Session {
+	if from.IsZero() && to.IsZero() {
+		return sessions
+	}
+
+	filtered := make([]agents.Session, 0, len(sessions))
+	for _, session := range sessions {
+		if timeInRange(session.CreatedAt, from, to) {
+			filtered = append(filtered, session)
+		}
+	}
+	return filtered
+}
+
+// timeInRange reports whether t lies within [from, to]; zero bounds are open
+func timeInRange(t, from, to time.Time) bool {
+	if !from.IsZero() && t.Before(from) {
+		return false
+	}
+	if !to.IsZero() && t.After(to) {
+		return false
+	}
+	return true
+}
+
+// writeSessionsCSV writes one row per session in sessionCSVHeader order
+func writeSessionsCSV(w io.Writer, sessions []agents.Session) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(sessionCSVHeader); err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		name := ""
+		if session.Options.AgentName != nil {
+			name = *session.Options.AgentName
+		}
+		model := session.ModelName
+		if model == "" && session.Options.Model != nil {
+			model = *session.Options.Model
+		}
+		endedAt := ""
+		if session.EndedAt != nil {
+			endedAt = session.EndedAt.UTC().Format(time.RFC3339)
+		}
+
+		if err := writer.Write([]string{
+			session.ID.String(),
+			name,
+			model,
+			session.CreatedAt.UTC().Format(time.RFC3339),
+			endedAt,
+			strconv.Itoa(session.NumTurns),
+			strconv.FormatInt(session.DurationMS, 10),
+			strconv.FormatFloat(session.CostUSD, 'f', 6, 64),
+			session.GitBranch,
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/schlunsen/claude-control-terminal/internal/analytics"
+	"github.com/schlunsen/claude-control-terminal/internal/database"
+	"github.com/schlunsen/claude-control-terminal/internal/logging"
+)
+
+const (
+	// shellSampleInterval is how often background shells are sampled
+	shellSampleInterval = time.Minute
+
+	// shellHistoryRetention is how long ended background shells are kept
+	shellHistoryRetention = 30 * 24 * time.Hour
+)
+
+// ShellSampler periodically records detected background shells so they remain
+// visible after they exit
+type ShellSampler struct {
+	detector *analytics.ShellDetector
+	repo     *database.Repository
+	interval time.Duration
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewShellSampler creates a sampler that records into the given repository
+func NewShellSampler(detector *analytics.ShellDetector, repo *database.Repository) *ShellSampler {
+	return &ShellSampler{
+		detector: detector,
+		repo:     repo,
+		interval: shellSampleInterval,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start begins sampling in a background goroutine
+func (ss *ShellSampler) Start() {
+	go func() {
+		ss.sample()
+
+		ticker := time.NewTicker(ss.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ss.sample()
+			case <-ss.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the sampling goroutine. Safe to call more than once.
+func (ss *ShellSampler) Stop() {
+	ss.stopOnce.Do(func() { close(ss.stop) })
+}
+
+// sample records the currently detected shells and prunes expired history
+func (ss *ShellSampler) sample() {
+	shells, err := ss.detector.DetectBackgroundShells()
+	if err != nil {
+		logging.Debug("Shell sampling failed: %v", err)
+		return
+	}
+
+	records := make([]*database.BackgroundShellRecord, 0, len(shells))
+	for _, shell := range shells {
+		workingDir := shell.WorkingDir
+		if workingDir == "unknown" {
+			workingDir = ""
+		}
+		records = append(records, &database.BackgroundShellRecord{
+			ShellID:          shell.ShellID,
+			PID:              shell.PID,
+			Command:          shell.Command,
+			Status:           shell.Status,
+			WorkingDirectory: workingDir,
+		})
+	}
+
+	now := time.Now()
+	if err := ss.repo.RecordShellSnapshot(records, now); err != nil {
+		logging.Warning("Failed to record shell snapshot: %v", err)
+		return
+	}
+
+	if _, err := ss.repo.DeleteShellHistoryBefore(now.Add(-shellHistoryRetention)); err != nil {
+		logging.Warning("Failed to prune shell history: %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// sseWriter writes agent events as Server-Sent Events frames. It satisfies
+// agents.EventWriter and is safe for the concurrent writes made by the
+// response stream and the permission forwarder.
+type sseWriter struct {
+	mu sync.Mutex
+	w  *bufio.Writer
+}
+
+// WriteJSON writes v as one "data:" frame named after its message type and
+// flushes it, so a write to a disconnected client fails immediately
+func (s *sseWriter) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", sseEventName(data), data); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+// sseEventName picks the SSE event name for a serialized agent message: its
+// "type", except that agent messages carrying the turn's result are named
+// "result" so clients can stop listening without parsing every frame
+func sseEventName(data []byte) string {
+	var envelope struct {
+		Type    string          `json:"type"`
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil || envelope.Type == "" {
+		return "message"
+	}
+
+	if envelope.Type == "agent_message" {
+		var content struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(envelope.Content, &content) == nil && content.Type == "result" {
+			return "result"
+		}
+	}
+
+	return envelope.Type
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/schlunsen/claude-control-terminal/internal/server/agents"
+)
+
+// parseUUIDParam parses the named route parameter as a UUID
+func parseUUIDParam(c *fiber.Ctx, name string) (uuid.UUID, error) {
+	return agents.ParseUUID(name, c.Params(name))
+}
+
+// parseUUIDQuery parses the named query parameter as a UUID
+func parseUUIDQuery(c *fiber.Ctx, name string) (uuid.UUID, error) {
+	return agents.ParseUUID(name, c.Query(name))
+}
+
+// invalidUUIDResponse responds 400 with the invalid_uuid error code and the
+// offending value
+func invalidUUIDResponse(c *fiber.Ctx, err error) error {
+	resp := fiber.Map{
+		"error":   agents.ErrorCodeInvalidUUID,
+		"message": err.Error(),
+	}
+	var uuidErr *agents.InvalidUUIDError
+	if errors.As(err, &uuidErr) {
+		resp["field"] = uuidErr.Field
+		resp["value"] = uuidErr.Value
+	}
+	return c.Status(400).JSON(resp)
+}
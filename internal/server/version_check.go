@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/schlunsen/claude-control-terminal/internal/version"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// latestReleaseURL is the GitHub API endpoint for the latest published release
+	latestReleaseURL = "https://api.github.com/repos/schlunsen/claude-control-terminal/releases/latest"
+
+	// versionCheckTTL is how long a successful release lookup is cached
+	versionCheckTTL = time.Hour
+
+	// versionCheckErrorTTL is how long a failed lookup is cached before retrying
+	versionCheckErrorTTL = time.Minute
+)
+
+// VersionCheckResult describes how the running version compares to the latest release
+type VersionCheckResult struct {
+	Current         string    `json:"current"`
+	Latest          string    `json:"latest,omitempty"`
+	UpdateAvailable bool      `json:"update_available"`
+	CheckedAt       time.Time `json:"checked_at"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// VersionChecker looks up the latest release on GitHub and caches the result
+type VersionChecker struct {
+	releaseURL string
+	client     *http.Client
+	group      singleflight.Group // Shares one in-flight lookup between concurrent callers
+	mu         sync.Mutex
+	cached     *VersionCheckResult
+}
+
+// NewVersionChecker creates a version checker for the project's GitHub releases
+func NewVersionChecker() *VersionChecker {
+	return &VersionChecker{
+		releaseURL: latestReleaseURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Check returns the cached result if it is still fresh, otherwise queries GitHub.
+// Network failures are reported in the result's Error field rather than returned,
+// and are cached for a shorter TTL so an unreachable GitHub isn't hit on every call.
+func (vc *VersionChecker) Check(ctx context.Context) VersionCheckResult {
+	if result, ok := vc.cachedResult(); ok {
+		return result
+	}
+
+	// The lookup is shared, so one caller going away must not cancel it for the rest
+	fetchCtx := context.WithoutCancel(ctx)
+	ch := vc.group.DoChan("latest", func() (interface{}, error) {
+		if result, ok := vc.cachedResult(); ok {
+			return result, nil
+		}
+		result := vc.fetch(fetchCtx)
+		vc.mu.Lock()
+		vc.cached = &result
+		vc.mu.Unlock()
+		return result, nil
+	})
+
+	select {
+	case res := <-ch:
+		return res.Val.(VersionCheckResult)
+	case <-ctx.Done():
+		return VersionCheckResult{
+			Current:   version.Version,
+			CheckedAt: time.Now(),
+			Error:     fmt.Sprintf("failed to check latest release: %v", ctx.Err()),
+		}
+	}
+}
+
+// cachedResult returns the cached result if it hasn't expired
+func (vc *VersionChecker) cachedResult() (VersionCheckResult, bool) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	if vc.cached == nil {
+		return VersionCheckResult{}, false
+	}
+	ttl := versionCheckTTL
+	if vc.cached.Error != "" {
+		ttl = versionCheckErrorTTL
+	}
+	if time.Since(vc.cached.CheckedAt) >= ttl {
+		return VersionCheckResult{}, false
+	}
+	return *vc.cached, true
+}
+
+// fetch queries GitHub and compares the latest release to the running version
+func (vc *VersionChecker) fetch(ctx context.Context) VersionCheckResult {
+	result := VersionCheckResult{
+		Current:   version.Version,
+		CheckedAt: time.Now(),
+	}
+
+	latest, err := vc.fetchLatestTag(ctx)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to check latest release: %v", err)
+		return result
+	}
+
+	result.Latest = strings.TrimPrefix(latest, "v")
+	result.UpdateAvailable = compareVersions(result.Latest, result.Current) > 0
+
+	return result
+}
+
+// fetchLatestTag queries the GitHub releases API for the latest tag name
+func (vc *VersionChecker) fetchLatestTag(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", vc.releaseURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := vc.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("network error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GitHub returned status %d", resp.StatusCode)
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", fmt.Errorf("failed to parse release: %w", err)
+	}
+
+	if release.TagName == "" {
+		return "", fmt.Errorf("release has no tag name")
+	}
+
+	return release.TagName, nil
+}
+
+// compareVersions compares two dotted version strings (e.g. "0.7.1").
+// Returns 1 if a > b, -1 if a < b, and 0 if equal. Non-numeric parts compare as 0.
+func compareVersions(a, b string) int {
+	aParts := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bParts := strings.Split(strings.TrimPrefix(b, "v"), ".")
+
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var aNum, bNum int
+		if i < len(aParts) {
+			aNum, _ = strconv.Atoi(strings.SplitN(aParts[i], "-", 2)[0])
+		}
+		if i < len(bParts) {
+			bNum, _ = strconv.Atoi(strings.SplitN(bParts[i], "-", 2)[0])
+		}
+
+		if aNum > bNum {
+			return 1
+		}
+		if aNum < bNum {
+			return -1
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/schlunsen/claude-control-terminal/internal/version"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"0.7.1", "0.7.1", 0},
+		{"v0.8.0", "0.7.1", 1},
+		{"0.7.1", "0.10.0", -1},
+		{"1.0", "0.9.9", 1},
+		{"0.7.1-beta", "0.7.1", 0},
+	}
+
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.expected {
+			t.Errorf("compareVersions(%q, %q) = %d, expected %d", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
+
+func TestVersionCheckerCachesResult(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"tag_name": "v999.0.0"}`))
+	}))
+	defer srv.Close()
+
+	vc := NewVersionChecker()
+	vc.releaseURL = srv.URL
+
+	result := vc.Check(context.Background())
+	if result.Current != version.Version {
+		t.Errorf("expected current %q, got %q", version.Version, result.Current)
+	}
+	if result.Latest != "999.0.0" {
+		t.Errorf("expected latest '999.0.0', got %q", result.Latest)
+	}
+	if !result.UpdateAvailable {
+		t.Error("expected update to be available")
+	}
+
+	vc.Check(context.Background())
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("expected 1 request due to caching, got %d", got)
+	}
+}
+
+func TestVersionCheckerNetworkFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	vc := NewVersionChecker()
+	vc.releaseURL = srv.URL
+
+	result := vc.Check(context.Background())
+	if result.UpdateAvailable {
+		t.Error("expected update_available to be false on failure")
+	}
+	if result.Error == "" {
+		t.Error("expected error note on failure")
+	}
+}
+
+func TestVersionCheckerCachesFailure(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	vc := NewVersionChecker()
+	vc.releaseURL = srv.URL
+
+	vc.Check(context.Background())
+	if result := vc.Check(context.Background()); result.Error == "" {
+		t.Error("expected cached failure to keep its error")
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("expected failure to be cached, got %d requests", got)
+	}
+
+	// Once the failure TTL passes the next call retries
+	vc.mu.Lock()
+	vc.cached.CheckedAt = time.Now().Add(-versionCheckErrorTTL)
+	vc.mu.Unlock()
+	vc.Check(context.Background())
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("expected a retry after the failure TTL, got %d requests", got)
+	}
+}
+
+func TestVersionCheckerSharesInFlightFetch(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+		w.Write([]byte(`{"tag_name": "v1.0.0"}`))
+	}))
+	defer srv.Close()
+
+	vc := NewVersionChecker()
+	vc.releaseURL = srv.URL
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if result := vc.Check(context.Background()); result.Latest != "1.0.0" {
+				t.Errorf("expected latest '1.0.0', got %q", result.Latest)
+			}
+		}()
+	}
+
+	// The cache lock must not be held while the fetch is in flight
+	time.Sleep(50 * time.Millisecond)
+	if _, ok := vc.cachedResult(); ok {
+		t.Error("expected no cached result while the fetch is in flight")
+	}
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("expected concurrent checks to share 1 request, got %d", got)
+	}
+}
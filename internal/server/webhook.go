@@ -0,0 +1,81 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/schlunsen/claude-control-terminal/internal/database"
+	"github.com/schlunsen/claude-control-terminal/internal/logging"
+)
+
+// defaultWebhookTimeout bounds each webhook attempt when no timeout is configured
+const defaultWebhookTimeout = 5 * time.Second
+
+// NotificationDispatcher forwards recorded notifications to an external webhook.
+// Deliveries run in the background so a slow endpoint never blocks recording.
+type NotificationDispatcher struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewNotificationDispatcher creates a dispatcher from the notification settings.
+// Returns nil when no webhook URL is configured.
+func NewNotificationDispatcher(settings NotificationSettings) *NotificationDispatcher {
+	if settings.WebhookURL == "" {
+		return nil
+	}
+
+	timeout := defaultWebhookTimeout
+	if settings.WebhookTimeoutSeconds > 0 {
+		timeout = time.Duration(settings.WebhookTimeoutSeconds) * time.Second
+	}
+
+	return &NotificationDispatcher{
+		webhookURL: settings.WebhookURL,
+		client:     &http.Client{Timeout: timeout},
+	}
+}
+
+// Dispatch posts the notification to the webhook asynchronously.
+// A failed delivery is retried once before being logged and dropped.
+func (d *NotificationDispatcher) Dispatch(notif *database.Notification) {
+	if d == nil || notif == nil {
+		return
+	}
+
+	payload, err := json.Marshal(notif)
+	if err != nil {
+		logging.Error("Failed to marshal notification %d for webhook: %v", notif.ID, err)
+		return
+	}
+
+	go func() {
+		err := d.post(payload)
+		if err == nil {
+			return
+		}
+
+		logging.Warning("Notification webhook delivery failed, retrying: %v", err)
+		if err := d.post(payload); err != nil {
+			logging.Error("Notification webhook delivery failed after retry: %v", err)
+		}
+	}()
+}
+
+// post sends a single webhook request and treats non-2xx responses as errors
+func (d *NotificationDispatcher) post(payload []byte) error {
+	resp, err := d.client.Post(d.webhookURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/schlunsen/claude-control-terminal/internal/database"
+)
+
+func TestNewNotificationDispatcherDisabled(t *testing.T) {
+	if d := NewNotificationDispatcher(NotificationSettings{}); d != nil {
+		t.Error("expected nil dispatcher when webhook URL is empty")
+	}
+
+	// Dispatch on a nil dispatcher must be a no-op
+	var d *NotificationDispatcher
+	d.Dispatch(&database.Notification{Message: "ignored"})
+}
+
+func TestNotificationDispatcherPostsJSON(t *testing.T) {
+	received := make(chan database.Notification, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notif database.Notification
+		if err := json.NewDecoder(r.Body).Decode(&notif); err != nil {
+			t.Errorf("failed to decode webhook body: %v", err)
+		}
+		received <- notif
+	}))
+	defer srv.Close()
+
+	d := NewNotificationDispatcher(NotificationSettings{WebhookURL: srv.URL})
+	d.Dispatch(&database.Notification{
+		ConversationID:   "conv-1",
+		NotificationType: "permission_request",
+		Message:          "Claude needs your permission to use Bash",
+	})
+
+	select {
+	case notif := <-received:
+		if notif.ConversationID != "conv-1" {
+			t.Errorf("expected conversation_id 'conv-1', got %q", notif.ConversationID)
+		}
+		if notif.NotificationType != "permission_request" {
+			t.Errorf("expected notification_type 'permission_request', got %q", notif.NotificationType)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+}
+
+func TestNotificationDispatcherRetriesOnce(t *testing.T) {
+	var attempts int32
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		close(done)
+	}))
+	defer srv.Close()
+
+	d := NewNotificationDispatcher(NotificationSettings{WebhookURL: srv.URL})
+	d.Dispatch(&database.Notification{Message: "idle"})
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not retried")
+	}
+
+	if got := atomic.LoadInt32(&attempts); got != 2 {
+		t.Errorf("expected 2 attempts, got %d", got)
+	}
+}
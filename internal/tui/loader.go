@@ -9,10 +9,19 @@ import (
 	"github.com/schlunsen/claude-control-terminal/internal/fileops"
 )
 
+// DefaultComponentPageSize is the number of components resolved per page
+// when loading incrementally
+const DefaultComponentPageSize = 50
+
 // ComponentLoader handles loading component lists from GitHub
 type ComponentLoader struct {
-	config *fileops.GitHubConfig
-	cache  *Cache
+	config   *fileops.GitHubConfig
+	cache    *Cache
+	pageSize int
+
+	// catalog holds the listed components per type so later pages
+	// don't refetch the tree when the disk cache is unavailable
+	catalog map[string][]ComponentItem
 }
 
 // NewComponentLoader creates a new component loader
@@ -20,9 +29,26 @@ func NewComponentLoader() *ComponentLoader {
 	cache, _ := NewCache()
 
 	return &ComponentLoader{
-		config: fileops.DefaultGitHubConfig(),
-		cache:  cache,
+		config:   fileops.DefaultGitHubConfig(),
+		cache:    cache,
+		pageSize: DefaultComponentPageSize,
+		catalog:  make(map[string][]ComponentItem),
+	}
+}
+
+// WithPageSize sets the page size used by LoadComponentsPage.
+// Non-positive sizes fall back to DefaultComponentPageSize.
+func (cl *ComponentLoader) WithPageSize(size int) *ComponentLoader {
+	if size <= 0 {
+		size = DefaultComponentPageSize
 	}
+	cl.pageSize = size
+	return cl
+}
+
+// PageSize returns the number of components returned per page
+func (cl *ComponentLoader) PageSize() int {
+	return cl.pageSize
 }
 
 // LoadComponents loads all available components of a specific type from GitHub using the Git Tree API
@@ -32,24 +58,78 @@ func (cl *ComponentLoader) LoadComponents(componentType, targetDir string) ([]Co
 
 // LoadComponentsWithCache loads components with optional cache bypass
 func (cl *ComponentLoader) LoadComponentsWithCache(componentType, targetDir string, forceRefresh bool) ([]ComponentItem, error) {
+	components, err := cl.listComponents(componentType, forceRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ComponentItem, len(components))
+	copy(result, components)
+	updateInstallationStatus(result, componentType, targetDir)
+	return result, nil
+}
+
+// LoadComponentsPage loads a single page of components starting at offset,
+// resolving installation status only for that page. The full listing is
+// fetched once and reused for subsequent pages. Returns the page and the
+// total number of components available.
+func (cl *ComponentLoader) LoadComponentsPage(componentType, targetDir string, offset int, forceRefresh bool) ([]ComponentItem, int, error) {
+	components, err := cl.listComponents(componentType, forceRefresh)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total := len(components)
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= total {
+		return []ComponentItem{}, total, nil
+	}
+
+	end := offset + cl.pageSize
+	if end > total {
+		end = total
+	}
+
+	page := make([]ComponentItem, end-offset)
+	copy(page, components[offset:end])
+	updateInstallationStatus(page, componentType, targetDir)
+
+	return page, total, nil
+}
+
+// updateInstallationStatus refreshes the installed flags of each component
+func updateInstallationStatus(components []ComponentItem, componentType, targetDir string) {
+	for i := range components {
+		installedGlobal, installedProject := CheckInstallationStatus(components[i].Name, componentType, targetDir)
+		components[i].InstalledGlobal = installedGlobal
+		components[i].InstalledProject = installedProject
+	}
+}
+
+// listComponents returns the components of a type without installation status,
+// from memory, the disk cache or GitHub (in that order unless forceRefresh is set)
+func (cl *ComponentLoader) listComponents(componentType string, forceRefresh bool) ([]ComponentItem, error) {
 	metadata := GetComponentMetadata()
 	meta, ok := metadata[componentType]
 	if !ok {
 		return nil, fmt.Errorf("unknown component type: %s", componentType)
 	}
 
-	// Try to load from cache first (unless force refresh)
-	if !forceRefresh && cl.cache != nil {
-		components, found, err := cl.cache.Get(componentType)
-		if err == nil && found {
-			// Update installation status for cached components
-			for i := range components {
-				installedGlobal, installedProject := CheckInstallationStatus(components[i].Name, componentType, targetDir)
-				components[i].InstalledGlobal = installedGlobal
-				components[i].InstalledProject = installedProject
-			}
+	if !forceRefresh {
+		if components, ok := cl.catalog[componentType]; ok {
 			return components, nil
 		}
+
+		// Try to load from cache first
+		if cl.cache != nil {
+			components, found, err := cl.cache.Get(componentType)
+			if err == nil && found {
+				cl.catalog[componentType] = components
+				return components, nil
+			}
+		}
 	}
 
 	// Cache miss or force refresh - fetch from GitHub
@@ -115,17 +195,12 @@ func (cl *ComponentLoader) LoadComponentsWithCache(componentType, targetDir stri
 			continue
 		}
 
-		// Check installation status
-		installedGlobal, installedProject := CheckInstallationStatus(name, componentType, targetDir)
-
 		allComponents = append(allComponents, ComponentItem{
-			Name:             name,
-			Category:         category,
-			Description:      fmt.Sprintf("%s from %s", name, category),
-			Type:             componentType,
-			Selected:         false,
-			InstalledGlobal:  installedGlobal,
-			InstalledProject: installedProject,
+			Name:        name,
+			Category:    category,
+			Description: fmt.Sprintf("%s from %s", name, category),
+			Type:        componentType,
+			Selected:    false,
 		})
 	}
 
@@ -133,6 +208,7 @@ func (cl *ComponentLoader) LoadComponentsWithCache(componentType, targetDir stri
 	if cl.cache != nil {
 		_ = cl.cache.Set(componentType, allComponents)
 	}
+	cl.catalog[componentType] = allComponents
 
 	return allComponents, nil
 }
@@ -136,6 +136,50 @@ func TestLoadComponentsWithCacheForceRefresh(t *testing.T) {
 	}
 }
 
+func TestLoadComponentsPage(t *testing.T) {
+	loader := NewComponentLoader().WithPageSize(2)
+
+	// Seed the in-memory catalog so no network request is made
+	loader.catalog["agent"] = []ComponentItem{
+		{Name: "a", Type: "agent"},
+		{Name: "b", Type: "agent"},
+		{Name: "c", Type: "agent"},
+	}
+
+	tempDir := t.TempDir()
+
+	page, total, err := loader.LoadComponentsPage("agent", tempDir, 0, false)
+	if err != nil {
+		t.Fatalf("LoadComponentsPage failed: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("Expected total 3, got %d", total)
+	}
+	if len(page) != 2 || page[0].Name != "a" || page[1].Name != "b" {
+		t.Errorf("Unexpected first page: %+v", page)
+	}
+
+	page, _, err = loader.LoadComponentsPage("agent", tempDir, 2, false)
+	if err != nil {
+		t.Fatalf("LoadComponentsPage failed: %v", err)
+	}
+	if len(page) != 1 || page[0].Name != "c" {
+		t.Errorf("Unexpected last page: %+v", page)
+	}
+
+	page, _, err = loader.LoadComponentsPage("agent", tempDir, 5, false)
+	if err != nil {
+		t.Fatalf("LoadComponentsPage failed: %v", err)
+	}
+	if len(page) != 0 {
+		t.Errorf("Expected empty page past the end, got %d items", len(page))
+	}
+
+	if loader.WithPageSize(0).PageSize() != DefaultComponentPageSize {
+		t.Errorf("Expected non-positive page size to fall back to default")
+	}
+}
+
 func TestComponentMetadataPaths(t *testing.T) {
 	metadata := GetComponentMetadata()
 
@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -9,6 +10,7 @@ import (
 	"runtime"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -55,19 +57,29 @@ type Model struct {
 	cursor          int
 	filteredIndices []int
 	loading         bool
+	loadingMore     bool // More pages are still loading in the background
 	loadError       error
+	loadGeneration  int  // Incremented per load so stale pages are dropped
+	totalComponents int  // Total components reported by the loader
+	pageSize        int  // Components fetched per page
 
 	// Search
-	searchInput  textinput.Model
-	searchActive bool
+	searchInput   textinput.Model
+	searchActive  bool
+	installFilter installFilter // Cycled with "f" on the component list
 
 	// Installation
 	targetDir      string
+	installDirs    []string // Extra targets for installs; defaults to targetDir
 	installing     bool
 	installError   error
 	installSuccess []string
 	installFailed  []string
-	lastOperation  string // "install" or "remove"
+	installResults []dirInstallResult // Per-directory outcome when installing into several dirs
+	lastOperation  string             // "install" or "remove"
+	installCancel  context.CancelFunc // Cancels the running install or removal
+	installGen     int                // Incremented per install/removal so cancelled results are dropped
+	cancelNotice   string             // Shown on the component list after a cancelled operation
 
 	// Preview
 	previewContent  string
@@ -90,6 +102,7 @@ type Model struct {
 	analyticsEnabled bool            // Whether analytics server is running
 	analyticsServer  *server.Server  // Reference to analytics server
 	claudeDir        string          // Claude directory for analytics
+	serverStats      *server.ServerStats // Latest polled server stats (nil until first poll)
 
 	// Hooks state
 	hookLoggerEnabled       bool  // Whether user-prompt-logger hook is installed
@@ -134,6 +147,11 @@ func NewModel(targetDir string) Model {
 
 // NewModelWithServer creates a new TUI model with analytics server reference
 func NewModelWithServer(targetDir, claudeDir string, analyticsServer *server.Server) Model {
+	// Restore the theme selected in a previous run
+	prefs, _ := LoadPreferences()
+	currentTheme := initialThemeIndex(prefs)
+	ApplyThemeByIndex(currentTheme)
+
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = SpinnerStyle
@@ -207,10 +225,11 @@ func NewModelWithServer(targetDir, claudeDir string, analyticsServer *server.Ser
 		selectedType:              0,
 		targetDir:                 targetDir,
 		spinner:                   s,
+		pageSize:                  DefaultComponentPageSize,
 		searchInput:               ti,
 		width:                     80,
 		height:                    24,
-		currentTheme:              GetCurrentThemeIndex(),
+		currentTheme:              currentTheme,
 		analyticsEnabled:          analyticsEnabled,
 		analyticsServer:           analyticsServer,
 		claudeDir:                 claudeDir,
@@ -233,6 +252,7 @@ func (m Model) Init() tea.Cmd {
 	return tea.Batch(
 		m.spinner.Tick,
 		watchForShutdownSignals(),
+		pollServerStatsCmd(),
 	)
 }
 
@@ -253,21 +273,43 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 
 	case componentsLoadedMsg:
-		m.loading = false
-		m.components = msg.components
+		// Ignore pages from a load that has since been restarted
+		if msg.generation != m.loadGeneration {
+			return m, nil
+		}
+		if msg.offset == 0 {
+			m.loading = false
+			m.components = msg.components
+		} else {
+			m.components = append(m.components, msg.components...)
+		}
 		m.loadError = msg.err
+		m.totalComponents = msg.total
+		m.loadingMore = msg.err == nil && msg.next != nil
 		m.updateFilteredIndices()
+		if m.loadingMore {
+			return m, msg.next
+		}
 		return m, nil
 
 	case installCompleteMsg:
+		if msg.generation != m.installGen {
+			return m, nil
+		}
+		m.finishInstall()
 		m.installing = false
 		m.installSuccess = msg.success
 		m.installFailed = msg.failed
+		m.installResults = msg.dirs
 		m.installError = msg.err
 		m.screen = ScreenComplete
 		return m, nil
 
 	case removeCompleteMsg:
+		if msg.generation != m.installGen {
+			return m, nil
+		}
+		m.finishInstall()
 		m.installing = false
 		m.installSuccess = msg.success
 		m.installFailed = msg.failed
@@ -285,7 +327,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Handle immediate analytics server toggle
 		if msg.enabled && m.analyticsServer == nil {
 			// Start analytics server with quiet mode (verbose=false for TUI)
-			m.analyticsServer = server.NewServerWithOptions(msg.targetDir, 3333, true, false)
+			m.analyticsServer = server.NewServerWithOptions(msg.targetDir, "", 3333, true, false)
 			if err := m.analyticsServer.Setup(); err == nil {
 				go func() {
 					if err := m.analyticsServer.Start(); err != nil {
@@ -339,6 +381,23 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case providerSavedMsg:
 		return m.handleProviderSavedMsg(msg)
 
+	case serverStatsTickMsg:
+		// Poll the in-process server; stats stay nil while it isn't running
+		if m.analyticsServer == nil {
+			m.serverStats = nil
+			return m, pollServerStatsCmd()
+		}
+		return m, fetchServerStatsCmd(m.analyticsServer)
+
+	case serverStatsMsg:
+		// Drop results that arrive after the server was stopped
+		m.serverStats = nil
+		if m.analyticsServer != nil {
+			stats := msg.stats
+			m.serverStats = &stats
+		}
+		return m, pollServerStatsCmd()
+
 	case toggleHookMsg:
 		// Handle hook toggle result for all three hooks
 		m.hookLoggerEnabled = msg.userPromptEnabled
@@ -381,6 +440,8 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleConfirmScreen(msg)
 	case ScreenConfirmRemove:
 		return m.handleConfirmRemoveScreen(msg)
+	case ScreenInstalling, ScreenRemoving:
+		return m.handleInstallingScreen(msg)
 	case ScreenComplete:
 		return m.handleCompleteScreen(msg)
 	case ScreenPermissions:
@@ -452,22 +513,20 @@ func (m Model) handleMainScreen(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 		// Load components for selected type
 		m.screen = ScreenComponentList
-		m.loading = true
 		m.cursor = 0
-		m.components = nil
 		// Clear search state when entering component list
 		m.searchInput.SetValue("")
 		m.searchActive = false
 		m.searchInput.Blur()
-		return m, loadComponentsCmd(m.getComponentType(), m.targetDir)
+		return m, m.startLoadingComponents(false)
 	case "esc":
 		m.quitting = true
 		return m, tea.Quit
 	case "t", "T":
 		// Cycle through themes
-		m.currentTheme = (m.currentTheme + 1) % 4
+		m.currentTheme = (m.currentTheme + 1) % themeCount
 		ApplyThemeByIndex(m.currentTheme)
-		return m, nil
+		return m, saveThemeCmd(m.currentTheme)
 	case "a", "A":
 		// Toggle analytics on/off
 		m.analyticsEnabled = !m.analyticsEnabled
@@ -499,6 +558,7 @@ func (m Model) handleComponentListScreen(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	if m.loading {
 		return m, nil
 	}
+	m.cancelNotice = ""
 
 	if m.searchActive {
 		switch msg.String() {
@@ -616,11 +676,14 @@ func (m Model) handleComponentListScreen(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				return m, nil
 			}
 		}
+	case "f":
+		// Cycle installed filter: all -> installed -> not installed
+		m.installFilter = m.installFilter.next()
+		m.cursor = 0
+		m.updateFilteredIndices()
 	case "r":
 		// Refresh components from GitHub
-		m.loading = true
-		m.components = nil
-		return m, loadComponentsCmd(m.getComponentType(), m.targetDir, true)
+		return m, m.startLoadingComponents(true)
 	case "esc":
 		// Clear search input and go back to main screen
 		m.searchInput.SetValue("")
@@ -691,7 +754,8 @@ func (m Model) handleConfirmScreen(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.screen = ScreenInstalling
 		m.installing = true
 		m.lastOperation = "install"
-		return m, installComponentsCmd(m.getSelectedComponents(), m.targetDir)
+		ctx := m.startInstall()
+		return m, installComponentsCmd(ctx, m.installGen, m.getSelectedComponents(), m.installTargets())
 	case "n", "esc":
 		// Go back to component list and clear selections
 		for i := range m.components {
@@ -703,6 +767,43 @@ func (m Model) handleConfirmScreen(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// startInstall creates the context for a new install or removal
+func (m *Model) startInstall() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.installCancel = cancel
+	m.installGen++
+	m.cancelNotice = ""
+	return ctx
+}
+
+// finishInstall releases the context of the running install or removal
+func (m *Model) finishInstall() {
+	if m.installCancel != nil {
+		m.installCancel()
+		m.installCancel = nil
+	}
+}
+
+// handleInstallingScreen handles input while components are installed or removed
+func (m Model) handleInstallingScreen(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		// Cancel the operation; its result is dropped when it arrives
+		m.finishInstall()
+		m.installGen++
+		m.installing = false
+		if m.lastOperation == "remove" {
+			m.cancelNotice = "Removal cancelled"
+		} else {
+			m.cancelNotice = "Installation cancelled"
+		}
+		m.screen = ScreenComponentList
+		// Reload to pick up components installed before cancelling
+		return m, m.startLoadingComponents(false)
+	}
+	return m, nil
+}
+
 // handleConfirmRemoveScreen handles input on the removal confirmation screen
 func (m Model) handleConfirmRemoveScreen(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
@@ -711,7 +812,8 @@ func (m Model) handleConfirmRemoveScreen(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.screen = ScreenRemoving
 		m.installing = true
 		m.lastOperation = "remove"
-		return m, removeComponentsCmd(m.getSelectedComponents(), m.targetDir)
+		ctx := m.startInstall()
+		return m, removeComponentsCmd(ctx, m.installGen, m.getSelectedComponents(), m.targetDir)
 	case "n", "esc":
 		// Go back to component list and clear selections
 		for i := range m.components {
@@ -765,6 +867,7 @@ func (m Model) handleCompleteScreen(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.components = nil
 		m.installSuccess = nil
 		m.installFailed = nil
+		m.installResults = nil
 		m.installError = nil
 		// Clear selections
 		for i := range m.components {
@@ -776,13 +879,9 @@ func (m Model) handleCompleteScreen(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.screen = ScreenComponentList
 		m.installSuccess = nil
 		m.installFailed = nil
+		m.installResults = nil
 		m.installError = nil
-		m.loading = true
-		// Clear selections
-		for i := range m.components {
-			m.components[i].Selected = false
-		}
-		return m, loadComponentsCmd(m.getComponentType(), m.targetDir)
+		return m, m.startLoadingComponents(false)
 	}
 	return m, nil
 }
@@ -932,6 +1031,18 @@ func (m Model) viewMainScreen() string {
 	b.WriteString(SubtitleStyle.Render("Server: ") + serverStyle.Render(serverStatus) + SubtitleStyle.Render(serverDesc))
 	b.WriteString(SubtitleStyle.Render(" (https://localhost:3333)") + "\n")
 
+	// Live server stats (only when the server is running and has been polled)
+	if m.analyticsEnabled && m.serverStats != nil {
+		healthStatus := "healthy"
+		healthStyle := StatusSuccessStyle
+		if !m.serverStats.Healthy {
+			healthStatus = "unhealthy"
+			healthStyle = StatusWarningStyle
+		}
+		b.WriteString(SubtitleStyle.Render(fmt.Sprintf("Active sessions: %d • Conversations: %d • ",
+			m.serverStats.ActiveSessions, m.serverStats.TotalConversations)) + healthStyle.Render(healthStatus) + "\n")
+	}
+
 	b.WriteString("\n")
 	if m.analyticsEnabled {
 		b.WriteString(HelpStyle.Render("↑/↓: Navigate • Enter: Select • T: Theme • A: Analytics • O: Open Dashboard • H: Hooks • U: User Auth • Q/Esc: Quit"))
@@ -983,6 +1094,15 @@ func (m Model) viewComponentListScreen() string {
 		b.WriteString(searchHint + "\n\n")
 	}
 
+	if m.cancelNotice != "" {
+		b.WriteString(StatusErrorStyle.Render(m.cancelNotice) + "\n\n")
+	}
+
+	if m.installFilter != installFilterAll {
+		b.WriteString(StatusInfoStyle.Render("Showing: "+m.installFilter.String()) + " " +
+			HelpStyle.Render("(f to change)") + "\n\n")
+	}
+
 	// Component list
 	if len(m.filteredIndices) == 0 {
 		b.WriteString(StatusInfoStyle.Render("No components found") + "\n")
@@ -1064,6 +1184,11 @@ func (m Model) viewComponentListScreen() string {
 		}
 	}
 
+	if m.loadingMore {
+		b.WriteString(m.spinner.View() + " " + StatusInfoStyle.Render(
+			fmt.Sprintf("Loading more... (%d/%d)", len(m.components), m.totalComponents)) + "\n")
+	}
+
 	b.WriteString("\n")
 
 	// Show selected count if any
@@ -1075,12 +1200,12 @@ func (m Model) viewComponentListScreen() string {
 	// Help - keep compact for small terminals
 	if m.height < 20 {
 		// Compact help for small terminals
-		b.WriteString(HelpStyle.Render("Space: Select • Enter: Action • P: Preview • D: Remove • Esc: Back\n"))
+		b.WriteString(HelpStyle.Render("Space: Select • Enter: Action • P: Preview • D: Remove • F: Filter • Esc: Back\n"))
 		b.WriteString(StatusSuccessStyle.Render("[P]=Project  "))
 		b.WriteString(StatusWarningStyle.Render("[G]=Global"))
 	} else {
 		// Full help for larger terminals
-		b.WriteString(HelpStyle.Render("↑/↓: Navigate • PgUp/PgDn: Page • /: Search • Space: Toggle • P: Preview • R: Refresh\n"))
+		b.WriteString(HelpStyle.Render("↑/↓: Navigate • PgUp/PgDn: Page • /: Search • F: Filter • Space: Toggle • P: Preview • R: Refresh\n"))
 		b.WriteString(HelpStyle.Render("Enter: Action • D: Remove (if installed) • Esc: Back • "))
 		b.WriteString(StatusSuccessStyle.Render("[P]=Project  "))
 		b.WriteString(StatusWarningStyle.Render("[G]=Global"))
@@ -1265,7 +1390,7 @@ func (m Model) viewConfirmScreen() string {
 	}
 
 	b.WriteString("\n")
-	b.WriteString(fmt.Sprintf("Target: %s\n\n", SubtitleStyle.Render(m.targetDir)))
+	b.WriteString(fmt.Sprintf("Target: %s\n\n", SubtitleStyle.Render(strings.Join(m.installTargets(), ", "))))
 
 	b.WriteString(HelpStyle.Render("Y/Enter: Install • N/Esc: Cancel"))
 
@@ -1286,7 +1411,8 @@ func (m Model) viewInstallingScreen() string {
 	var b strings.Builder
 
 	b.WriteString(TitleStyle.Render("Installing Components") + "\n\n")
-	b.WriteString(m.spinner.View() + " Installing components, please wait...\n")
+	b.WriteString(m.spinner.View() + " Installing components, please wait...\n\n")
+	b.WriteString(HelpStyle.Render("Esc: Cancel"))
 
 	content := BoxStyle.Render(b.String())
 
@@ -1382,7 +1508,8 @@ func (m Model) viewRemovingScreen() string {
 	var b strings.Builder
 
 	b.WriteString(TitleStyle.Render("Removing Components") + "\n\n")
-	b.WriteString(m.spinner.View() + " Removing components, please wait...\n")
+	b.WriteString(m.spinner.View() + " Removing components, please wait...\n\n")
+	b.WriteString(HelpStyle.Render("Esc: Cancel"))
 
 	content := BoxStyle.Render(b.String())
 
@@ -1428,6 +1555,15 @@ func (m Model) viewCompleteScreen() string {
 		b.WriteString(StatusSuccessStyle.Render(titleText) + "\n\n")
 	}
 
+	// Per-directory summary
+	if len(m.installResults) > 1 {
+		b.WriteString(SubtitleStyle.Render("Target directories:") + "\n")
+		for _, result := range m.installResults {
+			b.WriteString(fmt.Sprintf("  %s: %d %s, %d failed\n", result.dir, len(result.success), operationVerb, len(result.failed)))
+		}
+		b.WriteString("\n")
+	}
+
 	// Success list
 	if len(m.installSuccess) > 0 {
 		successText := fmt.Sprintf("Successfully %s %d component(s):", operationVerb, len(m.installSuccess))
@@ -1514,11 +1650,62 @@ func (m Model) getSelectedComponents() []ComponentItem {
 	return selected
 }
 
+// installTargets returns the directories components are installed into
+func (m Model) installTargets() []string {
+	if len(m.installDirs) > 0 {
+		return m.installDirs
+	}
+	return []string{m.targetDir}
+}
+
+// installFilter restricts the component list by installation status
+type installFilter int
+
+const (
+	installFilterAll installFilter = iota
+	installFilterInstalled
+	installFilterNotInstalled
+)
+
+// next returns the filter that follows f in the all -> installed -> not installed cycle
+func (f installFilter) next() installFilter {
+	return (f + 1) % 3
+}
+
+// String returns a human-readable label for the filter
+func (f installFilter) String() string {
+	switch f {
+	case installFilterInstalled:
+		return "installed only"
+	case installFilterNotInstalled:
+		return "not installed"
+	default:
+		return "all"
+	}
+}
+
+// matches reports whether the component passes the filter. A component counts
+// as installed when it is installed globally or in the project.
+func (f installFilter) matches(comp ComponentItem) bool {
+	installed := comp.InstalledGlobal || comp.InstalledProject
+	switch f {
+	case installFilterInstalled:
+		return installed
+	case installFilterNotInstalled:
+		return !installed
+	default:
+		return true
+	}
+}
+
 func (m *Model) updateFilteredIndices() {
 	searchTerm := strings.ToLower(m.searchInput.Value())
 	m.filteredIndices = nil
 
 	for i, comp := range m.components {
+		if !m.installFilter.matches(comp) {
+			continue
+		}
 		if searchTerm == "" ||
 			strings.Contains(strings.ToLower(comp.Name), searchTerm) ||
 			strings.Contains(strings.ToLower(comp.Category), searchTerm) {
@@ -1535,23 +1722,51 @@ func (m *Model) updateFilteredIndices() {
 	}
 }
 
+// startLoadingComponents resets the component list and starts loading the
+// selected type page by page, invalidating any load still in progress
+func (m *Model) startLoadingComponents(forceRefresh bool) tea.Cmd {
+	m.loadGeneration++
+	m.loading = true
+	m.loadingMore = false
+	m.loadError = nil
+	m.components = nil
+	m.totalComponents = 0
+	return loadComponentsCmd(m.getComponentType(), m.targetDir, m.loadGeneration, m.pageSize, forceRefresh)
+}
+
 // Messages
 
+// componentsLoadedMsg carries one page of components. The first page has
+// offset 0 and replaces the list; later pages are appended.
 type componentsLoadedMsg struct {
 	components []ComponentItem
 	err        error
+	generation int
+	offset     int
+	total      int
+	next       tea.Cmd // Loads the following page, nil on the last page
 }
 
 type installCompleteMsg struct {
+	success    []string
+	failed     []string
+	dirs       []dirInstallResult // One entry per target directory
+	err        error
+	generation int
+}
+
+// dirInstallResult aggregates the outcome of an install into one directory
+type dirInstallResult struct {
+	dir     string
 	success []string
 	failed  []string
-	err     error
 }
 
 type removeCompleteMsg struct {
-	success []string
-	failed  []string
-	err     error
+	success    []string
+	failed     []string
+	err        error
+	generation int
 }
 
 type previewLoadedMsg struct {
@@ -1561,63 +1776,108 @@ type previewLoadedMsg struct {
 
 // Commands
 
-func loadComponentsCmd(componentType, targetDir string, forceRefresh ...bool) tea.Cmd {
-	return func() tea.Msg {
-		loader := NewComponentLoader()
+func loadComponentsCmd(componentType, targetDir string, generation, pageSize int, forceRefresh ...bool) tea.Cmd {
+	loader := NewComponentLoader().WithPageSize(pageSize)
 
-		refresh := false
-		if len(forceRefresh) > 0 {
-			refresh = forceRefresh[0]
-		}
+	refresh := false
+	if len(forceRefresh) > 0 {
+		refresh = forceRefresh[0]
+	}
+
+	return loadComponentsPageCmd(loader, componentType, targetDir, generation, 0, refresh)
+}
+
+// loadComponentsPageCmd loads the page at offset and chains a command for the
+// next page so the list renders as soon as the first page arrives
+func loadComponentsPageCmd(loader *ComponentLoader, componentType, targetDir string, generation, offset int, forceRefresh bool) tea.Cmd {
+	return func() tea.Msg {
+		components, total, err := loader.LoadComponentsPage(componentType, targetDir, offset, forceRefresh)
 
-		components, err := loader.LoadComponentsWithCache(componentType, targetDir, refresh)
-		return componentsLoadedMsg{
+		msg := componentsLoadedMsg{
 			components: components,
 			err:        err,
+			generation: generation,
+			offset:     offset,
+			total:      total,
+		}
+
+		nextOffset := offset + len(components)
+		if err == nil && len(components) > 0 && nextOffset < total {
+			msg.next = loadComponentsPageCmd(loader, componentType, targetDir, generation, nextOffset, false)
 		}
+
+		return msg
 	}
 }
 
-func installComponentsCmd(components []ComponentItem, targetDir string) tea.Cmd {
+func installComponentsCmd(ctx context.Context, generation int, components []ComponentItem, targetDirs []string) tea.Cmd {
 	return func() tea.Msg {
-		var success []string
-		var failed []string
+		msg := installIntoDirs(ctx, components, targetDirs, installComponent)
+		msg.generation = generation
+		return msg
+	}
+}
+
+// installComponent installs a single component into targetDir
+func installComponent(comp ComponentItem, targetDir string) error {
+	switch comp.Type {
+	case "agent":
+		return NewAgentInstallerForTUI().InstallAgent(comp.Name, comp.Category, targetDir)
+	case "command":
+		return NewCommandInstallerForTUI().InstallCommand(comp.Name, comp.Category, targetDir)
+	case "mcp":
+		return NewMCPInstallerForTUI().InstallMCP(comp.Name, comp.Category, targetDir)
+	}
+	return nil
+}
+
+// installIntoDirs installs every component into each target directory and
+// aggregates the results per directory. With more than one directory the
+// success and failure lists name the directory of each entry. Stops before
+// the next component once ctx is cancelled.
+func installIntoDirs(ctx context.Context, components []ComponentItem, targetDirs []string, install func(ComponentItem, string) error) installCompleteMsg {
+	var msg installCompleteMsg
+	multiple := len(targetDirs) > 1
+
+	for _, dir := range targetDirs {
+		result := dirInstallResult{dir: dir}
 
 		for _, comp := range components {
-			var err error
-			switch comp.Type {
-			case "agent":
-				installer := NewAgentInstallerForTUI()
-				err = installer.InstallAgent(comp.Name, comp.Category, targetDir)
-			case "command":
-				installer := NewCommandInstallerForTUI()
-				err = installer.InstallCommand(comp.Name, comp.Category, targetDir)
-			case "mcp":
-				installer := NewMCPInstallerForTUI()
-				err = installer.InstallMCP(comp.Name, comp.Category, targetDir)
+			if ctx.Err() != nil {
+				msg.dirs = append(msg.dirs, result)
+				return msg
 			}
 
-			if err != nil {
-				failed = append(failed, comp.Name)
+			label := comp.Name
+			if multiple {
+				label = fmt.Sprintf("%s (%s)", comp.Name, dir)
+			}
+
+			if err := install(comp, dir); err != nil {
+				result.failed = append(result.failed, comp.Name)
+				msg.failed = append(msg.failed, label)
 			} else {
-				success = append(success, comp.Name)
+				result.success = append(result.success, comp.Name)
+				msg.success = append(msg.success, label)
 			}
 		}
 
-		return installCompleteMsg{
-			success: success,
-			failed:  failed,
-			err:     nil,
-		}
+		msg.dirs = append(msg.dirs, result)
 	}
+
+	return msg
 }
 
-func removeComponentsCmd(components []ComponentItem, targetDir string) tea.Cmd {
+func removeComponentsCmd(ctx context.Context, generation int, components []ComponentItem, targetDir string) tea.Cmd {
 	return func() tea.Msg {
 		var success []string
 		var failed []string
 
 		for _, comp := range components {
+			if ctx.Err() != nil {
+				return removeCompleteMsg{success: success, failed: failed, generation: generation}
+			}
+
 			var err error
 			switch comp.Type {
 			case "agent":
@@ -1639,9 +1899,10 @@ func removeComponentsCmd(components []ComponentItem, targetDir string) tea.Cmd {
 		}
 
 		return removeCompleteMsg{
-			success: success,
-			failed:  failed,
-			err:     nil,
+			success:    success,
+			failed:     failed,
+			err:        nil,
+			generation: generation,
 		}
 	}
 }
@@ -1684,6 +1945,37 @@ func toggleAnalyticsCmd(enabled bool, targetDir string) tea.Cmd {
 	}
 }
 
+// saveThemeCmd persists the selected theme in the background. Failing to save
+// only means the theme isn't restored next launch, so errors are ignored.
+func saveThemeCmd(index int) tea.Cmd {
+	return func() tea.Msg {
+		_ = saveThemeIndex(index)
+		return nil
+	}
+}
+
+// serverStatsInterval is how often the main screen polls the analytics server
+const serverStatsInterval = 2 * time.Second
+
+type serverStatsTickMsg struct{}
+
+func pollServerStatsCmd() tea.Cmd {
+	return tea.Tick(serverStatsInterval, func(time.Time) tea.Msg {
+		return serverStatsTickMsg{}
+	})
+}
+
+type serverStatsMsg struct {
+	stats server.ServerStats
+}
+
+// fetchServerStatsCmd reads the server's stats off the UI goroutine
+func fetchServerStatsCmd(srv *server.Server) tea.Cmd {
+	return func() tea.Msg {
+		return serverStatsMsg{stats: srv.GetStats()}
+	}
+}
+
 type toggleHookMsg struct {
 	userPromptEnabled   bool
 	toolEnabled         bool
@@ -1,9 +1,13 @@
 package tui
 
 import (
+	"context"
+	"errors"
+	"path/filepath"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/schlunsen/claude-control-terminal/internal/server"
 )
 
 func TestNewModel(t *testing.T) {
@@ -83,6 +87,54 @@ func TestUpdateFilteredIndices(t *testing.T) {
 	}
 }
 
+func TestInstallFilter(t *testing.T) {
+	m := NewModel(".")
+	m.screen = ScreenComponentList
+	m.components = []ComponentItem{
+		{Name: "agent-global", InstalledGlobal: true},
+		{Name: "agent-project", InstalledProject: true},
+		{Name: "agent-none"},
+	}
+	m.updateFilteredIndices()
+
+	press := func() {
+		updated, _ := m.handleComponentListScreen(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+		m = updated.(Model)
+	}
+
+	press()
+	if m.installFilter != installFilterInstalled {
+		t.Fatalf("Expected installed filter, got %s", m.installFilter)
+	}
+	if len(m.filteredIndices) != 2 {
+		t.Errorf("Expected 2 installed components, got %d", len(m.filteredIndices))
+	}
+
+	press()
+	if m.installFilter != installFilterNotInstalled {
+		t.Fatalf("Expected not-installed filter, got %s", m.installFilter)
+	}
+	if len(m.filteredIndices) != 1 || m.components[m.filteredIndices[0]].Name != "agent-none" {
+		t.Errorf("Expected only agent-none, got %v", m.filteredIndices)
+	}
+
+	// Search applies on top of the install filter
+	m.searchInput.SetValue("global")
+	m.updateFilteredIndices()
+	if len(m.filteredIndices) != 0 {
+		t.Errorf("Expected 0 components, got %d", len(m.filteredIndices))
+	}
+	m.searchInput.SetValue("")
+
+	press()
+	if m.installFilter != installFilterAll {
+		t.Fatalf("Expected filter to cycle back to all, got %s", m.installFilter)
+	}
+	if len(m.filteredIndices) != 3 {
+		t.Errorf("Expected 3 components, got %d", len(m.filteredIndices))
+	}
+}
+
 func TestGetSelectedComponents(t *testing.T) {
 	m := NewModel(".")
 
@@ -229,6 +281,66 @@ func TestComponentsLoadedMsg(t *testing.T) {
 	}
 }
 
+func TestComponentsLoadedMsgPaged(t *testing.T) {
+	m := NewModel(".")
+	m.loading = true
+	m.loadGeneration = 1
+
+	next := func() tea.Msg { return nil }
+	first := componentsLoadedMsg{
+		components: []ComponentItem{{Name: "a"}, {Name: "b"}},
+		generation: 1,
+		offset:     0,
+		total:      3,
+		next:       next,
+	}
+
+	updatedModel, cmd := m.Update(first)
+	m = updatedModel.(Model)
+
+	if m.loading {
+		t.Error("Expected loading to be false after the first page")
+	}
+	if !m.loadingMore {
+		t.Error("Expected loadingMore to be true while pages remain")
+	}
+	if cmd == nil {
+		t.Error("Expected a command to load the next page")
+	}
+
+	// Pages from a previous load are ignored
+	stale := componentsLoadedMsg{
+		components: []ComponentItem{{Name: "stale"}},
+		generation: 0,
+		offset:     2,
+		total:      3,
+	}
+	updatedModel, _ = m.Update(stale)
+	m = updatedModel.(Model)
+	if len(m.components) != 2 {
+		t.Errorf("Expected stale page to be dropped, got %d components", len(m.components))
+	}
+
+	last := componentsLoadedMsg{
+		components: []ComponentItem{{Name: "c"}},
+		generation: 1,
+		offset:     2,
+		total:      3,
+	}
+	updatedModel, cmd = m.Update(last)
+	m = updatedModel.(Model)
+
+	if len(m.components) != 3 || m.components[2].Name != "c" {
+		t.Errorf("Expected last page to be appended, got %+v", m.components)
+	}
+	if m.loadingMore {
+		t.Error("Expected loadingMore to be false after the last page")
+	}
+	if cmd != nil {
+		t.Error("Expected no further command after the last page")
+	}
+}
+
 func TestInstallCompleteMsg(t *testing.T) {
 	m := NewModel(".")
 	m.installing = true
@@ -405,3 +517,181 @@ func TestRemoveCompleteMsg(t *testing.T) {
 		t.Errorf("Expected screen to be ScreenComplete, got %v", m.screen)
 	}
 }
+
+func TestServerStatsTickWithoutServer(t *testing.T) {
+	m := NewModel(".")
+	m.analyticsServer = nil
+
+	updatedModel, cmd := m.Update(serverStatsTickMsg{})
+	m = updatedModel.(Model)
+
+	if m.serverStats != nil {
+		t.Error("Expected serverStats to be nil when server is not running")
+	}
+
+	if cmd == nil {
+		t.Error("Expected polling to be rescheduled")
+	}
+}
+
+func TestServerStatsFetchedAsynchronously(t *testing.T) {
+	m := NewModel(".")
+	m.analyticsServer = &server.Server{}
+
+	// The tick only schedules a fetch; Update must not read stats itself
+	updatedModel, cmd := m.Update(serverStatsTickMsg{})
+	m = updatedModel.(Model)
+	if cmd == nil {
+		t.Fatal("Expected a command to fetch server stats")
+	}
+	if m.serverStats != nil {
+		t.Error("Expected serverStats to stay unset until the fetch completes")
+	}
+
+	updatedModel, cmd = m.Update(serverStatsMsg{stats: server.ServerStats{Healthy: true, ActiveSessions: 3}})
+	m = updatedModel.(Model)
+	if m.serverStats == nil || m.serverStats.ActiveSessions != 3 {
+		t.Errorf("Expected fetched stats to be stored, got %+v", m.serverStats)
+	}
+	if cmd == nil {
+		t.Error("Expected polling to be rescheduled")
+	}
+}
+
+func TestPreferencesRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".claude", preferencesFileName)
+
+	prefs, err := loadPreferencesFrom(path)
+	if err != nil {
+		t.Fatalf("loadPreferencesFrom failed: %v", err)
+	}
+	if prefs.ThemeIndex != nil {
+		t.Errorf("Expected no saved theme, got %d", *prefs.ThemeIndex)
+	}
+
+	index := 2
+	if err := savePreferencesTo(path, &Preferences{ThemeIndex: &index}); err != nil {
+		t.Fatalf("savePreferencesTo failed: %v", err)
+	}
+
+	prefs, err = loadPreferencesFrom(path)
+	if err != nil {
+		t.Fatalf("loadPreferencesFrom failed: %v", err)
+	}
+	if prefs.ThemeIndex == nil || *prefs.ThemeIndex != 2 {
+		t.Errorf("Expected saved theme 2, got %v", prefs.ThemeIndex)
+	}
+}
+
+func TestInitialThemeIndex(t *testing.T) {
+	t.Setenv("CCT_THEME", "")
+
+	saved := 2
+	if got := initialThemeIndex(&Preferences{ThemeIndex: &saved}); got != 2 {
+		t.Errorf("Expected saved theme 2, got %d", got)
+	}
+
+	invalid := 9
+	if got := initialThemeIndex(&Preferences{ThemeIndex: &invalid}); got != 0 {
+		t.Errorf("Expected default theme for out-of-range index, got %d", got)
+	}
+
+	if got := initialThemeIndex(nil); got != 0 {
+		t.Errorf("Expected default theme without preferences, got %d", got)
+	}
+
+	// CCT_THEME overrides the saved theme
+	t.Setenv("CCT_THEME", "purple")
+	if got := initialThemeIndex(&Preferences{ThemeIndex: &saved}); got != 3 {
+		t.Errorf("Expected CCT_THEME purple (3), got %d", got)
+	}
+}
+
+func TestInstallIntoDirsAggregatesPerDirectory(t *testing.T) {
+	components := []ComponentItem{
+		{Name: "reviewer", Type: "agent"},
+		{Name: "planner", Type: "agent"},
+	}
+	dirs := []string{"/repo/api", "/repo/web"}
+
+	msg := installIntoDirs(context.Background(), components, dirs, func(comp ComponentItem, dir string) error {
+		if comp.Name == "planner" && dir == "/repo/web" {
+			return errors.New("write failed")
+		}
+		return nil
+	})
+
+	if len(msg.dirs) != 2 {
+		t.Fatalf("expected results for 2 directories, got %d", len(msg.dirs))
+	}
+	if len(msg.dirs[0].success) != 2 || len(msg.dirs[0].failed) != 0 {
+		t.Errorf("expected /repo/api to succeed fully, got %+v", msg.dirs[0])
+	}
+	if len(msg.dirs[1].failed) != 1 || msg.dirs[1].failed[0] != "planner" {
+		t.Errorf("expected planner to fail in /repo/web, got %+v", msg.dirs[1])
+	}
+	if len(msg.failed) != 1 || msg.failed[0] != "planner (/repo/web)" {
+		t.Errorf("expected failure to name its directory, got %v", msg.failed)
+	}
+	if len(msg.success) != 3 {
+		t.Errorf("expected 3 successful installs, got %v", msg.success)
+	}
+}
+
+func TestInstallIntoSingleDirKeepsPlainNames(t *testing.T) {
+	components := []ComponentItem{{Name: "reviewer", Type: "agent"}}
+
+	msg := installIntoDirs(context.Background(), components, []string{"."}, func(ComponentItem, string) error { return nil })
+
+	if len(msg.success) != 1 || msg.success[0] != "reviewer" {
+		t.Errorf("expected plain component name, got %v", msg.success)
+	}
+}
+
+func TestInstallIntoDirsStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	components := []ComponentItem{{Name: "first"}, {Name: "second"}, {Name: "third"}}
+
+	attempts := 0
+	msg := installIntoDirs(ctx, components, []string{"."}, func(ComponentItem, string) error {
+		attempts++
+		cancel()
+		return nil
+	})
+
+	if attempts != 1 {
+		t.Errorf("expected install to stop after cancellation, got %d attempts", attempts)
+	}
+	if len(msg.success) != 1 || msg.success[0] != "first" {
+		t.Errorf("expected only the first component to be installed, got %v", msg.success)
+	}
+}
+
+func TestCancelInstallReturnsToList(t *testing.T) {
+	m := NewModel(t.TempDir())
+	m.lastOperation = "install"
+	m.screen = ScreenInstalling
+	m.installing = true
+	ctx := m.startInstall()
+	gen := m.installGen
+
+	updated, _ := m.handleKeyPress(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updated.(Model)
+
+	if ctx.Err() == nil {
+		t.Error("expected install context to be cancelled")
+	}
+	if m.screen != ScreenComponentList || m.installing {
+		t.Errorf("expected to return to the component list, got screen %v installing=%v", m.screen, m.installing)
+	}
+	if m.cancelNotice != "Installation cancelled" {
+		t.Errorf("expected cancelled notice, got %q", m.cancelNotice)
+	}
+
+	// A result arriving after cancellation is dropped
+	updated, _ = m.Update(installCompleteMsg{success: []string{"late"}, generation: gen})
+	m = updated.(Model)
+	if m.screen != ScreenComponentList || len(m.installSuccess) != 0 {
+		t.Errorf("expected late install result to be ignored, got screen %v success %v", m.screen, m.installSuccess)
+	}
+}
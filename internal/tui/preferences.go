@@ -0,0 +1,101 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// preferencesFileName is the TUI preferences file inside ~/.claude
+const preferencesFileName = "cct-tui.json"
+
+// Preferences holds TUI settings persisted across runs
+type Preferences struct {
+	ThemeIndex *int `json:"theme_index,omitempty"`
+}
+
+// preferencesPath returns the path to ~/.claude/cct-tui.json
+func preferencesPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".claude", preferencesFileName), nil
+}
+
+// LoadPreferences reads the TUI preferences file. A missing file yields empty preferences.
+func LoadPreferences() (*Preferences, error) {
+	path, err := preferencesPath()
+	if err != nil {
+		return nil, err
+	}
+	return loadPreferencesFrom(path)
+}
+
+// SavePreferences writes the TUI preferences file
+func SavePreferences(prefs *Preferences) error {
+	path, err := preferencesPath()
+	if err != nil {
+		return err
+	}
+	return savePreferencesTo(path, prefs)
+}
+
+func loadPreferencesFrom(path string) (*Preferences, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Preferences{}, nil
+		}
+		return nil, fmt.Errorf("failed to read preferences: %w", err)
+	}
+
+	var prefs Preferences
+	if err := json.Unmarshal(data, &prefs); err != nil {
+		return nil, fmt.Errorf("failed to parse preferences: %w", err)
+	}
+
+	return &prefs, nil
+}
+
+func savePreferencesTo(path string, prefs *Preferences) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create preferences directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(prefs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal preferences: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write preferences: %w", err)
+	}
+
+	return nil
+}
+
+// initialThemeIndex returns the theme to start with. CCT_THEME takes precedence;
+// otherwise the last theme selected with the T key is restored.
+func initialThemeIndex(prefs *Preferences) int {
+	if os.Getenv("CCT_THEME") != "" || prefs == nil || prefs.ThemeIndex == nil {
+		return GetCurrentThemeIndex()
+	}
+
+	index := *prefs.ThemeIndex
+	if index < 0 || index >= themeCount {
+		return GetCurrentThemeIndex()
+	}
+	return index
+}
+
+// saveThemeIndex persists the selected theme, keeping any other preferences
+func saveThemeIndex(index int) error {
+	prefs, err := LoadPreferences()
+	if err != nil {
+		prefs = &Preferences{}
+	}
+	prefs.ThemeIndex = &index
+	return SavePreferences(prefs)
+}
@@ -391,6 +391,9 @@ func GetBannerStyled() string {
 	return "\n" + title + "\n" + subtitle + "\n"
 }
 
+// themeCount is the number of themes cycled with the T key
+const themeCount = 4
+
 // GetCurrentThemeIndex returns the index of the current theme
 func GetCurrentThemeIndex() int {
 	theme := os.Getenv("CCT_THEME")
@@ -20,6 +20,18 @@ type shutdownMsg struct {
 
 // Launch starts the TUI application with optional analytics server
 func Launch(targetDir string) error {
+	return LaunchWithTargets([]string{targetDir})
+}
+
+// LaunchWithTargets starts the TUI, installing selected components into every
+// directory in targetDirs. The first directory is used for everything else
+// (Claude directory, installation status, launching Claude).
+func LaunchWithTargets(targetDirs []string) error {
+	targetDir := "."
+	if len(targetDirs) > 0 {
+		targetDir = targetDirs[0]
+	}
+
 	// Get Claude directory
 	claudeDir := filepath.Join(os.Getenv("HOME"), ".claude")
 	if targetDir != "." && targetDir != "" {
@@ -29,7 +41,7 @@ func Launch(targetDir string) error {
 	// Start analytics server in background (enabled by default)
 	// Use quiet mode to suppress output when running in TUI (verbose=false)
 	var analyticsServer *server.Server
-	analyticsServer = server.NewServerWithOptions(claudeDir, 3333, true, false)
+	analyticsServer = server.NewServerWithOptions(claudeDir, "", 3333, true, false)
 	if err := analyticsServer.Setup(); err == nil {
 		// Start server in background goroutine
 		go func() {
@@ -52,6 +64,9 @@ func Launch(targetDir string) error {
 	for {
 		// Create the model with analytics server reference
 		m := NewModelWithServer(targetDir, claudeDir, analyticsServer)
+		if len(targetDirs) > 1 {
+			m.installDirs = targetDirs
+		}
 
 		// Update analytics enabled state based on server status
 		if m.analyticsServer == nil {
@@ -3,4 +3,30 @@ package version
 const (
 	Version = "0.7.1"
 	Name    = "claude-control-terminal"
-)
\ No newline at end of file
+)
+
+// Build metadata, overridden at build time with
+// -ldflags "-X github.com/schlunsen/claude-control-terminal/internal/version.Commit=<sha>
+// -X github.com/schlunsen/claude-control-terminal/internal/version.BuildDate=<date>"
+var (
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+// Info describes the running binary
+type Info struct {
+	Version   string `json:"version"`
+	Name      string `json:"name"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+}
+
+// Get returns the version and build metadata of the running binary
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Name:      Name,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+	}
+}
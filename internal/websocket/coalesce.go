@@ -0,0 +1,121 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// coalescer batches BroadcastData events of the same type that arrive within
+// a window into a single message whose data is an array of the individual
+// payloads:
+//
+//	{"event":"agent_tool_use","data":[...],"batched":true,"count":3,"timestamp":"..."}
+//
+// An event that arrives alone in its window is sent unchanged.
+type coalescer struct {
+	mu       sync.Mutex
+	window   time.Duration
+	events   map[string]bool // nil means all event types
+	prefixes []string
+	pending  map[string][]interface{}
+}
+
+// SetCoalescing enables batching of BroadcastData events within window.
+// events limits batching to the listed event types (a trailing * matches a
+// prefix); an empty list batches every event type. A window of 0 or less
+// disables batching. Events already queued are still flushed.
+func (h *Hub) SetCoalescing(window time.Duration, events []string) {
+	h.coalesce.mu.Lock()
+	defer h.coalesce.mu.Unlock()
+
+	h.coalesce.window = window
+	h.coalesce.events = nil
+	h.coalesce.prefixes = nil
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		switch {
+		case event == "":
+			continue
+		case strings.HasSuffix(event, "*"):
+			h.coalesce.prefixes = append(h.coalesce.prefixes, strings.TrimSuffix(event, "*"))
+		default:
+			if h.coalesce.events == nil {
+				h.coalesce.events = make(map[string]bool)
+			}
+			h.coalesce.events[event] = true
+		}
+	}
+}
+
+// enqueue queues data for a batched broadcast of eventType. It returns false
+// if the event type is not being coalesced and must be sent immediately.
+func (h *Hub) enqueue(eventType string, data interface{}) bool {
+	c := &h.coalesce
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.window <= 0 || !c.matches(eventType) {
+		return false
+	}
+
+	if c.pending == nil {
+		c.pending = make(map[string][]interface{})
+	}
+	queued, flushScheduled := c.pending[eventType]
+	c.pending[eventType] = append(queued, data)
+	if !flushScheduled {
+		time.AfterFunc(c.window, func() { h.flush(eventType) })
+	}
+	return true
+}
+
+// matches reports whether eventType is coalesced. Must be called with c.mu held.
+func (c *coalescer) matches(eventType string) bool {
+	if c.events == nil && len(c.prefixes) == 0 {
+		return true
+	}
+	if c.events[eventType] {
+		return true
+	}
+	for _, prefix := range c.prefixes {
+		if strings.HasPrefix(eventType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// flush broadcasts the events queued for eventType
+func (h *Hub) flush(eventType string) {
+	h.coalesce.mu.Lock()
+	queued := h.coalesce.pending[eventType]
+	delete(h.coalesce.pending, eventType)
+	h.coalesce.mu.Unlock()
+
+	switch len(queued) {
+	case 0:
+		return
+	case 1:
+		h.publishData(eventType, queued[0])
+		return
+	}
+
+	payload := map[string]interface{}{
+		"event":     eventType,
+		"data":      queued,
+		"batched":   true,
+		"count":     len(queued),
+		"timestamp": time.Now().Format(time.RFC3339),
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		fmt.Printf("Error marshaling WebSocket data: %v\n", err)
+		return
+	}
+
+	h.publish(eventType, jsonData)
+}
@@ -0,0 +1,126 @@
+package websocket
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+)
+
+// outboundMessage is a queued broadcast and the event type used to filter it
+type outboundMessage struct {
+	event string
+	data  []byte
+}
+
+// client is a connected WebSocket client and the event types it subscribed to.
+//
+// Clients receive every event until they send
+//
+//	{"type":"subscribe","events":["notification_recorded","agent_*"]}
+//
+// after which only the listed event types (a trailing * matches a prefix) are
+// delivered. An empty list or "*" subscribes to everything again. Messages
+// broadcast without an event type always reach every client.
+type client struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex // Serializes writes from the hub loop and the read loop
+
+	subMu    sync.RWMutex
+	events   map[string]bool // nil means all events
+	prefixes []string
+}
+
+// subscribeMessage is sent by a client to choose which event types it receives
+type subscribeMessage struct {
+	Type   string   `json:"type"`
+	Events []string `json:"events"`
+}
+
+func newClient(conn *websocket.Conn) *client {
+	return &client{conn: conn}
+}
+
+// wants reports whether the client is subscribed to the event type
+func (c *client) wants(event string) bool {
+	if event == "" {
+		return true
+	}
+
+	c.subMu.RLock()
+	defer c.subMu.RUnlock()
+
+	if c.events == nil {
+		return true
+	}
+	if c.events[event] {
+		return true
+	}
+	for _, prefix := range c.prefixes {
+		if strings.HasPrefix(event, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// subscribe replaces the client's subscriptions. An empty list or "*" means all events.
+// Returns the event types now subscribed to ("*" for all).
+func (c *client) subscribe(events []string) []string {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	c.events = make(map[string]bool, len(events))
+	c.prefixes = nil
+	subscribed := make([]string, 0, len(events))
+
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		switch {
+		case event == "":
+			continue
+		case event == "*":
+			c.events, c.prefixes = nil, nil
+			return []string{"*"}
+		case strings.HasSuffix(event, "*"):
+			c.prefixes = append(c.prefixes, strings.TrimSuffix(event, "*"))
+		default:
+			c.events[event] = true
+		}
+		subscribed = append(subscribed, event)
+	}
+
+	if len(subscribed) == 0 {
+		c.events = nil
+		return []string{"*"}
+	}
+	return subscribed
+}
+
+// handleMessage processes a message read from the client. Anything that is
+// not a subscription (keepalives, pings) is ignored.
+func (c *client) handleMessage(data []byte) error {
+	var msg subscribeMessage
+	if err := json.Unmarshal(data, &msg); err != nil || msg.Type != "subscribe" {
+		return nil
+	}
+
+	return c.writeJSON(fiber.Map{
+		"type":   "subscribed",
+		"events": c.subscribe(msg.Events),
+	})
+}
+
+func (c *client) write(data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (c *client) writeJSON(v interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteJSON(v)
+}
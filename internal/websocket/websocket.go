@@ -17,23 +17,24 @@ import (
 // Hub manages WebSocket connections and provides real-time updates to connected clients.
 // It is safe for concurrent use and supports graceful shutdown via context cancellation.
 type Hub struct {
-	clients    map[*websocket.Conn]bool
-	broadcast  chan []byte
-	register   chan *websocket.Conn
+	clients    map[*websocket.Conn]*client
+	broadcast  chan outboundMessage
+	register   chan *client
 	unregister chan *websocket.Conn
 	mutex      sync.RWMutex
 	ctx        context.Context
 	cancel     context.CancelFunc
 	done       chan struct{}
+	coalesce   coalescer // Optional batching of BroadcastData events, see SetCoalescing
 }
 
 // NewHub creates a new WebSocket hub with context support for graceful shutdown.
 func NewHub() *Hub {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Hub{
-		clients:    make(map[*websocket.Conn]bool),
-		broadcast:  make(chan []byte, 256),
-		register:   make(chan *websocket.Conn),
+		clients:    make(map[*websocket.Conn]*client),
+		broadcast:  make(chan outboundMessage, 256),
+		register:   make(chan *client),
 		unregister: make(chan *websocket.Conn),
 		ctx:        ctx,
 		cancel:     cancel,
@@ -54,23 +55,23 @@ func (h *Hub) Run() {
 		case <-h.ctx.Done():
 			// Graceful shutdown: close all clients
 			h.mutex.Lock()
-			for client := range h.clients {
-				client.Close()
+			for conn := range h.clients {
+				conn.Close()
 			}
-			h.clients = make(map[*websocket.Conn]bool)
+			h.clients = make(map[*websocket.Conn]*client)
 			h.mutex.Unlock()
 			return
 
-		case client := <-h.register:
+		case c := <-h.register:
 			h.mutex.Lock()
-			h.clients[client] = true
+			h.clients[c.conn] = c
 			h.mutex.Unlock()
 
-		case client := <-h.unregister:
+		case conn := <-h.unregister:
 			h.mutex.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				client.Close()
+			if _, ok := h.clients[conn]; ok {
+				delete(h.clients, conn)
+				conn.Close()
 			}
 			h.mutex.Unlock()
 
@@ -79,26 +80,35 @@ func (h *Hub) Run() {
 			failedClients = failedClients[:0]
 
 			h.mutex.RLock()
-			for client := range h.clients {
-				if err := client.WriteMessage(websocket.TextMessage, message); err != nil {
-					failedClients = append(failedClients, client)
+			for conn, c := range h.clients {
+				if !c.wants(message.event) {
+					continue
+				}
+				if err := c.write(message.data); err != nil {
+					failedClients = append(failedClients, conn)
 				}
 			}
 			h.mutex.RUnlock()
 
 			// Unregister failed clients after releasing the read lock
-			for _, client := range failedClients {
-				h.unregister <- client
+			for _, conn := range failedClients {
+				h.unregister <- conn
 			}
 		}
 	}
 }
 
-// Broadcast sends a message to all connected clients.
+// Broadcast sends a message to all connected clients, regardless of their subscriptions.
 // It is non-blocking and safe to call from multiple goroutines.
 func (h *Hub) Broadcast(message []byte) {
+	h.publish("", message)
+}
+
+// publish queues a message for every client subscribed to the event type.
+// An empty event type reaches all clients.
+func (h *Hub) publish(event string, message []byte) {
 	select {
-	case h.broadcast <- message:
+	case h.broadcast <- outboundMessage{event: event, data: message}:
 	case <-h.ctx.Done():
 		// Hub is shutting down, ignore broadcast
 	default:
@@ -134,16 +144,17 @@ func (h *Hub) HandleWebSocket() func(*websocket.Conn) {
 			}
 		}()
 
-		// Register the client
+		// Register the client (subscribed to all events until it says otherwise)
+		cl := newClient(c)
 		select {
-		case h.register <- c:
+		case h.register <- cl:
 		case <-h.ctx.Done():
 			// Hub is shutting down, close connection
 			return
 		}
 
 		// Send initial welcome message (ignore errors on shutdown)
-		if err := c.WriteJSON(fiber.Map{
+		if err := cl.writeJSON(fiber.Map{
 			"type":    "connected",
 			"message": "WebSocket connected",
 			"time":    time.Now(),
@@ -151,16 +162,19 @@ func (h *Hub) HandleWebSocket() func(*websocket.Conn) {
 			return
 		}
 
-		// Read messages from client (mostly for keepalive and ping/pong)
+		// Read messages from client (keepalive, ping/pong and subscriptions)
 		for {
 			select {
 			case <-h.ctx.Done():
 				return
 			default:
-				_, _, err := c.ReadMessage()
+				_, data, err := c.ReadMessage()
 				if err != nil {
 					return
 				}
+				if err := cl.handleMessage(data); err != nil {
+					return
+				}
 			}
 		}
 	}
@@ -174,13 +188,23 @@ func (h *Hub) SendUpdate(updateType string, data interface{}) {
 	// In production, consider proper JSON marshaling with encoding/json
 	_ = data
 	message := []byte(fmt.Sprintf(`{"type":"%s","time":"%s"}`, updateType, time.Now().Format(time.RFC3339)))
-	h.Broadcast(message)
+	h.publish(updateType, message)
 }
 
 // BroadcastData sends a structured event with data payload to all connected clients.
 // It marshals the data into JSON and includes the event type and timestamp.
 // This is the preferred method for sending real-time updates with actual data.
+// When coalescing is enabled for the event type, the event is batched instead
+// (see SetCoalescing).
 func (h *Hub) BroadcastData(eventType string, data interface{}) {
+	if h.enqueue(eventType, data) {
+		return
+	}
+	h.publishData(eventType, data)
+}
+
+// publishData marshals a single event and queues it for broadcast
+func (h *Hub) publishData(eventType string, data interface{}) {
 	payload := map[string]interface{}{
 		"event":     eventType,
 		"data":      data,
@@ -194,5 +218,5 @@ func (h *Hub) BroadcastData(eventType string, data interface{}) {
 		return
 	}
 
-	h.Broadcast(jsonData)
+	h.publish(eventType, jsonData)
 }
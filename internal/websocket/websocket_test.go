@@ -1,6 +1,8 @@
 package websocket
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 )
@@ -380,3 +382,96 @@ func BenchmarkHub_SendUpdate(b *testing.B) {
 	}
 }
 
+func TestClient_Subscriptions(t *testing.T) {
+	c := newClient(nil)
+
+	// New clients receive everything
+	if !c.wants("command_recorded") || !c.wants("notification_recorded") {
+		t.Error("new client should receive all events")
+	}
+
+	subscribed := c.subscribe([]string{"notification_recorded", "agent_*", " "})
+	if len(subscribed) != 2 {
+		t.Errorf("subscribe() = %v, want 2 entries", subscribed)
+	}
+
+	tests := []struct {
+		event string
+		want  bool
+	}{
+		{"notification_recorded", true},
+		{"agent_session_killed", true},
+		{"command_recorded", false},
+		{"", true}, // untyped broadcasts reach everyone
+	}
+	for _, tt := range tests {
+		if got := c.wants(tt.event); got != tt.want {
+			t.Errorf("wants(%q) = %v, want %v", tt.event, got, tt.want)
+		}
+	}
+
+	// An empty list or "*" resubscribes to everything
+	for _, events := range [][]string{nil, {"*"}} {
+		c.subscribe([]string{"notification_recorded"})
+		if got := c.subscribe(events); len(got) != 1 || got[0] != "*" {
+			t.Errorf("subscribe(%v) = %v, want [*]", events, got)
+		}
+		if !c.wants("command_recorded") {
+			t.Errorf("subscribe(%v) should restore all events", events)
+		}
+	}
+}
+
+func TestHub_Coalescing(t *testing.T) {
+	hub := NewHub()
+	defer hub.cancel()
+
+	hub.SetCoalescing(20*time.Millisecond, []string{"agent_*"})
+
+	for i := 0; i < 3; i++ {
+		hub.BroadcastData("agent_tool_use", map[string]int{"n": i})
+	}
+	hub.BroadcastData("command_recorded", "immediate")
+
+	// Event types that are not coalesced go out right away
+	select {
+	case msg := <-hub.broadcast:
+		if msg.event != "command_recorded" {
+			t.Fatalf("expected command_recorded first, got %s", msg.event)
+		}
+	case <-time.After(10 * time.Millisecond):
+		t.Fatal("expected uncoalesced event to be sent immediately")
+	}
+
+	select {
+	case msg := <-hub.broadcast:
+		var payload struct {
+			Event   string           `json:"event"`
+			Data    []map[string]int `json:"data"`
+			Batched bool             `json:"batched"`
+			Count   int              `json:"count"`
+		}
+		if err := json.Unmarshal(msg.data, &payload); err != nil {
+			t.Fatalf("failed to decode batch: %v", err)
+		}
+		if payload.Event != "agent_tool_use" || !payload.Batched || payload.Count != 3 || len(payload.Data) != 3 {
+			t.Errorf("unexpected batch: %+v", payload)
+		}
+		if payload.Data[2]["n"] != 2 {
+			t.Errorf("expected events in order, got %v", payload.Data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected batched event after the window")
+	}
+
+	// A single event in a window is sent unbatched
+	hub.BroadcastData("agent_tool_use", "solo")
+	select {
+	case msg := <-hub.broadcast:
+		if strings.Contains(string(msg.data), `"batched"`) {
+			t.Errorf("expected single event to be unbatched, got %s", msg.data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected single event after the window")
+	}
+}